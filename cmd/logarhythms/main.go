@@ -1,20 +1,547 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
+	"io"
+	"net"
+	"net/http"
 	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
 
+	"github.com/pkg/errors"
+
+	"github.com/jcfox412/logarhythms/internal/audio"
+	"github.com/jcfox412/logarhythms/internal/clocksync"
+	"github.com/jcfox412/logarhythms/internal/events"
 	"github.com/jcfox412/logarhythms/internal/input"
+	"github.com/jcfox412/logarhythms/internal/midi"
+	"github.com/jcfox412/logarhythms/internal/models"
+	"github.com/jcfox412/logarhythms/internal/render"
+	"github.com/jcfox412/logarhythms/internal/server"
+)
+
+const (
+	// Exit code used when interrupted, as shells do
+	interruptedExitCode = 130
+	// Time given to a playing track to stop cleanly once interrupted. The menus
+	// can't be interrupted while they wait for input, so they're given up on.
+	interruptTimeout = time.Second
+	// Range of beats per minute tracks can be played at
+	minBeatsPerMinute = 1
+	maxBeatsPerMinute = 1000
+	// Synth voice the metronome clicks with while recording
+	metronomeVoice = "rimshot"
+	midiOutUsage   = "MIDI port instruments' notes are sent to, a raw MIDI device such as /dev/snd/midiC1D0 " +
+		"or an ALSA sequencer port such as 20:0 (or set LOGARHYTHMS_MIDI_OUT)"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "serve" {
+		serve(os.Args[2:])
+		return
+	}
+
+	kitFilename := flag.String("kit", "", "kit file to play every track on, e.g. assets/kits/acoustic.json")
+	output := flag.String("output", envOrDefault("LOGARHYTHMS_OUTPUT", audio.SpeakerBackend),
+		fmt.Sprintf("where audio is played, one of %s (or set LOGARHYTHMS_OUTPUT)", strings.Join(audio.Backends, ", ")))
+	outputFilename := flag.String("output-file", envOrDefault("LOGARHYTHMS_OUTPUT_FILE", "logarhythms.wav"),
+		"file the wav output writes to (or set LOGARHYTHMS_OUTPUT_FILE)")
+	beatsPerMinute := flag.Float64("bpm", 0, "beats per minute to play every track at, which can be fractional, e.g. 92.5, or 0 for each track's own")
+	bars := flag.Int("bars", 0, "number of bars to play every track for")
+	loops := flag.Int("loops", 0, "number of times to play every track's pattern, the same as -bars")
+	duration := flag.Duration("duration", 0, "time to play every track for, rounded up to the end of the bar, e.g. 5m")
+	forever := flag.Bool("forever", false, "play every track until stopped")
+	display := flag.String("display", envOrDefault("LOGARHYTHMS_DISPLAY", render.Auto),
+		fmt.Sprintf("how tracks are drawn as they play, one of %s (or set LOGARHYTHMS_DISPLAY)", strings.Join(render.Renderers, ", ")))
+	eventsFilename := flag.String("events", "", "file the events of each track are written to as newline-delimited JSON as it plays")
+	eventsAddr := flag.String("events-addr", "", "address the events of each track are streamed from over a WebSocket at /events, e.g. localhost:8081")
+	midiOut := flag.String("midi-out", envOrDefault("LOGARHYTHMS_MIDI_OUT", ""), midiOutUsage)
+	recordMode := flag.String("record-mode", models.Overdub.String(),
+		fmt.Sprintf("how instruments recorded during playback combine with their patterns, %s or %s", models.Overdub, models.Replace))
+	quantise := flag.Float64("quantise", 1, "how far instruments recorded during playback are moved onto the nearest beat subdivision, from 0 (not at all) to 1 (fully)")
+	recordLatency := flag.Duration("record-latency", 0, "delay between pressing a key and hearing it, taken off instruments recorded during playback, e.g. 30ms")
+	clockOptions := addClockFlags(flag.CommandLine)
+	flag.Parse()
+
+	if err := setBackend(*output, *outputFilename); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	if *beatsPerMinute != 0 && !(*beatsPerMinute >= minBeatsPerMinute && *beatsPerMinute <= maxBeatsPerMinute) {
+		fmt.Printf("-bpm must be between %d and %d\n", minBeatsPerMinute, maxBeatsPerMinute)
+		os.Exit(1)
+	}
+
+	length, err := trackLength(*bars, *loops, *duration, *forever)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	renderer, err := render.New(*display, os.Stdout)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	eventRenderers, eventClosers, err := streamEvents(*eventsFilename, *eventsAddr)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	externalClock, clockRenderers, err := clockOptions.open()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	midiPort, err := openMIDI(*midiOut)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	record, err := recordSettings(*recordMode, *quantise, *recordLatency)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	renderers := append([]render.Renderer{renderer}, eventRenderers...)
+	renderer = render.Multi(append(renderers, clockRenderers...)...)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go handleSignals(cancel, eventClosers...)
 
 	userInput := input.UserInput{
-		Reader: os.Stdin,
+		Reader:         os.Stdin,
+		KitFilename:    *kitFilename,
+		BeatsPerMinute: *beatsPerMinute,
+		Length:         length,
+		Context:        ctx,
+		Renderer:       renderer,
+		Sync:           externalClock,
+		MIDI:           midiPort,
+		Record:         record,
+	}
+
+	// instruments can only be played along with tracks from a terminal
+	if keyboard, err := input.NewKeyboard(os.Stdin); err == nil {
+		userInput.Keyboard = keyboard
 	}
 
-	if err := userInput.PrintMainMenu(); err != nil {
+	exit(userInput.PrintMainMenu(), eventClosers...)
+}
+
+// serve plays tracks from the library as they're controlled over HTTP, and
+// OSC if it's listened for, until SIGINT or SIGTERM.
+func serve(args []string) {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := flags.String("addr", envOrDefault("LOGARHYTHMS_ADDR", "localhost:8080"),
+		"address the API listens on, e.g. :8080 (or set LOGARHYTHMS_ADDR)")
+	library := flags.String("library", "assets/tracks", "directory of tracks that can be loaded")
+	kitFilename := flags.String("kit", "", "kit file to play every track on, e.g. assets/kits/acoustic.json")
+	output := flags.String("output", envOrDefault("LOGARHYTHMS_OUTPUT", audio.SpeakerBackend),
+		fmt.Sprintf("where audio is played, one of %s (or set LOGARHYTHMS_OUTPUT)", strings.Join(audio.Backends, ", ")))
+	outputFilename := flags.String("output-file", envOrDefault("LOGARHYTHMS_OUTPUT_FILE", "logarhythms.wav"),
+		"file the wav output writes to (or set LOGARHYTHMS_OUTPUT_FILE)")
+	oscAddr := flags.String("osc-addr", envOrDefault("LOGARHYTHMS_OSC_ADDR", ""),
+		"UDP address OSC messages are received on, e.g. :9000, or empty for none (or set LOGARHYTHMS_OSC_ADDR)")
+	oscFeedback := flags.String("osc-feedback", envOrDefault("LOGARHYTHMS_OSC_FEEDBACK", ""),
+		"UDP address OSC feedback is sent to, e.g. 192.168.1.20:9001 (or set LOGARHYTHMS_OSC_FEEDBACK)")
+	midiOut := flags.String("midi-out", envOrDefault("LOGARHYTHMS_MIDI_OUT", ""), midiOutUsage)
+	clockOptions := addClockFlags(flags)
+	_ = flags.Parse(args)
+
+	if err := setBackend(*output, *outputFilename); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	externalClock, clockRenderers, err := clockOptions.open()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	midiPort, err := openMIDI(*midiOut)
+	if err != nil {
 		fmt.Println(err)
 		os.Exit(1)
 	}
+
+	s := server.New(*library, *kitFilename)
+	s.Sync = externalClock
+	s.MIDI = midiPort
+	if len(clockRenderers) > 0 {
+		s.Renderer = render.Multi(clockRenderers...)
+	}
+
+	httpServer := &http.Server{Addr: *addr, Handler: s}
+
+	var oscConn net.PacketConn
+	if *oscAddr != "" {
+		var err error
+		if oscConn, err = listenOSC(s, *oscAddr, *oscFeedback); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("Listening for OSC on %s\n", oscConn.LocalAddr())
+	}
+
+	go func() {
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+		<-signals
+
+		ctx, cancel := context.WithTimeout(context.Background(), interruptTimeout)
+		defer cancel()
+
+		_ = httpServer.Shutdown(ctx)
+	}()
+
+	fmt.Printf("Listening on %s\n", *addr)
+
+	err = httpServer.ListenAndServe()
+	if errors.Is(err, http.ErrServerClosed) {
+		err = nil
+	}
+
+	if oscConn != nil {
+		_ = oscConn.Close()
+	}
+
+	if closeErr := s.Close(); err == nil {
+		err = closeErr
+	}
+
+	exit(err)
+}
+
+// listenOSC starts the server receiving OSC messages at addr, sending feedback
+// to the feedback address unless it's empty. Closing the returned connection
+// stops it.
+func listenOSC(s *server.Server, addr, feedback string) (net.PacketConn, error) {
+	var feedbackAddr net.Addr
+	if feedback != "" {
+		udpAddr, err := net.ResolveUDPAddr("udp", feedback)
+		if err != nil {
+			return nil, errors.Wrap(err, "error resolving OSC feedback address")
+		}
+
+		feedbackAddr = udpAddr
+	}
+
+	conn, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return nil, errors.Wrap(err, "error listening for OSC")
+	}
+
+	go func() {
+		_ = s.ServeOSC(conn, feedbackAddr)
+	}()
+
+	return conn, nil
+}
+
+// Clock sources tracks can be played by
+const (
+	// Each track's own BPM
+	internalClock = "internal"
+	// MIDI clock received from a device
+	midiClock = "midi"
+	// Beats sent over the network by another instance
+	netClock = "net"
+)
+
+// clockFlags choose the clock tracks follow, and where their clock is sent.
+type clockFlags struct {
+	source    *string
+	midiIn    *string
+	midiOut   *string
+	netListen *string
+	netSend   *string
+}
+
+func addClockFlags(flags *flag.FlagSet) clockFlags {
+	return clockFlags{
+		source: flags.String("clock", envOrDefault("LOGARHYTHMS_CLOCK", internalClock),
+			fmt.Sprintf("clock tracks follow, one of %s, %s or %s (or set LOGARHYTHMS_CLOCK)", internalClock, midiClock, netClock)),
+		midiIn: flags.String("midi-clock-in", envOrDefault("LOGARHYTHMS_MIDI_CLOCK_IN", ""),
+			"MIDI port MIDI clock is followed from with -clock midi, e.g. /dev/snd/midiC1D0 or 20:0 (or set LOGARHYTHMS_MIDI_CLOCK_IN)"),
+		midiOut: flags.String("midi-clock-out", envOrDefault("LOGARHYTHMS_MIDI_CLOCK_OUT", ""),
+			"MIDI port MIDI clock is sent to as tracks play, e.g. /dev/snd/midiC1D0 or 20:0 (or set LOGARHYTHMS_MIDI_CLOCK_OUT)"),
+		netListen: flags.String("net-clock-listen", envOrDefault("LOGARHYTHMS_NET_CLOCK_LISTEN", ":9100"),
+			"UDP address beats are followed from with -clock net (or set LOGARHYTHMS_NET_CLOCK_LISTEN)"),
+		netSend: flags.String("net-clock-send", envOrDefault("LOGARHYTHMS_NET_CLOCK_SEND", ""),
+			"UDP address beats are sent to as tracks play, e.g. 192.168.1.255:9100 (or set LOGARHYTHMS_NET_CLOCK_SEND)"),
+	}
+}
+
+// open returns the external clock tracks follow, or nil to play them at their
+// own BPM, and renderers which send the clock of tracks as they play.
+func (f clockFlags) open() (models.Sync, []render.Renderer, error) {
+	var (
+		externalClock models.Sync
+		renderers     []render.Renderer
+	)
+
+	switch *f.source {
+	case internalClock:
+	case midiClock:
+		if *f.midiIn == "" {
+			return nil, nil, errors.New("-midi-clock-in must be set to follow MIDI clock")
+		}
+
+		port, err := openMIDI(*f.midiIn)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		externalClock = clocksync.NewMIDIFollower(port, nil)
+	case netClock:
+		conn, err := net.ListenPacket("udp", *f.netListen)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "error listening for beats")
+		}
+
+		follower := clocksync.NewNetFollower(conn, nil)
+		go func() {
+			for err := range follower.Errors() {
+				fmt.Fprintln(os.Stderr, err)
+			}
+		}()
+
+		externalClock = follower
+	default:
+		return nil, nil, fmt.Errorf("unknown clock %q, expected one of %s, %s or %s", *f.source, internalClock, midiClock, netClock)
+	}
+
+	if *f.midiOut != "" {
+		port, err := openMIDI(*f.midiOut)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		renderers = append(renderers, clocksync.NewMIDISender(port, nil))
+	}
+
+	if *f.netSend != "" {
+		addr, err := net.ResolveUDPAddr("udp", *f.netSend)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "error resolving beat address")
+		}
+
+		conn, err := net.ListenPacket("udp", ":0")
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "error sending beats")
+		}
+
+		renderers = append(renderers, clocksync.NewNetSender(conn, addr))
+	}
+
+	return externalClock, renderers, nil
+}
+
+// recordSettings returns how instruments played during playback are recorded,
+// with a metronome to play along to. Returns an error if the settings are
+// invalid.
+func recordSettings(mode string, strength float64, latency time.Duration) (models.RecordSettings, error) {
+	recordMode, err := models.ParseRecordMode(mode)
+	if err != nil {
+		return models.RecordSettings{}, err
+	}
+
+	params, err := audio.DefaultSynthParams(metronomeVoice)
+	if err != nil {
+		return models.RecordSettings{}, err
+	}
+
+	metronome, err := audio.NewSynth(metronomeVoice, params)
+	if err != nil {
+		return models.RecordSettings{}, errors.Wrap(err, "error creating metronome")
+	}
+
+	settings := models.RecordSettings{Mode: recordMode, Strength: strength, Latency: latency, Metronome: metronome}
+
+	return settings, settings.Validate()
+}
+
+// MIDI ports opened, by name, so that notes and clock can share a port
+var midiPorts = map[string]midi.Port{}
+
+// openMIDI opens the MIDI port with the given name, or returns nil if it's
+// empty.
+func openMIDI(name string) (midi.Port, error) {
+	if name == "" {
+		return nil, nil
+	}
+
+	if port, ok := midiPorts[name]; ok {
+		return port, nil
+	}
+
+	port, err := midi.Open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	midiPorts[name] = port
+
+	return port, nil
+}
+
+// streamEvents returns renderers which write the events of each track played
+// to the file, and stream them over a WebSocket served at addr, and the files
+// to close once they're finished with. Either is skipped if empty.
+func streamEvents(filename, addr string) ([]render.Renderer, []io.Closer, error) {
+	var (
+		renderers []render.Renderer
+		closers   []io.Closer
+	)
+
+	if filename != "" {
+		f, err := os.Create(filename)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "error creating events file")
+		}
+
+		renderers = append(renderers, events.NewNDJSON(f, nil))
+		closers = append(closers, f)
+	}
+
+	if addr != "" {
+		listener, err := net.Listen("tcp", addr)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "error listening for events")
+		}
+
+		hub := events.NewHub()
+		mux := http.NewServeMux()
+		mux.Handle("/events", hub)
+
+		go func() {
+			_ = http.Serve(listener, mux)
+		}()
+
+		renderers = append(renderers, events.NewRenderer(hub.Publish, nil))
+	}
+
+	return renderers, closers, nil
+}
+
+// handleSignals stops any playing track on SIGINT or SIGTERM, exiting if the
+// menus don't return in time, closing the closers as it does.
+func handleSignals(cancel context.CancelFunc, closers ...io.Closer) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	<-signals
+	cancel()
+
+	time.Sleep(interruptTimeout)
+	exit(context.Canceled, closers...)
+}
+
+// exiting ensures only the first call to exit shuts down, so that an interrupt
+// racing the menus returning can't close anything twice.
+var exiting sync.Once
+
+// exit closes the audio backend, which finishes the wav output, the closers
+// and the MIDI ports opened, and exits with a code reflecting err. Calls made
+// while another is exiting wait for it to, without closing anything.
+func exit(err error, closers ...io.Closer) {
+	exiting.Do(func() {
+		if closeErr := audio.Close(); err == nil {
+			err = closeErr
+		}
+
+		for _, closer := range closers {
+			if closeErr := closer.Close(); err == nil {
+				err = closeErr
+			}
+		}
+
+		for name, port := range midiPorts {
+			if closeErr := port.Close(); err == nil && closeErr != nil {
+				err = errors.Wrapf(closeErr, "error closing MIDI port %s", name)
+			}
+		}
+
+		switch {
+		case err == nil:
+			os.Exit(0)
+		case errors.Is(err, context.Canceled):
+			fmt.Println("Interrupted")
+			os.Exit(interruptedExitCode)
+		default:
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	})
+}
+
+// trackLength returns the length set by the length flags, or nil if none
+// were set. A pattern lasts a bar, so loops are played as bars. Returns an
+// error if more than one was set, or the length is invalid.
+func trackLength(bars, loops int, duration time.Duration, forever bool) (*models.Length, error) {
+	var lengths []models.Length
+
+	if bars != 0 {
+		lengths = append(lengths, models.Bars(bars))
+	}
+
+	if loops != 0 {
+		lengths = append(lengths, models.Bars(loops))
+	}
+
+	if duration != 0 {
+		lengths = append(lengths, models.Duration(duration))
+	}
+
+	if forever {
+		lengths = append(lengths, models.Infinite())
+	}
+
+	switch len(lengths) {
+	case 0:
+		return nil, nil
+	case 1:
+		if err := lengths[0].Validate(); err != nil {
+			return nil, err
+		}
+
+		return &lengths[0], nil
+	default:
+		return nil, errors.New("only one of -bars, -loops, -duration and -forever can be set")
+	}
+}
+
+func setBackend(name, filename string) error {
+	backend, err := audio.NewBackend(name, filename)
+	if err != nil {
+		return err
+	}
+
+	return audio.SetBackend(backend)
+}
+
+func envOrDefault(key, defaultValue string) string {
+	if value, ok := os.LookupEnv(key); ok {
+		return value
+	}
+
+	return defaultValue
 }
@@ -1,9 +1,10 @@
 module github.com/jcfox412/logarhythms
 
-go 1.14
+go 1.16
 
 require (
 	github.com/faiface/beep v1.0.2
+	github.com/gorilla/websocket v1.5.3
 	github.com/pkg/errors v0.9.1
 	github.com/stretchr/testify v1.6.1
 )
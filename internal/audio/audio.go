@@ -1,40 +1,81 @@
 package audio
 
 import (
-	"os"
-	"time"
+	"fmt"
+	"math"
+	"sync"
 
 	"github.com/faiface/beep"
 	"github.com/faiface/beep/effects"
-	"github.com/faiface/beep/speaker"
-	"github.com/faiface/beep/wav"
 	"github.com/pkg/errors"
 )
 
+// Sample rate all audio is played at. Samples recorded at other rates are
+// resampled when played.
+const sampleRate beep.SampleRate = 44100
+
 // Manager allows for mocking, which allows for easier testing of other packages.
 type Manager interface {
 	GetVolume() float64
 	SetVolume(float64) (float64, error)
-	Play()
+	GetPan() float64
+	SetPan(float64) error
+	GetChokeGroup() int
+	SetChokeGroup(int) error
+	GetSampleParams() SampleParams
+	SetSampleParams(SampleParams) error
+	GetEffects() []Effect
+	SetEffects([]Effect) error
+	GetSends() Sends
+	SetSends(Sends) error
+	Play(velocity float64)
+}
+
+// Metered is audio whose level can be measured as it plays, for drawing level
+// meters.
+type Metered interface {
+	// Level fetches the peak level of the audio played, from 0 to 1
+	Level() float64
 }
 
 // BeepManager manages audio state and functionality using the beep library.
+// It's safe to change while it plays.
 type BeepManager struct {
+	mu sync.RWMutex
 	// Volume of the audio object. Valid between -5 and 5. See
 	// https://godoc.org/github.com/faiface/beep/effects#Volume for full details
 	// of how Volume works, but essentially input signal is multiplied by
 	// math.Pow(2, Volume).
 	volume float64
+	// Stereo position of the audio object, from -1 (left) to 1 (right)
+	pan float64
 	// Buffer of audio data so file doesn't need to be opened every time it's played.
 	buffer *beep.Buffer
+	// Part of buffer selected by params, ready to be played
+	sample *beep.Buffer
+	// Controls which part of buffer is played, and how
+	params SampleParams
+	// Choke group of the audio object. Playing audio cuts off any audio still
+	// sounding in the same choke group. 0 means the audio is in no choke group.
+	chokeGroup int
+	// Insert effects applied to the audio, in order
+	effects []Effect
+	// How much of the audio is sent to the shared reverb and delay
+	sends Sends
+	// Follows the level of the audio played
+	meter meter
 }
 
-var _ Manager = new(BeepManager)
+var (
+	_ Manager = new(BeepManager)
+	_ Metered = new(BeepManager)
+)
 
-// New creates a new audio Manager from the given wav filename. Returns an error
-// if the file cannot be found or is not decodeable in the wav format.
-func New(wavFilename string) (Manager, error) {
-	buffer, err := setupSound(wavFilename)
+// New creates a new audio Manager from the given sound filename. WAV, FLAC,
+// MP3, OGG Vorbis and AIFF files are supported. Returns an error if the file
+// cannot be found or is not decodeable in any supported format.
+func New(filename string) (Manager, error) {
+	buffer, err := setupSound(filename)
 	if err != nil {
 		return nil, err
 	}
@@ -42,16 +83,23 @@ func New(wavFilename string) (Manager, error) {
 	return &BeepManager{
 		volume: 0,
 		buffer: buffer,
+		sample: buffer,
 	}, nil
 }
 
 // GetVolume fetches the Manager's scaled volume, from 0 to 100.
 func (m *BeepManager) GetVolume() float64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
 	return toScaledVolume(m.volume)
 }
 
 // SetVolume sets the Manager's volume. Accepts values between 0 and 100.
 func (m *BeepManager) SetVolume(volume float64) (float64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	if volume < 0 || volume > 100 {
 		return volume, errors.New("volume must be between 0 and 100")
 	}
@@ -61,38 +109,186 @@ func (m *BeepManager) SetVolume(volume float64) (float64, error) {
 	return toScaledVolume(m.volume), nil
 }
 
-// Play triggers audio to be played through the computer's default speaker.
-// NOTE: this function is not tested, as adding testing would be more hassle
-// than it's worth for a homework project.
-func (m *BeepManager) Play() {
-	speaker.Play(&effects.Volume{
-		Streamer: m.buffer.Streamer(0, m.buffer.Len()),
-		Base:     2,
-		Volume:   m.volume,
-		Silent:   false,
-	})
+// GetPan fetches the Manager's stereo position, from -1 (left) to 1 (right).
+func (m *BeepManager) GetPan() float64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.pan
 }
 
-func setupSound(wavFilename string) (*beep.Buffer, error) {
-	f, err := os.Open(wavFilename)
-	if err != nil {
-		return nil, errors.Wrap(err, "error opening sound file")
+// SetPan sets the Manager's stereo position. Accepts values between -1 (left)
+// and 1 (right), with 0 being centered.
+func (m *BeepManager) SetPan(pan float64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if pan < -1 || pan > 1 {
+		return errors.New("pan must be between -1 and 1")
 	}
 
-	defer f.Close()
+	m.pan = pan
+
+	return nil
+}
+
+// GetChokeGroup fetches the Manager's choke group, or 0 if it has none.
+func (m *BeepManager) GetChokeGroup() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.chokeGroup
+}
+
+// SetChokeGroup sets the Manager's choke group. 0 removes the Manager from any
+// choke group.
+func (m *BeepManager) SetChokeGroup(group int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if group < 0 {
+		return errors.New("choke group must not be negative")
+	}
 
-	streamer, format, err := wav.Decode(f)
+	m.chokeGroup = group
+
+	return nil
+}
+
+// GetSampleParams fetches the parameters controlling how the Manager's sample
+// is played.
+func (m *BeepManager) GetSampleParams() SampleParams {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.params
+}
+
+// SetSampleParams sets the parameters controlling how the Manager's sample is
+// played. Returns an error if the parameters are invalid.
+func (m *BeepManager) SetSampleParams(params SampleParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := params.validate(); err != nil {
+		return err
+	}
+
+	m.params = params
+
+	if m.buffer != nil {
+		m.sample = prepareSample(m.buffer, params)
+	}
+
+	return nil
+}
+
+// GetEffects fetches the Manager's effects chain.
+func (m *BeepManager) GetEffects() []Effect {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return append([]Effect(nil), m.effects...)
+}
+
+// SetEffects sets the Manager's effects chain, which is applied in order every
+// time the Manager is played. Returns an error if any effect is invalid.
+func (m *BeepManager) SetEffects(effects []Effect) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i, effect := range effects {
+		if err := effect.validate(); err != nil {
+			return errors.Wrap(err, fmt.Sprintf("error validating effect %d", i+1))
+		}
+	}
+
+	m.effects = append([]Effect(nil), effects...)
+
+	return nil
+}
+
+// GetSends fetches how much of the Manager's audio is sent to the shared
+// reverb and delay.
+func (m *BeepManager) GetSends() Sends {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.sends
+}
+
+// SetSends sets how much of the Manager's audio is sent to the shared reverb
+// and delay. Returns an error if either send level isn't between 0 and 1.
+func (m *BeepManager) SetSends(sends Sends) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := sends.validate(); err != nil {
+		return err
+	}
+
+	m.sends = sends
+
+	return nil
+}
+
+// Play triggers audio to be played through the backend. Velocity scales the
+// hit's amplitude, from 0 (silent) to 1 (full volume).
+func (m *BeepManager) Play(velocity float64) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var streamer beep.Streamer = m.sample.Streamer(0, m.sample.Len())
+
+	ratio := m.params.pitchRatio() * float64(m.sample.Format().SampleRate) / float64(sampleRate)
+	if ratio != 1 {
+		streamer = beep.ResampleRatio(resampleQuality, ratio, streamer)
+	}
+
+	if m.params.Envelope != nil {
+		streamer = newEnvelope(streamer, m.params.Envelope, sampleRate)
+	}
+
+	streamer = applyEffects(streamer, m.effects, sampleRate)
+
+	if m.pan != 0 {
+		streamer = &effects.Pan{Streamer: streamer, Pan: m.pan}
+	}
+
+	v := newVoice(&effects.Volume{
+		Streamer: streamer,
+		Base:     2,
+		Volume:   m.volume + velocityToVolume(velocity),
+		Silent:   velocity <= 0,
+	}, sampleRate.N(chokeFadeDuration))
+
+	if m.chokeGroup != 0 {
+		chokes.add(m.chokeGroup, v)
+	}
+
+	bus.add(v, m.sends, &m.meter)
+}
+
+// Level fetches the peak level of the audio the Manager has played, falling
+// back towards 0 once it stops.
+func (m *BeepManager) Level() float64 {
+	return bus.level(&m.meter)
+}
+
+func setupSound(filename string) (*beep.Buffer, error) {
+	f, streamer, format, err := openSound(filename)
 	if err != nil {
-		return nil, errors.Wrap(err, "error decoding sound file")
+		return nil, err
 	}
 
-	// 40 found to sound best through experimentation
-	speaker.Init(format.SampleRate, format.SampleRate.N(time.Second/40))
+	defer f.Close()
 
 	buffer := beep.NewBuffer(format)
 	buffer.Append(streamer)
 
-	streamer.Close()
+	if err := streamer.Err(); err != nil {
+		return nil, errors.Wrap(err, "error reading sound file")
+	}
 
 	return buffer, nil
 }
@@ -104,3 +300,13 @@ func toScaledVolume(volume float64) float64 {
 func toUnscaledVolume(volume float64) float64 {
 	return (volume / 10) - 5
 }
+
+// velocityToVolume converts a velocity between 0 and 1 into an unscaled volume
+// offset, so that a velocity of 0.5 halves the amplitude of a hit.
+func velocityToVolume(velocity float64) float64 {
+	if velocity <= 0 {
+		return 0
+	}
+
+	return math.Log2(math.Min(velocity, 1))
+}
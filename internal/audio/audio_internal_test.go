@@ -71,3 +71,60 @@ func TestToUnscaledVolume(t *testing.T) {
 		assert.Equal(t, testCase.expectedOutput, actualOutput)
 	}
 }
+
+func TestVelocityToVolume(t *testing.T) {
+	type testCase struct {
+		description    string
+		input          float64
+		expectedOutput float64
+	}
+
+	testCases := []testCase{
+		{
+			description:    "leaves full velocity unchanged",
+			input:          1,
+			expectedOutput: 0,
+		},
+		{
+			description:    "halves amplitude at half velocity",
+			input:          0.5,
+			expectedOutput: -1,
+		},
+		{
+			description:    "clamps velocity above 1",
+			input:          2,
+			expectedOutput: 0,
+		},
+		{
+			description:    "handles silent velocity",
+			input:          0,
+			expectedOutput: 0,
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		actualOutput := velocityToVolume(testCase.input)
+		assert.Equal(t, testCase.expectedOutput, actualOutput)
+	}
+}
+
+func TestPlay(t *testing.T) {
+	m, err := New("testfiles/valid.wav")
+	assert.Nil(t, err)
+	assert.Nil(t, m.SetSends(Sends{Reverb: 0.5}))
+
+	bus.mu.Lock()
+	playing := len(bus.channels)
+	bus.mu.Unlock()
+
+	m.Play(1)
+
+	bus.mu.Lock()
+	defer bus.mu.Unlock()
+
+	assert.Len(t, bus.channels, playing+1)
+	assert.Equal(t, Sends{Reverb: 0.5}, bus.channels[playing].sends)
+	assert.Same(t, &m.(*BeepManager).meter, bus.channels[playing].meter)
+}
@@ -2,6 +2,7 @@ package audio_test
 
 import (
 	"testing"
+	"time"
 
 	"github.com/jcfox412/logarhythms/internal/audio"
 	"github.com/stretchr/testify/assert"
@@ -61,13 +62,230 @@ func TestSetGetVolume(t *testing.T) {
 	}
 }
 
-func TestNew(t *testing.T) {
+func TestSetGetPan(t *testing.T) {
+	type testCase struct {
+		description     string
+		input           float64
+		expectedOutput  float64
+		expectedToError bool
+	}
+
+	testCases := []testCase{
+		{
+			description:     "Pans hard left",
+			input:           -1,
+			expectedOutput:  -1,
+			expectedToError: false,
+		},
+		{
+			description:     "Pans partly right",
+			input:           0.25,
+			expectedOutput:  0.25,
+			expectedToError: false,
+		},
+		{
+			description:     "Errors with pan too far right",
+			input:           1.5,
+			expectedOutput:  0,
+			expectedToError: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		a := audio.BeepManager{}
+
+		actualErr := a.SetPan(testCase.input)
+		if testCase.expectedToError {
+			assert.NotNil(t, actualErr)
+		} else {
+			assert.Nil(t, actualErr)
+		}
+
+		assert.Equal(t, testCase.expectedOutput, a.GetPan())
+	}
+}
+
+func TestSetGetEffects(t *testing.T) {
+	type testCase struct {
+		description     string
+		input           []audio.Effect
+		expectedOutput  []audio.Effect
+		expectedToError bool
+	}
+
+	effects := []audio.Effect{
+		{Type: audio.HighPass, Frequency: 200, Q: 0.7},
+		{Type: audio.Saturate, Drive: 3},
+	}
+
+	testCases := []testCase{
+		{
+			description:     "Sets effects chain",
+			input:           effects,
+			expectedOutput:  effects,
+			expectedToError: false,
+		},
+		{
+			description:     "Errors with invalid effect",
+			input:           []audio.Effect{effects[0], {Type: audio.BitCrush}},
+			expectedOutput:  nil,
+			expectedToError: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		a := audio.BeepManager{}
+
+		actualErr := a.SetEffects(testCase.input)
+		if testCase.expectedToError {
+			assert.NotNil(t, actualErr)
+		} else {
+			assert.Nil(t, actualErr)
+		}
+
+		assert.Equal(t, testCase.expectedOutput, a.GetEffects())
+	}
+}
+
+func TestSetGetSends(t *testing.T) {
+	type testCase struct {
+		description     string
+		input           audio.Sends
+		expectedOutput  audio.Sends
+		expectedToError bool
+	}
+
+	testCases := []testCase{
+		{
+			description:     "Sets sends",
+			input:           audio.Sends{Reverb: 0.3, Delay: 1},
+			expectedOutput:  audio.Sends{Reverb: 0.3, Delay: 1},
+			expectedToError: false,
+		},
+		{
+			description:     "Errors with send out of range",
+			input:           audio.Sends{Reverb: -0.1},
+			expectedOutput:  audio.Sends{},
+			expectedToError: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		a := audio.BeepManager{}
+
+		actualErr := a.SetSends(testCase.input)
+		if testCase.expectedToError {
+			assert.NotNil(t, actualErr)
+		} else {
+			assert.Nil(t, actualErr)
+		}
+
+		assert.Equal(t, testCase.expectedOutput, a.GetSends())
+	}
+}
+
+func TestSetGetChokeGroup(t *testing.T) {
 	type testCase struct {
 		description     string
-		input           string
+		input           int
+		expectedOutput  int
 		expectedToError bool
 	}
 
+	testCases := []testCase{
+		{
+			description:     "Sets choke group",
+			input:           2,
+			expectedOutput:  2,
+			expectedToError: false,
+		},
+		{
+			description:     "Removes choke group",
+			input:           0,
+			expectedOutput:  0,
+			expectedToError: false,
+		},
+		{
+			description:     "Errors with negative choke group",
+			input:           -1,
+			expectedOutput:  0,
+			expectedToError: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		a := audio.BeepManager{}
+
+		actualErr := a.SetChokeGroup(testCase.input)
+		if testCase.expectedToError {
+			assert.NotNil(t, actualErr)
+		} else {
+			assert.Nil(t, actualErr)
+		}
+
+		assert.Equal(t, testCase.expectedOutput, a.GetChokeGroup())
+	}
+}
+
+func TestSetGetSampleParams(t *testing.T) {
+	type testCase struct {
+		description     string
+		input           audio.SampleParams
+		expectedToError bool
+	}
+
+	testCases := []testCase{
+		{
+			description: "Sets sample params",
+			input: audio.SampleParams{
+				Start:     10 * time.Millisecond,
+				End:       100 * time.Millisecond,
+				Reverse:   true,
+				Semitones: 3,
+				Envelope:  &audio.Envelope{Decay: 50 * time.Millisecond},
+			},
+			expectedToError: false,
+		},
+		{
+			description:     "Errors with invalid sample params",
+			input:           audio.SampleParams{Start: 100 * time.Millisecond, End: 10 * time.Millisecond},
+			expectedToError: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		a, err := audio.New("testfiles/valid.wav")
+		assert.Nil(t, err)
+
+		actualErr := a.SetSampleParams(testCase.input)
+		if testCase.expectedToError {
+			assert.NotNil(t, actualErr)
+			assert.Equal(t, audio.SampleParams{}, a.GetSampleParams())
+		} else {
+			assert.Nil(t, actualErr)
+			assert.Equal(t, testCase.input, a.GetSampleParams())
+		}
+	}
+}
+
+func TestNew(t *testing.T) {
+	type testCase struct {
+		description          string
+		input                string
+		expectedToError      bool
+		expectedErrorMessage string
+	}
+
 	testCases := []testCase{
 		{
 			description:     "Successfully initializes Audio object",
@@ -84,6 +302,23 @@ func TestNew(t *testing.T) {
 			input:           "testfiles/invalid.wav",
 			expectedToError: true,
 		},
+		{
+			description:     "Successfully initializes Audio object from aiff file",
+			input:           "testfiles/valid.aiff",
+			expectedToError: false,
+		},
+		{
+			description:          "Fails with bad flac file, naming the format",
+			input:                "testfiles/invalid.flac",
+			expectedToError:      true,
+			expectedErrorMessage: "error decoding flac sound file",
+		},
+		{
+			description:          "Fails with unrecognised audio file",
+			input:                "testfiles/unknown.txt",
+			expectedToError:      true,
+			expectedErrorMessage: "unrecognised sound file format",
+		},
 	}
 
 	for _, testCase := range testCases {
@@ -93,6 +328,10 @@ func TestNew(t *testing.T) {
 		if testCase.expectedToError {
 			assert.Nil(t, actualOutput)
 			assert.NotNil(t, actualErr)
+
+			if testCase.expectedErrorMessage != "" {
+				assert.Contains(t, actualErr.Error(), testCase.expectedErrorMessage)
+			}
 		} else {
 			assert.NotNil(t, actualOutput)
 			assert.Nil(t, actualErr)
@@ -0,0 +1,271 @@
+package audio
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/faiface/beep"
+	"github.com/faiface/beep/speaker"
+	"github.com/pkg/errors"
+)
+
+// Names of the available backends
+const (
+	SpeakerBackend = "speaker"
+	NullBackend    = "null"
+	WAVBackend     = "wav"
+)
+
+// Backends lists the name of every backend, in the order they're offered to
+// users.
+var Backends = []string{SpeakerBackend, NullBackend, WAVBackend}
+
+// Length of audio pulled from the mixer at a time. 40 found to sound best
+// through experimentation.
+const bufferDuration = time.Second / 40
+
+// Backend is where the mixed audio of every Manager is played.
+type Backend interface {
+	// Start starts pulling audio from the streamer in real time.
+	Start(streamer beep.Streamer, sampleRate beep.SampleRate) error
+	// Close stops pulling audio, and releases anything the backend holds open.
+	Close() error
+}
+
+var (
+	backendMu      sync.Mutex
+	backend        Backend = new(speakerBackend)
+	backendStarted bool
+)
+
+// NewBackend creates the backend with the given name. Filename is where the
+// WAV backend writes to, and is ignored by the others. Returns an error if no
+// backend has the name.
+func NewBackend(name, filename string) (Backend, error) {
+	switch name {
+	case SpeakerBackend:
+		return new(speakerBackend), nil
+	case NullBackend:
+		return newRealtimeBackend(discard{}), nil
+	case WAVBackend:
+		if filename == "" {
+			return nil, errors.New("wav backend needs a filename to write to")
+		}
+
+		return newRealtimeBackend(&wavSink{filename: filename}), nil
+	default:
+		return nil, fmt.Errorf("unknown audio backend %q, must be one of %v", name, Backends)
+	}
+}
+
+// SetBackend sets the backend every Manager is played through, which is the
+// speaker until set. Returns an error if audio has already started playing.
+func SetBackend(b Backend) error {
+	backendMu.Lock()
+	defer backendMu.Unlock()
+
+	if backendStarted {
+		return errors.New("audio backend can't be changed once it has started")
+	}
+
+	backend = b
+
+	return nil
+}
+
+// Start starts the backend playing, if it isn't already. Hits played before
+// the backend starts are heard once it does.
+func Start() error {
+	backendMu.Lock()
+	defer backendMu.Unlock()
+
+	if backendStarted {
+		return nil
+	}
+
+	if err := backend.Start(bus, sampleRate); err != nil {
+		return errors.Wrap(err, "error starting audio backend")
+	}
+
+	backendStarted = true
+
+	return nil
+}
+
+// failing is a Backend which can stop playing because of an error, such as
+// being unable to write its audio out.
+type failing interface {
+	// Failed returns a channel closed once the backend has stopped because of
+	// an error, which Err then returns.
+	Failed() <-chan struct{}
+	Err() error
+}
+
+// Failed returns a channel closed if the started backend stops playing because
+// of an error, which Err then returns. The channel is nil, never being closed,
+// if the backend hasn't started or can't fail.
+func Failed() <-chan struct{} {
+	backendMu.Lock()
+	defer backendMu.Unlock()
+
+	if f, ok := backend.(failing); ok && backendStarted {
+		return f.Failed()
+	}
+
+	return nil
+}
+
+// Err returns the error which stopped the started backend playing, or nil if
+// it hasn't been stopped by one.
+func Err() error {
+	backendMu.Lock()
+	defer backendMu.Unlock()
+
+	if f, ok := backend.(failing); ok && backendStarted {
+		return f.Err()
+	}
+
+	return nil
+}
+
+// Close stops the backend, after which it can be set or started again.
+func Close() error {
+	backendMu.Lock()
+	defer backendMu.Unlock()
+
+	if !backendStarted {
+		return nil
+	}
+
+	backendStarted = false
+
+	return backend.Close()
+}
+
+// speakerBackend plays audio through the computer's default speaker.
+type speakerBackend struct{}
+
+func (s *speakerBackend) Start(streamer beep.Streamer, sampleRate beep.SampleRate) error {
+	if err := speaker.Init(sampleRate, sampleRate.N(bufferDuration)); err != nil {
+		return err
+	}
+
+	speaker.Play(streamer)
+
+	return nil
+}
+
+func (s *speakerBackend) Close() error {
+	speaker.Close()
+	return nil
+}
+
+// sink receives the audio pulled by a realtimeBackend.
+type sink interface {
+	open(sampleRate beep.SampleRate) error
+	write(samples [][2]float64) error
+	close() error
+}
+
+// realtimeBackend pulls audio at the pace a speaker would, without one, and
+// hands it to a sink.
+type realtimeBackend struct {
+	sink sink
+	done chan struct{}
+	// Closed once a write to the sink fails, stopping the backend
+	failed chan struct{}
+	// Any error from the sink, returned by Close
+	err  error
+	wait sync.WaitGroup
+}
+
+func newRealtimeBackend(s sink) *realtimeBackend {
+	return &realtimeBackend{sink: s}
+}
+
+func (r *realtimeBackend) Start(streamer beep.Streamer, sampleRate beep.SampleRate) error {
+	if err := r.sink.open(sampleRate); err != nil {
+		return err
+	}
+
+	r.done = make(chan struct{})
+	r.failed = make(chan struct{})
+	r.err = nil
+	r.wait.Add(1)
+
+	go r.run(streamer, sampleRate)
+
+	return nil
+}
+
+// run pulls however much audio is due every buffer, so the total pulled keeps
+// pace with the clock even when a tick is late.
+func (r *realtimeBackend) run(streamer beep.Streamer, sampleRate beep.SampleRate) {
+	defer r.wait.Done()
+
+	ticker := time.NewTicker(bufferDuration)
+	defer ticker.Stop()
+
+	start := time.Now()
+	pulled := 0
+	samples := make([][2]float64, sampleRate.N(bufferDuration))
+
+	for {
+		select {
+		case <-r.done:
+			return
+		case <-ticker.C:
+			for due := sampleRate.N(time.Since(start)) - pulled; due > 0; {
+				n, _ := streamer.Stream(samples[:min(due, len(samples))])
+				if n == 0 {
+					break
+				}
+
+				if err := r.sink.write(samples[:n]); err != nil {
+					r.err = err
+					close(r.failed)
+					return
+				}
+
+				pulled += n
+				due -= n
+			}
+		}
+	}
+}
+
+func (r *realtimeBackend) Failed() <-chan struct{} {
+	return r.failed
+}
+
+// Err must only be called once Failed is closed, or the backend is closed.
+func (r *realtimeBackend) Err() error {
+	return r.err
+}
+
+func (r *realtimeBackend) Close() error {
+	close(r.done)
+	r.wait.Wait()
+
+	if err := r.sink.close(); err != nil && r.err == nil {
+		r.err = err
+	}
+
+	return r.err
+}
+
+// discard throws away the audio it's given.
+type discard struct{}
+
+func (discard) open(beep.SampleRate) error { return nil }
+func (discard) write([][2]float64) error   { return nil }
+func (discard) close() error               { return nil }
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+
+	return b
+}
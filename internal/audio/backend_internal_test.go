@@ -0,0 +1,169 @@
+package audio
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/faiface/beep"
+	"github.com/faiface/beep/wav"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewBackend(t *testing.T) {
+	type input struct {
+		name     string
+		filename string
+	}
+
+	type testCase struct {
+		description     string
+		input           input
+		expectedToError bool
+	}
+
+	testCases := []testCase{
+		{
+			description:     "Creates speaker backend",
+			input:           input{name: SpeakerBackend},
+			expectedToError: false,
+		},
+		{
+			description:     "Creates null backend",
+			input:           input{name: NullBackend},
+			expectedToError: false,
+		},
+		{
+			description:     "Creates wav backend",
+			input:           input{name: WAVBackend, filename: "out.wav"},
+			expectedToError: false,
+		},
+		{
+			description:     "Errors on wav backend without a filename",
+			input:           input{name: WAVBackend},
+			expectedToError: true,
+		},
+		{
+			description:     "Errors on unknown backend",
+			input:           input{name: "cassette"},
+			expectedToError: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		actualOutput, actualErr := NewBackend(testCase.input.name, testCase.input.filename)
+		if testCase.expectedToError {
+			assert.NotNil(t, actualErr, testCase.description)
+			assert.Nil(t, actualOutput, testCase.description)
+		} else {
+			assert.Nil(t, actualErr, testCase.description)
+			assert.NotNil(t, actualOutput, testCase.description)
+		}
+	}
+}
+
+// countingSink counts the samples it's given.
+type countingSink struct {
+	samples int
+	closed  bool
+}
+
+func (c *countingSink) open(sampleRate beep.SampleRate) error { return nil }
+func (c *countingSink) write(samples [][2]float64) error {
+	c.samples += len(samples)
+	return nil
+}
+func (c *countingSink) close() error {
+	c.closed = true
+	return nil
+}
+
+func TestRealtimeBackend(t *testing.T) {
+	s := &countingSink{}
+	b := newRealtimeBackend(s)
+
+	assert.Nil(t, b.Start(newMixer(), sampleRate))
+	time.Sleep(200 * time.Millisecond)
+	assert.Nil(t, b.Close())
+
+	// audio is pulled at the pace it would be played, give or take a buffer
+	assert.InDelta(t, sampleRate.N(200*time.Millisecond), s.samples, float64(sampleRate.N(2*bufferDuration)))
+	assert.True(t, s.closed)
+}
+
+// failingSink fails every write.
+type failingSink struct {
+	countingSink
+}
+
+func (f *failingSink) write([][2]float64) error {
+	return errors.New("disk full")
+}
+
+func TestRealtimeBackendFails(t *testing.T) {
+	s := &failingSink{}
+	assert.Nil(t, SetBackend(newRealtimeBackend(s)))
+	assert.Nil(t, Start())
+
+	// playback stops as soon as a write fails, rather than once it's closed
+	select {
+	case <-Failed():
+	case <-time.After(time.Second):
+		assert.Fail(t, "backend didn't fail")
+	}
+
+	assert.EqualError(t, Err(), "disk full")
+	assert.EqualError(t, Close(), "disk full")
+	assert.True(t, s.closed)
+	assert.Nil(t, Failed())
+	assert.Nil(t, SetBackend(new(speakerBackend)))
+}
+
+func TestWAVSink(t *testing.T) {
+	f, err := ioutil.TempFile("", "logarhythms-*.wav")
+	assert.Nil(t, err)
+	f.Close()
+	defer os.Remove(f.Name())
+
+	w := &wavSink{filename: f.Name()}
+	assert.Nil(t, w.open(sampleRate))
+	assert.Nil(t, w.write([][2]float64{{0.5, -0.5}, {2, -2}, {0, 0}}))
+	assert.Nil(t, w.close())
+
+	file, err := os.Open(f.Name())
+	assert.Nil(t, err)
+	defer file.Close()
+
+	streamer, format, err := wav.Decode(file)
+	assert.Nil(t, err)
+	assert.Equal(t, sampleRate, format.SampleRate)
+	assert.Equal(t, 2, format.NumChannels)
+	assert.Equal(t, 3, streamer.Len())
+
+	samples := make([][2]float64, 3)
+	n, _ := streamer.Stream(samples)
+	assert.Equal(t, 3, n)
+	assert.InDelta(t, 0.5, samples[0][0], 1e-3)
+	assert.InDelta(t, -0.5, samples[0][1], 1e-3)
+	// audio over full scale is clipped rather than wrapped around
+	assert.InDelta(t, 1, samples[1][0], 1e-3)
+	assert.InDelta(t, -1, samples[1][1], 1e-3)
+}
+
+func TestSetBackend(t *testing.T) {
+	s := &countingSink{}
+	assert.Nil(t, SetBackend(newRealtimeBackend(s)))
+
+	assert.Nil(t, Start())
+	// starting again is harmless
+	assert.Nil(t, Start())
+	assert.NotNil(t, SetBackend(newRealtimeBackend(&countingSink{})))
+
+	assert.Nil(t, Close())
+	assert.True(t, s.closed)
+	assert.Nil(t, SetBackend(new(speakerBackend)))
+}
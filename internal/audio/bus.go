@@ -0,0 +1,156 @@
+package audio
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Longest time the delay can be set to. Longer note values are shortened to
+// fit.
+const maxDelayDuration = 4 * time.Second
+
+// MaxDelayFeedback is the highest delay feedback allowed, which keeps the delay
+// from running away.
+const MaxDelayFeedback = 0.95
+
+// Sends are how much of a Manager's audio is sent to the shared reverb and
+// delay, each from 0 (none) to 1 (all of it).
+type Sends struct {
+	Reverb float64
+	Delay  float64
+}
+
+// ReverbParams shapes the shared reverb.
+type ReverbParams struct {
+	// Size of the simulated room, from 0 (small) to 1 (large)
+	RoomSize float64
+	// How quickly high frequencies die away, from 0 (bright) to 1 (dark)
+	Damping float64
+	// Level the reverb is mixed back in at, from 0 to 1
+	Return float64
+}
+
+// DelayParams shapes the shared delay.
+type DelayParams struct {
+	// Time between repeats as a note value, synced to the tempo
+	Note NoteValue
+	// How much of each repeat is fed back into the delay, from 0 to 0.95
+	Feedback float64
+	// Level the delay is mixed back in at, from 0 to 1
+	Return float64
+}
+
+// BusParams shapes the effects shared by every Manager.
+type BusParams struct {
+	Reverb ReverbParams
+	Delay  DelayParams
+}
+
+// DefaultBusParams returns the shared effects' params before any are set.
+func DefaultBusParams() BusParams {
+	return BusParams{
+		Reverb: ReverbParams{RoomSize: 0.5, Damping: 0.5, Return: 0.3},
+		Delay:  DelayParams{Note: "1/8d", Feedback: 0.35, Return: 0.25},
+	}
+}
+
+// NoteValue is a length of time relative to the tempo, written as a fraction
+// of a whole note, e.g. 1/8. A d suffix makes the note dotted, and a t suffix
+// makes it a triplet. Quarter notes are one beat long.
+type NoteValue string
+
+// NoteValues lists common note values, in the order they're offered to users.
+var NoteValues = []NoteValue{"1/2", "1/4", "1/4d", "1/4t", "1/8", "1/8d", "1/8t", "1/16", "1/16d", "1/16t"}
+
+// beats returns the length of the note value in beats.
+func (n NoteValue) beats() (float64, error) {
+	value := string(n)
+
+	multiplier := 1.0
+	switch {
+	case strings.HasSuffix(value, "d"):
+		multiplier = 1.5
+		value = strings.TrimSuffix(value, "d")
+	case strings.HasSuffix(value, "t"):
+		multiplier = 2.0 / 3
+		value = strings.TrimSuffix(value, "t")
+	}
+
+	var numerator, denominator int
+	if _, err := fmt.Sscanf(value, "%d/%d", &numerator, &denominator); err != nil || fmt.Sprintf("%d/%d", numerator, denominator) != value {
+		return 0, fmt.Errorf("note value %q must look like 1/8, 1/8d or 1/8t", n)
+	}
+
+	if numerator < 1 || denominator < 1 {
+		return 0, fmt.Errorf("note value %q must be positive", n)
+	}
+
+	// a whole note is 4 beats
+	return 4 * float64(numerator) / float64(denominator) * multiplier, nil
+}
+
+// duration returns the length of the note value at the given tempo.
+func (n NoteValue) duration(beatsPerMinute float64) (time.Duration, error) {
+	beats, err := n.beats()
+	if err != nil {
+		return 0, err
+	}
+
+	if beatsPerMinute <= 0 {
+		return 0, errors.New("tempo must be greater than 0")
+	}
+
+	return time.Duration(beats * float64(time.Minute) / beatsPerMinute), nil
+}
+
+func (s Sends) validate() error {
+	if s.Reverb < 0 || s.Reverb > 1 || s.Delay < 0 || s.Delay > 1 {
+		return errors.New("send levels must be between 0 and 1")
+	}
+
+	return nil
+}
+
+// Validate checks that the params are within range.
+func (p BusParams) Validate() error {
+	r := p.Reverb
+	if r.RoomSize < 0 || r.RoomSize > 1 || r.Damping < 0 || r.Damping > 1 || r.Return < 0 || r.Return > 1 {
+		return errors.New("reverb room size, damping and return must be between 0 and 1")
+	}
+
+	d := p.Delay
+	if _, err := d.Note.beats(); err != nil {
+		return errors.Wrap(err, "error reading delay time")
+	}
+
+	if d.Feedback < 0 || d.Feedback > MaxDelayFeedback {
+		return fmt.Errorf("delay feedback must be between 0 and %v", MaxDelayFeedback)
+	}
+
+	if d.Return < 0 || d.Return > 1 {
+		return errors.New("delay return must be between 0 and 1")
+	}
+
+	return nil
+}
+
+// SetBus sets the params of the effects shared by every Manager. Returns an
+// error if the params are invalid.
+func SetBus(params BusParams) error {
+	return bus.setParams(params)
+}
+
+// SetTempo sets the tempo the shared delay is synced to. Returns an error if
+// the tempo isn't positive.
+func SetTempo(beatsPerMinute float64) error {
+	return bus.setTempo(beatsPerMinute)
+}
+
+// Silence quickly fades out every hit still playing, leaving only the shared
+// effects' tails.
+func Silence() {
+	bus.silence()
+}
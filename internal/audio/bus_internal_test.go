@@ -0,0 +1,125 @@
+package audio
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNoteValueBeats(t *testing.T) {
+	type testCase struct {
+		description     string
+		input           NoteValue
+		expectedOutput  float64
+		expectedToError bool
+	}
+
+	testCases := []testCase{
+		{
+			description:     "Quarter note is a beat",
+			input:           "1/4",
+			expectedOutput:  1,
+			expectedToError: false,
+		},
+		{
+			description:     "Dotted eighth note is three quarters of a beat",
+			input:           "1/8d",
+			expectedOutput:  0.75,
+			expectedToError: false,
+		},
+		{
+			description:     "Quarter note triplet is two thirds of a beat",
+			input:           "1/4t",
+			expectedOutput:  2.0 / 3,
+			expectedToError: false,
+		},
+		{
+			description:     "Whole note is four beats",
+			input:           "1/1",
+			expectedOutput:  4,
+			expectedToError: false,
+		},
+		{
+			description:     "Errors with empty note value",
+			input:           "",
+			expectedToError: true,
+		},
+		{
+			description:     "Errors with trailing characters",
+			input:           "1/8x",
+			expectedToError: true,
+		},
+		{
+			description:     "Errors with zero denominator",
+			input:           "1/0",
+			expectedToError: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		actualOutput, actualErr := testCase.input.beats()
+		if testCase.expectedToError {
+			assert.NotNil(t, actualErr, testCase.description)
+		} else {
+			assert.Nil(t, actualErr, testCase.description)
+			assert.InDelta(t, testCase.expectedOutput, actualOutput, 1e-9, testCase.description)
+		}
+	}
+}
+
+func TestNoteValueDuration(t *testing.T) {
+	duration, err := NoteValue("1/8").duration(120)
+	assert.Nil(t, err)
+	assert.Equal(t, 250*time.Millisecond, duration)
+
+	_, err = NoteValue("1/8").duration(0)
+	assert.NotNil(t, err)
+}
+
+func TestBusParamsValidate(t *testing.T) {
+	type testCase struct {
+		description     string
+		input           func(*BusParams)
+		expectedToError bool
+	}
+
+	testCases := []testCase{
+		{
+			description:     "Succeeds with defaults",
+			input:           func(*BusParams) {},
+			expectedToError: false,
+		},
+		{
+			description:     "Errors with room size out of range",
+			input:           func(p *BusParams) { p.Reverb.RoomSize = 1.5 },
+			expectedToError: true,
+		},
+		{
+			description:     "Errors with invalid delay note",
+			input:           func(p *BusParams) { p.Delay.Note = "eighth" },
+			expectedToError: true,
+		},
+		{
+			description:     "Errors with runaway delay feedback",
+			input:           func(p *BusParams) { p.Delay.Feedback = 1 },
+			expectedToError: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		params := DefaultBusParams()
+		testCase.input(&params)
+
+		actualErr := params.Validate()
+		if testCase.expectedToError {
+			assert.NotNil(t, actualErr, testCase.description)
+		} else {
+			assert.Nil(t, actualErr, testCase.description)
+		}
+	}
+}
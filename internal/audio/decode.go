@@ -0,0 +1,280 @@
+package audio
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/faiface/beep"
+	"github.com/faiface/beep/flac"
+	"github.com/faiface/beep/mp3"
+	"github.com/faiface/beep/vorbis"
+	"github.com/faiface/beep/wav"
+	"github.com/pkg/errors"
+)
+
+// soundFormat is an audio file format which samples can be decoded from.
+type soundFormat string
+
+const (
+	unknownFormat soundFormat = ""
+	wavFormat     soundFormat = "wav"
+	flacFormat    soundFormat = "flac"
+	mp3Format     soundFormat = "mp3"
+	vorbisFormat  soundFormat = "ogg vorbis"
+	aiffFormat    soundFormat = "aiff"
+)
+
+// Number of bytes read from the start of a file to detect its format
+const headerLength = 12
+
+var extensionFormats = map[string]soundFormat{
+	".wav":  wavFormat,
+	".wave": wavFormat,
+	".flac": flacFormat,
+	".mp3":  mp3Format,
+	".ogg":  vorbisFormat,
+	".oga":  vorbisFormat,
+	".aif":  aiffFormat,
+	".aiff": aiffFormat,
+	".aifc": aiffFormat,
+}
+
+// detectFormat works out the format of a sound file from its header, falling
+// back to its extension for formats without a reliable header, such as MP3s
+// without ID3 tags.
+func detectFormat(header []byte, filename string) soundFormat {
+	switch {
+	case len(header) >= 12 && string(header[:4]) == "RIFF" && string(header[8:12]) == "WAVE":
+		return wavFormat
+	case len(header) >= 4 && string(header[:4]) == "fLaC":
+		return flacFormat
+	case len(header) >= 4 && string(header[:4]) == "OggS":
+		return vorbisFormat
+	case len(header) >= 12 && string(header[:4]) == "FORM" && (string(header[8:12]) == "AIFF" || string(header[8:12]) == "AIFC"):
+		return aiffFormat
+	case len(header) >= 3 && string(header[:3]) == "ID3":
+		return mp3Format
+	case len(header) >= 2 && header[0] == 0xFF && header[1]&0xE0 == 0xE0:
+		// MPEG audio frame sync
+		return mp3Format
+	}
+
+	return extensionFormats[strings.ToLower(filepath.Ext(filename))]
+}
+
+// decodeSound decodes the file in the given format.
+func decodeSound(f *os.File, format soundFormat) (beep.Streamer, beep.Format, error) {
+	switch format {
+	case wavFormat:
+		return wav.Decode(f)
+	case flacFormat:
+		return flac.Decode(f)
+	case mp3Format:
+		return mp3.Decode(f)
+	case vorbisFormat:
+		return vorbis.Decode(f)
+	case aiffFormat:
+		return decodeAIFF(f)
+	}
+
+	return nil, beep.Format{}, errors.New("unrecognised sound file format")
+}
+
+// openSound opens the sound file, detects its format and decodes it. The
+// detected format is named in any decoding error.
+func openSound(filename string) (*os.File, beep.Streamer, beep.Format, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, nil, beep.Format{}, errors.Wrap(err, "error opening sound file")
+	}
+
+	header := make([]byte, headerLength)
+	n, err := io.ReadFull(f, header)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		f.Close()
+		return nil, nil, beep.Format{}, errors.Wrap(err, "error reading sound file header")
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		f.Close()
+		return nil, nil, beep.Format{}, errors.Wrap(err, "error reading sound file header")
+	}
+
+	format := detectFormat(header[:n], filename)
+	if format == unknownFormat {
+		f.Close()
+		return nil, nil, beep.Format{}, errors.New("error decoding sound file: unrecognised sound file format")
+	}
+
+	streamer, beepFormat, err := decodeSound(f, format)
+	if err != nil {
+		f.Close()
+		return nil, nil, beep.Format{}, errors.Wrap(err, fmt.Sprintf("error decoding %s sound file", format))
+	}
+
+	return f, streamer, beepFormat, nil
+}
+
+// decodeAIFF decodes uncompressed AIFF and AIFF-C files, which beep has no
+// decoder for.
+func decodeAIFF(r io.Reader) (beep.Streamer, beep.Format, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, beep.Format{}, err
+	}
+
+	if len(data) < 12 || string(data[:4]) != "FORM" {
+		return nil, beep.Format{}, errors.New("missing FORM chunk")
+	}
+
+	compressed := string(data[8:12]) == "AIFC"
+
+	var (
+		numChannels, sampleSize int
+		rate                    float64
+		littleEndian            bool
+		soundData               []byte
+		foundComm               bool
+	)
+
+	for chunks := data[12:]; len(chunks) >= 8; {
+		id := string(chunks[:4])
+		size := int(binary.BigEndian.Uint32(chunks[4:8]))
+		if size > len(chunks)-8 {
+			return nil, beep.Format{}, fmt.Errorf("%s chunk is truncated", strings.TrimSpace(id))
+		}
+
+		chunk := chunks[8 : 8+size]
+
+		switch id {
+		case "COMM":
+			if len(chunk) < 18 {
+				return nil, beep.Format{}, errors.New("COMM chunk is too short")
+			}
+
+			numChannels = int(binary.BigEndian.Uint16(chunk[0:2]))
+			sampleSize = int(binary.BigEndian.Uint16(chunk[6:8]))
+			rate = extendedFloat(chunk[8:18])
+			foundComm = true
+
+			if compressed {
+				if len(chunk) < 22 {
+					return nil, beep.Format{}, errors.New("COMM chunk is too short")
+				}
+
+				switch compression := string(chunk[18:22]); compression {
+				case "NONE", "twos":
+				case "sowt":
+					littleEndian = true
+				default:
+					return nil, beep.Format{}, fmt.Errorf("unsupported compression %q", compression)
+				}
+			}
+		case "SSND":
+			if len(chunk) < 8 {
+				return nil, beep.Format{}, errors.New("SSND chunk is too short")
+			}
+
+			offset := int(binary.BigEndian.Uint32(chunk[0:4]))
+			if offset > len(chunk)-8 {
+				return nil, beep.Format{}, errors.New("SSND chunk offset is out of range")
+			}
+
+			soundData = chunk[8+offset:]
+		}
+
+		// chunks are padded to an even length, although the padding is sometimes
+		// missing from the last chunk
+		next := 8 + size + size%2
+		if next > len(chunks) {
+			break
+		}
+
+		chunks = chunks[next:]
+	}
+
+	if !foundComm {
+		return nil, beep.Format{}, errors.New("missing COMM chunk")
+	}
+
+	if numChannels < 1 || numChannels > 2 {
+		return nil, beep.Format{}, fmt.Errorf("unsupported number of channels %d", numChannels)
+	}
+
+	if sampleSize < 1 || sampleSize > 32 {
+		return nil, beep.Format{}, fmt.Errorf("unsupported sample size %d", sampleSize)
+	}
+
+	if rate <= 0 {
+		return nil, beep.Format{}, errors.New("sample rate must be greater than 0")
+	}
+
+	bytesPerSample := (sampleSize + 7) / 8
+	frameSize := bytesPerSample * numChannels
+	samples := make([][2]float64, len(soundData)/frameSize)
+
+	for i := range samples {
+		frame := soundData[i*frameSize:]
+		for channel := 0; channel < 2; channel++ {
+			// mono samples play through both channels
+			offset := (channel % numChannels) * bytesPerSample
+			samples[i][channel] = pcmSample(frame[offset:offset+bytesPerSample], littleEndian)
+		}
+	}
+
+	format := beep.Format{
+		SampleRate:  beep.SampleRate(math.Round(rate)),
+		NumChannels: numChannels,
+		Precision:   bytesPerSample,
+	}
+
+	return samplesStreamer(samples), format, nil
+}
+
+// pcmSample converts a signed PCM sample into a float between -1 and 1.
+// Samples are left justified, so the full width of their bytes is used.
+func pcmSample(b []byte, littleEndian bool) float64 {
+	var value int64
+	for i := range b {
+		index := i
+		if littleEndian {
+			index = len(b) - 1 - i
+		}
+
+		value = value<<8 | int64(b[index])
+	}
+
+	bits := uint(len(b) * 8)
+
+	// sign extend
+	if value&(1<<(bits-1)) != 0 {
+		value -= 1 << bits
+	}
+
+	return float64(value) / float64(int64(1)<<(bits-1))
+}
+
+// extendedFloat decodes an 80-bit IEEE 754 extended precision float, which is
+// how AIFF files store their sample rate.
+func extendedFloat(b []byte) float64 {
+	exponent := int(binary.BigEndian.Uint16(b[0:2]))
+	mantissa := binary.BigEndian.Uint64(b[2:10])
+
+	sign := 1.0
+	if exponent&0x8000 != 0 {
+		sign = -1
+		exponent &= 0x7FFF
+	}
+
+	if exponent == 0 && mantissa == 0 {
+		return 0
+	}
+
+	return sign * math.Ldexp(float64(mantissa), exponent-16383-63)
+}
@@ -0,0 +1,198 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/faiface/beep"
+	"github.com/stretchr/testify/assert"
+)
+
+// 44100 as an 80-bit extended precision float
+var extended44100 = []byte{0x40, 0x0E, 0xAC, 0x44, 0, 0, 0, 0, 0, 0}
+
+// aiffChunk builds an AIFF chunk with the given id and data.
+func aiffChunk(id string, data []byte) []byte {
+	chunk := append([]byte(id), make([]byte, 4)...)
+	binary.BigEndian.PutUint32(chunk[4:], uint32(len(data)))
+	chunk = append(chunk, data...)
+
+	if len(data)%2 == 1 {
+		chunk = append(chunk, 0)
+	}
+
+	return chunk
+}
+
+// aiffFile builds an AIFF file of the given form type from its chunks.
+func aiffFile(formType string, chunks ...[]byte) []byte {
+	body := []byte(formType)
+	for _, chunk := range chunks {
+		body = append(body, chunk...)
+	}
+
+	return aiffChunk("FORM", body)
+}
+
+func commChunk(numChannels, sampleSize int, compression string) []byte {
+	data := make([]byte, 8)
+	binary.BigEndian.PutUint16(data[0:2], uint16(numChannels))
+	binary.BigEndian.PutUint16(data[6:8], uint16(sampleSize))
+	data = append(data, extended44100...)
+	data = append(data, compression...)
+
+	return aiffChunk("COMM", data)
+}
+
+func ssndChunk(sound []byte) []byte {
+	return aiffChunk("SSND", append(make([]byte, 8), sound...))
+}
+
+func TestDetectFormat(t *testing.T) {
+	type input struct {
+		header   []byte
+		filename string
+	}
+
+	type testCase struct {
+		description    string
+		input          input
+		expectedOutput soundFormat
+	}
+
+	testCases := []testCase{
+		{
+			description:    "Detects wav header",
+			input:          input{header: []byte("RIFF\x00\x00\x00\x00WAVE"), filename: "kick.flac"},
+			expectedOutput: wavFormat,
+		},
+		{
+			description:    "Detects flac header",
+			input:          input{header: []byte("fLaC\x00\x00\x00\x22"), filename: "kick"},
+			expectedOutput: flacFormat,
+		},
+		{
+			description:    "Detects ogg header",
+			input:          input{header: []byte("OggS\x00\x02"), filename: "kick"},
+			expectedOutput: vorbisFormat,
+		},
+		{
+			description:    "Detects aiff header",
+			input:          input{header: []byte("FORM\x00\x00\x00\x00AIFF"), filename: "kick"},
+			expectedOutput: aiffFormat,
+		},
+		{
+			description:    "Detects aifc header",
+			input:          input{header: []byte("FORM\x00\x00\x00\x00AIFC"), filename: "kick"},
+			expectedOutput: aiffFormat,
+		},
+		{
+			description:    "Detects mp3 ID3 tag",
+			input:          input{header: []byte("ID3\x03\x00"), filename: "kick"},
+			expectedOutput: mp3Format,
+		},
+		{
+			description:    "Detects mp3 frame sync",
+			input:          input{header: []byte{0xFF, 0xFB, 0x90, 0x00}, filename: "kick"},
+			expectedOutput: mp3Format,
+		},
+		{
+			description:    "Falls back to extension",
+			input:          input{header: []byte("\x00\x00\x00\x00"), filename: "Kick.MP3"},
+			expectedOutput: mp3Format,
+		},
+		{
+			description:    "Detects nothing without header or extension",
+			input:          input{header: []byte("\x00\x00\x00\x00"), filename: "kick.txt"},
+			expectedOutput: unknownFormat,
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		actualOutput := detectFormat(testCase.input.header, testCase.input.filename)
+		assert.Equal(t, testCase.expectedOutput, actualOutput, testCase.description)
+	}
+}
+
+func TestDecodeAIFF(t *testing.T) {
+	type testCase struct {
+		description     string
+		input           []byte
+		expectedFormat  beep.Format
+		expectedSamples [][2]float64
+		expectedToError bool
+	}
+
+	testCases := []testCase{
+		{
+			description:     "Decodes mono 16 bit aiff",
+			input:           aiffFile("AIFF", commChunk(1, 16, ""), ssndChunk([]byte{0x40, 0x00, 0xC0, 0x00})),
+			expectedFormat:  beep.Format{SampleRate: 44100, NumChannels: 1, Precision: 2},
+			expectedSamples: [][2]float64{{0.5, 0.5}, {-0.5, -0.5}},
+		},
+		{
+			description:     "Decodes stereo 24 bit aiff",
+			input:           aiffFile("AIFF", commChunk(2, 24, ""), ssndChunk([]byte{0x40, 0x00, 0x00, 0xC0, 0x00, 0x00})),
+			expectedFormat:  beep.Format{SampleRate: 44100, NumChannels: 2, Precision: 3},
+			expectedSamples: [][2]float64{{0.5, -0.5}},
+		},
+		{
+			description:     "Decodes little endian aifc",
+			input:           aiffFile("AIFC", commChunk(1, 16, "sowt"), ssndChunk([]byte{0x00, 0x40})),
+			expectedFormat:  beep.Format{SampleRate: 44100, NumChannels: 1, Precision: 2},
+			expectedSamples: [][2]float64{{0.5, 0.5}},
+		},
+		{
+			description:     "Skips unknown chunks",
+			input:           aiffFile("AIFF", aiffChunk("NAME", []byte("kick")), commChunk(1, 8, ""), ssndChunk([]byte{0x40})),
+			expectedFormat:  beep.Format{SampleRate: 44100, NumChannels: 1, Precision: 1},
+			expectedSamples: [][2]float64{{0.5, 0.5}},
+		},
+		{
+			description:     "Fails with compressed aifc",
+			input:           aiffFile("AIFC", commChunk(1, 16, "ima4"), ssndChunk(nil)),
+			expectedToError: true,
+		},
+		{
+			description:     "Fails without COMM chunk",
+			input:           aiffFile("AIFF", ssndChunk(nil)),
+			expectedToError: true,
+		},
+		{
+			description:     "Fails with too many channels",
+			input:           aiffFile("AIFF", commChunk(6, 16, ""), ssndChunk(nil)),
+			expectedToError: true,
+		},
+		{
+			description:     "Fails with truncated chunk",
+			input:           aiffFile("AIFF", commChunk(1, 16, ""))[:20],
+			expectedToError: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		streamer, format, err := decodeAIFF(bytes.NewReader(testCase.input))
+		if testCase.expectedToError {
+			assert.NotNil(t, err, testCase.description)
+			continue
+		}
+
+		assert.Nil(t, err, testCase.description)
+		assert.Equal(t, testCase.expectedFormat, format, testCase.description)
+
+		samples := make([][2]float64, len(testCase.expectedSamples)+1)
+		n, _ := streamer.Stream(samples)
+		assert.Equal(t, testCase.expectedSamples, samples[:n], testCase.description)
+	}
+}
+
+func TestExtendedFloat(t *testing.T) {
+	assert.Equal(t, 44100.0, extendedFloat(extended44100))
+	assert.Equal(t, 48000.0, extendedFloat([]byte{0x40, 0x0E, 0xBB, 0x80, 0, 0, 0, 0, 0, 0}))
+	assert.Equal(t, 0.0, extendedFloat(make([]byte, 10)))
+}
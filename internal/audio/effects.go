@@ -0,0 +1,350 @@
+package audio
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/faiface/beep"
+	"github.com/pkg/errors"
+)
+
+// EffectType is a kind of insert effect which can be added to a Manager's
+// effects chain.
+type EffectType string
+
+const (
+	// LowPass cuts frequencies above Frequency
+	LowPass EffectType = "lowpass"
+	// HighPass cuts frequencies below Frequency
+	HighPass EffectType = "highpass"
+	// Peaking boosts or cuts frequencies around Frequency by Gain decibels
+	Peaking EffectType = "peaking"
+	// BitCrush reduces the audio to Bits bits, holding every sample for
+	// Downsample samples
+	BitCrush EffectType = "bitcrush"
+	// Saturate softly clips the audio after boosting it by Drive
+	Saturate EffectType = "saturate"
+	// Transient boosts or cuts the start of each hit by Attack, and the rest of
+	// it by Sustain
+	Transient EffectType = "transient"
+)
+
+// Ranges the params of effects must be within
+const (
+	MinFilterFrequency = 20
+	MaxFilterFrequency = 20000
+	MinFilterQ         = 0.1
+	MaxFilterQ         = 20
+	// Most a peaking filter can boost or cut by, in decibels
+	MaxPeakingGain = 24
+	MaxBits        = 24
+	MaxDownsample  = 64
+	MaxDrive       = 50
+)
+
+const (
+	// Attack and release times of the envelope followers used by the transient
+	// shaper. The fast follower tracks the start of a hit, and the slow follower
+	// its body.
+	transientFastAttack  = 500 * time.Microsecond
+	transientSlowAttack  = 15 * time.Millisecond
+	transientRelease     = 30 * time.Millisecond
+	transientAttackRange = 2
+)
+
+// EffectTypes lists every type of effect, in the order they're offered to
+// users.
+var EffectTypes = []EffectType{LowPass, HighPass, Peaking, BitCrush, Saturate, Transient}
+
+// Effect is a single insert effect in a Manager's effects chain. Only the
+// fields used by its Type are read.
+type Effect struct {
+	Type EffectType
+	// Cutoff or center frequency of a filter, in hertz
+	Frequency float64
+	// Resonance of a filter, or the width of a peaking filter
+	Q float64
+	// Boost or cut of a peaking filter, in decibels
+	Gain float64
+	// Bit depth of a bit crusher, from 1 to 24
+	Bits int
+	// Number of samples a bit crusher holds each sample for, from 1 to 64
+	Downsample int
+	// Amount a saturator boosts the audio by before clipping it, from 1 to 50
+	Drive float64
+	// Boost (positive) or cut (negative) of the start of each hit, from -1 to 1
+	Attack float64
+	// Boost (positive) or cut (negative) of the rest of each hit, from -1 to 1
+	Sustain float64
+}
+
+func (e Effect) validate() error {
+	switch e.Type {
+	case LowPass, HighPass, Peaking:
+		if e.Frequency < MinFilterFrequency || e.Frequency > MaxFilterFrequency {
+			return fmt.Errorf("%s frequency must be between %d and %d hertz", e.Type, MinFilterFrequency, MaxFilterFrequency)
+		}
+
+		if e.Q < MinFilterQ || e.Q > MaxFilterQ {
+			return fmt.Errorf("%s Q must be between %v and %v", e.Type, MinFilterQ, MaxFilterQ)
+		}
+
+		if e.Type == Peaking && (e.Gain < -MaxPeakingGain || e.Gain > MaxPeakingGain) {
+			return fmt.Errorf("peaking gain must be between -%d and %d decibels", MaxPeakingGain, MaxPeakingGain)
+		}
+	case BitCrush:
+		if e.Bits < 1 || e.Bits > MaxBits {
+			return fmt.Errorf("bitcrush bits must be between 1 and %d", MaxBits)
+		}
+
+		if e.Downsample < 1 || e.Downsample > MaxDownsample {
+			return fmt.Errorf("bitcrush downsample must be between 1 and %d", MaxDownsample)
+		}
+	case Saturate:
+		if e.Drive < 1 || e.Drive > MaxDrive {
+			return fmt.Errorf("saturate drive must be between 1 and %d", MaxDrive)
+		}
+	case Transient:
+		if e.Attack < -1 || e.Attack > 1 || e.Sustain < -1 || e.Sustain > 1 {
+			return errors.New("transient attack and sustain must be between -1 and 1")
+		}
+	default:
+		return fmt.Errorf("unknown effect type %q", e.Type)
+	}
+
+	return nil
+}
+
+// String describes the effect and its params.
+func (e Effect) String() string {
+	switch e.Type {
+	case LowPass, HighPass:
+		return fmt.Sprintf("%s %.fHz Q%.2g", e.Type, e.Frequency, e.Q)
+	case Peaking:
+		return fmt.Sprintf("%s %.fHz Q%.2g %+.1fdB", e.Type, e.Frequency, e.Q, e.Gain)
+	case BitCrush:
+		return fmt.Sprintf("%s %d bits /%d", e.Type, e.Bits, e.Downsample)
+	case Saturate:
+		return fmt.Sprintf("%s drive %.2g", e.Type, e.Drive)
+	case Transient:
+		return fmt.Sprintf("%s attack %+.2f sustain %+.2f", e.Type, e.Attack, e.Sustain)
+	default:
+		return string(e.Type)
+	}
+}
+
+// apply wraps the streamer in the effect. Effects keep state between samples,
+// so each hit needs its own.
+func (e Effect) apply(streamer beep.Streamer, sampleRate beep.SampleRate) beep.Streamer {
+	switch e.Type {
+	case LowPass, HighPass, Peaking:
+		return newBiquad(streamer, e, sampleRate)
+	case BitCrush:
+		return &bitCrusher{streamer: streamer, levels: math.Pow(2, float64(e.Bits-1)), downsample: e.Downsample}
+	case Saturate:
+		return &saturator{streamer: streamer, drive: e.Drive, normalize: 1 / math.Tanh(e.Drive)}
+	case Transient:
+		return newTransientShaper(streamer, e, sampleRate)
+	default:
+		return streamer
+	}
+}
+
+// applyEffects wraps the streamer in each of the effects, in order.
+func applyEffects(streamer beep.Streamer, effects []Effect, sampleRate beep.SampleRate) beep.Streamer {
+	for _, effect := range effects {
+		streamer = effect.apply(streamer, sampleRate)
+	}
+
+	return streamer
+}
+
+// biquad is a second order filter, using the coefficients from Robert
+// Bristow-Johnson's Audio EQ Cookbook.
+type biquad struct {
+	streamer           beep.Streamer
+	b0, b1, b2, a1, a2 float64
+	// Previous two inputs and outputs of each channel
+	x1, x2, y1, y2 [2]float64
+}
+
+func newBiquad(streamer beep.Streamer, e Effect, sampleRate beep.SampleRate) *biquad {
+	w0 := 2 * math.Pi * math.Min(e.Frequency, float64(sampleRate)*0.49) / float64(sampleRate)
+	cos := math.Cos(w0)
+	alpha := math.Sin(w0) / (2 * e.Q)
+
+	var b0, b1, b2, a0, a1, a2 float64
+
+	switch e.Type {
+	case LowPass:
+		b0, b1, b2 = (1-cos)/2, 1-cos, (1-cos)/2
+		a0, a1, a2 = 1+alpha, -2*cos, 1-alpha
+	case HighPass:
+		b0, b1, b2 = (1+cos)/2, -(1 + cos), (1+cos)/2
+		a0, a1, a2 = 1+alpha, -2*cos, 1-alpha
+	case Peaking:
+		a := math.Pow(10, e.Gain/40)
+		b0, b1, b2 = 1+alpha*a, -2*cos, 1-alpha*a
+		a0, a1, a2 = 1+alpha/a, -2*cos, 1-alpha/a
+	}
+
+	return &biquad{
+		streamer: streamer,
+		b0:       b0 / a0,
+		b1:       b1 / a0,
+		b2:       b2 / a0,
+		a1:       a1 / a0,
+		a2:       a2 / a0,
+	}
+}
+
+// Stream streams the filtered audio.
+func (f *biquad) Stream(samples [][2]float64) (int, bool) {
+	n, ok := f.streamer.Stream(samples)
+
+	for i := 0; i < n; i++ {
+		for c := 0; c < 2; c++ {
+			x := samples[i][c]
+			y := f.b0*x + f.b1*f.x1[c] + f.b2*f.x2[c] - f.a1*f.y1[c] - f.a2*f.y2[c]
+
+			f.x2[c], f.x1[c] = f.x1[c], x
+			f.y2[c], f.y1[c] = f.y1[c], y
+
+			samples[i][c] = y
+		}
+	}
+
+	return n, ok
+}
+
+// Err returns any error from the underlying streamer.
+func (f *biquad) Err() error {
+	return f.streamer.Err()
+}
+
+// bitCrusher reduces the bit depth and sample rate of audio.
+type bitCrusher struct {
+	streamer beep.Streamer
+	// Number of levels each side of zero a sample is rounded to
+	levels     float64
+	downsample int
+	held       [2]float64
+	position   int
+}
+
+// Stream streams the crushed audio.
+func (b *bitCrusher) Stream(samples [][2]float64) (int, bool) {
+	n, ok := b.streamer.Stream(samples)
+
+	for i := 0; i < n; i++ {
+		if b.position%b.downsample == 0 {
+			for c := 0; c < 2; c++ {
+				b.held[c] = math.Round(samples[i][c]*b.levels) / b.levels
+			}
+		}
+
+		samples[i] = b.held
+		b.position++
+	}
+
+	return n, ok
+}
+
+// Err returns any error from the underlying streamer.
+func (b *bitCrusher) Err() error {
+	return b.streamer.Err()
+}
+
+// saturator softly clips audio, keeping full scale audio at full scale.
+type saturator struct {
+	streamer  beep.Streamer
+	drive     float64
+	normalize float64
+}
+
+// Stream streams the saturated audio.
+func (s *saturator) Stream(samples [][2]float64) (int, bool) {
+	n, ok := s.streamer.Stream(samples)
+
+	for i := 0; i < n; i++ {
+		for c := 0; c < 2; c++ {
+			samples[i][c] = math.Tanh(samples[i][c]*s.drive) * s.normalize
+		}
+	}
+
+	return n, ok
+}
+
+// Err returns any error from the underlying streamer.
+func (s *saturator) Err() error {
+	return s.streamer.Err()
+}
+
+// transientShaper changes the level of the start of each hit independently of
+// the rest of it, by comparing a fast and a slow envelope follower.
+type transientShaper struct {
+	streamer beep.Streamer
+	attack   float64
+	sustain  float64
+	// Smoothing coefficients of the envelope followers
+	fastAttack, slowAttack, release float64
+	fast, slow                      float64
+}
+
+func newTransientShaper(streamer beep.Streamer, e Effect, sampleRate beep.SampleRate) *transientShaper {
+	return &transientShaper{
+		streamer:   streamer,
+		attack:     e.Attack,
+		sustain:    e.Sustain,
+		fastAttack: smoothingCoefficient(transientFastAttack, sampleRate),
+		slowAttack: smoothingCoefficient(transientSlowAttack, sampleRate),
+		release:    smoothingCoefficient(transientRelease, sampleRate),
+	}
+}
+
+// smoothingCoefficient returns how far a one pole envelope follower moves
+// towards its input each sample, to settle over duration.
+func smoothingCoefficient(duration time.Duration, sampleRate beep.SampleRate) float64 {
+	return 1 - math.Exp(-1/float64(sampleRate.N(duration)+1))
+}
+
+// Stream streams the shaped audio.
+func (t *transientShaper) Stream(samples [][2]float64) (int, bool) {
+	n, ok := t.streamer.Stream(samples)
+
+	for i := 0; i < n; i++ {
+		level := math.Max(math.Abs(samples[i][0]), math.Abs(samples[i][1]))
+
+		t.fast = follow(t.fast, level, t.fastAttack, t.release)
+		t.slow = follow(t.slow, level, t.slowAttack, t.release)
+
+		// how much of the current level is the start of a hit, from 0 to 1
+		transient := 0.0
+		if t.fast > 0 {
+			transient = math.Max(0, t.fast-t.slow) / t.fast
+		}
+
+		gain := math.Max(0, 1+transientAttackRange*t.attack*transient+t.sustain*(1-transient))
+
+		samples[i][0] *= gain
+		samples[i][1] *= gain
+	}
+
+	return n, ok
+}
+
+// Err returns any error from the underlying streamer.
+func (t *transientShaper) Err() error {
+	return t.streamer.Err()
+}
+
+// follow moves an envelope follower towards the level, rising by attack and
+// falling by release.
+func follow(envelope, level, attack, release float64) float64 {
+	if level > envelope {
+		return envelope + attack*(level-envelope)
+	}
+
+	return envelope + release*(level-envelope)
+}
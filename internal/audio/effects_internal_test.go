@@ -0,0 +1,218 @@
+package audio
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/faiface/beep"
+	"github.com/stretchr/testify/assert"
+)
+
+// sineSamples returns length samples of a full scale sine wave at frequency.
+func sineSamples(frequency float64, length int) [][2]float64 {
+	samples := make([][2]float64, length)
+	for i := range samples {
+		v := math.Sin(2 * math.Pi * frequency * float64(i) / float64(sampleRate))
+		samples[i] = [2]float64{v, v}
+	}
+
+	return samples
+}
+
+// streamAll streams every sample out of the streamer.
+func streamAll(streamer beep.Streamer, length int) [][2]float64 {
+	samples := make([][2]float64, length)
+	n, _ := streamer.Stream(samples)
+
+	return samples[:n]
+}
+
+// peak returns the loudest sample in the second half of the samples, once any
+// filter has settled.
+func peak(samples [][2]float64) float64 {
+	p := 0.0
+	for _, sample := range samples[len(samples)/2:] {
+		p = math.Max(p, math.Abs(sample[0]))
+	}
+
+	return p
+}
+
+func TestEffectValidate(t *testing.T) {
+	type testCase struct {
+		description     string
+		input           Effect
+		expectedToError bool
+	}
+
+	testCases := []testCase{
+		{
+			description:     "Succeeds with valid filter",
+			input:           Effect{Type: LowPass, Frequency: 1000, Q: 0.7},
+			expectedToError: false,
+		},
+		{
+			description:     "Errors with filter frequency too high",
+			input:           Effect{Type: HighPass, Frequency: 30000, Q: 0.7},
+			expectedToError: true,
+		},
+		{
+			description:     "Errors with filter without Q",
+			input:           Effect{Type: LowPass, Frequency: 1000},
+			expectedToError: true,
+		},
+		{
+			description:     "Errors with peaking gain out of range",
+			input:           Effect{Type: Peaking, Frequency: 1000, Q: 1, Gain: 30},
+			expectedToError: true,
+		},
+		{
+			description:     "Succeeds with valid bit crusher",
+			input:           Effect{Type: BitCrush, Bits: 8, Downsample: 4},
+			expectedToError: false,
+		},
+		{
+			description:     "Errors with bit crusher without downsample",
+			input:           Effect{Type: BitCrush, Bits: 8},
+			expectedToError: true,
+		},
+		{
+			description:     "Errors with saturator drive too low",
+			input:           Effect{Type: Saturate, Drive: 0.5},
+			expectedToError: true,
+		},
+		{
+			description:     "Errors with transient attack out of range",
+			input:           Effect{Type: Transient, Attack: 2},
+			expectedToError: true,
+		},
+		{
+			description:     "Errors with unknown effect type",
+			input:           Effect{Type: "reverb"},
+			expectedToError: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		actualErr := testCase.input.validate()
+		if testCase.expectedToError {
+			assert.NotNil(t, actualErr, testCase.description)
+		} else {
+			assert.Nil(t, actualErr, testCase.description)
+		}
+	}
+}
+
+func TestBiquad(t *testing.T) {
+	type input struct {
+		effect    Effect
+		frequency float64
+	}
+
+	type testCase struct {
+		description  string
+		input        input
+		expectedPeak float64
+		delta        float64
+	}
+
+	testCases := []testCase{
+		{
+			description:  "Low pass passes low frequencies",
+			input:        input{effect: Effect{Type: LowPass, Frequency: 5000, Q: 0.707}, frequency: 100},
+			expectedPeak: 1,
+			delta:        0.01,
+		},
+		{
+			description:  "Low pass cuts high frequencies",
+			input:        input{effect: Effect{Type: LowPass, Frequency: 200, Q: 0.707}, frequency: 5000},
+			expectedPeak: 0,
+			delta:        0.01,
+		},
+		{
+			description:  "High pass cuts low frequencies",
+			input:        input{effect: Effect{Type: HighPass, Frequency: 5000, Q: 0.707}, frequency: 100},
+			expectedPeak: 0,
+			delta:        0.01,
+		},
+		{
+			description:  "Peaking boosts its center frequency",
+			input:        input{effect: Effect{Type: Peaking, Frequency: 1000, Q: 1, Gain: 6}, frequency: 1000},
+			expectedPeak: math.Pow(10, 6.0/20),
+			delta:        0.02,
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		samples := sineSamples(testCase.input.frequency, 8820)
+		filtered := streamAll(testCase.input.effect.apply(samplesStreamer(samples), sampleRate), len(samples))
+
+		assert.InDelta(t, testCase.expectedPeak, peak(filtered), testCase.delta, testCase.description)
+	}
+}
+
+func TestBitCrusher(t *testing.T) {
+	samples := [][2]float64{{0.3, -0.3}, {0.9, 0.9}, {-0.6, 0.1}, {0.1, 0.1}}
+
+	crushed := streamAll(Effect{Type: BitCrush, Bits: 2, Downsample: 2}.apply(samplesStreamer(samples), sampleRate), len(samples))
+
+	assert.Equal(t, [][2]float64{{0.5, -0.5}, {0.5, -0.5}, {-0.5, 0}, {-0.5, 0}}, crushed)
+}
+
+func TestSaturator(t *testing.T) {
+	samples := [][2]float64{{1, -1}, {0.1, 0}}
+
+	saturated := streamAll(Effect{Type: Saturate, Drive: 4}.apply(samplesStreamer(samples), sampleRate), len(samples))
+
+	assert.InDelta(t, 1, saturated[0][0], 1e-9)
+	assert.InDelta(t, -1, saturated[0][1], 1e-9)
+	assert.Greater(t, saturated[1][0], 0.1)
+	assert.Equal(t, 0.0, saturated[1][1])
+}
+
+func TestTransientShaper(t *testing.T) {
+	type testCase struct {
+		description   string
+		input         Effect
+		expectedStart func(float64) bool
+		expectedBody  func(float64) bool
+	}
+
+	testCases := []testCase{
+		{
+			description:   "Boosting attack boosts start of hit only",
+			input:         Effect{Type: Transient, Attack: 1},
+			expectedStart: func(gain float64) bool { return gain > 1.5 },
+			expectedBody:  func(gain float64) bool { return math.Abs(gain-1) < 0.05 },
+		},
+		{
+			description:   "Cutting sustain cuts rest of hit only",
+			input:         Effect{Type: Transient, Sustain: -0.5},
+			expectedStart: func(gain float64) bool { return gain > 0.7 },
+			expectedBody:  func(gain float64) bool { return math.Abs(gain-0.5) < 0.05 },
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		// a hit of constant level, so the gain applied can be read straight off
+		samples := make([][2]float64, sampleRate.N(200*time.Millisecond))
+		for i := range samples {
+			samples[i] = [2]float64{0.5, 0.5}
+		}
+
+		shaped := streamAll(testCase.input.apply(samplesStreamer(samples), sampleRate), len(samples))
+
+		startGain := shaped[sampleRate.N(2*time.Millisecond)][0] / 0.5
+		bodyGain := shaped[len(shaped)-1][0] / 0.5
+
+		assert.True(t, testCase.expectedStart(startGain), "%s: start gain %v", testCase.description, startGain)
+		assert.True(t, testCase.expectedBody(bodyGain), "%s: body gain %v", testCase.description, bodyGain)
+	}
+}
@@ -0,0 +1,192 @@
+package audio
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Ranges the master bus' params must be within, with gains in decibels
+const (
+	MinMasterGain          = -24
+	MaxMasterGain          = 12
+	MinCompressorThreshold = -60
+	MinCompressorRatio     = 1
+	MaxCompressorRatio     = 20
+	MaxCompressorMakeup    = 24
+	MinCompressorAttack    = 100 * time.Microsecond
+	MaxCompressorAttack    = 200 * time.Millisecond
+	MinCompressorRelease   = 10 * time.Millisecond
+	MaxCompressorRelease   = 2 * time.Second
+)
+
+const (
+	// Highest level the limiter lets through, just under full scale (-0.3dB)
+	limiterCeiling = 0.966
+	// Time the limiter takes to let go of a peak
+	limiterRelease = 50 * time.Millisecond
+)
+
+// CompressorParams shapes the master bus' glue compressor.
+type CompressorParams struct {
+	// Whether the compressor is used at all
+	Enabled bool
+	// Level above which the audio is compressed, in decibels from -60 to 0
+	Threshold float64
+	// How much audio above the threshold is reduced by, from 1 (not at all) to 20
+	Ratio float64
+	// Time the compressor takes to react to audio above the threshold
+	Attack time.Duration
+	// Time the compressor takes to let go once audio falls below the threshold
+	Release time.Duration
+	// Gain applied after compressing, in decibels from 0 to 24
+	Makeup float64
+}
+
+// MasterParams shapes the master bus every Manager is played through. A peak
+// limiter always follows the compressor and gain, so the mix never clips.
+type MasterParams struct {
+	// Gain applied to the whole mix, in decibels from -24 to 12
+	Gain       float64
+	Compressor CompressorParams
+}
+
+// DefaultMasterParams returns the master bus' params before any are set.
+func DefaultMasterParams() MasterParams {
+	return MasterParams{
+		Compressor: CompressorParams{
+			Threshold: -18,
+			Ratio:     4,
+			Attack:    10 * time.Millisecond,
+			Release:   100 * time.Millisecond,
+		},
+	}
+}
+
+// Validate checks that the params are within range.
+func (p MasterParams) Validate() error {
+	if p.Gain < MinMasterGain || p.Gain > MaxMasterGain {
+		return fmt.Errorf("master gain must be between %d and %d decibels", MinMasterGain, MaxMasterGain)
+	}
+
+	c := p.Compressor
+	if c.Threshold < MinCompressorThreshold || c.Threshold > 0 {
+		return fmt.Errorf("compressor threshold must be between %d and 0 decibels", MinCompressorThreshold)
+	}
+
+	if c.Ratio < MinCompressorRatio || c.Ratio > MaxCompressorRatio {
+		return fmt.Errorf("compressor ratio must be between %d and %d", MinCompressorRatio, MaxCompressorRatio)
+	}
+
+	if c.Attack < MinCompressorAttack || c.Attack > MaxCompressorAttack {
+		return fmt.Errorf("compressor attack must be between %v and %v", MinCompressorAttack, MaxCompressorAttack)
+	}
+
+	if c.Release < MinCompressorRelease || c.Release > MaxCompressorRelease {
+		return fmt.Errorf("compressor release must be between %v and %v", MinCompressorRelease, MaxCompressorRelease)
+	}
+
+	if c.Makeup < 0 || c.Makeup > MaxCompressorMakeup {
+		return fmt.Errorf("compressor makeup gain must be between 0 and %d decibels", MaxCompressorMakeup)
+	}
+
+	return nil
+}
+
+// SetMaster sets the params of the master bus every Manager is played through.
+// Returns an error if the params are invalid.
+func SetMaster(params MasterParams) error {
+	return bus.setMaster(params)
+}
+
+// Clipped reports whether the mix has gone over full scale since Clipped was
+// last called. The limiter keeps such peaks from clipping the speaker, but
+// catching them squashes the mix.
+func Clipped() bool {
+	return bus.clipped()
+}
+
+// master is the last stage of the mixer: an optional compressor, then gain,
+// then a peak limiter.
+type master struct {
+	params MasterParams
+	// Smoothing coefficients of the compressor's envelope follower
+	attack, release float64
+	// Gain reduction of the compressor, in decibels
+	reduction float64
+	// Gain of the limiter, from 0 to 1
+	limit        float64
+	limitRelease float64
+	clipped      bool
+}
+
+func newMaster() *master {
+	m := &master{
+		limit:        1,
+		limitRelease: smoothingCoefficient(limiterRelease, sampleRate),
+	}
+
+	m.setParams(DefaultMasterParams())
+
+	return m
+}
+
+func (m *master) setParams(params MasterParams) {
+	m.params = params
+	m.attack = smoothingCoefficient(params.Compressor.Attack, sampleRate)
+	m.release = smoothingCoefficient(params.Compressor.Release, sampleRate)
+}
+
+// process compresses, gains and limits samples in place.
+func (m *master) process(samples [][2]float64) {
+	c := m.params.Compressor
+	gain := decibels(m.params.Gain)
+
+	for i := range samples {
+		if c.Enabled {
+			level := toDecibels(math.Max(math.Abs(samples[i][0]), math.Abs(samples[i][1])))
+
+			target := 0.0
+			if level > c.Threshold {
+				target = (level - c.Threshold) * (1 - 1/c.Ratio)
+			}
+
+			m.reduction = follow(m.reduction, target, m.attack, m.release)
+
+			compression := decibels(c.Makeup - m.reduction)
+			samples[i][0] *= compression
+			samples[i][1] *= compression
+		}
+
+		samples[i][0] *= gain
+		samples[i][1] *= gain
+
+		peak := math.Max(math.Abs(samples[i][0]), math.Abs(samples[i][1]))
+		if peak > 1 {
+			m.clipped = true
+		}
+
+		// catch peaks straight away, and let go of them slowly
+		m.limit += m.limitRelease * (1 - m.limit)
+		if peak*m.limit > limiterCeiling {
+			m.limit = limiterCeiling / peak
+		}
+
+		samples[i][0] *= m.limit
+		samples[i][1] *= m.limit
+	}
+}
+
+// decibels converts decibels to a gain.
+func decibels(db float64) float64 {
+	return math.Pow(10, db/20)
+}
+
+// toDecibels converts a level to decibels, with silence at -120dB.
+func toDecibels(level float64) float64 {
+	if level < 1e-6 {
+		return -120
+	}
+
+	return 20 * math.Log10(level)
+}
@@ -0,0 +1,137 @@
+package audio
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMasterParamsValidate(t *testing.T) {
+	type testCase struct {
+		description     string
+		input           func(*MasterParams)
+		expectedToError bool
+	}
+
+	testCases := []testCase{
+		{
+			description:     "Validates default params",
+			input:           func(p *MasterParams) {},
+			expectedToError: false,
+		},
+		{
+			description:     "Errors with gain too high",
+			input:           func(p *MasterParams) { p.Gain = 13 },
+			expectedToError: true,
+		},
+		{
+			description:     "Errors with threshold above 0",
+			input:           func(p *MasterParams) { p.Compressor.Threshold = 1 },
+			expectedToError: true,
+		},
+		{
+			description:     "Errors with ratio below 1",
+			input:           func(p *MasterParams) { p.Compressor.Ratio = 0.5 },
+			expectedToError: true,
+		},
+		{
+			description:     "Errors with attack too long",
+			input:           func(p *MasterParams) { p.Compressor.Attack = time.Second },
+			expectedToError: true,
+		},
+		{
+			description:     "Errors with release too short",
+			input:           func(p *MasterParams) { p.Compressor.Release = time.Millisecond },
+			expectedToError: true,
+		},
+		{
+			description:     "Errors with negative makeup gain",
+			input:           func(p *MasterParams) { p.Compressor.Makeup = -1 },
+			expectedToError: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		params := DefaultMasterParams()
+		testCase.input(&params)
+
+		actualErr := params.Validate()
+		if testCase.expectedToError {
+			assert.NotNil(t, actualErr)
+		} else {
+			assert.Nil(t, actualErr)
+		}
+	}
+}
+
+func TestMasterProcess(t *testing.T) {
+	type testCase struct {
+		description    string
+		params         func(*MasterParams)
+		level          float64
+		expectedOutput float64
+		expectedClip   bool
+	}
+
+	testCases := []testCase{
+		{
+			description:    "Passes quiet audio unchanged",
+			params:         func(p *MasterParams) {},
+			level:          0.5,
+			expectedOutput: 0.5,
+			expectedClip:   false,
+		},
+		{
+			description:    "Applies master gain",
+			params:         func(p *MasterParams) { p.Gain = -6 },
+			level:          0.5,
+			expectedOutput: 0.5 * math.Pow(10, -6.0/20),
+			expectedClip:   false,
+		},
+		{
+			description:    "Limits audio over full scale",
+			params:         func(p *MasterParams) {},
+			level:          4,
+			expectedOutput: limiterCeiling,
+			expectedClip:   true,
+		},
+		{
+			description: "Compresses audio over the threshold",
+			params: func(p *MasterParams) {
+				p.Compressor.Enabled = true
+				p.Compressor.Threshold = -12
+				p.Compressor.Ratio = 4
+			},
+			// 6dB over the threshold is compressed to 1.5dB over it
+			level:          math.Pow(10, -6.0/20),
+			expectedOutput: math.Pow(10, -10.5/20),
+			expectedClip:   false,
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		params := DefaultMasterParams()
+		testCase.params(&params)
+
+		m := newMaster()
+		m.setParams(params)
+
+		samples := make([][2]float64, sampleRate.N(time.Second))
+		for i := range samples {
+			samples[i] = [2]float64{testCase.level, -testCase.level}
+		}
+
+		m.process(samples)
+
+		last := samples[len(samples)-1]
+		assert.InDelta(t, testCase.expectedOutput, last[0], 1e-3, testCase.description)
+		assert.InDelta(t, -testCase.expectedOutput, last[1], 1e-3, testCase.description)
+		assert.Equal(t, testCase.expectedClip, m.clipped, testCase.description)
+	}
+}
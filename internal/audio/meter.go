@@ -0,0 +1,42 @@
+package audio
+
+import (
+	"math"
+	"time"
+)
+
+// Time a meter takes to fall to about a third of its peak once the audio it's
+// following stops
+const meterFallDuration = 300 * time.Millisecond
+
+// meter follows the peak level of everything a Manager plays, falling back
+// towards silence once it stops. Meters are updated by the mixer, and are
+// read and written while holding its lock.
+type meter struct {
+	peak float64
+	// Position of the mixer, in samples, when the peak was set
+	at int
+}
+
+// level returns the meter's level once the mixer has reached position.
+func (m *meter) level(position int) float64 {
+	elapsed := float64(position - m.at)
+	if elapsed <= 0 {
+		return m.peak
+	}
+
+	return m.peak * math.Exp(-elapsed/float64(sampleRate.N(meterFallDuration)))
+}
+
+// update raises the meter to the loudest of samples, which end at position.
+func (m *meter) update(samples [][2]float64, position int) {
+	peak := 0.0
+	for _, sample := range samples {
+		peak = math.Max(peak, math.Max(math.Abs(sample[0]), math.Abs(sample[1])))
+	}
+
+	if peak >= m.level(position) {
+		m.peak = peak
+		m.at = position
+	}
+}
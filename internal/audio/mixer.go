@@ -0,0 +1,380 @@
+package audio
+
+import (
+	"sync"
+
+	"github.com/faiface/beep"
+	"github.com/pkg/errors"
+)
+
+// Freeverb tunings, in samples at 44.1kHz. See
+// https://ccrma.stanford.edu/~jos/pasp/Freeverb.html for details.
+var (
+	combTunings    = []int{1116, 1188, 1277, 1356, 1422, 1491, 1557, 1617}
+	allpassTunings = []int{556, 441, 341, 225}
+)
+
+const (
+	// Extra delay given to the right channel, which widens the reverb
+	stereoSpread    = 23
+	reverbInputGain = 0.015
+	allpassFeedback = 0.5
+)
+
+// channel is a single hit playing through the mixer.
+type channel struct {
+	streamer beep.Streamer
+	sends    Sends
+	// Meter following the hit's level, or nil
+	meter *meter
+}
+
+// mixer mixes every hit together, feeding their sends through the shared
+// reverb and delay, and the mix through the master bus. It is played on the
+// speaker once, and never finishes.
+type mixer struct {
+	mu             sync.Mutex
+	channels       []*channel
+	params         BusParams
+	beatsPerMinute float64
+	reverb         *freeverb
+	delay          *delayLine
+	master         *master
+	// Number of samples mixed so far, which meters are timed by
+	position int
+	// Scratch buffers, reused between calls to Stream
+	hit, reverbIn, delayIn, wet [][2]float64
+}
+
+var _ beep.Streamer = new(mixer)
+
+// bus is shared by every Manager, so that they can share effects.
+var bus = newMixer()
+
+func newMixer() *mixer {
+	m := &mixer{
+		params:         DefaultBusParams(),
+		beatsPerMinute: 120,
+		reverb:         newFreeverb(),
+		delay:          newDelayLine(sampleRate.N(maxDelayDuration)),
+		master:         newMaster(),
+	}
+
+	m.reverb.setParams(m.params.Reverb)
+	m.updateDelay()
+
+	return m
+}
+
+// add starts playing the streamer through the mixer, following its level on
+// the meter if it isn't nil.
+func (m *mixer) add(streamer beep.Streamer, sends Sends, meter *meter) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.channels = append(m.channels, &channel{streamer: streamer, sends: sends, meter: meter})
+}
+
+// level returns the current level of the meter.
+func (m *mixer) level(meter *meter) float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return meter.level(m.position)
+}
+
+// silence fades out every hit playing. Hits which can't be faded are cut off.
+func (m *mixer) silence() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	playing := m.channels[:0]
+	for _, c := range m.channels {
+		if v, ok := c.streamer.(*voice); ok {
+			v.choke()
+			playing = append(playing, c)
+		}
+	}
+
+	for i := len(playing); i < len(m.channels); i++ {
+		m.channels[i] = nil
+	}
+	m.channels = playing
+}
+
+func (m *mixer) setParams(params BusParams) error {
+	if err := params.Validate(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.params = params
+	m.reverb.setParams(params.Reverb)
+	m.updateDelay()
+
+	return nil
+}
+
+func (m *mixer) setMaster(params MasterParams) error {
+	if err := params.Validate(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.master.setParams(params)
+
+	return nil
+}
+
+// clipped reports whether the mix has gone over full scale since it was last
+// called.
+func (m *mixer) clipped() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	clipped := m.master.clipped
+	m.master.clipped = false
+
+	return clipped
+}
+
+func (m *mixer) setTempo(beatsPerMinute float64) error {
+	if beatsPerMinute <= 0 {
+		return errors.New("tempo must be greater than 0")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.beatsPerMinute = beatsPerMinute
+	m.updateDelay()
+
+	return nil
+}
+
+// updateDelay resizes the delay to match its note value at the current tempo.
+// The params are validated before they're set, so the note value is always
+// readable.
+func (m *mixer) updateDelay() {
+	duration, err := m.params.Delay.Note.duration(m.beatsPerMinute)
+	if err != nil {
+		return
+	}
+
+	m.delay.setLength(sampleRate.N(duration))
+	m.delay.feedback = m.params.Delay.Feedback
+}
+
+// Stream mixes every hit, and the shared effects' output, into samples, then
+// passes them through the master bus.
+func (m *mixer) Stream(samples [][2]float64) (int, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	n := len(samples)
+	m.hit = resize(m.hit, n)
+	m.reverbIn = resize(m.reverbIn, n)
+	m.delayIn = resize(m.delayIn, n)
+	m.wet = resize(m.wet, n)
+
+	for i := range samples {
+		samples[i] = [2]float64{}
+		m.reverbIn[i] = [2]float64{}
+		m.delayIn[i] = [2]float64{}
+	}
+
+	playing := m.channels[:0]
+	for _, c := range m.channels {
+		streamed, ok := c.streamer.Stream(m.hit)
+
+		if c.meter != nil {
+			c.meter.update(m.hit[:streamed], m.position+streamed)
+		}
+
+		for i := 0; i < streamed; i++ {
+			for j := 0; j < 2; j++ {
+				samples[i][j] += m.hit[i][j]
+				m.reverbIn[i][j] += m.hit[i][j] * c.sends.Reverb
+				m.delayIn[i][j] += m.hit[i][j] * c.sends.Delay
+			}
+		}
+
+		if ok && streamed == n {
+			playing = append(playing, c)
+		}
+	}
+
+	// clear finished channels so that they can be garbage collected
+	for i := len(playing); i < len(m.channels); i++ {
+		m.channels[i] = nil
+	}
+	m.channels = playing
+
+	m.reverb.process(m.reverbIn, m.wet)
+	for i := range samples {
+		samples[i][0] += m.wet[i][0] * m.params.Reverb.Return
+		samples[i][1] += m.wet[i][1] * m.params.Reverb.Return
+	}
+
+	m.delay.process(m.delayIn, m.wet)
+	for i := range samples {
+		samples[i][0] += m.wet[i][0] * m.params.Delay.Return
+		samples[i][1] += m.wet[i][1] * m.params.Delay.Return
+	}
+
+	m.master.process(samples)
+	m.position += n
+
+	return n, true
+}
+
+// Err always returns nil, as errors from hits end only that hit.
+func (m *mixer) Err() error {
+	return nil
+}
+
+func resize(buffer [][2]float64, length int) [][2]float64 {
+	if cap(buffer) < length {
+		return make([][2]float64, length)
+	}
+
+	return buffer[:length]
+}
+
+// comb is a lowpass feedback comb filter, the building block of the reverb's
+// echoes.
+type comb struct {
+	buffer      []float64
+	position    int
+	feedback    float64
+	damping     float64
+	filterStore float64
+}
+
+func (c *comb) process(input float64) float64 {
+	output := c.buffer[c.position]
+
+	c.filterStore = output*(1-c.damping) + c.filterStore*c.damping
+	c.buffer[c.position] = input + c.filterStore*c.feedback
+
+	c.position = (c.position + 1) % len(c.buffer)
+
+	return output
+}
+
+// allpass diffuses the reverb's echoes without colouring them.
+type allpass struct {
+	buffer   []float64
+	position int
+}
+
+func (a *allpass) process(input float64) float64 {
+	buffered := a.buffer[a.position]
+	output := buffered - input
+
+	a.buffer[a.position] = input + buffered*allpassFeedback
+	a.position = (a.position + 1) % len(a.buffer)
+
+	return output
+}
+
+// freeverb is a stereo Schroeder-Moorer reverb, following Jezar's Freeverb.
+type freeverb struct {
+	combs     [2][]*comb
+	allpasses [2][]*allpass
+}
+
+func newFreeverb() *freeverb {
+	f := &freeverb{}
+
+	for channel, spread := range []int{0, stereoSpread} {
+		for _, tuning := range combTunings {
+			f.combs[channel] = append(f.combs[channel], &comb{buffer: make([]float64, tuning+spread)})
+		}
+
+		for _, tuning := range allpassTunings {
+			f.allpasses[channel] = append(f.allpasses[channel], &allpass{buffer: make([]float64, tuning+spread)})
+		}
+	}
+
+	return f
+}
+
+func (f *freeverb) setParams(params ReverbParams) {
+	for _, combs := range f.combs {
+		for _, c := range combs {
+			c.feedback = 0.7 + params.RoomSize*0.28
+			c.damping = params.Damping * 0.4
+		}
+	}
+}
+
+// process writes the reverb of in into out.
+func (f *freeverb) process(in, out [][2]float64) {
+	for i := range in {
+		input := (in[i][0] + in[i][1]) * reverbInputGain
+
+		for channel := 0; channel < 2; channel++ {
+			output := 0.0
+			for _, c := range f.combs[channel] {
+				output += c.process(input)
+			}
+
+			for _, a := range f.allpasses[channel] {
+				output = a.process(output)
+			}
+
+			out[i][channel] = output
+		}
+	}
+}
+
+// delayLine is a stereo feedback delay whose length can change while it plays.
+type delayLine struct {
+	buffer   [][2]float64
+	position int
+	length   int
+	feedback float64
+}
+
+func newDelayLine(maxLength int) *delayLine {
+	return &delayLine{
+		buffer: make([][2]float64, maxLength),
+		length: 1,
+	}
+}
+
+// setLength sets the delay's length in samples, shortening it to fit the
+// buffer if needed.
+func (d *delayLine) setLength(length int) {
+	if length < 1 {
+		length = 1
+	}
+
+	if length > len(d.buffer) {
+		length = len(d.buffer)
+	}
+
+	d.length = length
+}
+
+// process writes the delayed repeats of in into out.
+func (d *delayLine) process(in, out [][2]float64) {
+	size := len(d.buffer)
+
+	for i := range in {
+		read := (d.position - d.length + size) % size
+		delayed := d.buffer[read]
+
+		for channel := 0; channel < 2; channel++ {
+			d.buffer[d.position][channel] = in[i][channel] + delayed[channel]*d.feedback
+		}
+
+		out[i] = delayed
+		d.position = (d.position + 1) % size
+	}
+}
@@ -0,0 +1,197 @@
+package audio
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/faiface/beep"
+	"github.com/stretchr/testify/assert"
+)
+
+// impulse returns a streamer of a single full scale sample.
+func impulse() *voice {
+	return newVoice(samplesStreamer([][2]float64{{1, 1}}), 1)
+}
+
+// Master gain which quarters the mix, keeping it clear of the limiter
+var quarterGain = 20 * math.Log10(0.25)
+
+// quietMixer returns a mixer whose master bus quarters the mix.
+func quietMixer(t *testing.T) *mixer {
+	m := newMixer()
+
+	params := DefaultMasterParams()
+	params.Gain = quarterGain
+	assert.Nil(t, m.setMaster(params))
+
+	return m
+}
+
+func TestMixerStream(t *testing.T) {
+	m := quietMixer(t)
+	assert.Nil(t, m.setParams(BusParams{
+		Reverb: ReverbParams{Return: 0},
+		Delay:  DelayParams{Note: "1/4", Return: 0},
+	}))
+
+	m.add(constantStreamer(10), Sends{}, nil)
+	m.add(constantStreamer(5), Sends{}, nil)
+
+	samples := make([][2]float64, 8)
+	n, ok := m.Stream(samples)
+
+	assert.Equal(t, 8, n)
+	assert.True(t, ok)
+	assert.InDelta(t, 0.5, samples[0][0], 1e-9)
+	assert.InDelta(t, 0.25, samples[7][1], 1e-9)
+
+	// the shorter channel has finished
+	assert.Len(t, m.channels, 1)
+
+	n, ok = m.Stream(samples)
+	assert.Equal(t, 8, n)
+	assert.True(t, ok)
+	assert.InDelta(t, 0.25, samples[1][0], 1e-9)
+	assert.Equal(t, [2]float64{}, samples[2])
+	assert.Len(t, m.channels, 0)
+}
+
+func TestMixerDelay(t *testing.T) {
+	m := quietMixer(t)
+	assert.Nil(t, m.setParams(BusParams{
+		Reverb: ReverbParams{Return: 0},
+		Delay:  DelayParams{Note: "1/4", Feedback: 0.5, Return: 0.8},
+	}))
+
+	// a quarter note at 6000 BPM is 10ms, or 441 samples
+	assert.Nil(t, m.setTempo(6000))
+
+	m.add(impulse(), Sends{Delay: 0.5}, nil)
+
+	samples := make([][2]float64, 1000)
+	m.Stream(samples)
+
+	assert.InDelta(t, 0.25, samples[0][0], 1e-9)
+	assert.InDelta(t, 0.1, samples[441][0], 1e-9)
+	assert.InDelta(t, 0.05, samples[882][0], 1e-9)
+	assert.Equal(t, 0.0, samples[440][0])
+
+	assert.NotNil(t, m.setTempo(0))
+}
+
+func TestMixerReverb(t *testing.T) {
+	m := newMixer()
+	assert.Nil(t, m.setParams(BusParams{
+		Reverb: ReverbParams{RoomSize: 0.8, Damping: 0.2, Return: 1},
+		Delay:  DelayParams{Note: "1/4", Return: 0},
+	}))
+
+	m.add(impulse(), Sends{Reverb: 1}, nil)
+
+	samples := make([][2]float64, sampleRate.N(500*time.Millisecond))
+	m.Stream(samples)
+
+	tail, stereoDifference := 0.0, 0.0
+	for _, sample := range samples[1:] {
+		tail = math.Max(tail, math.Abs(sample[0]))
+		stereoDifference = math.Max(stereoDifference, math.Abs(sample[0]-sample[1]))
+	}
+
+	// the impulse rings on after it has finished, and differently in each
+	// channel
+	assert.Greater(t, tail, 0.0)
+	assert.Greater(t, stereoDifference, 0.0)
+}
+
+func TestMixerLimitsClipping(t *testing.T) {
+	m := newMixer()
+	assert.Nil(t, m.setParams(BusParams{
+		Reverb: ReverbParams{Return: 0},
+		Delay:  DelayParams{Note: "1/4", Return: 0},
+	}))
+
+	for i := 0; i < 4; i++ {
+		m.add(constantStreamer(1000), Sends{}, nil)
+	}
+
+	samples := make([][2]float64, 1000)
+	m.Stream(samples)
+
+	for _, sample := range samples {
+		assert.LessOrEqual(t, math.Abs(sample[0]), limiterCeiling+1e-9)
+	}
+
+	assert.True(t, m.clipped())
+	// reading the clip indicator resets it
+	assert.False(t, m.clipped())
+
+	m.Stream(samples)
+	assert.False(t, m.clipped())
+}
+
+func TestMixerSilence(t *testing.T) {
+	m := quietMixer(t)
+	assert.Nil(t, m.setParams(BusParams{
+		Reverb: ReverbParams{Return: 0},
+		Delay:  DelayParams{Note: "1/4", Return: 0},
+	}))
+
+	m.add(newVoice(constantStreamer(sampleRate.N(time.Second)), sampleRate.N(chokeFadeDuration)), Sends{}, nil)
+	m.add(constantStreamer(sampleRate.N(time.Second)), Sends{}, nil)
+
+	m.silence()
+	assert.Len(t, m.channels, 1)
+
+	samples := make([][2]float64, sampleRate.N(2*chokeFadeDuration))
+	m.Stream(samples)
+
+	// the voice fades out rather than stopping dead
+	assert.Greater(t, samples[0][0], 0.0)
+	assert.Equal(t, 0.0, samples[len(samples)-1][0])
+	assert.Len(t, m.channels, 0)
+}
+
+func TestMixerMeters(t *testing.T) {
+	m := quietMixer(t)
+
+	loud := &meter{}
+	quiet := &meter{}
+
+	m.add(constantStreamer(10), Sends{}, loud)
+	m.add(&scaled{streamer: constantStreamer(10), gain: 0.5}, Sends{}, quiet)
+
+	samples := make([][2]float64, 10)
+	m.Stream(samples)
+
+	// meters follow each hit before the master bus
+	assert.InDelta(t, 1, m.level(loud), 1e-9)
+	assert.InDelta(t, 0.5, m.level(quiet), 1e-9)
+
+	// and fall once the hits stop
+	samples = make([][2]float64, sampleRate.N(meterFallDuration))
+	m.Stream(samples)
+
+	assert.InDelta(t, math.Exp(-1), m.level(loud), 1e-3)
+	assert.InDelta(t, 0.5*math.Exp(-1), m.level(quiet), 1e-3)
+}
+
+// scaled multiplies a streamer by gain.
+type scaled struct {
+	streamer beep.Streamer
+	gain     float64
+}
+
+func (s *scaled) Stream(samples [][2]float64) (int, bool) {
+	n, ok := s.streamer.Stream(samples)
+	for i := 0; i < n; i++ {
+		samples[i][0] *= s.gain
+		samples[i][1] *= s.gain
+	}
+
+	return n, ok
+}
+
+func (s *scaled) Err() error {
+	return nil
+}
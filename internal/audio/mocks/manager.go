@@ -4,13 +4,88 @@
 
 package mocks
 
-import mock "github.com/stretchr/testify/mock"
+import (
+	audio "github.com/jcfox412/logarhythms/internal/audio"
+	mock "github.com/stretchr/testify/mock"
+)
 
 // Manager is an autogenerated mock type for the Manager type
 type Manager struct {
 	mock.Mock
 }
 
+// GetChokeGroup provides a mock function with given fields:
+func (_m *Manager) GetChokeGroup() int {
+	ret := _m.Called()
+
+	var r0 int
+	if rf, ok := ret.Get(0).(func() int); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	return r0
+}
+
+// GetEffects provides a mock function with given fields:
+func (_m *Manager) GetEffects() []audio.Effect {
+	ret := _m.Called()
+
+	var r0 []audio.Effect
+	if rf, ok := ret.Get(0).(func() []audio.Effect); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]audio.Effect)
+		}
+	}
+
+	return r0
+}
+
+// GetPan provides a mock function with given fields:
+func (_m *Manager) GetPan() float64 {
+	ret := _m.Called()
+
+	var r0 float64
+	if rf, ok := ret.Get(0).(func() float64); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(float64)
+	}
+
+	return r0
+}
+
+// GetSampleParams provides a mock function with given fields:
+func (_m *Manager) GetSampleParams() audio.SampleParams {
+	ret := _m.Called()
+
+	var r0 audio.SampleParams
+	if rf, ok := ret.Get(0).(func() audio.SampleParams); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(audio.SampleParams)
+	}
+
+	return r0
+}
+
+// GetSends provides a mock function with given fields:
+func (_m *Manager) GetSends() audio.Sends {
+	ret := _m.Called()
+
+	var r0 audio.Sends
+	if rf, ok := ret.Get(0).(func() audio.Sends); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(audio.Sends)
+	}
+
+	return r0
+}
+
 // GetVolume provides a mock function with given fields:
 func (_m *Manager) GetVolume() float64 {
 	ret := _m.Called()
@@ -25,9 +100,79 @@ func (_m *Manager) GetVolume() float64 {
 	return r0
 }
 
-// Play provides a mock function with given fields:
-func (_m *Manager) Play() {
-	_m.Called()
+// Play provides a mock function with given fields: velocity
+func (_m *Manager) Play(velocity float64) {
+	_m.Called(velocity)
+}
+
+// SetChokeGroup provides a mock function with given fields: _a0
+func (_m *Manager) SetChokeGroup(_a0 int) error {
+	ret := _m.Called(_a0)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(int) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SetEffects provides a mock function with given fields: _a0
+func (_m *Manager) SetEffects(_a0 []audio.Effect) error {
+	ret := _m.Called(_a0)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func([]audio.Effect) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SetPan provides a mock function with given fields: _a0
+func (_m *Manager) SetPan(_a0 float64) error {
+	ret := _m.Called(_a0)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(float64) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SetSampleParams provides a mock function with given fields: _a0
+func (_m *Manager) SetSampleParams(_a0 audio.SampleParams) error {
+	ret := _m.Called(_a0)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(audio.SampleParams) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SetSends provides a mock function with given fields: _a0
+func (_m *Manager) SetSends(_a0 audio.Sends) error {
+	ret := _m.Called(_a0)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(audio.Sends) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
 }
 
 // SetVolume provides a mock function with given fields: _a0
@@ -0,0 +1,184 @@
+package audio
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/faiface/beep"
+	"github.com/pkg/errors"
+)
+
+const (
+	// MaxSemitones is the furthest a sample can be tuned up or down
+	MaxSemitones = 24
+	// MaxCents is the furthest a sample can be fine tuned up or down
+	MaxCents = 100
+)
+
+const (
+	// Quality of the resampling used to tune samples. See
+	// https://godoc.org/github.com/faiface/beep#Resample for details.
+	resampleQuality = 4
+)
+
+// SampleParams controls which part of a sample is played, and how.
+type SampleParams struct {
+	// Offset into the sample at which playback starts
+	Start time.Duration
+	// Offset into the sample at which playback ends, or 0 to play to the end
+	End time.Duration
+	// Whether the sample is played backwards
+	Reverse bool
+	// Tuning of the sample in semitones, between -24 and 24
+	Semitones int
+	// Fine tuning of the sample in cents, between -100 and 100
+	Cents int
+	// Amplitude envelope applied to the sample, or nil to play it untouched
+	Envelope *Envelope
+}
+
+// Envelope shapes the amplitude of a sample over time. The sample fades in
+// over Attack, stays at full volume for Hold, then fades out over Decay.
+type Envelope struct {
+	Attack time.Duration
+	Hold   time.Duration
+	// A Decay of 0 leaves the sample sounding after Hold, rather than cutting it
+	Decay time.Duration
+}
+
+func (p SampleParams) validate() error {
+	if p.Start < 0 || p.End < 0 {
+		return errors.New("sample start and end must not be negative")
+	}
+
+	if p.End != 0 && p.End <= p.Start {
+		return errors.New("sample end must be after sample start")
+	}
+
+	if p.Semitones < -MaxSemitones || p.Semitones > MaxSemitones {
+		return fmt.Errorf("sample tuning must be between -%d and %d semitones", MaxSemitones, MaxSemitones)
+	}
+
+	if p.Cents < -MaxCents || p.Cents > MaxCents {
+		return fmt.Errorf("sample fine tuning must be between -%d and %d cents", MaxCents, MaxCents)
+	}
+
+	if e := p.Envelope; e != nil && (e.Attack < 0 || e.Hold < 0 || e.Decay < 0) {
+		return errors.New("sample envelope times must not be negative")
+	}
+
+	return nil
+}
+
+// pitchRatio returns the playback speed needed to tune the sample.
+func (p SampleParams) pitchRatio() float64 {
+	return math.Pow(2, (float64(p.Semitones)+float64(p.Cents)/100)/12)
+}
+
+// prepareSample returns the part of the buffer selected by the params,
+// reversed if needed, so that the work isn't repeated every time it's played.
+func prepareSample(buffer *beep.Buffer, params SampleParams) *beep.Buffer {
+	format := buffer.Format()
+
+	start := format.SampleRate.N(params.Start)
+	if start > buffer.Len() {
+		start = buffer.Len()
+	}
+
+	end := buffer.Len()
+	if params.End > 0 && format.SampleRate.N(params.End) < end {
+		end = format.SampleRate.N(params.End)
+	}
+
+	prepared := beep.NewBuffer(format)
+	if start >= end {
+		return prepared
+	}
+
+	samples := make([][2]float64, end-start)
+	n, _ := buffer.Streamer(start, end).Stream(samples)
+	samples = samples[:n]
+
+	if params.Reverse {
+		for i, j := 0, len(samples)-1; i < j; i, j = i+1, j-1 {
+			samples[i], samples[j] = samples[j], samples[i]
+		}
+	}
+
+	prepared.Append(samplesStreamer(samples))
+
+	return prepared
+}
+
+// samplesStreamer streams the given samples once.
+func samplesStreamer(samples [][2]float64) beep.Streamer {
+	return beep.StreamerFunc(func(out [][2]float64) (int, bool) {
+		if len(samples) == 0 {
+			return 0, false
+		}
+
+		n := copy(out, samples)
+		samples = samples[n:]
+
+		return n, true
+	})
+}
+
+// envelope applies an Envelope to a streamer. Times are measured in samples.
+type envelope struct {
+	streamer beep.Streamer
+	attack   int
+	hold     int
+	decay    int
+	position int
+}
+
+var _ beep.Streamer = new(envelope)
+
+func newEnvelope(streamer beep.Streamer, e *Envelope, sampleRate beep.SampleRate) *envelope {
+	return &envelope{
+		streamer: streamer,
+		attack:   sampleRate.N(e.Attack),
+		hold:     sampleRate.N(e.Hold),
+		decay:    sampleRate.N(e.Decay),
+	}
+}
+
+// Stream streams the underlying audio, stopping once it has fully decayed.
+func (e *envelope) Stream(samples [][2]float64) (int, bool) {
+	n, ok := e.streamer.Stream(samples)
+
+	for i := 0; i < n; i++ {
+		gain, sounding := e.gain()
+		if !sounding {
+			return i, i > 0
+		}
+
+		samples[i][0] *= gain
+		samples[i][1] *= gain
+		e.position++
+	}
+
+	return n, ok
+}
+
+// Err returns any error from the underlying streamer.
+func (e *envelope) Err() error {
+	return e.streamer.Err()
+}
+
+// gain returns the envelope's gain at its current position, and whether the
+// envelope is still sounding.
+func (e *envelope) gain() (float64, bool) {
+	switch {
+	case e.position < e.attack:
+		return float64(e.position) / float64(e.attack), true
+	case e.position < e.attack+e.hold || e.decay == 0:
+		return 1, true
+	case e.position < e.attack+e.hold+e.decay:
+		return 1 - float64(e.position-e.attack-e.hold)/float64(e.decay), true
+	default:
+		return 0, false
+	}
+}
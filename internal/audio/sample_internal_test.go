@@ -0,0 +1,196 @@
+package audio
+
+import (
+	"testing"
+	"time"
+
+	"github.com/faiface/beep"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSampleParamsValidate(t *testing.T) {
+	type testCase struct {
+		description     string
+		input           SampleParams
+		expectedToError bool
+	}
+
+	testCases := []testCase{
+		{
+			description: "Succeeds with valid params",
+			input: SampleParams{
+				Start:     10 * time.Millisecond,
+				End:       200 * time.Millisecond,
+				Reverse:   true,
+				Semitones: -12,
+				Cents:     50,
+				Envelope:  &Envelope{Attack: time.Millisecond, Hold: 50 * time.Millisecond, Decay: 100 * time.Millisecond},
+			},
+			expectedToError: false,
+		},
+		{
+			description:     "Errors with negative start",
+			input:           SampleParams{Start: -time.Millisecond},
+			expectedToError: true,
+		},
+		{
+			description:     "Errors with end before start",
+			input:           SampleParams{Start: 20 * time.Millisecond, End: 10 * time.Millisecond},
+			expectedToError: true,
+		},
+		{
+			description:     "Errors with tuning out of range",
+			input:           SampleParams{Semitones: 25},
+			expectedToError: true,
+		},
+		{
+			description:     "Errors with fine tuning out of range",
+			input:           SampleParams{Cents: -101},
+			expectedToError: true,
+		},
+		{
+			description:     "Errors with negative envelope time",
+			input:           SampleParams{Envelope: &Envelope{Decay: -time.Millisecond}},
+			expectedToError: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		actualErr := testCase.input.validate()
+		if testCase.expectedToError {
+			assert.NotNil(t, actualErr)
+		} else {
+			assert.Nil(t, actualErr)
+		}
+	}
+}
+
+func TestSampleParamsPitchRatio(t *testing.T) {
+	type testCase struct {
+		description    string
+		input          SampleParams
+		expectedOutput float64
+	}
+
+	testCases := []testCase{
+		{
+			description:    "Leaves untuned sample at original speed",
+			input:          SampleParams{},
+			expectedOutput: 1,
+		},
+		{
+			description:    "Doubles speed an octave up",
+			input:          SampleParams{Semitones: 12},
+			expectedOutput: 2,
+		},
+		{
+			description:    "Halves speed an octave down using cents",
+			input:          SampleParams{Semitones: -11, Cents: -100},
+			expectedOutput: 0.5,
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		actualOutput := testCase.input.pitchRatio()
+		assert.InDelta(t, testCase.expectedOutput, actualOutput, 1e-9)
+	}
+}
+
+func TestPrepareSample(t *testing.T) {
+	type testCase struct {
+		description    string
+		input          SampleParams
+		expectedOutput [][2]float64
+	}
+
+	// 1 sample per millisecond keeps offsets easy to follow
+	format := beep.Format{SampleRate: 1000, NumChannels: 2, Precision: 3}
+
+	testCases := []testCase{
+		{
+			description:    "Plays whole sample by default",
+			input:          SampleParams{},
+			expectedOutput: [][2]float64{{0, 0}, {0.25, 0.25}, {0.5, 0.5}, {0.75, 0.75}},
+		},
+		{
+			description:    "Trims start and end",
+			input:          SampleParams{Start: time.Millisecond, End: 3 * time.Millisecond},
+			expectedOutput: [][2]float64{{0.25, 0.25}, {0.5, 0.5}},
+		},
+		{
+			description:    "Reverses sample",
+			input:          SampleParams{Start: time.Millisecond, Reverse: true},
+			expectedOutput: [][2]float64{{0.75, 0.75}, {0.5, 0.5}, {0.25, 0.25}},
+		},
+		{
+			description:    "Handles start past the end of the sample",
+			input:          SampleParams{Start: time.Second},
+			expectedOutput: [][2]float64{},
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		buffer := beep.NewBuffer(format)
+		buffer.Append(samplesStreamer([][2]float64{{0, 0}, {0.25, 0.25}, {0.5, 0.5}, {0.75, 0.75}}))
+
+		prepared := prepareSample(buffer, testCase.input)
+
+		actualOutput := make([][2]float64, prepared.Len())
+		prepared.Streamer(0, prepared.Len()).Stream(actualOutput)
+
+		assert.Equal(t, len(testCase.expectedOutput), len(actualOutput))
+		for i := range actualOutput {
+			assert.InDelta(t, testCase.expectedOutput[i][0], actualOutput[i][0], 1e-6)
+			assert.InDelta(t, testCase.expectedOutput[i][1], actualOutput[i][1], 1e-6)
+		}
+	}
+}
+
+func TestEnvelopeStream(t *testing.T) {
+	type testCase struct {
+		description     string
+		input           *Envelope
+		expectedSamples []float64
+	}
+
+	testCases := []testCase{
+		{
+			description:     "Attacks, holds and decays",
+			input:           &Envelope{Attack: 2 * time.Millisecond, Hold: time.Millisecond, Decay: 2 * time.Millisecond},
+			expectedSamples: []float64{0, 0.5, 1, 1, 0.5},
+		},
+		{
+			description:     "Sustains without decay",
+			input:           &Envelope{Attack: 2 * time.Millisecond},
+			expectedSamples: []float64{0, 0.5, 1, 1, 1, 1, 1, 1},
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		e := newEnvelope(constantStreamer(8), testCase.input, 1000)
+
+		actualSamples := []float64{}
+		buffer := make([][2]float64, 3)
+
+		for {
+			n, ok := e.Stream(buffer)
+			for _, sample := range buffer[:n] {
+				actualSamples = append(actualSamples, sample[0])
+			}
+
+			if !ok {
+				break
+			}
+		}
+
+		assert.Equal(t, testCase.expectedSamples, actualSamples)
+	}
+}
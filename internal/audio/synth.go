@@ -0,0 +1,338 @@
+package audio
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/faiface/beep"
+	"github.com/pkg/errors"
+)
+
+const (
+	// Rate at which synthesised voices fall by 60dB over their decay time
+	decayRate = 6.9
+	// Loudest peak a synthesised voice is rendered at, leaving some headroom
+	synthPeak = 0.9
+)
+
+// SynthParams shapes a synthesised drum voice.
+type SynthParams struct {
+	// Base pitch of the voice, in hertz
+	Pitch float64
+	// Time taken for the voice to die away
+	Decay time.Duration
+	// Brightness of the voice, from 0 (dark) to 1 (bright)
+	Tone float64
+	// Mix between the voice's pitched body and its noise, from 0 (all body) to
+	// 1 (all noise)
+	Noise float64
+}
+
+// SynthManager manages audio state and functionality for a synthesised drum
+// voice, so that no sample file is needed. The voice is rendered once when
+// its params are set, then played back like any other sample.
+type SynthManager struct {
+	*BeepManager
+	// Name of the synthesised voice, e.g. kick
+	voice string
+	// Params the voice was rendered with
+	synthParams SynthParams
+}
+
+var (
+	_ Manager = new(SynthManager)
+	_ Metered = new(SynthManager)
+)
+
+type synthVoice struct {
+	defaults SynthParams
+	render   func(SynthParams, *rand.Rand) []float64
+}
+
+var synthVoices = map[string]synthVoice{
+	"kick": {
+		defaults: SynthParams{Pitch: 50, Decay: 500 * time.Millisecond, Tone: 0.5, Noise: 0.05},
+		render:   renderKick,
+	},
+	"snare": {
+		defaults: SynthParams{Pitch: 180, Decay: 250 * time.Millisecond, Tone: 0.6, Noise: 0.65},
+		render:   renderSnare,
+	},
+	"hat_closed": {
+		defaults: SynthParams{Pitch: 400, Decay: 60 * time.Millisecond, Tone: 0.8, Noise: 0.6},
+		render:   renderHat,
+	},
+	"hat_open": {
+		defaults: SynthParams{Pitch: 400, Decay: 450 * time.Millisecond, Tone: 0.8, Noise: 0.6},
+		render:   renderHat,
+	},
+	"clap": {
+		defaults: SynthParams{Pitch: 1000, Decay: 300 * time.Millisecond, Tone: 0.5, Noise: 0.9},
+		render:   renderClap,
+	},
+	"rimshot": {
+		defaults: SynthParams{Pitch: 480, Decay: 40 * time.Millisecond, Tone: 0.7, Noise: 0.2},
+		render:   renderRimshot,
+	},
+}
+
+// SynthVoices returns the names of all voices which can be synthesised.
+func SynthVoices() []string {
+	voices := make([]string, 0, len(synthVoices))
+	for voice := range synthVoices {
+		voices = append(voices, voice)
+	}
+
+	sort.Strings(voices)
+
+	return voices
+}
+
+// DefaultSynthParams returns the params a voice is synthesised with when none
+// are given. Returns an error if the voice doesn't exist.
+func DefaultSynthParams(voice string) (SynthParams, error) {
+	v, ok := synthVoices[voice]
+	if !ok {
+		return SynthParams{}, fmt.Errorf("unknown synth voice %q", voice)
+	}
+
+	return v.defaults, nil
+}
+
+// NewSynth creates a new audio Manager which synthesises the given voice.
+// Returns an error if the voice doesn't exist or the params are invalid.
+func NewSynth(voice string, params SynthParams) (Manager, error) {
+	if _, ok := synthVoices[voice]; !ok {
+		return nil, fmt.Errorf("unknown synth voice %q", voice)
+	}
+
+	m := &SynthManager{
+		BeepManager: &BeepManager{},
+		voice:       voice,
+	}
+
+	if err := m.SetSynthParams(params); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// GetSynthParams fetches the params the Manager's voice is synthesised with.
+func (m *SynthManager) GetSynthParams() SynthParams {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.synthParams
+}
+
+// SetSynthParams re-renders the Manager's voice with the given params.
+// Returns an error if the params are invalid.
+func (m *SynthManager) SetSynthParams(params SynthParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := params.validate(); err != nil {
+		return err
+	}
+
+	// a fixed seed keeps the noise in a voice the same every time it's rendered
+	rendered := synthVoices[m.voice].render(params, rand.New(rand.NewSource(1)))
+	normalize(rendered)
+
+	stereo := make([][2]float64, len(rendered))
+	for i, sample := range rendered {
+		stereo[i] = [2]float64{sample, sample}
+	}
+
+	buffer := beep.NewBuffer(beep.Format{SampleRate: sampleRate, NumChannels: 2, Precision: 2})
+	buffer.Append(samplesStreamer(stereo))
+
+	m.synthParams = params
+	m.buffer = buffer
+	m.sample = prepareSample(buffer, m.params)
+
+	return nil
+}
+
+func (p SynthParams) validate() error {
+	if p.Pitch <= 0 {
+		return errors.New("synth pitch must be greater than 0")
+	}
+
+	if p.Decay <= 0 {
+		return errors.New("synth decay must be greater than 0")
+	}
+
+	if p.Tone < 0 || p.Tone > 1 {
+		return errors.New("synth tone must be between 0 and 1")
+	}
+
+	if p.Noise < 0 || p.Noise > 1 {
+		return errors.New("synth noise must be between 0 and 1")
+	}
+
+	return nil
+}
+
+// decayEnvelope returns the amplitude of a voice t seconds after it starts,
+// falling by 60dB over decay seconds.
+func decayEnvelope(t, decay float64) float64 {
+	return math.Exp(-decayRate * t / decay)
+}
+
+// onePole is a simple one-pole lowpass filter. Subtracting its output from its
+// input gives a highpass filter.
+type onePole struct {
+	coefficient float64
+	state       float64
+}
+
+func newOnePole(cutoff float64) *onePole {
+	return &onePole{coefficient: 1 - math.Exp(-2*math.Pi*cutoff/float64(sampleRate))}
+}
+
+func (f *onePole) lowpass(x float64) float64 {
+	f.state += f.coefficient * (x - f.state)
+	return f.state
+}
+
+func (f *onePole) highpass(x float64) float64 {
+	return x - f.lowpass(x)
+}
+
+func renderKick(p SynthParams, rng *rand.Rand) []float64 {
+	decay := p.Decay.Seconds()
+	out := make([]float64, sampleRate.N(p.Decay))
+
+	phase := 0.0
+	for i := range out {
+		t := sampleRate.D(i).Seconds()
+
+		// the pitch sweeps down from up to 4 times the base pitch, which gives
+		// the kick its punch
+		frequency := p.Pitch * (1 + 3*p.Tone*math.Exp(-t/0.03))
+		phase += 2 * math.Pi * frequency / float64(sampleRate)
+
+		body := math.Sin(phase)
+		click := (rng.Float64()*2 - 1) * math.Exp(-t/0.005)
+
+		out[i] = ((1-p.Noise)*body + p.Noise*click) * decayEnvelope(t, decay)
+	}
+
+	return out
+}
+
+func renderSnare(p SynthParams, rng *rand.Rand) []float64 {
+	decay := p.Decay.Seconds()
+	out := make([]float64, sampleRate.N(p.Decay))
+	filter := newOnePole(2000 + p.Tone*8000)
+
+	for i := range out {
+		t := sampleRate.D(i).Seconds()
+
+		// the drum's body dies away faster than the snare wires
+		body := (math.Sin(2*math.Pi*p.Pitch*t) + 0.5*math.Sin(2*math.Pi*p.Pitch*1.6*t)) / 1.5
+		body *= decayEnvelope(t, decay*0.4)
+
+		noise := filter.lowpass(rng.Float64()*2-1) * decayEnvelope(t, decay)
+
+		out[i] = (1-p.Noise)*body + p.Noise*noise
+	}
+
+	return out
+}
+
+func renderHat(p SynthParams, rng *rand.Rand) []float64 {
+	// inharmonic ratios of square waves give a metallic sound
+	ratios := []float64{2, 3, 4.16, 5.43, 6.79, 8.21}
+
+	decay := p.Decay.Seconds()
+	out := make([]float64, sampleRate.N(p.Decay))
+	filter := newOnePole(3000 + p.Tone*5000)
+
+	for i := range out {
+		t := sampleRate.D(i).Seconds()
+
+		metal := 0.0
+		for _, ratio := range ratios {
+			metal += math.Copysign(1, math.Sin(2*math.Pi*p.Pitch*ratio*t))
+		}
+		metal /= float64(len(ratios))
+
+		noise := rng.Float64()*2 - 1
+
+		out[i] = filter.highpass((1-p.Noise)*metal+p.Noise*noise) * decayEnvelope(t, decay)
+	}
+
+	return out
+}
+
+func renderClap(p SynthParams, rng *rand.Rand) []float64 {
+	const (
+		bursts       = 3
+		burstSpacing = 10 * time.Millisecond
+		burstDecay   = 8 * time.Millisecond
+	)
+
+	decay := p.Decay.Seconds()
+	burstsLength := (bursts * burstSpacing).Seconds()
+
+	out := make([]float64, sampleRate.N(bursts*burstSpacing+p.Decay))
+	highpass := newOnePole(p.Pitch * 0.5)
+	lowpass := newOnePole(p.Pitch * 2 * (1 + p.Tone))
+
+	for i := range out {
+		t := sampleRate.D(i).Seconds()
+
+		// a few quick bursts of noise, like several hands clapping, followed by
+		// a longer tail
+		envelope := decayEnvelope(t-burstsLength, decay)
+		if t < burstsLength {
+			envelope = decayEnvelope(math.Mod(t, burstSpacing.Seconds()), burstDecay.Seconds())
+		}
+
+		noise := lowpass.lowpass(highpass.highpass(rng.Float64()*2 - 1))
+		body := math.Sin(2 * math.Pi * p.Pitch * t)
+
+		out[i] = ((1-p.Noise)*body + p.Noise*noise) * envelope
+	}
+
+	return out
+}
+
+func renderRimshot(p SynthParams, rng *rand.Rand) []float64 {
+	decay := p.Decay.Seconds()
+	out := make([]float64, sampleRate.N(p.Decay))
+	filter := newOnePole(4000 + p.Tone*6000)
+
+	for i := range out {
+		t := sampleRate.D(i).Seconds()
+
+		body := (math.Sin(2*math.Pi*p.Pitch*t) + 0.6*math.Sin(2*math.Pi*p.Pitch*3.5*t)) / 1.6
+		click := filter.highpass(rng.Float64()*2 - 1)
+
+		out[i] = ((1-p.Noise)*body + p.Noise*click) * decayEnvelope(t, decay)
+	}
+
+	return out
+}
+
+// normalize scales the samples so that their loudest peak is at synthPeak.
+func normalize(samples []float64) {
+	peak := 0.0
+	for _, sample := range samples {
+		peak = math.Max(peak, math.Abs(sample))
+	}
+
+	if peak == 0 {
+		return
+	}
+
+	for i := range samples {
+		samples[i] *= synthPeak / peak
+	}
+}
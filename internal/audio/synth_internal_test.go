@@ -0,0 +1,104 @@
+package audio
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSynthParamsValidate(t *testing.T) {
+	type testCase struct {
+		description     string
+		input           SynthParams
+		expectedToError bool
+	}
+
+	testCases := []testCase{
+		{
+			description:     "Succeeds with valid params",
+			input:           SynthParams{Pitch: 60, Decay: time.Second, Tone: 1, Noise: 0},
+			expectedToError: false,
+		},
+		{
+			description:     "Errors with no pitch",
+			input:           SynthParams{Decay: time.Second},
+			expectedToError: true,
+		},
+		{
+			description:     "Errors with no decay",
+			input:           SynthParams{Pitch: 60},
+			expectedToError: true,
+		},
+		{
+			description:     "Errors with tone out of range",
+			input:           SynthParams{Pitch: 60, Decay: time.Second, Tone: 1.1},
+			expectedToError: true,
+		},
+		{
+			description:     "Errors with noise out of range",
+			input:           SynthParams{Pitch: 60, Decay: time.Second, Noise: -0.1},
+			expectedToError: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		actualErr := testCase.input.validate()
+		if testCase.expectedToError {
+			assert.NotNil(t, actualErr)
+		} else {
+			assert.Nil(t, actualErr)
+		}
+	}
+}
+
+func TestSynthVoicesRender(t *testing.T) {
+	for name, voice := range synthVoices {
+		rendered := voice.render(voice.defaults, rand.New(rand.NewSource(1)))
+		assert.GreaterOrEqual(t, len(rendered), sampleRate.N(voice.defaults.Decay), name)
+
+		normalize(rendered)
+
+		peak := 0.0
+		for _, sample := range rendered {
+			peak = math.Max(peak, math.Abs(sample))
+		}
+
+		assert.InDelta(t, synthPeak, peak, 1e-9, name)
+
+		// voices should have died away by the end of their decay
+		assert.Less(t, math.Abs(rendered[len(rendered)-1]), 0.01, name)
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	type testCase struct {
+		description    string
+		input          []float64
+		expectedOutput []float64
+	}
+
+	testCases := []testCase{
+		{
+			description:    "Scales loudest peak",
+			input:          []float64{0.1, -0.3, 0.15},
+			expectedOutput: []float64{0.3, -0.9, 0.45},
+		},
+		{
+			description:    "Handles silence",
+			input:          []float64{0, 0},
+			expectedOutput: []float64{0, 0},
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		normalize(testCase.input)
+		assert.InDeltaSlice(t, testCase.expectedOutput, testCase.input, 1e-9)
+	}
+}
@@ -0,0 +1,85 @@
+package audio_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jcfox412/logarhythms/internal/audio"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewSynth(t *testing.T) {
+	type input struct {
+		voice  string
+		params audio.SynthParams
+	}
+
+	type testCase struct {
+		description     string
+		input           input
+		expectedToError bool
+	}
+
+	kickParams, err := audio.DefaultSynthParams("kick")
+	assert.Nil(t, err)
+
+	testCases := []testCase{
+		{
+			description:     "Successfully synthesises voice",
+			input:           input{voice: "kick", params: kickParams},
+			expectedToError: false,
+		},
+		{
+			description:     "Fails with unknown voice",
+			input:           input{voice: "cowbell", params: kickParams},
+			expectedToError: true,
+		},
+		{
+			description:     "Fails with invalid params",
+			input:           input{voice: "kick", params: audio.SynthParams{Pitch: 60}},
+			expectedToError: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		actualOutput, actualErr := audio.NewSynth(testCase.input.voice, testCase.input.params)
+		if testCase.expectedToError {
+			assert.Nil(t, actualOutput)
+			assert.NotNil(t, actualErr)
+		} else {
+			assert.NotNil(t, actualOutput)
+			assert.Nil(t, actualErr)
+			assert.Equal(t, testCase.input.params, actualOutput.(*audio.SynthManager).GetSynthParams())
+		}
+	}
+}
+
+func TestSetSynthParams(t *testing.T) {
+	m, err := audio.NewSynth("snare", audio.SynthParams{Pitch: 200, Decay: 100 * time.Millisecond, Tone: 0.5, Noise: 0.5})
+	assert.Nil(t, err)
+
+	synth := m.(*audio.SynthManager)
+
+	// sample params should survive the voice being re-rendered
+	assert.Nil(t, synth.SetSampleParams(audio.SampleParams{Reverse: true}))
+
+	updatedParams := audio.SynthParams{Pitch: 220, Decay: 200 * time.Millisecond, Tone: 0.2, Noise: 0.8}
+	assert.Nil(t, synth.SetSynthParams(updatedParams))
+	assert.Equal(t, updatedParams, synth.GetSynthParams())
+	assert.Equal(t, audio.SampleParams{Reverse: true}, synth.GetSampleParams())
+
+	assert.NotNil(t, synth.SetSynthParams(audio.SynthParams{}))
+	assert.Equal(t, updatedParams, synth.GetSynthParams())
+}
+
+func TestSynthVoices(t *testing.T) {
+	voices := audio.SynthVoices()
+	assert.Equal(t, []string{"clap", "hat_closed", "hat_open", "kick", "rimshot", "snare"}, voices)
+
+	for _, voice := range voices {
+		_, err := audio.DefaultSynthParams(voice)
+		assert.Nil(t, err)
+	}
+}
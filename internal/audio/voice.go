@@ -0,0 +1,112 @@
+package audio
+
+import (
+	"sync"
+	"time"
+
+	"github.com/faiface/beep"
+)
+
+// How long a choked voice takes to fade to silence. Short enough to sound like
+// a cut, but long enough to avoid an audible click.
+const chokeFadeDuration = 10 * time.Millisecond
+
+// voice is a single sounding hit, which can be cut short by a later hit in the
+// same choke group.
+type voice struct {
+	mu       sync.Mutex
+	streamer beep.Streamer
+	// Length of the fade out in samples once choked, or 0 if not choked
+	fadeLength int
+	// Samples left before a choked voice falls silent
+	fadeRemaining int
+	// Samples to fade over when the voice is choked
+	chokeLength int
+	done        bool
+}
+
+var _ beep.Streamer = new(voice)
+
+func newVoice(streamer beep.Streamer, chokeLength int) *voice {
+	if chokeLength < 1 {
+		chokeLength = 1
+	}
+
+	return &voice{
+		streamer:    streamer,
+		chokeLength: chokeLength,
+	}
+}
+
+// Stream streams the voice's audio, fading it out if it has been choked.
+func (v *voice) Stream(samples [][2]float64) (int, bool) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.done {
+		return 0, false
+	}
+
+	n, ok := v.streamer.Stream(samples)
+	if !ok {
+		v.done = true
+	}
+
+	if v.fadeLength == 0 {
+		return n, ok
+	}
+
+	for i := 0; i < n; i++ {
+		if v.fadeRemaining <= 0 {
+			v.done = true
+			return i, i > 0
+		}
+
+		gain := float64(v.fadeRemaining) / float64(v.fadeLength)
+		samples[i][0] *= gain
+		samples[i][1] *= gain
+		v.fadeRemaining--
+	}
+
+	return n, ok
+}
+
+// Err returns any error from the voice's underlying streamer.
+func (v *voice) Err() error {
+	return v.streamer.Err()
+}
+
+// choke starts fading the voice out. Choking an already choked voice has no
+// effect.
+func (v *voice) choke() {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.fadeLength == 0 {
+		v.fadeLength = v.chokeLength
+		v.fadeRemaining = v.chokeLength
+	}
+}
+
+// chokeGroups tracks the voices sounding in each choke group.
+type chokeGroups struct {
+	mu     sync.Mutex
+	voices map[int][]*voice
+}
+
+// chokes is shared by every Manager, so that instruments can choke each other.
+var chokes = &chokeGroups{voices: map[int][]*voice{}}
+
+// add chokes every voice still sounding in the group, then adds the new voice
+// to it.
+func (c *chokeGroups) add(group int, v *voice) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, sounding := range c.voices[group] {
+		sounding.choke()
+	}
+
+	// choked voices fade out on their own, so only the new voice needs tracking
+	c.voices[group] = []*voice{v}
+}
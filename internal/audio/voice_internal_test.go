@@ -0,0 +1,90 @@
+package audio
+
+import (
+	"testing"
+
+	"github.com/faiface/beep"
+	"github.com/stretchr/testify/assert"
+)
+
+// constantStreamer streams the given number of full-scale samples.
+func constantStreamer(length int) beep.Streamer {
+	return beep.StreamerFunc(func(samples [][2]float64) (int, bool) {
+		if length <= 0 {
+			return 0, false
+		}
+
+		n := 0
+		for ; n < len(samples) && n < length; n++ {
+			samples[n] = [2]float64{1, 1}
+		}
+
+		length -= n
+
+		return n, true
+	})
+}
+
+func TestVoiceStream(t *testing.T) {
+	type testCase struct {
+		description     string
+		choked          bool
+		expectedSamples [][2]float64
+	}
+
+	testCases := []testCase{
+		{
+			description:     "Plays unchoked voice to the end",
+			choked:          false,
+			expectedSamples: [][2]float64{{1, 1}, {1, 1}, {1, 1}, {1, 1}, {1, 1}, {1, 1}},
+		},
+		{
+			description:     "Fades out choked voice",
+			choked:          true,
+			expectedSamples: [][2]float64{{1, 1}, {0.75, 0.75}, {0.5, 0.5}, {0.25, 0.25}},
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		v := newVoice(constantStreamer(6), 4)
+
+		if testCase.choked {
+			v.choke()
+			// choking twice should not restart the fade
+			v.choke()
+		}
+
+		actualSamples := [][2]float64{}
+		buffer := make([][2]float64, 4)
+
+		for {
+			n, ok := v.Stream(buffer)
+			actualSamples = append(actualSamples, buffer[:n]...)
+
+			if !ok {
+				break
+			}
+		}
+
+		assert.Equal(t, testCase.expectedSamples, actualSamples)
+	}
+}
+
+func TestChokeGroupsAdd(t *testing.T) {
+	groups := &chokeGroups{voices: map[int][]*voice{}}
+
+	openHat := newVoice(constantStreamer(100), 10)
+	otherGroup := newVoice(constantStreamer(100), 10)
+	closedHat := newVoice(constantStreamer(100), 10)
+
+	groups.add(1, openHat)
+	groups.add(2, otherGroup)
+	groups.add(1, closedHat)
+
+	assert.Equal(t, 10, openHat.fadeLength)
+	assert.Equal(t, 0, otherGroup.fadeLength)
+	assert.Equal(t, 0, closedHat.fadeLength)
+	assert.Equal(t, []*voice{closedHat}, groups.voices[1])
+}
@@ -0,0 +1,118 @@
+package audio
+
+import (
+	"bufio"
+	"encoding/binary"
+	"math"
+	"os"
+
+	"github.com/faiface/beep"
+	"github.com/pkg/errors"
+)
+
+const (
+	wavHeaderSize    = 44
+	wavChannels      = 2
+	wavBitsPerSample = 16
+)
+
+// wavSink writes the audio it's given to a 16 bit stereo WAV file. The sizes
+// in the file's header are filled in when it's closed.
+type wavSink struct {
+	filename   string
+	sampleRate beep.SampleRate
+	file       *os.File
+	writer     *bufio.Writer
+	frames     int
+}
+
+func (w *wavSink) open(sampleRate beep.SampleRate) error {
+	file, err := os.Create(w.filename)
+	if err != nil {
+		return errors.Wrap(err, "error creating wav file")
+	}
+
+	w.sampleRate = sampleRate
+	w.file = file
+	w.writer = bufio.NewWriter(file)
+	w.frames = 0
+
+	// written again with the real sizes once they're known
+	return w.writeHeader()
+}
+
+func (w *wavSink) write(samples [][2]float64) error {
+	frame := make([]byte, wavChannels*wavBitsPerSample/8)
+
+	for _, sample := range samples {
+		for c := 0; c < wavChannels; c++ {
+			value := math.Max(-1, math.Min(1, sample[c]))
+			binary.LittleEndian.PutUint16(frame[c*2:], uint16(int16(value*math.MaxInt16)))
+		}
+
+		if _, err := w.writer.Write(frame); err != nil {
+			return errors.Wrap(err, "error writing wav file")
+		}
+	}
+
+	w.frames += len(samples)
+
+	return nil
+}
+
+func (w *wavSink) close() error {
+	if err := w.finish(); err != nil {
+		w.file.Close()
+		return err
+	}
+
+	return errors.Wrap(w.file.Close(), "error closing wav file")
+}
+
+// finish writes out what's buffered, and the header with the real sizes.
+func (w *wavSink) finish() error {
+	if err := w.writer.Flush(); err != nil {
+		return errors.Wrap(err, "error writing wav file")
+	}
+
+	if _, err := w.file.Seek(0, 0); err != nil {
+		return errors.Wrap(err, "error finishing wav file")
+	}
+
+	w.writer.Reset(w.file)
+	if err := w.writeHeader(); err != nil {
+		return err
+	}
+
+	return errors.Wrap(w.writer.Flush(), "error finishing wav file")
+}
+
+func (w *wavSink) writeHeader() error {
+	blockAlign := wavChannels * wavBitsPerSample / 8
+	dataSize := uint32(w.frames * blockAlign)
+
+	header := []interface{}{
+		[4]byte{'R', 'I', 'F', 'F'},
+		uint32(wavHeaderSize - 8 + dataSize),
+		[4]byte{'W', 'A', 'V', 'E'},
+		[4]byte{'f', 'm', 't', ' '},
+		uint32(16),
+		// PCM
+		uint16(1),
+		uint16(wavChannels),
+		uint32(w.sampleRate),
+		uint32(int(w.sampleRate) * blockAlign),
+		uint16(blockAlign),
+		uint16(wavBitsPerSample),
+		[4]byte{'d', 'a', 't', 'a'},
+		dataSize,
+	}
+
+	for _, field := range header {
+		if err := binary.Write(w.writer, binary.LittleEndian, field); err != nil {
+			return errors.Wrap(err, "error writing wav header")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,104 @@
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock tells the time and waits for it to pass. Playback is driven by a
+// Clock so that tests can use a Manual clock instead of waiting in real time.
+type Clock interface {
+	// Now returns the current time
+	Now() time.Time
+	// Sleep blocks until d has passed
+	Sleep(d time.Duration)
+	// After sends the time on the returned channel once d has passed
+	After(d time.Duration) <-chan time.Time
+	// AfterFunc calls f in its own goroutine once d has passed, unless stopped
+	// first
+	AfterFunc(d time.Duration, f func()) Timer
+	// Every calls f every d until stopped
+	Every(d time.Duration, f func()) Ticker
+}
+
+// Timer is a single call waiting to be made by a Clock.
+type Timer interface {
+	// Stop cancels the call, returning false if it was already made or stopped
+	Stop() bool
+}
+
+// Ticker is a repeating call made by a Clock.
+type Ticker interface {
+	// Reset changes the time between calls to d, the next call being made d
+	// after Reset
+	Reset(d time.Duration)
+	// Stop cancels any further calls, waiting for one in progress to finish
+	Stop()
+}
+
+// New returns a Clock which follows real time.
+func New() Clock {
+	return realClock{}
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) Sleep(d time.Duration) {
+	time.Sleep(d)
+}
+
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+func (realClock) AfterFunc(d time.Duration, f func()) Timer {
+	return time.AfterFunc(d, f)
+}
+
+func (realClock) Every(d time.Duration, f func()) Ticker {
+	t := &realTicker{
+		ticker: time.NewTicker(d),
+		done:   make(chan struct{}),
+	}
+
+	t.wait.Add(1)
+
+	go func() {
+		defer t.wait.Done()
+
+		for {
+			select {
+			case <-t.done:
+				return
+			case <-t.ticker.C:
+				f()
+			}
+		}
+	}()
+
+	return t
+}
+
+type realTicker struct {
+	ticker *time.Ticker
+	done   chan struct{}
+	wait   sync.WaitGroup
+	once   sync.Once
+}
+
+func (t *realTicker) Reset(d time.Duration) {
+	t.ticker.Reset(d)
+}
+
+func (t *realTicker) Stop() {
+	t.once.Do(func() {
+		t.ticker.Stop()
+		close(t.done)
+	})
+
+	t.wait.Wait()
+}
@@ -0,0 +1,30 @@
+package clock_test
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jcfox412/logarhythms/internal/clock"
+)
+
+func TestRealClockEvery(t *testing.T) {
+	var calls int32
+
+	ticker := clock.New().Every(5*time.Millisecond, func() {
+		atomic.AddInt32(&calls, 1)
+	})
+
+	time.Sleep(50 * time.Millisecond)
+	ticker.Stop()
+
+	stopped := atomic.LoadInt32(&calls)
+	assert.Greater(t, stopped, int32(0))
+
+	// no calls are made once stopped, and stopping again is harmless
+	time.Sleep(20 * time.Millisecond)
+	ticker.Stop()
+	assert.Equal(t, stopped, atomic.LoadInt32(&calls))
+}
@@ -0,0 +1,207 @@
+package clock
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Manual is a Clock whose time only moves when advanced. Calls due while it's
+// advanced are made in the advancing goroutine, in the order they're due, so
+// tests can check exactly what happened at each point in time.
+type Manual struct {
+	mu     sync.Mutex
+	now    time.Time
+	events []*event
+	// Number of events created, used to order events due at the same time
+	count int
+	// Signalled whenever a sleeper, timer or ticker starts waiting on the clock
+	waiting *sync.Cond
+}
+
+var _ Clock = new(Manual)
+
+// event is a sleeper, timer or ticker waiting on a Manual clock.
+type event struct {
+	clock  *Manual
+	due    time.Time
+	order  int
+	period time.Duration
+	f      func()
+	// Channel the time is sent on when due, for sleepers
+	fire chan time.Time
+}
+
+// NewManual returns a Manual clock starting at the given time.
+func NewManual(start time.Time) *Manual {
+	m := &Manual{now: start}
+	m.waiting = sync.NewCond(&m.mu)
+
+	return m
+}
+
+// Now returns the clock's current time.
+func (m *Manual) Now() time.Time {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.now
+}
+
+// Sleep blocks until the clock is advanced by d.
+func (m *Manual) Sleep(d time.Duration) {
+	if d <= 0 {
+		return
+	}
+
+	<-m.After(d)
+}
+
+// After sends the time on the returned channel once the clock is advanced by
+// d.
+func (m *Manual) After(d time.Duration) <-chan time.Time {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e := m.schedule(d, 0, nil)
+	e.fire = make(chan time.Time, 1)
+
+	return e.fire
+}
+
+// AfterFunc calls f once the clock is advanced by d. Unlike a real timer, f is
+// called in the advancing goroutine.
+func (m *Manual) AfterFunc(d time.Duration, f func()) Timer {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.schedule(d, 0, f)
+}
+
+// Every calls f each time the clock is advanced by another d.
+func (m *Manual) Every(d time.Duration, f func()) Ticker {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return manualTicker{m.schedule(d, d, f)}
+}
+
+// Advance moves the clock forward by d, making every call due on the way.
+func (m *Manual) Advance(d time.Duration) {
+	m.mu.Lock()
+	target := m.now.Add(d)
+
+	for {
+		e := m.next(target)
+		if e == nil {
+			break
+		}
+
+		m.now = e.due
+
+		if e.period > 0 {
+			e.due = e.due.Add(e.period)
+		} else {
+			m.remove(e)
+		}
+
+		if e.fire != nil {
+			e.fire <- m.now
+			continue
+		}
+
+		m.mu.Unlock()
+		e.f()
+		m.mu.Lock()
+	}
+
+	m.now = target
+	m.mu.Unlock()
+}
+
+// BlockUntil blocks until at least n sleepers, timers or tickers are waiting
+// on the clock, so that a test can wait for another goroutine to catch up
+// before advancing it.
+func (m *Manual) BlockUntil(n int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for len(m.events) < n {
+		m.waiting.Wait()
+	}
+}
+
+func (m *Manual) schedule(d, period time.Duration, f func()) *event {
+	e := &event{
+		clock:  m,
+		due:    m.now.Add(d),
+		order:  m.count,
+		period: period,
+		f:      f,
+	}
+
+	m.count++
+	m.events = append(m.events, e)
+	m.waiting.Broadcast()
+
+	return e
+}
+
+// next returns the earliest event due by target, or nil if there are none.
+func (m *Manual) next(target time.Time) *event {
+	sort.SliceStable(m.events, func(i, j int) bool {
+		a, b := m.events[i], m.events[j]
+		if !a.due.Equal(b.due) {
+			return a.due.Before(b.due)
+		}
+
+		return a.order < b.order
+	})
+
+	if len(m.events) == 0 || m.events[0].due.After(target) {
+		return nil
+	}
+
+	return m.events[0]
+}
+
+// remove takes the event off the clock, returning false if it wasn't on it.
+func (m *Manual) remove(e *event) bool {
+	for i, other := range m.events {
+		if other == e {
+			m.events = append(m.events[:i], m.events[i+1:]...)
+			return true
+		}
+	}
+
+	return false
+}
+
+// Stop takes the timer or ticker off the clock.
+func (e *event) Stop() bool {
+	e.clock.mu.Lock()
+	defer e.clock.mu.Unlock()
+
+	return e.clock.remove(e)
+}
+
+type manualTicker struct {
+	*event
+}
+
+// Reset makes the ticker's next call once the clock is advanced by d, and
+// every d after that.
+func (t manualTicker) Reset(d time.Duration) {
+	m := t.event.clock
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	t.event.due = m.now.Add(d)
+	t.event.period = d
+}
+
+// Stop takes the ticker off the clock.
+func (t manualTicker) Stop() {
+	t.event.Stop()
+}
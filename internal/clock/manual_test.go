@@ -0,0 +1,106 @@
+package clock_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jcfox412/logarhythms/internal/clock"
+)
+
+var start = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func TestManualAdvance(t *testing.T) {
+	c := clock.NewManual(start)
+
+	var calls []string
+	record := func(name string) func() {
+		return func() {
+			calls = append(calls, name+" "+c.Now().Sub(start).String())
+		}
+	}
+
+	ticker := c.Every(10*time.Millisecond, record("tick"))
+	c.AfterFunc(15*time.Millisecond, record("timer"))
+	stopped := c.AfterFunc(5*time.Millisecond, record("stopped"))
+	c.AfterFunc(20*time.Millisecond, record("tie"))
+
+	assert.True(t, stopped.Stop())
+	assert.False(t, stopped.Stop())
+
+	c.Advance(25 * time.Millisecond)
+	assert.Equal(t, start.Add(25*time.Millisecond), c.Now())
+
+	ticker.Stop()
+	c.Advance(time.Second)
+
+	// calls due at the same time are made in the order they were scheduled
+	assert.Equal(t, []string{"tick 10ms", "timer 15ms", "tick 20ms", "tie 20ms"}, calls)
+}
+
+func TestManualSleep(t *testing.T) {
+	c := clock.NewManual(start)
+
+	woken := make(chan time.Time)
+	go func() {
+		c.Sleep(time.Second)
+		woken <- c.Now()
+	}()
+
+	c.BlockUntil(1)
+	c.Advance(999 * time.Millisecond)
+
+	select {
+	case <-woken:
+		assert.Fail(t, "sleeper woke early")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	c.Advance(time.Millisecond)
+	assert.Equal(t, start.Add(time.Second), <-woken)
+
+	// sleeping for no time doesn't block
+	c.Sleep(0)
+}
+
+func TestManualAfter(t *testing.T) {
+	c := clock.NewManual(start)
+
+	after := c.After(time.Second)
+	c.BlockUntil(1)
+
+	select {
+	case <-after:
+		assert.Fail(t, "channel fired early")
+	default:
+	}
+
+	c.Advance(time.Second)
+	assert.Equal(t, start.Add(time.Second), <-after)
+}
+
+func TestManualTickerReset(t *testing.T) {
+	c := clock.NewManual(start)
+
+	var calls []time.Duration
+
+	var ticker clock.Ticker
+	ticker = c.Every(10*time.Millisecond, func() {
+		calls = append(calls, c.Now().Sub(start))
+
+		// resetting from the call itself times the next call from this one
+		if len(calls) == 2 {
+			ticker.Reset(25 * time.Millisecond)
+		}
+	})
+
+	c.Advance(30 * time.Millisecond)
+	ticker.Reset(5 * time.Millisecond)
+	c.Advance(12 * time.Millisecond)
+	ticker.Stop()
+
+	assert.Equal(t, []time.Duration{
+		10 * time.Millisecond, 20 * time.Millisecond, 35 * time.Millisecond, 40 * time.Millisecond,
+	}, calls)
+}
@@ -0,0 +1,256 @@
+// Package clocksync keeps tracks in time with other instruments, by sending
+// the tempo and transport of a playing track as MIDI clock or over the
+// network, and by following them in place of a track's BPM.
+package clocksync
+
+import (
+	"sync"
+	"time"
+
+	"github.com/jcfox412/logarhythms/internal/clock"
+)
+
+// follower turns the pulses of an external clock, a fixed number to the beat,
+// into the beat subdivisions of the track following it.
+type follower struct {
+	clock         clock.Clock
+	pulsesPerBeat int
+
+	mu sync.Mutex
+	// Whether the external transport is running
+	running bool
+	// Pulses since the transport started
+	pulses int
+	// Track following the clock, or nil if none is
+	following *following
+	// Set once the clock can no longer be followed
+	closed bool
+}
+
+// following is a single track following a clock.
+type following struct {
+	clock            clock.Clock
+	divisionsPerBeat int
+	step             func(position int)
+	done             chan struct{}
+
+	// Held while a step is being played, so stopping can wait for it
+	stepping sync.Mutex
+	mu       sync.Mutex
+	// Set once the transport has started and a beat has been reached
+	started bool
+	stopped bool
+	// Subdivisions of the current beat waiting to be played
+	timers []clock.Timer
+}
+
+func newFollower(c clock.Clock, pulsesPerBeat int) *follower {
+	if c == nil {
+		c = clock.New()
+	}
+
+	return &follower{clock: c, pulsesPerBeat: pulsesPerBeat}
+}
+
+// Follow calls step for each beat subdivision of the clock, with the number of
+// subdivisions since its transport started, from the next beat played once
+// it's started, until stopped. The done channel is closed if the clock stops
+// after that, or can no longer be followed.
+func (f *follower) Follow(divisionsPerBeat int, step func(position int)) (clock.Ticker, <-chan struct{}) {
+	fl := &following{
+		clock:            f.clock,
+		divisionsPerBeat: divisionsPerBeat,
+		step:             step,
+		done:             make(chan struct{}),
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed {
+		close(fl.done)
+		return fl, fl.done
+	}
+
+	if f.following != nil {
+		f.following.end()
+	}
+
+	f.following = fl
+
+	return fl, fl.done
+}
+
+// start starts the transport from the beginning, or from where it stopped if
+// resuming.
+func (f *follower) start(resume bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.running = true
+	if !resume {
+		f.pulses = 0
+	}
+}
+
+// stop stops the transport, ending the track following the clock if it has
+// started.
+func (f *follower) stop() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.running = false
+
+	if f.following != nil && f.following.hasStarted() {
+		f.following.end()
+		f.following = nil
+	}
+}
+
+// close ends any track following the clock, and any which follow it later.
+func (f *follower) close() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.closed = true
+	f.running = false
+
+	if f.following != nil {
+		f.following.end()
+		f.following = nil
+	}
+}
+
+// pulse moves the running transport on by a pulse, playing any beat
+// subdivisions which fall on it. Subdivisions which fall between pulses are
+// spaced evenly across the beat, which is expected to last beatDuration.
+func (f *follower) pulse(beatDuration time.Duration) {
+	f.mu.Lock()
+	n := f.pulses
+	f.mu.Unlock()
+
+	f.pulseAt(n, beatDuration)
+}
+
+// pulseAt moves the running transport to its nth pulse since it started,
+// playing any beat subdivisions which fall on it like pulse, so that tracks
+// keep in phase with a clock which sends its position.
+func (f *follower) pulseAt(n int, beatDuration time.Duration) {
+	f.mu.Lock()
+	if !f.running {
+		f.mu.Unlock()
+		return
+	}
+
+	f.pulses = n + 1
+	fl := f.following
+	f.mu.Unlock()
+
+	if fl != nil {
+		fl.pulse(n, f.pulsesPerBeat, beatDuration)
+	}
+}
+
+// pulse plays the beat subdivisions falling on the pulse, the nth since the
+// transport started.
+func (fl *following) pulse(n, pulsesPerBeat int, beatDuration time.Duration) {
+	fl.mu.Lock()
+
+	interpolated := pulsesPerBeat%fl.divisionsPerBeat != 0
+
+	// subdivisions between pulses can't be spaced until the tempo is known
+	if fl.stopped || (!fl.started && (n%pulsesPerBeat != 0 || (interpolated && beatDuration <= 0))) {
+		fl.mu.Unlock()
+		return
+	}
+
+	fl.started = true
+
+	if !interpolated {
+		fl.mu.Unlock()
+
+		if pulsesPerDivision := pulsesPerBeat / fl.divisionsPerBeat; n%pulsesPerDivision == 0 {
+			fl.play(n / pulsesPerDivision)
+		}
+
+		return
+	}
+
+	if n%pulsesPerBeat != 0 {
+		fl.mu.Unlock()
+		return
+	}
+
+	// the subdivisions of the last beat have all been played by the time the
+	// next beat arrives, unless it arrived early
+	for _, timer := range fl.timers {
+		timer.Stop()
+	}
+
+	first := n / pulsesPerBeat * fl.divisionsPerBeat
+
+	fl.timers = fl.timers[:0]
+	for i := 1; i < fl.divisionsPerBeat; i++ {
+		position := first + i
+		delay := beatDuration * time.Duration(i) / time.Duration(fl.divisionsPerBeat)
+		fl.timers = append(fl.timers, fl.clock.AfterFunc(delay, func() {
+			fl.play(position)
+		}))
+	}
+
+	fl.mu.Unlock()
+
+	fl.play(first)
+}
+
+// play plays the beat subdivision at the position, unless the track has
+// stopped following.
+func (fl *following) play(position int) {
+	fl.stepping.Lock()
+	defer fl.stepping.Unlock()
+
+	fl.mu.Lock()
+	stopped := fl.stopped
+	fl.mu.Unlock()
+
+	if !stopped {
+		fl.step(position)
+	}
+}
+
+func (fl *following) hasStarted() bool {
+	fl.mu.Lock()
+	defer fl.mu.Unlock()
+
+	return fl.started
+}
+
+// end tells the track the clock has stopped.
+func (fl *following) end() {
+	fl.mu.Lock()
+	defer fl.mu.Unlock()
+
+	select {
+	case <-fl.done:
+	default:
+		close(fl.done)
+	}
+}
+
+// Reset does nothing, as the external clock sets the time between beat
+// subdivisions.
+func (fl *following) Reset(time.Duration) {}
+
+// Stop stops the track following the clock, waiting for a beat subdivision
+// being played to finish.
+func (fl *following) Stop() {
+	fl.mu.Lock()
+	fl.stopped = true
+	for _, timer := range fl.timers {
+		timer.Stop()
+	}
+	fl.mu.Unlock()
+
+	fl.stepping.Lock()
+	defer fl.stepping.Unlock()
+}
@@ -0,0 +1,314 @@
+package clocksync_test
+
+import (
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jcfox412/logarhythms/internal/clock"
+	"github.com/jcfox412/logarhythms/internal/clocksync"
+	"github.com/jcfox412/logarhythms/internal/midi"
+	"github.com/jcfox412/logarhythms/internal/osc"
+	"github.com/jcfox412/logarhythms/internal/render"
+)
+
+// Active sensing, which followers ignore
+const activeSensing byte = 0xFE
+
+// deliver delivers the message to the port's follower, waiting for it to be
+// handled.
+func deliver(port *midi.Memory, message byte) {
+	port.Deliver([]byte{message})
+	port.Deliver([]byte{activeSensing})
+}
+
+// steps records when beat subdivisions are played.
+type steps struct {
+	mu        sync.Mutex
+	clock     clock.Clock
+	start     time.Time
+	times     []time.Duration
+	positions []int
+}
+
+func (s *steps) step(position int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.times = append(s.times, s.clock.Now().Sub(s.start))
+	s.positions = append(s.positions, position)
+}
+
+func TestMIDISender(t *testing.T) {
+	c := clock.NewManual(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	port := midi.NewMemory()
+	sender := clocksync.NewMIDISender(port, c)
+
+	// 125 BPM, which is 20ms a pulse
+	sender.Start(render.Track{BeatsPerMinute: 125, DivisionsPerBeat: 2, StepDuration: 240 * time.Millisecond})
+	assert.Empty(t, port.Sent())
+
+	sender.Step(render.Step{Index: 0})
+	assert.Equal(t, [][]byte{{midi.Start}, {midi.TimingClock}}, port.Sent())
+
+	// the pulses carry on between beat subdivisions
+	c.Advance(60 * time.Millisecond)
+	sender.Step(render.Step{Index: 1})
+	assert.Len(t, port.Sent(), 5)
+
+	sender.Stop()
+	c.Advance(time.Second)
+	assert.Equal(t, [][]byte{
+		{midi.Start}, {midi.TimingClock}, {midi.TimingClock}, {midi.TimingClock}, {midi.TimingClock}, {midi.Stop},
+	}, port.Sent())
+}
+
+func TestMIDISenderRetime(t *testing.T) {
+	c := clock.NewManual(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	port := midi.NewMemory()
+	sender := clocksync.NewMIDISender(port, c)
+
+	sender.Start(render.Track{BeatsPerMinute: 125, DivisionsPerBeat: 2, StepDuration: 240 * time.Millisecond})
+	sender.Step(render.Step{Index: 0})
+
+	// 250 BPM is 10ms a pulse, from the next pulse
+	render.Retime(sender, 250, 120*time.Millisecond, 0)
+	c.Advance(30 * time.Millisecond)
+	assert.Len(t, port.Sent(), 5)
+
+	sender.Stop()
+}
+
+func TestMIDIFollower(t *testing.T) {
+	type testCase struct {
+		description      string
+		divisionsPerBeat int
+		// pulses received before the transport starts, from which the tempo is
+		// measured
+		pulsesBeforeStart int
+		expectedSteps     []time.Duration
+		expectedPositions []int
+	}
+
+	ms := time.Millisecond
+
+	testCases := []testCase{
+		{
+			description:       "Plays subdivisions on the pulses they fall on",
+			divisionsPerBeat:  2,
+			pulsesBeforeStart: 0,
+			expectedSteps:     []time.Duration{0, 240 * ms, 480 * ms, 720 * ms},
+			expectedPositions: []int{0, 1, 2, 3},
+		},
+		{
+			description:       "Spaces subdivisions falling between pulses by the measured tempo",
+			divisionsPerBeat:  5,
+			pulsesBeforeStart: 24,
+			expectedSteps: []time.Duration{
+				0, 96 * ms, 192 * ms, 288 * ms, 384 * ms, 480 * ms, 576 * ms, 672 * ms, 768 * ms, 864 * ms,
+			},
+			expectedPositions: []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9},
+		},
+		{
+			description:       "Waits for the tempo to be measured before spacing subdivisions between pulses",
+			divisionsPerBeat:  5,
+			pulsesBeforeStart: 0,
+			expectedSteps:     []time.Duration{480 * ms, 576 * ms, 672 * ms, 768 * ms, 864 * ms},
+			expectedPositions: []int{5, 6, 7, 8, 9},
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		c := clock.NewManual(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+		port := midi.NewMemory()
+		follower := clocksync.NewMIDIFollower(port, c)
+
+		// 125 BPM, which is 20ms a pulse
+		pulse := 20 * time.Millisecond
+		for i := 0; i < testCase.pulsesBeforeStart; i++ {
+			deliver(port, midi.TimingClock)
+			c.Advance(pulse)
+		}
+
+		s := &steps{clock: c}
+		ticker, done := follower.Follow(testCase.divisionsPerBeat, s.step)
+
+		deliver(port, midi.Start)
+		s.start = c.Now()
+
+		for i := 0; i < 2*midi.PulsesPerQuarterNote; i++ {
+			deliver(port, midi.TimingClock)
+			c.Advance(pulse)
+		}
+
+		select {
+		case <-done:
+			assert.Fail(t, "done before the transport stopped", testCase.description)
+		default:
+		}
+
+		deliver(port, midi.Stop)
+		<-done
+		ticker.Stop()
+
+		assert.Equal(t, testCase.expectedSteps, s.times, testCase.description)
+		assert.Equal(t, testCase.expectedPositions, s.positions, testCase.description)
+		assert.Nil(t, port.Close())
+	}
+}
+
+func TestMIDIFollowerClosed(t *testing.T) {
+	port := midi.NewMemory()
+	follower := clocksync.NewMIDIFollower(port, nil)
+
+	_, done := follower.Follow(1, func(int) {})
+	assert.Nil(t, port.Close())
+
+	// closing the port ends the track following it, even before it started
+	<-done
+
+	_, done = follower.Follow(1, func(int) {})
+	<-done
+}
+
+func TestNetSync(t *testing.T) {
+	type testCase struct {
+		description string
+		// index of the first beat subdivision the sender plays
+		input             int
+		expectedPositions []int
+	}
+
+	testCases := []testCase{
+		{
+			description:       "Follows from the start of the sender's track",
+			input:             0,
+			expectedPositions: []int{0, 1},
+		},
+		{
+			description:       "Keeps in phase with a sender already playing",
+			input:             10,
+			expectedPositions: []int{5, 6},
+		},
+	}
+
+	for _, testCase := range testCases {
+		conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+		if !assert.Nil(t, err) {
+			return
+		}
+
+		sender, err := net.ListenPacket("udp", "127.0.0.1:0")
+		if !assert.Nil(t, err) {
+			return
+		}
+
+		follower := clocksync.NewNetFollower(conn, nil)
+
+		positions := make(chan int, 10)
+		ticker, done := follower.Follow(1, func(position int) {
+			positions <- position
+		})
+
+		renderer := clocksync.NewNetSender(sender, conn.LocalAddr())
+		renderer.Start(render.Track{BeatsPerMinute: 120, DivisionsPerBeat: 2, StepDuration: 250 * time.Millisecond})
+
+		// a beat is sent every other subdivision
+		for i := testCase.input; i < testCase.input+4; i++ {
+			renderer.Step(render.Step{Index: i})
+		}
+
+		actual := []int{}
+		for range testCase.expectedPositions {
+			select {
+			case position := <-positions:
+				actual = append(actual, position)
+			case <-time.After(5 * time.Second):
+				assert.Fail(t, "waiting for beat", testCase.description)
+			}
+		}
+
+		renderer.Stop()
+
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			assert.Fail(t, "waiting for stop", testCase.description)
+		}
+
+		ticker.Stop()
+		assert.Equal(t, testCase.expectedPositions, actual, testCase.description)
+		assert.Len(t, positions, 0, testCase.description)
+		assert.Nil(t, conn.Close())
+		assert.Nil(t, sender.Close())
+	}
+}
+
+func TestNetFollowerErrors(t *testing.T) {
+	type testCase struct {
+		description string
+		input       osc.Message
+	}
+
+	testCases := []testCase{
+		{
+			description: "Beat without a beat number",
+			input:       osc.Message{Address: clocksync.BeatAddress, Arguments: []interface{}{float32(120)}},
+		},
+		{
+			description: "Beat with a BPM that isn't a float",
+			input:       osc.Message{Address: clocksync.BeatAddress, Arguments: []interface{}{int32(120), int32(0)}},
+		},
+		{
+			description: "Beat with a BPM of 0",
+			input:       osc.Message{Address: clocksync.BeatAddress, Arguments: []interface{}{float32(0), int32(0)}},
+		},
+		{
+			description: "Beat with a beat number that isn't an int",
+			input:       osc.Message{Address: clocksync.BeatAddress, Arguments: []interface{}{float32(120), "1"}},
+		},
+		{
+			description: "Beat with a negative beat number",
+			input:       osc.Message{Address: clocksync.BeatAddress, Arguments: []interface{}{float32(120), int32(-1)}},
+		},
+	}
+
+	for _, testCase := range testCases {
+		conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+		if !assert.Nil(t, err) {
+			return
+		}
+
+		sender, err := net.ListenPacket("udp", "127.0.0.1:0")
+		if !assert.Nil(t, err) {
+			return
+		}
+
+		follower := clocksync.NewNetFollower(conn, nil)
+
+		packet, err := testCase.input.MarshalBinary()
+		assert.Nil(t, err, testCase.description)
+
+		_, err = sender.WriteTo(packet, conn.LocalAddr())
+		assert.Nil(t, err, testCase.description)
+
+		select {
+		case err := <-follower.Errors():
+			assert.NotNil(t, err, testCase.description)
+		case <-time.After(5 * time.Second):
+			assert.Fail(t, "waiting for error", testCase.description)
+		}
+
+		assert.Nil(t, conn.Close())
+		assert.Nil(t, sender.Close())
+
+		// the channel is closed with the connection
+		for range follower.Errors() {
+		}
+	}
+}
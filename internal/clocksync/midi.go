@@ -0,0 +1,157 @@
+package clocksync
+
+import (
+	"sync"
+	"time"
+
+	"github.com/jcfox412/logarhythms/internal/clock"
+	"github.com/jcfox412/logarhythms/internal/midi"
+	"github.com/jcfox412/logarhythms/internal/render"
+)
+
+// Weight given to each pulse's interval in the running estimate of a followed
+// MIDI clock's tempo, smoothing out the jitter of its pulses
+const pulseSmoothing = 0.125
+
+// midiSender is a renderer which sends MIDI clock to a port while tracks play.
+type midiSender struct {
+	port  midi.Port
+	clock clock.Clock
+
+	mu sync.Mutex
+	// Time between pulses of the track playing
+	pulseDuration time.Duration
+	// Sends pulses once the track's first beat subdivision has played
+	ticker clock.Ticker
+}
+
+// NewMIDISender returns a renderer which sends MIDI clock to the port, at
+// the tempo of each track it draws, timed by the clock. A nil clock follows
+// real time. A start message and the first pulse are sent with the track's
+// first beat, and a stop message once it stops. Messages which can't be sent
+// are dropped.
+func NewMIDISender(port midi.Port, c clock.Clock) render.Renderer {
+	if c == nil {
+		c = clock.New()
+	}
+
+	return &midiSender{port: port, clock: c}
+}
+
+func (s *midiSender) Start(track render.Track) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	beatDuration := track.StepDuration * time.Duration(track.DivisionsPerBeat)
+	s.pulseDuration = beatDuration / midi.PulsesPerQuarterNote
+}
+
+func (s *midiSender) Step(render.Step) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.ticker != nil {
+		return
+	}
+
+	_ = s.port.Send([]byte{midi.Start})
+	_ = s.port.Send([]byte{midi.TimingClock})
+
+	s.ticker = s.clock.Every(s.pulseDuration, func() {
+		_ = s.port.Send([]byte{midi.TimingClock})
+	})
+}
+
+// Retime sends pulses at the track's new tempo from the next one.
+func (s *midiSender) Retime(beatsPerMinute float64, stepDuration time.Duration, totalSteps int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pulseDuration = time.Duration(float64(time.Minute) / beatsPerMinute / midi.PulsesPerQuarterNote)
+	if s.ticker != nil {
+		s.ticker.Reset(s.pulseDuration)
+	}
+}
+
+func (s *midiSender) Clip() {}
+
+func (s *midiSender) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.ticker == nil {
+		return
+	}
+
+	s.ticker.Stop()
+	s.ticker = nil
+
+	_ = s.port.Send([]byte{midi.Stop})
+}
+
+// MIDIFollower follows the MIDI clock received on a port, playing tracks at
+// its tempo while its transport runs.
+type MIDIFollower struct {
+	*follower
+	port midi.Port
+
+	// Time the last pulse was received, or zero since the transport started
+	lastPulse time.Time
+	// Running estimate of the time between pulses
+	pulseDuration time.Duration
+}
+
+// NewMIDIFollower returns a follower of the MIDI clock received on the port,
+// timed by the clock. A nil clock follows real time. The port is read until
+// it's closed, which ends any track following it.
+func NewMIDIFollower(port midi.Port, c clock.Clock) *MIDIFollower {
+	f := &MIDIFollower{follower: newFollower(c, midi.PulsesPerQuarterNote), port: port}
+
+	go f.receive()
+
+	return f
+}
+
+// receive follows the messages received on the port until it's closed.
+func (f *MIDIFollower) receive() {
+	defer f.close()
+
+	for {
+		message, err := f.port.Receive()
+		if err != nil {
+			return
+		}
+
+		f.handle(message)
+	}
+}
+
+// handle follows a single message, ignoring any which aren't clock or
+// transport messages.
+func (f *MIDIFollower) handle(message []byte) {
+	if len(message) != 1 {
+		return
+	}
+
+	switch message[0] {
+	case midi.Start, midi.Continue:
+		f.lastPulse = time.Time{}
+		f.start(message[0] == midi.Continue)
+	case midi.Stop:
+		f.stop()
+	case midi.TimingClock:
+		now := f.clock.Now()
+
+		if !f.lastPulse.IsZero() {
+			interval := now.Sub(f.lastPulse)
+			if f.pulseDuration == 0 {
+				f.pulseDuration = interval
+			} else {
+				f.pulseDuration += time.Duration(pulseSmoothing * float64(interval-f.pulseDuration))
+			}
+		}
+
+		f.lastPulse = now
+		f.pulse(f.pulseDuration * midi.PulsesPerQuarterNote)
+	}
+}
@@ -0,0 +1,200 @@
+package clocksync
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/jcfox412/logarhythms/internal/clock"
+	"github.com/jcfox412/logarhythms/internal/osc"
+	"github.com/jcfox412/logarhythms/internal/render"
+)
+
+// OSC addresses of the messages tracks are kept in time with over the network
+const (
+	// Sent on each beat with the track's BPM and the number of beats played
+	// before it
+	BeatAddress = "/clock/beat"
+	// Sent once the track stops
+	StopAddress = "/clock/stop"
+)
+
+const (
+	// Largest packet read, which is the largest UDP payload
+	maxPacketSize = 65507
+	// Errors kept for NetFollower.Errors until they're read
+	maxErrors = 16
+)
+
+// netSender is a renderer which sends the beats of the tracks it draws over
+// the network.
+type netSender struct {
+	conn net.PacketConn
+	addr net.Addr
+
+	mu             sync.Mutex
+	beatsPerMinute float64
+	// Beat subdivisions to the beat of the track playing
+	divisionsPerBeat int
+	started          bool
+}
+
+// NewNetSender returns a renderer which sends a beat message to addr from conn
+// on each beat of the tracks it draws, and a stop message once each stops,
+// so that followers listening at addr play in time. addr can be a broadcast
+// address, such as 255.255.255.255:9100, to reach every follower on the local
+// network. Messages which can't be sent are dropped, as UDP would drop them
+// anyway.
+func NewNetSender(conn net.PacketConn, addr net.Addr) render.Renderer {
+	return &netSender{conn: conn, addr: addr}
+}
+
+func (s *netSender) Start(track render.Track) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.beatsPerMinute = track.BeatsPerMinute
+	s.divisionsPerBeat = track.DivisionsPerBeat
+	s.started = true
+}
+
+func (s *netSender) Step(step render.Step) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.divisionsPerBeat == 0 || step.Index%s.divisionsPerBeat != 0 {
+		return
+	}
+
+	s.send(osc.Message{Address: BeatAddress, Arguments: []interface{}{
+		float32(s.beatsPerMinute), int32(step.Index / s.divisionsPerBeat),
+	}})
+}
+
+// Retime sends the track's new BPM from its next beat.
+func (s *netSender) Retime(beatsPerMinute float64, stepDuration time.Duration, totalSteps int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.beatsPerMinute = beatsPerMinute
+}
+
+func (s *netSender) Clip() {}
+
+func (s *netSender) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.started {
+		s.started = false
+		s.send(osc.Message{Address: StopAddress})
+	}
+}
+
+func (s *netSender) send(message osc.Message) {
+	packet, err := message.MarshalBinary()
+	if err != nil {
+		return
+	}
+
+	_, _ = s.conn.WriteTo(packet, s.addr)
+}
+
+// NetFollower follows the beats sent over the network by the renderers of
+// NewNetSender, playing tracks at the sender's tempo and in phase with its
+// beats from the next beat received.
+type NetFollower struct {
+	*follower
+	conn net.PacketConn
+	errs chan error
+}
+
+// NewNetFollower returns a follower of the beats received on conn, timed by
+// the clock. A nil clock follows real time. conn is read until it's closed,
+// which ends any track following it.
+func NewNetFollower(conn net.PacketConn, c clock.Clock) *NetFollower {
+	f := &NetFollower{follower: newFollower(c, 1), conn: conn, errs: make(chan error, maxErrors)}
+
+	go f.receive()
+
+	return f
+}
+
+// Errors returns a channel of the packets which couldn't be followed, such as
+// beats without a BPM, which is closed once conn is. Errors are dropped while
+// the channel is full.
+func (f *NetFollower) Errors() <-chan error {
+	return f.errs
+}
+
+// receive follows the messages received on conn until it's closed.
+func (f *NetFollower) receive() {
+	defer close(f.errs)
+	defer f.close()
+
+	packet := make([]byte, maxPacketSize)
+	for {
+		n, addr, err := f.conn.ReadFrom(packet)
+		if err != nil {
+			return
+		}
+
+		messages, err := osc.Parse(packet[:n])
+		if err != nil {
+			f.report(errors.Wrapf(err, "error parsing packet from %s", addr))
+			continue
+		}
+
+		for _, message := range messages {
+			if err := f.handle(message); err != nil {
+				f.report(errors.Wrapf(err, "error following %s from %s", message.Address, addr))
+			}
+		}
+	}
+}
+
+// handle follows a single message, ignoring any which aren't beat or stop
+// messages. Returns an error if a beat doesn't have a BPM and beat number.
+func (f *NetFollower) handle(message osc.Message) error {
+	switch message.Address {
+	case BeatAddress:
+		if len(message.Arguments) != 2 {
+			return fmt.Errorf("expected a BPM and beat number, got %d arguments", len(message.Arguments))
+		}
+
+		beatsPerMinute, ok := message.Arguments[0].(float32)
+		if !ok || !(beatsPerMinute > 0) {
+			return fmt.Errorf("BPM must be a float greater than 0, got %v", message.Arguments[0])
+		}
+
+		beat, ok := message.Arguments[1].(int32)
+		if !ok || beat < 0 {
+			return fmt.Errorf("beat number must be an int of at least 0, got %v", message.Arguments[1])
+		}
+
+		f.mu.Lock()
+		running := f.running
+		f.mu.Unlock()
+
+		if !running {
+			f.start(false)
+		}
+
+		f.pulseAt(int(beat), time.Duration(float64(time.Minute)/float64(beatsPerMinute)))
+	case StopAddress:
+		f.stop()
+	}
+
+	return nil
+}
+
+// report sends err on the errors channel, unless it's full.
+func (f *NetFollower) report(err error) {
+	select {
+	case f.errs <- err:
+	default:
+	}
+}
@@ -0,0 +1,172 @@
+package events
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/jcfox412/logarhythms/internal/clock"
+	"github.com/jcfox412/logarhythms/internal/render"
+)
+
+// Types of event
+const (
+	// A track started playing
+	Start = "start"
+	// The tempo changed, sent as a track starts at a different BPM to the
+	// last, or its BPM changes while it plays
+	Tempo = "tempo"
+	// A new bar started
+	Bar = "bar"
+	// A beat subdivision was played
+	Step = "step"
+	// An instrument was triggered in the beat subdivision just played
+	Hit = "hit"
+	// The mix clipped
+	Clip = "clip"
+	// The track stopped playing, however it stopped
+	Stop = "stop"
+	// The loaded track or its settings changed, published by the server rather
+	// than as tracks play
+	Changed = "changed"
+)
+
+// Event is something that happened as a track played.
+type Event struct {
+	// When the event happened, by the clock the track is played by
+	Time time.Time `json:"time"`
+	Type string    `json:"type"`
+	// Title of the track, for start events
+	Title string `json:"title,omitempty"`
+	// BPM of the track, for start and tempo events
+	BeatsPerMinute float64 `json:"bpm,omitempty"`
+	// Number of beat subdivisions played before this one, for step and hit
+	// events
+	Index *int `json:"index,omitempty"`
+	// Position of the beat subdivision, each counting from 1, for bar, step
+	// and hit events
+	Bar  int `json:"bar,omitempty"`
+	Beat int `json:"beat,omitempty"`
+	Step int `json:"step,omitempty"`
+	// Instrument triggered, by name and position in the track, and how hard it
+	// was played from 0 to 1, for hit events
+	Instrument      string  `json:"instrument,omitempty"`
+	InstrumentIndex *int    `json:"instrument_index,omitempty"`
+	Velocity        float64 `json:"velocity,omitempty"`
+}
+
+// renderer turns the steps of a playing track into events.
+type renderer struct {
+	publish func(Event)
+	clock   clock.Clock
+	track   render.Track
+	bar     int
+	// BPM of the last track played, or 0 before the first
+	beatsPerMinute float64
+}
+
+// NewRenderer returns a renderer which publishes the events of each track it
+// draws, timed by the clock. A nil clock follows real time.
+func NewRenderer(publish func(Event), c clock.Clock) render.Renderer {
+	if c == nil {
+		c = clock.New()
+	}
+
+	return &renderer{publish: publish, clock: c}
+}
+
+// NewNDJSON returns a renderer which writes the events of each track it draws
+// to w as newline-delimited JSON, timed by the clock. A nil clock follows real
+// time.
+func NewNDJSON(w io.Writer, c clock.Clock) render.Renderer {
+	var mu sync.Mutex
+	encoder := json.NewEncoder(w)
+
+	return NewRenderer(func(event Event) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		// a visualiser missing events is better than playback stopping
+		_ = encoder.Encode(event)
+	}, c)
+}
+
+func (r *renderer) Start(track render.Track) {
+	now := r.clock.Now()
+
+	r.track = track
+	r.bar = 0
+
+	r.publish(Event{Time: now, Type: Start, Title: track.Title, BeatsPerMinute: track.BeatsPerMinute})
+
+	if track.BeatsPerMinute != r.beatsPerMinute {
+		r.beatsPerMinute = track.BeatsPerMinute
+		r.publish(Event{Time: now, Type: Tempo, BeatsPerMinute: track.BeatsPerMinute})
+	}
+}
+
+func (r *renderer) Step(step render.Step) {
+	now := r.clock.Now()
+
+	divisionsPerBeat := r.track.DivisionsPerBeat
+	if divisionsPerBeat <= 0 {
+		divisionsPerBeat = 1
+	}
+
+	index := step.Index
+	position := Event{
+		Time:  now,
+		Index: &index,
+		Bar:   step.Bar,
+		Beat:  step.Division/divisionsPerBeat + 1,
+		Step:  step.Division%divisionsPerBeat + 1,
+	}
+
+	if step.Bar != r.bar {
+		r.bar = step.Bar
+		r.publish(Event{Time: now, Type: Bar, Bar: step.Bar})
+	}
+
+	event := position
+	event.Type = Step
+	r.publish(event)
+
+	for i, hit := range step.Hits {
+		if hit.Glyph == "" {
+			continue
+		}
+
+		instrument := i
+		event := position
+		event.Type = Hit
+		event.InstrumentIndex = &instrument
+		event.Velocity = hit.Velocity
+
+		if i < len(r.track.Instruments) {
+			event.Instrument = r.track.Instruments[i]
+		}
+
+		r.publish(event)
+	}
+}
+
+// Retime publishes the new tempo of the playing track.
+func (r *renderer) Retime(beatsPerMinute float64, stepDuration time.Duration, totalSteps int) {
+	r.track.BeatsPerMinute = beatsPerMinute
+	r.track.StepDuration = stepDuration
+	r.track.TotalSteps = totalSteps
+
+	if beatsPerMinute != r.beatsPerMinute {
+		r.beatsPerMinute = beatsPerMinute
+		r.publish(Event{Time: r.clock.Now(), Type: Tempo, BeatsPerMinute: beatsPerMinute})
+	}
+}
+
+func (r *renderer) Clip() {
+	r.publish(Event{Time: r.clock.Now(), Type: Clip})
+}
+
+func (r *renderer) Stop() {
+	r.publish(Event{Time: r.clock.Now(), Type: Stop})
+}
@@ -0,0 +1,107 @@
+package events_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jcfox412/logarhythms/internal/clock"
+	"github.com/jcfox412/logarhythms/internal/events"
+	"github.com/jcfox412/logarhythms/internal/render"
+)
+
+var (
+	start = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	track = render.Track{
+		Title:            "Playing track at BPM: 120",
+		BeatsPerMinute:   120,
+		Instruments:      []string{"Kick", "Hi-Hat"},
+		DivisionsPerBeat: 2,
+		StepDuration:     250 * time.Millisecond,
+	}
+)
+
+func intPointer(i int) *int {
+	return &i
+}
+
+func TestRenderer(t *testing.T) {
+	c := clock.NewManual(start)
+
+	var actualEvents []events.Event
+	renderer := events.NewRenderer(func(event events.Event) {
+		actualEvents = append(actualEvents, event)
+	}, c)
+
+	renderer.Start(track)
+	renderer.Step(render.Step{Index: 0, Bar: 1, Division: 0, Count: "1 ", Hits: []render.Hit{{Glyph: "X", Velocity: 1}, {Glyph: "o", Velocity: 0.4}}})
+	c.Advance(250 * time.Millisecond)
+	renderer.Step(render.Step{Index: 1, Bar: 1, Division: 1, Count: "  ", Hits: []render.Hit{{}, {Glyph: "X", Velocity: 1}}})
+	renderer.Clip()
+	c.Advance(250 * time.Millisecond)
+	renderer.Step(render.Step{Index: 2, Bar: 2, Division: 0, Count: "1 ", Hits: []render.Hit{{}, {}}})
+	renderer.Stop()
+
+	// a track played again at the same BPM doesn't change the tempo
+	renderer.Start(track)
+
+	later := start.Add(250 * time.Millisecond)
+	latest := start.Add(500 * time.Millisecond)
+
+	assert.Equal(t, []events.Event{
+		{Time: start, Type: events.Start, Title: "Playing track at BPM: 120", BeatsPerMinute: 120},
+		{Time: start, Type: events.Tempo, BeatsPerMinute: 120},
+		{Time: start, Type: events.Bar, Bar: 1},
+		{Time: start, Type: events.Step, Index: intPointer(0), Bar: 1, Beat: 1, Step: 1},
+		{Time: start, Type: events.Hit, Index: intPointer(0), Bar: 1, Beat: 1, Step: 1, Instrument: "Kick", InstrumentIndex: intPointer(0), Velocity: 1},
+		{Time: start, Type: events.Hit, Index: intPointer(0), Bar: 1, Beat: 1, Step: 1, Instrument: "Hi-Hat", InstrumentIndex: intPointer(1), Velocity: 0.4},
+		{Time: later, Type: events.Step, Index: intPointer(1), Bar: 1, Beat: 1, Step: 2},
+		{Time: later, Type: events.Hit, Index: intPointer(1), Bar: 1, Beat: 1, Step: 2, Instrument: "Hi-Hat", InstrumentIndex: intPointer(1), Velocity: 1},
+		{Time: later, Type: events.Clip},
+		{Time: latest, Type: events.Bar, Bar: 2},
+		{Time: latest, Type: events.Step, Index: intPointer(2), Bar: 2, Beat: 1, Step: 1},
+		{Time: latest, Type: events.Stop},
+		{Time: latest, Type: events.Start, Title: "Playing track at BPM: 120", BeatsPerMinute: 120},
+	}, actualEvents)
+}
+
+func TestRendererRetime(t *testing.T) {
+	c := clock.NewManual(start)
+
+	var actualEvents []events.Event
+	renderer := events.NewRenderer(func(event events.Event) {
+		actualEvents = append(actualEvents, event)
+	}, c)
+
+	renderer.Start(track)
+	c.Advance(time.Second)
+
+	// retiming to the same BPM doesn't change the tempo
+	render.Retime(renderer, 120, 250*time.Millisecond, 0)
+	render.Retime(renderer, 92.5, 324324324*time.Nanosecond, 0)
+
+	assert.Equal(t, []events.Event{
+		{Time: start, Type: events.Start, Title: "Playing track at BPM: 120", BeatsPerMinute: 120},
+		{Time: start, Type: events.Tempo, BeatsPerMinute: 120},
+		{Time: start.Add(time.Second), Type: events.Tempo, BeatsPerMinute: 92.5},
+	}, actualEvents)
+}
+
+func TestNDJSON(t *testing.T) {
+	w := &bytes.Buffer{}
+
+	renderer := events.NewNDJSON(w, clock.NewManual(start))
+	renderer.Start(track)
+	renderer.Step(render.Step{Index: 0, Bar: 1, Division: 0, Count: "1 ", Hits: []render.Hit{{Glyph: "X", Velocity: 1}, {}}})
+	renderer.Stop()
+
+	assert.Equal(t, `{"time":"2020-01-01T00:00:00Z","type":"start","title":"Playing track at BPM: 120","bpm":120}
+{"time":"2020-01-01T00:00:00Z","type":"tempo","bpm":120}
+{"time":"2020-01-01T00:00:00Z","type":"bar","bar":1}
+{"time":"2020-01-01T00:00:00Z","type":"step","index":0,"bar":1,"beat":1,"step":1}
+{"time":"2020-01-01T00:00:00Z","type":"hit","index":0,"bar":1,"beat":1,"step":1,"instrument":"Kick","instrument_index":0,"velocity":1}
+{"time":"2020-01-01T00:00:00Z","type":"stop"}
+`, w.String())
+}
@@ -0,0 +1,129 @@
+package events
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// Events buffered for each subscriber. Events published while a
+	// subscriber's buffer is full are dropped for it, rather than holding up
+	// playback.
+	subscriberBuffer = 256
+	// Time given to a WebSocket client to take each event
+	writeTimeout = time.Second
+)
+
+// Hub publishes events to each of its subscribers, which can subscribe over
+// a WebSocket by requesting the hub as an HTTP handler.
+type Hub struct {
+	mu          sync.Mutex
+	subscribers map[chan Event]struct{}
+	closed      bool
+	upgrader    websocket.Upgrader
+}
+
+// NewHub creates a hub without any subscribers.
+func NewHub() *Hub {
+	return &Hub{subscribers: map[chan Event]struct{}{}}
+}
+
+// Publish sends the event to every subscriber with room for it.
+func (h *Hub) Publish(event Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for subscriber := range h.subscribers {
+		select {
+		case subscriber <- event:
+		default:
+		}
+	}
+}
+
+// Subscribe returns a channel receiving every event published from now on,
+// and a function which unsubscribes from them. The channel is closed once
+// unsubscribed, or when the hub is closed.
+func (h *Hub) Subscribe() (<-chan Event, func()) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	events := make(chan Event, subscriberBuffer)
+	if h.closed {
+		close(events)
+		return events, func() {}
+	}
+
+	h.subscribers[events] = struct{}{}
+
+	return events, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+
+		if _, ok := h.subscribers[events]; ok {
+			delete(h.subscribers, events)
+			close(events)
+		}
+	}
+}
+
+// Close unsubscribes every subscriber, which disconnects WebSocket clients.
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+
+	for subscriber := range h.subscribers {
+		delete(h.subscribers, subscriber)
+		close(subscriber)
+	}
+}
+
+// ServeHTTP upgrades the request to a WebSocket, then sends each event
+// published as a JSON text message until the client disconnects.
+func (h *Hub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// the upgrader has already responded with the error
+		return
+	}
+	defer conn.Close()
+
+	events, unsubscribe := h.Subscribe()
+	defer unsubscribe()
+
+	// clients aren't expected to send anything, but reading handles their
+	// control messages and notices when they disconnect
+	disconnected := make(chan struct{})
+	go func() {
+		defer close(disconnected)
+
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-disconnected:
+			return
+		case event, ok := <-events:
+			if !ok {
+				_ = conn.WriteControl(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.CloseGoingAway, ""), time.Now().Add(writeTimeout))
+				return
+			}
+
+			_ = conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+			if err := conn.WriteJSON(event); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package events_test
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jcfox412/logarhythms/internal/events"
+)
+
+func TestHubSubscribe(t *testing.T) {
+	hub := events.NewHub()
+
+	first, unsubscribeFirst := hub.Subscribe()
+	second, _ := hub.Subscribe()
+
+	hub.Publish(events.Event{Time: start, Type: events.Clip})
+	unsubscribeFirst()
+	hub.Publish(events.Event{Time: start, Type: events.Stop})
+	hub.Close()
+
+	var firstEvents, secondEvents []events.Event
+	for event := range first {
+		firstEvents = append(firstEvents, event)
+	}
+
+	for event := range second {
+		secondEvents = append(secondEvents, event)
+	}
+
+	assert.Equal(t, []events.Event{{Time: start, Type: events.Clip}}, firstEvents)
+	assert.Equal(t, []events.Event{{Time: start, Type: events.Clip}, {Time: start, Type: events.Stop}}, secondEvents)
+
+	// subscribing to a closed hub receives nothing
+	closed, unsubscribe := hub.Subscribe()
+	_, ok := <-closed
+	assert.False(t, ok)
+	unsubscribe()
+}
+
+func TestHubWebSocket(t *testing.T) {
+	hub := events.NewHub()
+	server := httptest.NewServer(hub)
+	defer server.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer conn.Close()
+
+	// the client is only subscribed after the upgrade has been answered, so
+	// events published straight after dialling can be missed until one arrives
+	received := make(chan events.Event)
+	go func() {
+		var event events.Event
+		if err := conn.ReadJSON(&event); err == nil {
+			received <- event
+		}
+		close(received)
+	}()
+
+	var event events.Event
+	for waiting := true; waiting; {
+		hub.Publish(events.Event{Time: start, Type: events.Clip})
+
+		select {
+		case event = <-received:
+			waiting = false
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+
+	assert.Equal(t, events.Event{Time: start, Type: events.Clip}, event)
+
+	// closing the hub disconnects the client
+	hub.Close()
+
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			assert.True(t, websocket.IsCloseError(err, websocket.CloseGoingAway))
+			break
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package input
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+
+	"github.com/jcfox412/logarhythms/internal/audio"
+	"github.com/jcfox412/logarhythms/internal/models"
+	"github.com/jcfox412/logarhythms/internal/utils"
+)
+
+// Highest delay feedback that can be entered, as a percentage
+const maxDelayFeedbackPercent = int(audio.MaxDelayFeedback * 100)
+
+// AllInstrumentsSendsMenu prints out the user menu for viewing all
+// instruments' reverb and delay sends and choosing one to modify. Returns an
+// error if invalid input is given.
+func (u *UserInput) AllInstrumentsSendsMenu(iface interface{}) error {
+	track := iface.(*models.Track)
+
+	instrument, err := u.selectInstrument(track, "sends", func(instrument *models.Instrument) string {
+		return describeSends(instrument.Audio.GetSends())
+	})
+	if err != nil {
+		return err
+	}
+
+	if instrument == nil {
+		return u.PrintSettingsMenu(track)
+	}
+
+	return retry(3, instrument, u.InstrumentSendsMenu)
+}
+
+// InstrumentSendsMenu prints out the user menu for modifying how much of an
+// instrument is sent to the shared reverb and delay. Returns an error if
+// invalid input is given or if instrument's Audio object is nil.
+func (u *UserInput) InstrumentSendsMenu(iface interface{}) error {
+	instrument := iface.(*models.Instrument)
+
+	if instrument.Audio == nil {
+		return errors.New("instrument audio must be set to change sends")
+	}
+
+	fmt.Print(utils.Bold(fmt.Sprintf("\nHow much of the %s should be sent to the reverb and delay? Current sends: %s\n", instrument.Name, describeSends(instrument.Audio.GetSends()))))
+
+	reverb, err := u.promptBoundedInteger("Please enter a reverb send percentage", 0, 100)
+	if err != nil {
+		return err
+	}
+
+	delay, err := u.promptBoundedInteger("Please enter a delay send percentage", 0, 100)
+	if err != nil {
+		return err
+	}
+
+	sends := audio.Sends{Reverb: float64(reverb) / 100, Delay: float64(delay) / 100}
+	if err := instrument.Audio.SetSends(sends); err != nil {
+		return errors.Wrap(err, "error setting sends")
+	}
+
+	fmt.Printf("Sends set to %s!\n", describeSends(sends))
+
+	return nil
+}
+
+// BusMenu prints out the user menu for modifying the reverb and delay shared
+// by a track's instruments. Returns an error if invalid input is given.
+func (u *UserInput) BusMenu(iface interface{}) error {
+	track := iface.(*models.Track)
+
+	params := audio.DefaultBusParams()
+	if track.Bus != nil {
+		params = *track.Bus
+	}
+
+	fmt.Print(utils.Bold(fmt.Sprintf("\nWhich reverb or delay setting would you like to change? Current settings: %s\n", describeBusParams(params))))
+	fmt.Print(busMenuOptions)
+	fmt.Print(utils.Bold("\nWhat would you like to do? (Please enter number 1-7): "))
+
+	var err error
+
+	switch userInput := getUserInput(u.Reader); userInput {
+	case "1":
+		params.Reverb.RoomSize, err = u.promptPercentage("Please enter a reverb room size percentage", 100)
+	case "2":
+		params.Reverb.Damping, err = u.promptPercentage("Please enter a reverb damping percentage", 100)
+	case "3":
+		params.Reverb.Return, err = u.promptPercentage("Please enter a reverb return percentage", 100)
+	case "4":
+		params.Delay.Note, err = u.promptNoteValue()
+	case "5":
+		params.Delay.Feedback, err = u.promptPercentage("Please enter a delay feedback percentage", maxDelayFeedbackPercent)
+	case "6":
+		params.Delay.Return, err = u.promptPercentage("Please enter a delay return percentage", 100)
+	case "7":
+		return nil
+	default:
+		err = errors.New("I'm sorry, I didn't understand your input")
+		fmt.Println(err.Error())
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if err := params.Validate(); err != nil {
+		fmt.Println(err.Error())
+		return err
+	}
+
+	track.Bus = &params
+	fmt.Printf("Reverb and delay set to %s!\n", describeBusParams(params))
+
+	return nil
+}
+
+// promptPercentage reads a percentage up to upperBound from the user, and
+// returns it as a fraction.
+func (u *UserInput) promptPercentage(prompt string, upperBound int) (float64, error) {
+	percentage, err := u.promptBoundedInteger(prompt, 0, upperBound)
+	if err != nil {
+		return 0, err
+	}
+
+	return float64(percentage) / 100, nil
+}
+
+func (u *UserInput) promptNoteValue() (audio.NoteValue, error) {
+	fmt.Print(utils.Bold("\nWhich note value should the delay repeat at? (d is dotted, t is triplet)\n"))
+	for i, note := range audio.NoteValues {
+		fmt.Printf("%d) %s\n", i+1, note)
+	}
+
+	index, err := u.promptBoundedInteger("Please enter a note value", 1, len(audio.NoteValues))
+	if err != nil {
+		return "", err
+	}
+
+	return audio.NoteValues[index-1], nil
+}
+
+func describeSends(sends audio.Sends) string {
+	return fmt.Sprintf("reverb %.f%%, delay %.f%%", sends.Reverb*100, sends.Delay*100)
+}
+
+func describeBusParams(params audio.BusParams) string {
+	r, d := params.Reverb, params.Delay
+
+	return fmt.Sprintf("reverb room %.f%% damping %.f%% return %.f%%, delay %s feedback %.f%% return %.f%%",
+		r.RoomSize*100, r.Damping*100, r.Return*100, d.Note, d.Feedback*100, d.Return*100)
+}
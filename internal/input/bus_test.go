@@ -0,0 +1,215 @@
+package input_test
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jcfox412/logarhythms/internal/audio"
+	audiomocks "github.com/jcfox412/logarhythms/internal/audio/mocks"
+	"github.com/jcfox412/logarhythms/internal/input"
+	"github.com/jcfox412/logarhythms/internal/models"
+	_ "github.com/jcfox412/logarhythms/testing"
+)
+
+// TODO: test more than error path
+func TestAllInstrumentsSendsMenu(t *testing.T) {
+	type testCase struct {
+		description     string
+		input           string
+		expectedToError bool
+	}
+
+	testCases := []testCase{
+		{
+			description:     "Errors on user input out of range",
+			input:           "3",
+			expectedToError: true,
+		},
+		{
+			description:     "Errors on non-integer input",
+			input:           "help",
+			expectedToError: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		var stdin bytes.Buffer
+		stdin.Write([]byte(fmt.Sprintf("%s\n", testCase.input)))
+
+		userInput := input.UserInput{
+			Reader: &stdin,
+		}
+
+		m := &audiomocks.Manager{}
+		m.On("GetSends").Return(audio.Sends{}).Once()
+
+		instrument := &models.Instrument{Name: "testInstrument", Audio: m}
+
+		track := &models.Track{Instruments: []*models.Instrument{instrument}}
+
+		actualErr := userInput.AllInstrumentsSendsMenu(track)
+		if testCase.expectedToError {
+			assert.NotNil(t, actualErr)
+		} else {
+			assert.Nil(t, actualErr)
+		}
+
+		m.AssertExpectations(t)
+	}
+}
+
+func TestInstrumentSendsMenu(t *testing.T) {
+	type testCase struct {
+		description        string
+		input              []string
+		shouldIncludeAudio bool
+		expectedSends      *audio.Sends
+		expectedToError    bool
+	}
+
+	testCases := []testCase{
+		{
+			description:        "Sets sends",
+			input:              []string{"30", "15"},
+			shouldIncludeAudio: true,
+			expectedSends:      &audio.Sends{Reverb: 0.3, Delay: 0.15},
+			expectedToError:    false,
+		},
+		{
+			description:        "Errors on send out of range",
+			input:              []string{"30", "101"},
+			shouldIncludeAudio: true,
+			expectedSends:      nil,
+			expectedToError:    true,
+		},
+		{
+			description:        "Errors on nil instrument Audio",
+			input:              []string{""},
+			shouldIncludeAudio: false,
+			expectedSends:      nil,
+			expectedToError:    true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		var stdin bytes.Buffer
+		for _, i := range testCase.input {
+			stdin.Write([]byte(fmt.Sprintf("%s\n", i)))
+		}
+
+		userInput := input.UserInput{
+			Reader: &stdin,
+		}
+
+		m := &audiomocks.Manager{}
+		instrument := &models.Instrument{Name: "testInstrument", Audio: m}
+
+		if testCase.shouldIncludeAudio {
+			m.On("GetSends").Return(audio.Sends{}).Once()
+		} else {
+			instrument.Audio = nil
+		}
+
+		if testCase.expectedSends != nil {
+			m.On("SetSends", *testCase.expectedSends).Return(nil).Once()
+		}
+
+		actualErr := userInput.InstrumentSendsMenu(instrument)
+		if testCase.expectedToError {
+			assert.NotNil(t, actualErr)
+		} else {
+			assert.Nil(t, actualErr)
+		}
+
+		m.AssertExpectations(t)
+	}
+}
+
+func TestBusMenu(t *testing.T) {
+	type testCase struct {
+		description     string
+		input           []string
+		expectedBus     func(*audio.BusParams)
+		expectedToError bool
+	}
+
+	testCases := []testCase{
+		{
+			description:     "Sets reverb room size",
+			input:           []string{"1", "90"},
+			expectedBus:     func(p *audio.BusParams) { p.Reverb.RoomSize = 0.9 },
+			expectedToError: false,
+		},
+		{
+			description:     "Sets reverb return",
+			input:           []string{"3", "0"},
+			expectedBus:     func(p *audio.BusParams) { p.Reverb.Return = 0 },
+			expectedToError: false,
+		},
+		{
+			description:     "Sets delay time",
+			input:           []string{"4", "2"},
+			expectedBus:     func(p *audio.BusParams) { p.Delay.Note = "1/4" },
+			expectedToError: false,
+		},
+		{
+			description:     "Sets delay feedback",
+			input:           []string{"5", "50"},
+			expectedBus:     func(p *audio.BusParams) { p.Delay.Feedback = 0.5 },
+			expectedToError: false,
+		},
+		{
+			description:     "Returns to settings menu",
+			input:           []string{"7"},
+			expectedBus:     func(p *audio.BusParams) {},
+			expectedToError: false,
+		},
+		{
+			description:     "Errors on runaway delay feedback",
+			input:           []string{"5", "100"},
+			expectedBus:     func(p *audio.BusParams) {},
+			expectedToError: true,
+		},
+		{
+			description:     "Errors on non-integer input",
+			input:           []string{"help"},
+			expectedBus:     func(p *audio.BusParams) {},
+			expectedToError: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		var stdin bytes.Buffer
+		for _, i := range testCase.input {
+			stdin.Write([]byte(fmt.Sprintf("%s\n", i)))
+		}
+
+		userInput := input.UserInput{
+			Reader: &stdin,
+		}
+
+		bus := audio.DefaultBusParams()
+		track := &models.Track{Bus: &bus}
+
+		expectedBus := audio.DefaultBusParams()
+		testCase.expectedBus(&expectedBus)
+
+		actualErr := userInput.BusMenu(track)
+		if testCase.expectedToError {
+			assert.NotNil(t, actualErr)
+		} else {
+			assert.Nil(t, actualErr)
+		}
+
+		assert.Equal(t, expectedBus, *track.Bus)
+	}
+}
@@ -0,0 +1,168 @@
+package input
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/jcfox412/logarhythms/internal/audio"
+	"github.com/jcfox412/logarhythms/internal/models"
+	"github.com/jcfox412/logarhythms/internal/utils"
+)
+
+// AllInstrumentsEffectsMenu prints out the user menu for viewing all
+// instruments' effects chains and choosing one to modify. Returns an error if
+// invalid input is given.
+func (u *UserInput) AllInstrumentsEffectsMenu(iface interface{}) error {
+	track := iface.(*models.Track)
+
+	instrument, err := u.selectInstrument(track, "effects", func(instrument *models.Instrument) string {
+		return describeEffects(instrument.Audio.GetEffects())
+	})
+	if err != nil {
+		return err
+	}
+
+	if instrument == nil {
+		return u.PrintSettingsMenu(track)
+	}
+
+	return retry(3, instrument, u.InstrumentEffectsMenu)
+}
+
+// InstrumentEffectsMenu prints out the user menu for adding, editing and
+// removing effects in an instrument's effects chain. Returns an error if
+// invalid input is given or if instrument's Audio object is nil.
+func (u *UserInput) InstrumentEffectsMenu(iface interface{}) error {
+	instrument := iface.(*models.Instrument)
+
+	if instrument.Audio == nil {
+		return errors.New("instrument audio must be set to change effects")
+	}
+
+	effects := instrument.Audio.GetEffects()
+
+	fmt.Print(utils.Bold(fmt.Sprintf("\nHow would you like to change the %s's effects? Current effects: %s\n", instrument.Name, describeEffects(effects))))
+	fmt.Print(effectsMenuOptions)
+	fmt.Print(utils.Bold("\nWhat would you like to do? (Please enter number 1-4): "))
+
+	var err error
+
+	switch userInput := getUserInput(u.Reader); userInput {
+	case "1":
+		var effectType audio.EffectType
+		effectType, err = u.promptEffectType()
+		if err == nil {
+			var effect audio.Effect
+			effect, err = u.promptEffect(effectType)
+			effects = append(effects, effect)
+		}
+	case "2":
+		var index int
+		index, err = u.promptEffectIndex(effects, "edit")
+		if err == nil {
+			effects[index], err = u.promptEffect(effects[index].Type)
+		}
+	case "3":
+		var index int
+		index, err = u.promptEffectIndex(effects, "remove")
+		if err == nil {
+			effects = append(effects[:index], effects[index+1:]...)
+		}
+	case "4":
+		return nil
+	default:
+		err = errors.New("I'm sorry, I didn't understand your input")
+		fmt.Println(err.Error())
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if err := instrument.Audio.SetEffects(effects); err != nil {
+		fmt.Println(err.Error())
+		return err
+	}
+
+	fmt.Printf("Effects set to %s!\n", describeEffects(effects))
+
+	return nil
+}
+
+func (u *UserInput) promptEffectType() (audio.EffectType, error) {
+	fmt.Print(utils.Bold("\nWhich effect would you like to add?\n"))
+	for i, effectType := range audio.EffectTypes {
+		fmt.Printf("%d) %s\n", i+1, effectType)
+	}
+
+	index, err := u.promptBoundedInteger("Please enter an effect", 1, len(audio.EffectTypes))
+	if err != nil {
+		return "", err
+	}
+
+	return audio.EffectTypes[index-1], nil
+}
+
+func (u *UserInput) promptEffectIndex(effects []audio.Effect, action string) (int, error) {
+	if len(effects) == 0 {
+		err := fmt.Errorf("there are no effects to %s", action)
+		fmt.Println(err.Error())
+		return 0, err
+	}
+
+	index, err := u.promptBoundedInteger(fmt.Sprintf("Please enter the number of the effect to %s", action), 1, len(effects))
+	if err != nil {
+		return 0, err
+	}
+
+	return index - 1, nil
+}
+
+// promptEffect reads the params used by the effect type from the user.
+func (u *UserInput) promptEffect(effectType audio.EffectType) (audio.Effect, error) {
+	effect := audio.Effect{Type: effectType}
+
+	var err error
+
+	switch effectType {
+	case audio.LowPass, audio.HighPass, audio.Peaking:
+		effect.Frequency, err = u.promptBoundedFloat("Please enter a frequency in hertz", audio.MinFilterFrequency, audio.MaxFilterFrequency)
+		if err == nil {
+			effect.Q, err = u.promptBoundedFloat("Please enter a Q (0.707 is flat)", audio.MinFilterQ, audio.MaxFilterQ)
+		}
+
+		if err == nil && effectType == audio.Peaking {
+			effect.Gain, err = u.promptBoundedFloat("Please enter a gain in decibels", -audio.MaxPeakingGain, audio.MaxPeakingGain)
+		}
+	case audio.BitCrush:
+		effect.Bits, err = u.promptBoundedInteger("Please enter a bit depth", 1, audio.MaxBits)
+		if err == nil {
+			effect.Downsample, err = u.promptBoundedInteger("Please enter a downsample factor", 1, audio.MaxDownsample)
+		}
+	case audio.Saturate:
+		effect.Drive, err = u.promptBoundedFloat("Please enter a drive", 1, audio.MaxDrive)
+	case audio.Transient:
+		effect.Attack, err = u.promptBoundedFloat("Please enter an attack boost (negative cuts)", -1, 1)
+		if err == nil {
+			effect.Sustain, err = u.promptBoundedFloat("Please enter a sustain boost (negative cuts)", -1, 1)
+		}
+	}
+
+	return effect, err
+}
+
+func describeEffects(effects []audio.Effect) string {
+	if len(effects) == 0 {
+		return "none"
+	}
+
+	descriptions := make([]string, 0, len(effects))
+	for i, effect := range effects {
+		descriptions = append(descriptions, strconv.Itoa(i+1)+". "+effect.String())
+	}
+
+	return strings.Join(descriptions, ", ")
+}
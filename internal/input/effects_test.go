@@ -0,0 +1,185 @@
+package input_test
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jcfox412/logarhythms/internal/audio"
+	audiomocks "github.com/jcfox412/logarhythms/internal/audio/mocks"
+	"github.com/jcfox412/logarhythms/internal/input"
+	"github.com/jcfox412/logarhythms/internal/models"
+	_ "github.com/jcfox412/logarhythms/testing"
+)
+
+// TODO: test more than error path
+func TestAllInstrumentsEffectsMenu(t *testing.T) {
+	type testCase struct {
+		description     string
+		input           string
+		expectedToError bool
+	}
+
+	testCases := []testCase{
+		{
+			description:     "Errors on user input out of range",
+			input:           "3",
+			expectedToError: true,
+		},
+		{
+			description:     "Errors on non-integer input",
+			input:           "help",
+			expectedToError: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		var stdin bytes.Buffer
+		stdin.Write([]byte(fmt.Sprintf("%s\n", testCase.input)))
+
+		userInput := input.UserInput{
+			Reader: &stdin,
+		}
+
+		m := &audiomocks.Manager{}
+		m.On("GetEffects").Return([]audio.Effect(nil)).Once()
+
+		instrument := &models.Instrument{Name: "testInstrument", Audio: m}
+
+		track := &models.Track{Instruments: []*models.Instrument{instrument}}
+
+		actualErr := userInput.AllInstrumentsEffectsMenu(track)
+		if testCase.expectedToError {
+			assert.NotNil(t, actualErr)
+		} else {
+			assert.Nil(t, actualErr)
+		}
+
+		m.AssertExpectations(t)
+	}
+}
+
+func TestInstrumentEffectsMenu(t *testing.T) {
+	type testCase struct {
+		description        string
+		input              []string
+		shouldIncludeAudio bool
+		expectedEffects    []audio.Effect
+		expectedToError    bool
+	}
+
+	lowPass := audio.Effect{Type: audio.LowPass, Frequency: 8000, Q: 0.707}
+	saturate := audio.Effect{Type: audio.Saturate, Drive: 2}
+
+	testCases := []testCase{
+		{
+			description:        "Adds peaking filter",
+			input:              []string{"1", "3", "250", "1.5", "-3"},
+			shouldIncludeAudio: true,
+			expectedEffects:    []audio.Effect{lowPass, saturate, {Type: audio.Peaking, Frequency: 250, Q: 1.5, Gain: -3}},
+			expectedToError:    false,
+		},
+		{
+			description:        "Adds bit crusher",
+			input:              []string{"1", "4", "8", "2"},
+			shouldIncludeAudio: true,
+			expectedEffects:    []audio.Effect{lowPass, saturate, {Type: audio.BitCrush, Bits: 8, Downsample: 2}},
+			expectedToError:    false,
+		},
+		{
+			description:        "Adds transient shaper",
+			input:              []string{"1", "6", "0.5", "-0.25"},
+			shouldIncludeAudio: true,
+			expectedEffects:    []audio.Effect{lowPass, saturate, {Type: audio.Transient, Attack: 0.5, Sustain: -0.25}},
+			expectedToError:    false,
+		},
+		{
+			description:        "Edits effect",
+			input:              []string{"2", "2", "10"},
+			shouldIncludeAudio: true,
+			expectedEffects:    []audio.Effect{lowPass, {Type: audio.Saturate, Drive: 10}},
+			expectedToError:    false,
+		},
+		{
+			description:        "Removes effect",
+			input:              []string{"3", "1"},
+			shouldIncludeAudio: true,
+			expectedEffects:    []audio.Effect{saturate},
+			expectedToError:    false,
+		},
+		{
+			description:        "Returns to settings menu",
+			input:              []string{"4"},
+			shouldIncludeAudio: true,
+			expectedEffects:    nil,
+			expectedToError:    false,
+		},
+		{
+			description:        "Errors on frequency out of range",
+			input:              []string{"1", "1", "5"},
+			shouldIncludeAudio: true,
+			expectedEffects:    nil,
+			expectedToError:    true,
+		},
+		{
+			description:        "Errors on removing effect that doesn't exist",
+			input:              []string{"3", "3"},
+			shouldIncludeAudio: true,
+			expectedEffects:    nil,
+			expectedToError:    true,
+		},
+		{
+			description:        "Errors on non-integer input",
+			input:              []string{"help"},
+			shouldIncludeAudio: true,
+			expectedEffects:    nil,
+			expectedToError:    true,
+		},
+		{
+			description:        "Errors on nil instrument Audio",
+			input:              []string{""},
+			shouldIncludeAudio: false,
+			expectedEffects:    nil,
+			expectedToError:    true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		var stdin bytes.Buffer
+		for _, i := range testCase.input {
+			stdin.Write([]byte(fmt.Sprintf("%s\n", i)))
+		}
+
+		userInput := input.UserInput{
+			Reader: &stdin,
+		}
+
+		m := &audiomocks.Manager{}
+		instrument := &models.Instrument{Name: "testInstrument", Audio: m}
+
+		if testCase.shouldIncludeAudio {
+			m.On("GetEffects").Return([]audio.Effect{lowPass, saturate}).Once()
+		} else {
+			instrument.Audio = nil
+		}
+
+		if testCase.expectedEffects != nil {
+			m.On("SetEffects", testCase.expectedEffects).Return(nil).Once()
+		}
+
+		actualErr := userInput.InstrumentEffectsMenu(instrument)
+		if testCase.expectedToError {
+			assert.NotNil(t, actualErr)
+		} else {
+			assert.Nil(t, actualErr)
+		}
+
+		m.AssertExpectations(t)
+	}
+}
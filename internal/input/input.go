@@ -1,25 +1,57 @@
 package input
 
 import (
-	"bufio"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"math"
 	"os"
 	"os/exec"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/pkg/errors"
 
+	"github.com/jcfox412/logarhythms/internal/audio"
+	"github.com/jcfox412/logarhythms/internal/clock"
+	"github.com/jcfox412/logarhythms/internal/midi"
 	"github.com/jcfox412/logarhythms/internal/models"
+	"github.com/jcfox412/logarhythms/internal/render"
 	"github.com/jcfox412/logarhythms/internal/utils"
 )
 
 // UserInput provides an easy way to mimic user input for testing.
 type UserInput struct {
 	Reader io.Reader
+	// File location of a kit to play every track on, overriding each track's own
+	// kit. Empty keeps each track's kit.
+	KitFilename string
+	// Beats per minute to play every track at, overriding each track's
+	// suggested BPM. 0 keeps each track's BPM.
+	BeatsPerMinute float64
+	// Length to play every track for, overriding the default. Nil keeps the
+	// default.
+	Length *models.Length
+	// Context tracks are played with, cancelled to stop playback early. Nil
+	// plays tracks to the end.
+	Context context.Context
+	// Renderer tracks are drawn by as they play. Nil draws nothing.
+	Renderer render.Renderer
+	// External clock tracks follow in place of their BPM. Nil plays them at
+	// their BPM.
+	Sync models.Sync
+	// Port the MIDI notes of tracks' instruments are sent to. Nil sends none.
+	MIDI midi.Port
+	// Clock tempos tapped in the menus are timed by. Nil follows real time.
+	Clock clock.Clock
+	// Keyboard the instruments of tracks are played and recorded on as they
+	// play. Nil reads no keys.
+	Keyboard Keyboard
+	// How instruments played on the keyboard are recorded
+	Record models.RecordSettings
 }
 
 // PrintMainMenu prints out the main user menu for using LogaRhythms.
@@ -30,11 +62,23 @@ func (u *UserInput) PrintMainMenu() error {
 
 	switch userInput := getUserInput(u.Reader); userInput {
 	case "1", "2", "3":
-		track, err := prepareTrack(inputTrackMap[userInput])
+		track, err := PrepareTrack(inputTrackMap[userInput], u.KitFilename)
 		if err != nil {
 			return errors.Wrap(err, "could not prepare track")
 		}
 
+		if u.BeatsPerMinute != 0 {
+			track.BeatsPerMinute = u.BeatsPerMinute
+		}
+
+		if u.Length != nil {
+			track.Length = *u.Length
+		}
+
+		track.Renderer = u.Renderer
+		track.Sync = u.Sync
+		track.MIDI = u.MIDI
+
 		return retry(3, track, u.PrintSettingsMenu)
 	case "4":
 		// normally I would never do something like this as it is extremely dangerous,
@@ -60,29 +104,35 @@ func (u *UserInput) PrintSettingsMenu(iface interface{}) error {
 	fmt.Printf("\nYou've selected to play %s!\n", track.Title)
 
 	fmt.Print(utils.Bold("Available settings:"))
-	fmt.Println(settingsMenuOptions)
-	fmt.Print(utils.Bold("\nWhat would you like to do? (Please enter number 1-5): "))
+	fmt.Print(settingsMenuOptions, "\n")
+	fmt.Print(utils.Bold("\nWhat would you like to do? (Please enter number 1-11): "))
 
 	inputMenuMap := map[string]func(interface{}) error{
 		"1": u.BeatsPerMinuteMenu,
 		"2": u.AllInstrumentsVolumeMenu,
-		"3": u.TrackLengthMenu,
+		"3": u.AllInstrumentsSampleMenu,
+		"4": u.AllInstrumentsEffectsMenu,
+		"5": u.AllInstrumentsSendsMenu,
+		"6": u.BusMenu,
+		"7": u.MasterMenu,
+		"8": u.KitMenu,
+		"9": u.TrackLengthMenu,
 	}
 
 	switch userInput := getUserInput(u.Reader); userInput {
-	case "1", "2", "3":
+	case "1", "2", "3", "4", "5", "6", "7", "8", "9":
 		if err := retry(3, track, inputMenuMap[userInput]); err != nil {
 			return errors.Wrap(err, "error loading menu")
 		}
 
 		return u.PrintSettingsMenu(track)
-	case "4":
-		if err := track.Play(); err != nil {
+	case "10":
+		if err := u.play(track); err != nil {
 			return errors.Wrap(err, "error playing track")
 		}
 
 		return u.PrintMainMenu()
-	case "5":
+	case "11":
 		return u.PrintMainMenu()
 	default:
 		err := errors.New("I'm sorry, I didn't understand your input")
@@ -96,17 +146,72 @@ func (u *UserInput) PrintSettingsMenu(iface interface{}) error {
 func (u *UserInput) BeatsPerMinuteMenu(iface interface{}) error {
 	track := iface.(*models.Track)
 
-	fmt.Print(utils.Bold(fmt.Sprintf("\nAt what beats per minute (BPM) would you like the track to play at? Current BPM: %d\n", track.BeatsPerMinute)))
-	fmt.Print("Please enter a BPM (beats per minute) between 1 and 1000: ")
+	fmt.Print(utils.Bold(fmt.Sprintf("\nAt what beats per minute (BPM) would you like the track to play at? Current BPM: %v\n", track.BeatsPerMinute)))
+	fmt.Printf("Please enter a BPM (beats per minute) between %d and %d, or %s to tap the tempo: ", minBeatsPerMinute, maxBeatsPerMinute, tapTempoOption)
+
+	userInput := getUserInput(u.Reader)
+	if userInput == tapTempoOption {
+		return u.tapTempo(track)
+	}
 
-	beatsPerMinute, err := validateBoundedIntegerInput(getUserInput(u.Reader), 1, 1000)
+	beatsPerMinute, err := validateBoundedFloatInput(userInput, minBeatsPerMinute, maxBeatsPerMinute)
 	if err != nil {
 		fmt.Println(err.Error())
 		return err
 	}
 
 	track.BeatsPerMinute = beatsPerMinute
-	fmt.Printf("Beats per minute set to %d!\n", beatsPerMinute)
+	fmt.Printf("Beats per minute set to %v!\n", beatsPerMinute)
+
+	return nil
+}
+
+// tapTempo sets the track's BPM to the tempo tapped by pressing Enter on each
+// beat, showing the tempo as it's tapped. Returns an error if the tempo isn't
+// tapped, or is out of range.
+func (u *UserInput) tapTempo(track *models.Track) error {
+	fmt.Printf("Press Enter on each beat, at least %d times, then enter anything else to use the tempo tapped:\n", models.MinTaps)
+
+	tapper := models.NewTapTempo(u.Clock)
+	tapped := 0.0
+
+	for {
+		line, err := readUserInput(u.Reader)
+		if line != "" {
+			break
+		}
+
+		if err != nil {
+			err := errors.New("input ended while tapping the tempo")
+			fmt.Println(err.Error())
+			return err
+		}
+
+		beatsPerMinute, ok := tapper.Tap()
+		if !ok {
+			tapped = 0
+			fmt.Print("Keep tapping...")
+			continue
+		}
+
+		tapped = beatsPerMinute
+		fmt.Printf("%v BPM", tapped)
+	}
+
+	if tapped == 0 {
+		err := fmt.Errorf("tap at least %d times in a row to set the tempo", models.MinTaps)
+		fmt.Println(err.Error())
+		return err
+	}
+
+	if tapped < minBeatsPerMinute || tapped > maxBeatsPerMinute {
+		err := fmt.Errorf("tapped BPM must be between %d and %d, got %v", minBeatsPerMinute, maxBeatsPerMinute, tapped)
+		fmt.Println(err.Error())
+		return err
+	}
+
+	track.BeatsPerMinute = tapped
+	fmt.Printf("Beats per minute set to %v!\n", tapped)
 
 	return nil
 }
@@ -116,26 +221,18 @@ func (u *UserInput) BeatsPerMinuteMenu(iface interface{}) error {
 func (u *UserInput) AllInstrumentsVolumeMenu(iface interface{}) error {
 	track := iface.(*models.Track)
 
-	fmt.Print(utils.Bold("\nSelect an instrument to change its volume:\n"))
-	i := 1
-	for _, instrument := range track.Instruments {
-		fmt.Printf("%d) %s: %.f\n", i, instrument.Name, instrument.Audio.GetVolume())
-		i++
-	}
-	fmt.Printf("%d) Return to settings menu\n", i)
-	fmt.Print(utils.Bold(fmt.Sprintf("\nWhich instrument's volume do want to change? (Please enter number 1-%d): ", i)))
-
-	index, err := validateBoundedIntegerInput(getUserInput(u.Reader), 1, i)
+	instrument, err := u.selectInstrument(track, "volume", func(instrument *models.Instrument) string {
+		return fmt.Sprintf("%.f", instrument.Audio.GetVolume())
+	})
 	if err != nil {
-		fmt.Println(err.Error())
 		return err
 	}
 
-	if index == i {
+	if instrument == nil {
 		return u.PrintSettingsMenu(track)
 	}
 
-	return retry(3, track.Instruments[index-1], u.InstrumentVolumeMenu)
+	return retry(3, instrument, u.InstrumentVolumeMenu)
 }
 
 // InstrumentVolumeMenu prints out the user menu for modifying an instrument's
@@ -166,28 +263,114 @@ func (u *UserInput) InstrumentVolumeMenu(iface interface{}) error {
 	return nil
 }
 
-// TrackLengthMenu prints out the user menu for modifying a track's length.
-// Returns an error if invalid input is given.
+// TrackLengthMenu prints out the user menu for modifying how long a track
+// plays for. Returns an error if invalid input is given.
 func (u *UserInput) TrackLengthMenu(iface interface{}) error {
 	track := iface.(*models.Track)
 
-	fmt.Print(utils.Bold(fmt.Sprintf("\nHow many seconds would you like the track to play? Current track length: %.f seconds\n", track.Length.Seconds())))
-	fmt.Print("Please enter a track length between 1 and 100: ")
+	fmt.Print(utils.Bold(fmt.Sprintf("\nHow long would you like the track to play? Current track length: %s\n", track.Length)))
+	fmt.Print(trackLengthMenuOptions)
+	fmt.Print(utils.Bold("\nWhat would you like to do? (Please enter number 1-5): "))
+
+	var (
+		length models.Length
+		err    error
+	)
 
-	length, err := validateBoundedIntegerInput(getUserInput(u.Reader), 1, 100)
-	if err != nil {
+	switch userInput := getUserInput(u.Reader); userInput {
+	case "1":
+		var bars int
+		bars, err = u.promptBoundedInteger("Please enter a number of bars", 1, maxTrackCount)
+		length = models.Bars(bars)
+	case "2":
+		// a pattern lasts a bar, so loops are played as bars
+		var loops int
+		loops, err = u.promptBoundedInteger("Please enter a number of loops", 1, maxTrackCount)
+		length = models.Bars(loops)
+	case "3":
+		var seconds int
+		seconds, err = u.promptBoundedInteger("Please enter a number of seconds", 1, maxTrackSeconds)
+		length = models.Duration(time.Duration(seconds) * time.Second)
+	case "4":
+		length = models.Infinite()
+	case "5":
+		return nil
+	default:
+		err = errors.New("I'm sorry, I didn't understand your input")
 		fmt.Println(err.Error())
+	}
+
+	if err != nil {
 		return err
 	}
 
-	track.Length = time.Duration(length) * time.Second
-	fmt.Printf("Track length set to %d!\n", length)
+	track.Length = length
+	fmt.Printf("Track length set to %s!\n", length)
 
 	return nil
 }
 
+// selectInstrument prints out a numbered list of the track's instruments, each
+// described by describe, and returns the instrument the user chooses. Returns
+// a nil instrument if the user chooses to return to the settings menu.
+func (u *UserInput) selectInstrument(track *models.Track, setting string, describe func(*models.Instrument) string) (*models.Instrument, error) {
+	fmt.Print(utils.Bold(fmt.Sprintf("\nSelect an instrument to change its %s:\n", setting)))
+	i := 1
+	for _, instrument := range track.Instruments {
+		fmt.Printf("%d) %s: %s\n", i, instrument.Name, describe(instrument))
+		i++
+	}
+	fmt.Printf("%d) Return to settings menu\n", i)
+	fmt.Print(utils.Bold(fmt.Sprintf("\nWhich instrument's %s do want to change? (Please enter number 1-%d): ", setting, i)))
+
+	index, err := validateBoundedIntegerInput(getUserInput(u.Reader), 1, i)
+	if err != nil {
+		fmt.Println(err.Error())
+		return nil, err
+	}
+
+	if index == i {
+		return nil, nil
+	}
+
+	return track.Instruments[index-1], nil
+}
+
+// promptBoundedInteger prints out the prompt and reads an integer between the
+// given bounds from the user. Returns an error if invalid input is given.
+func (u *UserInput) promptBoundedInteger(prompt string, lowerBound, upperBound int) (int, error) {
+	fmt.Printf("%s between %d and %d: ", prompt, lowerBound, upperBound)
+
+	value, err := validateBoundedIntegerInput(getUserInput(u.Reader), lowerBound, upperBound)
+	if err != nil {
+		fmt.Println(err.Error())
+		return 0, err
+	}
+
+	return value, nil
+}
+
+// promptBoundedFloat prints out the prompt and reads a number between the given
+// bounds from the user. Returns an error if invalid input is given.
+func (u *UserInput) promptBoundedFloat(prompt string, lowerBound, upperBound float64) (float64, error) {
+	fmt.Printf("%s between %v and %v: ", prompt, lowerBound, upperBound)
+
+	value, err := validateBoundedFloatInput(getUserInput(u.Reader), lowerBound, upperBound)
+	if err != nil {
+		fmt.Println(err.Error())
+		return 0, err
+	}
+
+	return value, nil
+}
+
 func retry(attempts int, input interface{}, f func(interface{}) error) error {
 	if err := f(input); err != nil {
+		// an interrupted track shouldn't be mistaken for bad input
+		if errors.Is(err, context.Canceled) {
+			return err
+		}
+
 		if attempts--; attempts > 0 {
 			return retry(attempts, input, f)
 		}
@@ -201,58 +384,167 @@ func retry(attempts int, input interface{}, f func(interface{}) error) error {
 	return nil
 }
 
-func prepareTrack(metadataFilename string) (*models.Track, error) {
-	type instrumentMetadata struct {
-		Name     string `json:"name"`
-		Filename string `json:"filename"`
-		Pattern  []int  `json:"pattern"`
-	}
-
-	type trackMetadata struct {
-		Instruments      []instrumentMetadata `json:"instruments"`
-		Title            string               `json:"title"`
-		BeatsPerMeasure  int                  `json:"beats_per_measure"`
-		DivisionsPerBeat int                  `json:"divisions_per_beat"`
-		SuggestedBPM     int                  `json:"suggested_bpm"`
+// PrepareTrack builds the track described by the metadata file. The track is
+// played on the kit in kitFilename if given, or otherwise on the kit the
+// metadata file names.
+func PrepareTrack(metadataFilename, kitFilename string) (*models.Track, error) {
+	metadata, err := readTrackMetadata(metadataFilename)
+	if err != nil {
+		return nil, err
 	}
 
-	// nolint: gosec
-	data, err := ioutil.ReadFile(metadataFilename)
-	if err != nil {
-		return nil, errors.Wrap(err, "error opening metadata file")
+	if kitFilename == "" {
+		kitFilename = metadata.Kit
 	}
 
-	var metadata trackMetadata
-	err = json.Unmarshal(data, &metadata)
-	if err != nil {
-		return nil, errors.Wrap(err, "error unmarshalling metadata into struct")
+	var kit *models.Kit
+	if kitFilename != "" {
+		kit, err = prepareKit(kitFilename)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	instruments := make([]*models.Instrument, 0, len(metadata.Instruments))
 	for _, i := range metadata.Instruments {
-		instrument, err := models.NewInstrument(i.Name, i.Filename, i.Pattern)
+		pattern, articulations, err := i.pattern()
+		if err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("error reading %s pattern", i.Name))
+		}
+
+		instrument, err := i.instrument(pattern, kit)
 		if err != nil {
 			return nil, errors.Wrap(err, "error creating instrument from metadata")
 		}
 
+		instrument.Articulations = articulations
+		instrument.Humanize = i.Humanize.humanize()
+		instrument.MIDI = i.MIDI.note()
+
+		if i.Audio != nil {
+			instrument.AudioOff = !*i.Audio
+		}
+
+		if err := instrument.Audio.SetChokeGroup(i.ChokeGroup); err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("error setting %s choke group", i.Name))
+		}
+
+		if i.Sample != nil {
+			if err := instrument.Audio.SetSampleParams(i.Sample.params()); err != nil {
+				return nil, errors.Wrap(err, fmt.Sprintf("error setting %s sample params", i.Name))
+			}
+		}
+
+		if len(i.Effects) > 0 {
+			if err := instrument.Audio.SetEffects(effects(i.Effects)); err != nil {
+				return nil, errors.Wrap(err, fmt.Sprintf("error setting %s effects", i.Name))
+			}
+		}
+
+		if i.Sends != nil {
+			if err := instrument.Audio.SetSends(audio.Sends{Reverb: i.Sends.Reverb, Delay: i.Sends.Delay}); err != nil {
+				return nil, errors.Wrap(err, fmt.Sprintf("error setting %s sends", i.Name))
+			}
+		}
+
 		instruments = append(instruments, instrument)
 	}
 
-	return models.NewTrack(metadata.Title, instruments, metadata.SuggestedBPM, metadata.BeatsPerMeasure, metadata.DivisionsPerBeat)
+	track, err := models.NewTrack(metadata.Title, instruments, metadata.SuggestedBPM, metadata.BeatsPerMeasure, metadata.DivisionsPerBeat)
+	if err != nil {
+		return nil, err
+	}
+
+	if metadata.HumanizeSeed != 0 {
+		track.Seed = metadata.HumanizeSeed
+	}
+
+	track.Kit = kit
+
+	busParams := metadata.busParams()
+	if err := busParams.Validate(); err != nil {
+		return nil, errors.Wrap(err, "error reading reverb and delay")
+	}
+
+	track.Bus = &busParams
+
+	masterParams := metadata.masterParams()
+	if err := masterParams.Validate(); err != nil {
+		return nil, errors.Wrap(err, "error reading master bus")
+	}
+
+	track.Master = &masterParams
+
+	return track, nil
+}
+
+// ReadTrackTitle returns the title of the track described by the metadata
+// file, without preparing the track.
+func ReadTrackTitle(metadataFilename string) (string, error) {
+	metadata, err := readTrackMetadata(metadataFilename)
+	if err != nil {
+		return "", err
+	}
+
+	return metadata.Title, nil
+}
+
+func readTrackMetadata(metadataFilename string) (trackMetadata, error) {
+	// nolint: gosec
+	data, err := ioutil.ReadFile(metadataFilename)
+	if err != nil {
+		return trackMetadata{}, errors.Wrap(err, "error opening metadata file")
+	}
+
+	var metadata trackMetadata
+	if err := json.Unmarshal(data, &metadata); err != nil {
+		return trackMetadata{}, errors.Wrap(err, "error unmarshalling metadata into struct")
+	}
+
+	return metadata, nil
+}
+
+func (u *UserInput) context() context.Context {
+	if u.Context == nil {
+		return context.Background()
+	}
+
+	return u.Context
 }
 
 func getUserInput(stdin io.Reader) string {
+	line, _ := readUserInput(stdin)
+	return line
+}
+
+// readUserInput reads a line of input, returning an error if the input ended
+// or couldn't be read before the end of the line.
+func readUserInput(stdin io.Reader) (string, error) {
 	if stdin == nil {
 		stdin = os.Stdin
 	}
 
-	scanner := bufio.NewScanner(stdin)
+	// read a byte at a time rather than through a buffered scanner, so that any
+	// input after the first line is left for the next call
+	line := []byte{}
+	b := make([]byte, 1)
+
+	for {
+		n, err := stdin.Read(b)
+		if n > 0 {
+			if b[0] == '\n' {
+				break
+			}
 
-	if scanner.Scan() {
-		return scanner.Text()
+			line = append(line, b[0])
+		}
+
+		if err != nil {
+			return strings.TrimSuffix(string(line), "\r"), err
+		}
 	}
 
-	return ""
+	return strings.TrimSuffix(string(line), "\r"), nil
 }
 
 func validateBoundedIntegerInput(input string, lowerBound, upperBound int) (int, error) {
@@ -271,3 +563,20 @@ func validateBoundedIntegerInput(input string, lowerBound, upperBound int) (int,
 
 	return inputInt, nil
 }
+
+func validateBoundedFloatInput(input string, lowerBound, upperBound float64) (float64, error) {
+	inputFloat, err := strconv.ParseFloat(input, 64)
+	if err != nil || math.IsNaN(inputFloat) {
+		return 0, errors.New("input must be a number")
+	}
+
+	if inputFloat < lowerBound {
+		return 0, fmt.Errorf("input must be greater than %v", lowerBound)
+	}
+
+	if inputFloat > upperBound {
+		return 0, fmt.Errorf("input must be less than %v", upperBound)
+	}
+
+	return inputFloat, nil
+}
@@ -2,11 +2,14 @@ package input
 
 import (
 	"bytes"
+	"context"
 	"testing"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 
+	"github.com/jcfox412/logarhythms/internal/audio"
 	"github.com/jcfox412/logarhythms/internal/models"
 	_ "github.com/jcfox412/logarhythms/testing"
 )
@@ -35,6 +38,70 @@ func TestPrepareTrack(t *testing.T) {
 			},
 			expectedToError: false,
 		},
+		{
+			description: "Successfully creates humanized track",
+			input:       "internal/input/testfiles/humanized_track.json",
+			expectedOutput: &models.Track{
+				Instruments: []*models.Instrument{
+					{
+						Humanize: &models.Humanize{
+							Timing:         8 * time.Millisecond,
+							TimingFraction: 0.05,
+							AroundBeat:     true,
+							Velocity:       0.15,
+						},
+					},
+				},
+				Patterns:         make([][]*models.Instrument, 8),
+				Title:            "Humanized Track",
+				BeatsPerMeasure:  4,
+				DivisionsPerBeat: 2,
+				BeatsPerMinute:   120,
+				Seed:             42,
+			},
+			expectedToError: false,
+		},
+		{
+			description: "Successfully creates articulated track",
+			input:       "internal/input/testfiles/articulated_track.json",
+			expectedOutput: &models.Track{
+				Instruments: []*models.Instrument{
+					{
+						Pattern: []int{0, 2, 4, 6},
+						Articulations: map[int]models.Articulation{
+							2: {Velocity: 0.7, Ornament: models.Flam},
+							6: {Velocity: 1, Repeat: 3},
+						},
+					},
+					{
+						Pattern: []int{0, 1, 4, 5, 7},
+						Articulations: map[int]models.Articulation{
+							1: {Velocity: 0.4, Repeat: 1},
+							5: {Velocity: 1, Repeat: 1, Ornament: models.Drag},
+							7: {Velocity: 1, Repeat: 1, Ornament: models.Roll},
+						},
+					},
+				},
+				Patterns:         make([][]*models.Instrument, 8),
+				Title:            "Articulated Track",
+				BeatsPerMeasure:  4,
+				DivisionsPerBeat: 2,
+				BeatsPerMinute:   120,
+			},
+			expectedToError: false,
+		},
+		{
+			description:     "Errors on instrument with both pattern and steps",
+			input:           "internal/input/testfiles/invalid_steps_track.json",
+			expectedOutput:  &models.Track{},
+			expectedToError: true,
+		},
+		{
+			description:     "Errors on invalid humanize settings",
+			input:           "internal/input/testfiles/invalid_humanize_track.json",
+			expectedOutput:  &models.Track{},
+			expectedToError: true,
+		},
 		{
 			description:     "Errors on nonexistant track file",
 			input:           "internal/input/testfiles/nonexistant.json",
@@ -52,7 +119,7 @@ func TestPrepareTrack(t *testing.T) {
 	for _, testCase := range testCases {
 		testCase := testCase
 
-		actualOutput, actualErr := prepareTrack(testCase.input)
+		actualOutput, actualErr := PrepareTrack(testCase.input, "")
 		if testCase.expectedToError {
 			assert.NotNil(t, actualErr)
 		} else {
@@ -62,6 +129,251 @@ func TestPrepareTrack(t *testing.T) {
 	}
 }
 
+func TestPrepareTrackChokeGroups(t *testing.T) {
+	track, err := PrepareTrack("internal/input/testfiles/choked_track.json", "")
+	assert.Nil(t, err)
+
+	actualChokeGroups := []int{}
+	for _, instrument := range track.Instruments {
+		actualChokeGroups = append(actualChokeGroups, instrument.Audio.GetChokeGroup())
+	}
+
+	assert.Equal(t, []int{1, 1, 0}, actualChokeGroups)
+}
+
+func TestPrepareTrackFractionalBPM(t *testing.T) {
+	track, err := PrepareTrack("internal/input/testfiles/fractional_bpm_track.json", "")
+	assert.Nil(t, err)
+	assert.Equal(t, 92.5, track.BeatsPerMinute)
+}
+
+func TestPrepareTrackMIDI(t *testing.T) {
+	track, err := PrepareTrack("internal/input/testfiles/midi_track.json", "")
+	assert.Nil(t, err)
+
+	expectedNotes := []*models.MIDINote{
+		{Channel: 10, Note: 36},
+		{Channel: 2, Note: 38, Length: 50 * time.Millisecond},
+		nil,
+	}
+
+	actualNotes := []*models.MIDINote{}
+	actualAudioOff := []bool{}
+	for _, instrument := range track.Instruments {
+		actualNotes = append(actualNotes, instrument.MIDI)
+		actualAudioOff = append(actualAudioOff, instrument.AudioOff)
+	}
+
+	assert.Equal(t, expectedNotes, actualNotes)
+	assert.Equal(t, []bool{false, true, false}, actualAudioOff)
+
+	_, err = PrepareTrack("internal/input/testfiles/invalid_midi_track.json", "")
+	assert.NotNil(t, err)
+}
+
+func TestPrepareTrackSampleParams(t *testing.T) {
+	track, err := PrepareTrack("internal/input/testfiles/sampled_track.json", "")
+	assert.Nil(t, err)
+
+	expectedParams := audio.SampleParams{
+		Start:     5 * time.Millisecond,
+		End:       250 * time.Millisecond,
+		Reverse:   true,
+		Semitones: -3,
+		Cents:     25,
+		Envelope: &audio.Envelope{
+			Attack: time.Millisecond,
+			Hold:   40 * time.Millisecond,
+			Decay:  120 * time.Millisecond,
+		},
+	}
+
+	assert.Equal(t, expectedParams, track.Instruments[0].Audio.GetSampleParams())
+}
+
+func TestPrepareTrackSynthVoices(t *testing.T) {
+	track, err := PrepareTrack("internal/input/testfiles/synth_track.json", "")
+	assert.Nil(t, err)
+
+	expectedParams := []audio.SynthParams{
+		{Pitch: 55, Decay: 400 * time.Millisecond, Tone: 0.5, Noise: 0.05},
+		{Pitch: 180, Decay: 250 * time.Millisecond, Tone: 0.6, Noise: 0.65},
+		{Pitch: 400, Decay: 60 * time.Millisecond, Tone: 1, Noise: 0.3},
+	}
+
+	actualParams := []audio.SynthParams{}
+	for _, instrument := range track.Instruments {
+		actualParams = append(actualParams, instrument.Audio.(*audio.SynthManager).GetSynthParams())
+	}
+
+	assert.Equal(t, expectedParams, actualParams)
+
+	_, err = PrepareTrack("internal/input/testfiles/invalid_synth_track.json", "")
+	assert.NotNil(t, err)
+}
+
+func TestPrepareTrackEffects(t *testing.T) {
+	track, err := PrepareTrack("internal/input/testfiles/effects_track.json", "")
+	assert.Nil(t, err)
+
+	expectedEffects := [][]audio.Effect{
+		{
+			{Type: audio.HighPass, Frequency: 6000, Q: defaultQ},
+			{Type: audio.BitCrush, Bits: 10, Downsample: 2},
+		},
+		{
+			{Type: audio.Peaking, Frequency: 200, Q: 1.2, Gain: 4},
+			{Type: audio.Saturate, Drive: 3},
+			{Type: audio.Transient, Attack: 0.5, Sustain: -0.3},
+		},
+	}
+
+	actualEffects := [][]audio.Effect{}
+	for _, instrument := range track.Instruments {
+		actualEffects = append(actualEffects, instrument.Audio.GetEffects())
+	}
+
+	assert.Equal(t, expectedEffects, actualEffects)
+}
+
+func TestPrepareTrackBus(t *testing.T) {
+	track, err := PrepareTrack("internal/input/testfiles/bus_track.json", "")
+	assert.Nil(t, err)
+
+	expectedBus := &audio.BusParams{
+		Reverb: audio.ReverbParams{RoomSize: 0.8, Damping: 0.5, Return: 0.5},
+		Delay:  audio.DelayParams{Note: "1/16t", Feedback: 0.6, Return: 0.25},
+	}
+
+	assert.Equal(t, expectedBus, track.Bus)
+	assert.Equal(t, audio.Sends{Reverb: 0.4, Delay: 0.2}, track.Instruments[0].Audio.GetSends())
+	assert.Equal(t, audio.Sends{}, track.Instruments[1].Audio.GetSends())
+
+	_, err = PrepareTrack("internal/input/testfiles/invalid_bus_track.json", "")
+	assert.NotNil(t, err)
+}
+
+func TestPrepareTrackMaster(t *testing.T) {
+	track, err := PrepareTrack("internal/input/testfiles/master_track.json", "")
+	assert.Nil(t, err)
+
+	expectedMaster := &audio.MasterParams{
+		Gain: -3,
+		Compressor: audio.CompressorParams{
+			Enabled:   true,
+			Threshold: -24,
+			Ratio:     2,
+			Attack:    10 * time.Millisecond,
+			Release:   250 * time.Millisecond,
+		},
+	}
+
+	assert.Equal(t, expectedMaster, track.Master)
+
+	track, err = PrepareTrack("internal/input/testfiles/bus_track.json", "")
+	assert.Nil(t, err)
+
+	defaultMaster := audio.DefaultMasterParams()
+	assert.Equal(t, &defaultMaster, track.Master)
+
+	_, err = PrepareTrack("internal/input/testfiles/invalid_master_track.json", "")
+	assert.NotNil(t, err)
+}
+
+func TestPrepareTrackKit(t *testing.T) {
+	type input struct {
+		metadataFilename string
+		kitFilename      string
+	}
+
+	type testCase struct {
+		description     string
+		input           input
+		expectedKitName string
+		expectedToError bool
+	}
+
+	testCases := []testCase{
+		{
+			description:     "Plays track on its own kit",
+			input:           input{metadataFilename: "internal/input/testfiles/kit_track.json"},
+			expectedKitName: "Test Kit",
+			expectedToError: false,
+		},
+		{
+			description: "Plays track on the given kit",
+			input: input{
+				metadataFilename: "internal/input/testfiles/kit_track.json",
+				kitFilename:      "assets/kits/synthesised.json",
+			},
+			expectedKitName: "Synthesised",
+			expectedToError: false,
+		},
+		{
+			description:     "Fails with missing kit file",
+			input:           input{metadataFilename: "internal/input/testfiles/kit_track.json", kitFilename: "internal/input/testfiles/nonexistant.json"},
+			expectedToError: true,
+		},
+		{
+			description:     "Fails with invalid kit",
+			input:           input{metadataFilename: "internal/input/testfiles/kit_track.json", kitFilename: "internal/input/testfiles/invalid_kit.json"},
+			expectedToError: true,
+		},
+		{
+			description:     "Fails with kit missing a voice",
+			input:           input{metadataFilename: "assets/tracks/take_five.json", kitFilename: "internal/input/testfiles/kit.json"},
+			expectedToError: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		actualOutput, actualErr := PrepareTrack(testCase.input.metadataFilename, testCase.input.kitFilename)
+		if testCase.expectedToError {
+			assert.Nil(t, actualOutput)
+			assert.NotNil(t, actualErr)
+		} else {
+			assert.Nil(t, actualErr)
+			assert.Equal(t, testCase.expectedKitName, actualOutput.Kit.Name)
+			assert.Equal(t, "kick", actualOutput.Instruments[0].Voice)
+			assert.Equal(t, 1, actualOutput.Instruments[1].Audio.GetChokeGroup())
+		}
+	}
+}
+
+func TestPrepareKit(t *testing.T) {
+	kit, err := prepareKit("internal/input/testfiles/kit.json")
+	assert.Nil(t, err)
+
+	expectedKit := &models.Kit{
+		Name: "Test Kit",
+		Voices: map[string]models.Voice{
+			"kick": {
+				Synth:       "kick",
+				SynthParams: audio.SynthParams{Pitch: 60, Decay: 500 * time.Millisecond, Tone: 0.5, Noise: 0.05},
+				Volume:      70,
+				Pan:         -0.5,
+			},
+			"snare": {
+				Filename: "internal/audio/testfiles/valid.wav",
+				Volume:   defaultVolume,
+			},
+		},
+	}
+
+	assert.Equal(t, expectedKit, kit)
+}
+
+func TestBundledTracksPlayOnBundledKits(t *testing.T) {
+	for _, trackFilename := range inputTrackMap {
+		for _, kitFilename := range inputKitMap {
+			_, err := PrepareTrack(trackFilename, kitFilename)
+			assert.Nil(t, err, "%s on %s", trackFilename, kitFilename)
+		}
+	}
+}
+
 func TestRetry(t *testing.T) {
 	type retryInput struct {
 		attempts             int
@@ -124,6 +436,19 @@ func TestRetry(t *testing.T) {
 			},
 			expectedToError: true,
 		},
+		{
+			description: "Doesn't retry an interrupted track",
+			input: retryInput{
+				attempts:             3,
+				expectedCallAttempts: 1,
+				f: func(i interface{}) error {
+					callCount := i.(*callCounter)
+					callCount.count++
+					return errors.Wrap(context.Canceled, "error playing track")
+				},
+			},
+			expectedToError: true,
+		},
 	}
 
 	for _, testCase := range testCases {
@@ -160,6 +485,11 @@ func TestGetUserInput(t *testing.T) {
 			input:          "",
 			expectedOutput: "",
 		},
+		{
+			description:    "Succeeds with Windows line endings",
+			input:          "abc123\r\n",
+			expectedOutput: "abc123",
+		},
 	}
 
 	for _, testCase := range testCases {
@@ -173,6 +503,15 @@ func TestGetUserInput(t *testing.T) {
 	}
 }
 
+func TestGetUserInputLeavesLaterLines(t *testing.T) {
+	var stdin bytes.Buffer
+	stdin.Write([]byte("first\nsecond\n"))
+
+	assert.Equal(t, "first", getUserInput(&stdin))
+	assert.Equal(t, "second", getUserInput(&stdin))
+	assert.Equal(t, "", getUserInput(&stdin))
+}
+
 // Helper function for comparing tracks without actually caring about audio
 func compareTracks(t *testing.T, expectedTrack *models.Track, actualTrack *models.Track) {
 	assert.Equal(t, expectedTrack.Title, actualTrack.Title)
@@ -181,4 +520,17 @@ func compareTracks(t *testing.T, expectedTrack *models.Track, actualTrack *model
 	assert.Equal(t, expectedTrack.BeatsPerMinute, actualTrack.BeatsPerMinute)
 	assert.Equal(t, len(expectedTrack.Instruments), len(actualTrack.Instruments))
 	assert.Equal(t, len(expectedTrack.Patterns), len(actualTrack.Patterns))
+
+	if expectedTrack.Seed != 0 {
+		assert.Equal(t, expectedTrack.Seed, actualTrack.Seed)
+	}
+
+	for i := 0; i < len(expectedTrack.Instruments) && i < len(actualTrack.Instruments); i++ {
+		assert.Equal(t, expectedTrack.Instruments[i].Humanize, actualTrack.Instruments[i].Humanize)
+
+		if expectedTrack.Instruments[i].Pattern != nil {
+			assert.Equal(t, expectedTrack.Instruments[i].Pattern, actualTrack.Instruments[i].Pattern)
+			assert.Equal(t, expectedTrack.Instruments[i].Articulations, actualTrack.Instruments[i].Articulations)
+		}
+	}
 }
@@ -3,13 +3,16 @@ package input_test
 import (
 	"bytes"
 	"fmt"
+	"io"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 
 	audiomocks "github.com/jcfox412/logarhythms/internal/audio/mocks"
+	"github.com/jcfox412/logarhythms/internal/clock"
 	"github.com/jcfox412/logarhythms/internal/input"
 	"github.com/jcfox412/logarhythms/internal/models"
 	_ "github.com/jcfox412/logarhythms/testing"
@@ -60,7 +63,7 @@ func TestSettingsMenu(t *testing.T) {
 		expectedToError bool
 	}
 
-	initialBeatsPerMinute := 100
+	initialBeatsPerMinute := 100.0
 
 	testCases := []testCase{
 		{
@@ -89,8 +92,8 @@ func TestSettingsMenu(t *testing.T) {
 		} else {
 			assert.Nil(t, actualErr)
 
-			userInputInt, _ := strconv.Atoi(testCase.input)
-			assert.Equal(t, userInputInt, track.BeatsPerMinute)
+			userInputFloat, _ := strconv.ParseFloat(testCase.input, 64)
+			assert.Equal(t, userInputFloat, track.BeatsPerMinute)
 		}
 	}
 }
@@ -102,7 +105,7 @@ func TestBeatsPerMinuteMenu(t *testing.T) {
 		expectedToError bool
 	}
 
-	initialBeatsPerMinute := 100
+	initialBeatsPerMinute := 100.0
 
 	testCases := []testCase{
 		{
@@ -111,7 +114,7 @@ func TestBeatsPerMinuteMenu(t *testing.T) {
 			expectedToError: true,
 		},
 		{
-			description:     "Errors on non-integer input",
+			description:     "Errors on non-numeric input",
 			input:           "help",
 			expectedToError: true,
 		},
@@ -120,6 +123,11 @@ func TestBeatsPerMinuteMenu(t *testing.T) {
 			input:           "999",
 			expectedToError: false,
 		},
+		{
+			description:     "Handles fractional input",
+			input:           "92.5",
+			expectedToError: false,
+		},
 	}
 
 	for _, testCase := range testCases {
@@ -141,10 +149,88 @@ func TestBeatsPerMinuteMenu(t *testing.T) {
 		} else {
 			assert.Nil(t, actualErr)
 
-			userInputInt, _ := strconv.Atoi(testCase.input)
-			assert.Equal(t, userInputInt, track.BeatsPerMinute)
+			userInputFloat, _ := strconv.ParseFloat(testCase.input, 64)
+			assert.Equal(t, userInputFloat, track.BeatsPerMinute)
+		}
+	}
+}
+
+func TestBeatsPerMinuteMenuTapTempo(t *testing.T) {
+	type testCase struct {
+		description            string
+		input                  string
+		interval               time.Duration
+		expectedBeatsPerMinute float64
+		expectedToError        bool
+	}
+
+	initialBeatsPerMinute := 100.0
+
+	// each line of input is entered the interval after the last
+	testCases := []testCase{
+		{
+			description:            "Sets the tempo tapped",
+			input:                  "t\n\n\n\n\ndone\n",
+			interval:               480 * time.Millisecond,
+			expectedBeatsPerMinute: 125,
+			expectedToError:        false,
+		},
+		{
+			description:            "Errors with too few taps",
+			input:                  "t\n\n\n\ndone\n",
+			interval:               480 * time.Millisecond,
+			expectedBeatsPerMinute: initialBeatsPerMinute,
+			expectedToError:        true,
+		},
+		{
+			description:            "Errors once taps are too far apart",
+			input:                  "t\n\n\n\n\ndone\n",
+			interval:               models.TapTimeout + time.Millisecond,
+			expectedBeatsPerMinute: initialBeatsPerMinute,
+			expectedToError:        true,
+		},
+		{
+			description:            "Errors if the input ends while tapping",
+			input:                  "t\n\n\n\n\n",
+			interval:               480 * time.Millisecond,
+			expectedBeatsPerMinute: initialBeatsPerMinute,
+			expectedToError:        true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		c := clock.NewManual(time.Unix(0, 0))
+
+		userInput := input.UserInput{
+			Reader: &timedReader{r: strings.NewReader(testCase.input), clock: c, interval: testCase.interval},
+			Clock:  c,
 		}
+
+		track := &models.Track{BeatsPerMinute: initialBeatsPerMinute}
+
+		actualErr := userInput.BeatsPerMinuteMenu(track)
+		assert.Equal(t, testCase.expectedToError, actualErr != nil, testCase.description)
+		assert.Equal(t, testCase.expectedBeatsPerMinute, track.BeatsPerMinute, testCase.description)
+	}
+}
+
+// timedReader reads from r, advancing the clock by the interval after each
+// line.
+type timedReader struct {
+	r        io.Reader
+	clock    *clock.Manual
+	interval time.Duration
+}
+
+func (t *timedReader) Read(p []byte) (int, error) {
+	n, err := t.r.Read(p)
+	if bytes.Contains(p[:n], []byte("\n")) {
+		t.clock.Advance(t.interval)
 	}
+
+	return n, err
 }
 
 // TODO: test more than error path
@@ -298,51 +384,122 @@ func TestInstrumentVolumeMenu(t *testing.T) {
 func TestTrackLengthMenu(t *testing.T) {
 	type testCase struct {
 		description     string
-		input           string
+		input           []string
+		expectedLength  models.Length
 		expectedToError bool
 	}
 
-	initialTrackLength := 10
+	initialTrackLength := models.Duration(10 * time.Second)
 
 	testCases := []testCase{
+		{
+			description:     "Sets length in bars",
+			input:           []string{"1", "16"},
+			expectedLength:  models.Bars(16),
+			expectedToError: false,
+		},
+		{
+			description:     "Sets length in loops, as bars",
+			input:           []string{"2", "3"},
+			expectedLength:  models.Bars(3),
+			expectedToError: false,
+		},
+		{
+			description:     "Sets length in seconds",
+			input:           []string{"3", "600"},
+			expectedLength:  models.Duration(10 * time.Minute),
+			expectedToError: false,
+		},
+		{
+			description:     "Sets length to until stopped",
+			input:           []string{"4"},
+			expectedLength:  models.Infinite(),
+			expectedToError: false,
+		},
+		{
+			description:     "Returns to settings menu",
+			input:           []string{"5"},
+			expectedLength:  initialTrackLength,
+			expectedToError: false,
+		},
 		{
 			description:     "Errors on user input out of range",
-			input:           "0",
+			input:           []string{"1", "0"},
+			expectedLength:  initialTrackLength,
 			expectedToError: true,
 		},
 		{
 			description:     "Errors on non-integer input",
-			input:           "help",
+			input:           []string{"help"},
+			expectedLength:  initialTrackLength,
 			expectedToError: true,
 		},
-		{
-			description:     "Handles good input",
-			input:           "1",
-			expectedToError: false,
-		},
 	}
 
 	for _, testCase := range testCases {
 		testCase := testCase
 
 		var stdin bytes.Buffer
-		stdin.Write([]byte(fmt.Sprintf("%s\n", testCase.input)))
+		for _, i := range testCase.input {
+			stdin.Write([]byte(fmt.Sprintf("%s\n", i)))
+		}
 
 		userInput := input.UserInput{
 			Reader: &stdin,
 		}
 
-		track := &models.Track{Length: time.Duration(initialTrackLength) * time.Second}
+		track := &models.Track{Length: initialTrackLength}
 
 		actualErr := userInput.TrackLengthMenu(track)
 		if testCase.expectedToError {
 			assert.NotNil(t, actualErr)
-			assert.Equal(t, initialTrackLength, int(track.Length.Seconds()))
 		} else {
 			assert.Nil(t, actualErr)
+		}
+
+		assert.Equal(t, testCase.expectedLength, track.Length, testCase.description)
+	}
+}
 
-			userInputInt, _ := strconv.Atoi(testCase.input)
-			assert.Equal(t, userInputInt, int(track.Length.Seconds()))
+func TestReadTrackTitle(t *testing.T) {
+	type testCase struct {
+		description     string
+		input           string
+		expectedOutput  string
+		expectedToError bool
+	}
+
+	testCases := []testCase{
+		{
+			description:     "Reads title of valid track",
+			input:           "internal/input/testfiles/synth_track.json",
+			expectedOutput:  "Synth Track",
+			expectedToError: false,
+		},
+		{
+			description:     "Errors with missing file",
+			input:           "internal/input/testfiles/missing_track.json",
+			expectedOutput:  "",
+			expectedToError: true,
+		},
+		{
+			description:     "Errors with file which isn't a track",
+			input:           "internal/input/input.go",
+			expectedOutput:  "",
+			expectedToError: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		actualOutput, actualErr := input.ReadTrackTitle(testCase.input)
+		if testCase.expectedToError {
+			assert.NotNil(t, actualErr, testCase.description)
+		} else {
+			assert.Nil(t, actualErr, testCase.description)
 		}
+
+		assert.Equal(t, testCase.expectedOutput, actualOutput, testCase.description)
 	}
 }
@@ -0,0 +1,233 @@
+package input
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/jcfox412/logarhythms/internal/clock"
+	"github.com/jcfox412/logarhythms/internal/models"
+	"github.com/jcfox412/logarhythms/internal/render"
+)
+
+// Keys pressed during playback, besides 1 to 9 which play the instruments
+// in order
+const (
+	// Starts and stops recording
+	recordKey = 'r'
+	// Switches between overdubbing and replacing patterns
+	recordModeKey = 'm'
+	// Undoes the last recorded pass
+	undoKey = 'u'
+	// Stops the track
+	stopKey = 'q'
+	// Taps the tempo, which the track plays at once the taps stop
+	tapKey = 't'
+)
+
+// Keyboard reads keys as they're pressed, without waiting for a whole line.
+type Keyboard interface {
+	// Listen sends each key pressed on keys until stop is called, which closes
+	// keys
+	Listen() (keys <-chan byte, stop func(), err error)
+}
+
+// play plays the track, recording the instruments played on the keyboard into
+// its patterns if there is one.
+func (u *UserInput) play(track *models.Track) error {
+	if u.Keyboard == nil {
+		return track.PlayContext(u.context())
+	}
+
+	recorder, err := models.NewRecorder(track, track.Clock, u.Record)
+	if err != nil {
+		return errors.Wrap(err, "error creating recorder")
+	}
+
+	renderer := track.Renderer
+	defer func() {
+		track.Renderer = renderer
+	}()
+
+	track.Renderer = recorder
+	if renderer != nil {
+		track.Renderer = render.Multi(renderer, recorder)
+	}
+
+	keys, stop, err := u.Keyboard.Listen()
+	if err != nil {
+		return errors.Wrap(err, "error reading keys")
+	}
+
+	fmt.Print(playbackKeys)
+
+	c := track.Clock
+	if c == nil {
+		c = clock.New()
+	}
+
+	p := &player{
+		track:    track,
+		recorder: recorder,
+		renderer: renderer,
+		clock:    c,
+		tapper:   models.NewTapTempo(c),
+		ctx:      u.context(),
+	}
+
+	handled := make(chan struct{})
+	go func() {
+		defer close(handled)
+
+		for key := range keys {
+			p.press(key)
+		}
+	}()
+
+	err = p.play()
+
+	stop()
+	<-handled
+
+	return err
+}
+
+// player plays a track along with the keyboard, which plays and records its
+// instruments, taps a new tempo for it and stops it.
+type player struct {
+	track    *models.Track
+	recorder *models.Recorder
+	// Renderer messages are shown with, or nil to show none
+	renderer render.Renderer
+	clock    clock.Clock
+	tapper   *models.TapTempo
+	// Context the track is played with until it's interrupted
+	ctx context.Context
+
+	mu sync.Mutex
+	// Cancels the playing of the track
+	cancel context.CancelFunc
+	// Whether the track has been stopped from the keyboard, or has finished
+	stopped bool
+	// Sets the tempo tapped once the taps stop, or nil if none is being tapped
+	tapped clock.Timer
+}
+
+// play plays the track until it finishes or is stopped, from the keyboard or
+// by being interrupted.
+func (p *player) play() error {
+	defer p.finish()
+
+	p.mu.Lock()
+	if p.stopped {
+		p.mu.Unlock()
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(p.ctx)
+	defer cancel()
+
+	p.cancel = cancel
+	p.mu.Unlock()
+
+	err := p.track.PlayContext(ctx)
+
+	// a track stopped from the keyboard, rather than interrupted, has finished
+	if errors.Is(err, context.Canceled) && p.ctx.Err() == nil {
+		return nil
+	}
+
+	return err
+}
+
+// finish stops any tempo being tapped from being set.
+func (p *player) finish() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.stopped = true
+	if p.tapped != nil {
+		p.tapped.Stop()
+	}
+}
+
+// press carries out what a key pressed during playback does.
+func (p *player) press(key byte) {
+	switch key {
+	case stopKey:
+		p.mu.Lock()
+		p.stopped = true
+		if p.cancel != nil {
+			p.cancel()
+		}
+		p.mu.Unlock()
+	case tapKey:
+		p.tap()
+	default:
+		pressKey(p.recorder, key)
+	}
+}
+
+// tap taps the tempo, showing it as it's tapped. The tempo is set once the
+// taps stop.
+func (p *player) tap() {
+	tapped, ok := p.tapper.Tap()
+	if !ok {
+		render.Message(p.renderer, "Tapping tempo...")
+		return
+	}
+
+	render.Message(p.renderer, fmt.Sprintf("Tapped %v BPM", tapped))
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.tapped != nil {
+		p.tapped.Stop()
+	}
+
+	p.tapped = p.clock.AfterFunc(models.TapTimeout, func() {
+		p.setTempo(tapped)
+	})
+}
+
+// setTempo sets the track's BPM, which the track plays at from its next beat
+// subdivision.
+func (p *player) setTempo(beatsPerMinute float64) {
+	if beatsPerMinute < minBeatsPerMinute || beatsPerMinute > maxBeatsPerMinute {
+		render.Message(p.renderer, fmt.Sprintf("Tapped BPM must be between %d and %d", minBeatsPerMinute, maxBeatsPerMinute))
+		return
+	}
+
+	_ = p.track.Update(func(t *models.Track) error {
+		t.BeatsPerMinute = beatsPerMinute
+		return nil
+	})
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.tapped = nil
+}
+
+// pressKey carries out what a key pressed during playback does. Keys which do
+// nothing, such as those of instruments the track doesn't have, are ignored.
+func pressKey(recorder *models.Recorder, key byte) {
+	switch key {
+	case '1', '2', '3', '4', '5', '6', '7', '8', '9':
+		_ = recorder.Hit(int(key - '1'))
+	case recordKey:
+		recorder.SetRecording(!recorder.Recording())
+	case recordModeKey:
+		mode := models.Replace
+		if recorder.Mode() == models.Replace {
+			mode = models.Overdub
+		}
+
+		_ = recorder.SetMode(mode)
+	case undoKey:
+		_ = recorder.Undo()
+	}
+}
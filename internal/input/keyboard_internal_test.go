@@ -0,0 +1,226 @@
+package input
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	audiomocks "github.com/jcfox412/logarhythms/internal/audio/mocks"
+	"github.com/jcfox412/logarhythms/internal/clock"
+	"github.com/jcfox412/logarhythms/internal/models"
+	"github.com/jcfox412/logarhythms/internal/render"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestPressKey(t *testing.T) {
+	type testCase struct {
+		description       string
+		input             string
+		expectedPattern   []int
+		expectedRecording bool
+		expectedMode      models.RecordMode
+	}
+
+	// each key is pressed on the second subdivision of the bar, with the snare
+	// playing on the third
+	testCases := []testCase{
+		{
+			description:       "Plays an instrument without recording it",
+			input:             "2",
+			expectedPattern:   []int{2},
+			expectedRecording: false,
+			expectedMode:      models.Overdub,
+		},
+		{
+			description:       "Records an instrument",
+			input:             "r2",
+			expectedPattern:   []int{1, 2},
+			expectedRecording: true,
+			expectedMode:      models.Overdub,
+		},
+		{
+			description:       "Stops recording",
+			input:             "rr2",
+			expectedPattern:   []int{2},
+			expectedRecording: false,
+			expectedMode:      models.Overdub,
+		},
+		{
+			description:       "Switches to replacing the pattern",
+			input:             "rm2",
+			expectedPattern:   []int{1},
+			expectedRecording: true,
+			expectedMode:      models.Replace,
+		},
+		{
+			description:       "Undoes the recorded pass",
+			input:             "r2u",
+			expectedPattern:   []int{2},
+			expectedRecording: true,
+			expectedMode:      models.Overdub,
+		},
+		{
+			description:       "Ignores instruments the track doesn't have, and unknown keys",
+			input:             "r9x",
+			expectedPattern:   []int{2},
+			expectedRecording: true,
+			expectedMode:      models.Overdub,
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		m := &audiomocks.Manager{}
+		m.On("Play", mock.AnythingOfType("float64")).Return()
+
+		kick := &models.Instrument{Name: "Kick", Pattern: []int{0}, Audio: m}
+		snare := &models.Instrument{Name: "Snare", Pattern: []int{2}, Audio: m}
+
+		track, err := models.NewTrack("testTitle", []*models.Instrument{kick, snare}, 120, 2, 2)
+		assert.Nil(t, err)
+
+		recorder, err := models.NewRecorder(track, clock.NewManual(time.Unix(0, 0)), models.RecordSettings{Strength: 1})
+		assert.Nil(t, err)
+
+		recorder.Start(render.Track{Pattern: make([]render.Step, 4), DivisionsPerBeat: 2, StepDuration: 250 * time.Millisecond})
+		recorder.Step(render.Step{Bar: 1, Division: 1})
+
+		for _, key := range []byte(testCase.input) {
+			pressKey(recorder, key)
+		}
+
+		assert.Equal(t, testCase.expectedPattern, snare.Pattern, testCase.description)
+		assert.Equal(t, []int{0}, kick.Pattern, testCase.description)
+		assert.Equal(t, testCase.expectedRecording, recorder.Recording(), testCase.description)
+		assert.Equal(t, testCase.expectedMode, recorder.Mode(), testCase.description)
+	}
+}
+
+func TestPlayerTap(t *testing.T) {
+	type testCase struct {
+		description            string
+		input                  []time.Duration
+		expectedBeatsPerMinute float64
+	}
+
+	initialBeatsPerMinute := 100.0
+
+	// each input is the time before each tap after the first
+	testCases := []testCase{
+		{
+			description:            "Sets the tempo tapped",
+			input:                  []time.Duration{500 * time.Millisecond, 500 * time.Millisecond, 500 * time.Millisecond},
+			expectedBeatsPerMinute: 120,
+		},
+		{
+			description:            "Keeps a fractional tempo tapped",
+			input:                  []time.Duration{648500 * time.Microsecond, 648500 * time.Microsecond, 648500 * time.Microsecond},
+			expectedBeatsPerMinute: 92.5,
+		},
+		{
+			description:            "Leaves the tempo with too few taps",
+			input:                  []time.Duration{500 * time.Millisecond, 500 * time.Millisecond},
+			expectedBeatsPerMinute: initialBeatsPerMinute,
+		},
+		{
+			description:            "Leaves the tempo out of range",
+			input:                  []time.Duration{50 * time.Millisecond, 50 * time.Millisecond, 50 * time.Millisecond},
+			expectedBeatsPerMinute: initialBeatsPerMinute,
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		c := clock.NewManual(time.Unix(0, 0))
+		track := &models.Track{BeatsPerMinute: initialBeatsPerMinute}
+
+		cancelled := false
+		p := &player{
+			track:  track,
+			clock:  c,
+			tapper: models.NewTapTempo(c),
+			cancel: func() { cancelled = true },
+		}
+
+		p.press(tapKey)
+		for _, interval := range testCase.input {
+			c.Advance(interval)
+			p.press(tapKey)
+		}
+
+		// the tempo is only set once the taps stop
+		assert.Equal(t, initialBeatsPerMinute, track.BeatsPerMinute, testCase.description)
+
+		c.Advance(models.TapTimeout)
+
+		// the playing track takes up the tempo, rather than being played again
+		assert.Equal(t, testCase.expectedBeatsPerMinute, track.BeatsPerMinute, testCase.description)
+		assert.False(t, cancelled, testCase.description)
+	}
+}
+
+func TestPlayerTapRetimesPlayback(t *testing.T) {
+	start := time.Unix(0, 0)
+	c := clock.NewManual(start)
+
+	var hits []time.Duration
+
+	m := &audiomocks.Manager{}
+	m.On("Play", 1.0).Run(func(mock.Arguments) {
+		hits = append(hits, c.Now().Sub(start))
+	}).Return()
+
+	instrument := &models.Instrument{Name: "Kick", Pattern: []int{0}, Audio: m}
+
+	// a step a second at 60 BPM
+	track, err := models.NewTrack("testTitle", []*models.Instrument{instrument}, 60, 1, 1)
+	assert.Nil(t, err)
+
+	track.Length = models.Infinite()
+	track.Clock = c
+
+	p := &player{
+		track:  track,
+		clock:  c,
+		tapper: models.NewTapTempo(c),
+		ctx:    context.Background(),
+	}
+
+	done := make(chan error)
+	go func() {
+		done <- p.play()
+	}()
+
+	// the track starts ticking after a short delay
+	c.BlockUntil(1)
+	c.Advance(200 * time.Millisecond)
+	c.BlockUntil(1)
+	c.Advance(2 * time.Second)
+
+	// tapping at 120 BPM, which is set once the taps stop
+	p.press(tapKey)
+	for i := 0; i < 3; i++ {
+		c.Advance(500 * time.Millisecond)
+		p.press(tapKey)
+	}
+
+	c.Advance(models.TapTimeout)
+	assert.Equal(t, 120.0, track.BeatsPerMinute)
+
+	c.Advance(1500 * time.Millisecond)
+
+	p.press(stopKey)
+	assert.Nil(t, <-done)
+
+	// the step after the tempo is set, at 5.7s, and those after it are half
+	// as long, carrying on from where the track was without starting it again
+	expected := []time.Duration{}
+	for _, ms := range []int{1200, 2200, 3200, 4200, 5200, 6200, 6700, 7200} {
+		expected = append(expected, time.Duration(ms)*time.Millisecond)
+	}
+
+	assert.Equal(t, expected, hits)
+}
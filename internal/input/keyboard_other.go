@@ -0,0 +1,16 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd
+
+package input
+
+import (
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+// NewKeyboard can't read keys as they're pressed on this platform, so always
+// returns an error.
+func NewKeyboard(*os.File) (Keyboard, error) {
+	return nil, errors.New("keys can't be read as they're pressed on this platform")
+}
@@ -0,0 +1,123 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+// +build darwin dragonfly freebsd linux netbsd openbsd
+
+package input
+
+import (
+	"os"
+	"sync"
+	"syscall"
+	"unsafe"
+
+	"github.com/pkg/errors"
+)
+
+// terminalKeyboard reads keys from a terminal, taking it out of line mode
+// while it listens.
+type terminalKeyboard struct {
+	f *os.File
+}
+
+// NewKeyboard returns a keyboard reading keys from the terminal f, such as
+// os.Stdin. Returns an error if f isn't a terminal.
+func NewKeyboard(f *os.File) (Keyboard, error) {
+	if _, err := termios(f); err != nil {
+		return nil, errors.Wrap(err, "error reading terminal settings")
+	}
+
+	return &terminalKeyboard{f: f}, nil
+}
+
+func (k *terminalKeyboard) Listen() (<-chan byte, func(), error) {
+	saved, err := termios(k.f)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "error reading terminal settings")
+	}
+
+	// keys are read as they're pressed without being echoed, and each read
+	// gives up after a tenth of a second so that listening can stop
+	raw := saved
+	raw.Lflag &^= syscall.ICANON | syscall.ECHO
+	raw.Cc[syscall.VMIN] = 0
+	raw.Cc[syscall.VTIME] = 1
+
+	if err := setTermios(k.f, &raw); err != nil {
+		return nil, nil, errors.Wrap(err, "error changing terminal settings")
+	}
+
+	keys := make(chan byte)
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+		defer close(keys)
+
+		fd := int(k.f.Fd())
+		b := make([]byte, 1)
+
+		for {
+			select {
+			case <-done:
+				drain(fd)
+				return
+			default:
+			}
+
+			n, err := syscall.Read(fd, b)
+			if err != nil && err != syscall.EINTR && err != syscall.EAGAIN {
+				return
+			}
+
+			if n == 1 {
+				select {
+				case keys <- b[0]:
+				case <-done:
+				}
+			}
+		}
+	}()
+
+	var once sync.Once
+	stop := func() {
+		once.Do(func() {
+			close(done)
+			<-stopped
+
+			_ = setTermios(k.f, &saved)
+		})
+	}
+
+	return keys, stop, nil
+}
+
+// drain discards the keys pressed since the last read, rather than leaving
+// them for whatever reads the terminal next.
+func drain(fd int) {
+	b := make([]byte, 64)
+	for {
+		if n, err := syscall.Read(fd, b); n <= 0 || err != nil {
+			return
+		}
+	}
+}
+
+func termios(f *os.File) (syscall.Termios, error) {
+	var t syscall.Termios
+
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), uintptr(ioctlGetTermios), uintptr(unsafe.Pointer(&t)))
+	if errno != 0 {
+		return t, errno
+	}
+
+	return t, nil
+}
+
+func setTermios(f *os.File, t *syscall.Termios) error {
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), uintptr(ioctlSetTermios), uintptr(unsafe.Pointer(t)))
+	if errno != 0 {
+		return errno
+	}
+
+	return nil
+}
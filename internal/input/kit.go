@@ -0,0 +1,75 @@
+package input
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+
+	"github.com/pkg/errors"
+
+	"github.com/jcfox412/logarhythms/internal/models"
+	"github.com/jcfox412/logarhythms/internal/utils"
+)
+
+// KitMenu prints out the user menu for swapping the kit a track is played on.
+// Returns an error if invalid input is given or the kit can't be loaded.
+func (u *UserInput) KitMenu(iface interface{}) error {
+	track := iface.(*models.Track)
+
+	current := "none"
+	if track.Kit != nil {
+		current = track.Kit.Name
+	}
+
+	fmt.Print(utils.Bold(fmt.Sprintf("\nWhich kit would you like the track to play on? Current kit: %s\n", current)))
+	fmt.Print(kitMenuOptions)
+	fmt.Print(utils.Bold(fmt.Sprintf("\nWhat would you like to do? (Please enter number 1-%d): ", len(inputKitMap)+1)))
+
+	userInput := getUserInput(u.Reader)
+	if userInput == strconv.Itoa(len(inputKitMap)+1) {
+		return nil
+	}
+
+	kitFilename, ok := inputKitMap[userInput]
+	if !ok {
+		err := errors.New("I'm sorry, I didn't understand your input")
+		fmt.Println(err.Error())
+		return err
+	}
+
+	kit, err := prepareKit(kitFilename)
+	if err != nil {
+		fmt.Println(err.Error())
+		return err
+	}
+
+	if err := track.SetKit(kit); err != nil {
+		fmt.Println(err.Error())
+		return err
+	}
+
+	fmt.Printf("Kit set to %s!\n", kit.Name)
+
+	return nil
+}
+
+func prepareKit(kitFilename string) (*models.Kit, error) {
+	// nolint: gosec
+	data, err := ioutil.ReadFile(kitFilename)
+	if err != nil {
+		return nil, errors.Wrap(err, "error opening kit file")
+	}
+
+	var metadata kitMetadata
+	if err := json.Unmarshal(data, &metadata); err != nil {
+		return nil, errors.Wrap(err, "error unmarshalling kit into struct")
+	}
+
+	kit, err := metadata.kit()
+	if err != nil {
+		return nil, errors.Wrap(err, "error creating kit from metadata")
+	}
+
+	return kit, nil
+}
@@ -0,0 +1,83 @@
+package input_test
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jcfox412/logarhythms/internal/audio"
+	"github.com/jcfox412/logarhythms/internal/input"
+	"github.com/jcfox412/logarhythms/internal/models"
+	_ "github.com/jcfox412/logarhythms/testing"
+)
+
+func TestKitMenu(t *testing.T) {
+	type testCase struct {
+		description     string
+		input           string
+		expectedKitName string
+		expectedToError bool
+	}
+
+	testCases := []testCase{
+		{
+			description:     "Swaps to chosen kit",
+			input:           "3",
+			expectedKitName: "Synthesised",
+			expectedToError: false,
+		},
+		{
+			description:     "Keeps kit when returning to settings menu",
+			input:           "4",
+			expectedKitName: "Original",
+			expectedToError: false,
+		},
+		{
+			description:     "Errors on user input out of range",
+			input:           "5",
+			expectedKitName: "Original",
+			expectedToError: true,
+		},
+		{
+			description:     "Errors on non-integer input",
+			input:           "help",
+			expectedKitName: "Original",
+			expectedToError: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		var stdin bytes.Buffer
+		stdin.Write([]byte(fmt.Sprintf("%s\n", testCase.input)))
+
+		userInput := input.UserInput{
+			Reader: &stdin,
+		}
+
+		params, err := audio.DefaultSynthParams("kick")
+		assert.Nil(t, err)
+
+		kit, err := models.NewKit("Original", map[string]models.Voice{
+			"kick": {Synth: "kick", SynthParams: params, Volume: 50},
+		})
+		assert.Nil(t, err)
+
+		instrument, err := models.NewKitInstrument("Kick", kit, "kick", []int{0})
+		assert.Nil(t, err)
+
+		track := &models.Track{Instruments: []*models.Instrument{instrument}, Kit: kit}
+
+		actualErr := userInput.KitMenu(track)
+		if testCase.expectedToError {
+			assert.NotNil(t, actualErr)
+		} else {
+			assert.Nil(t, actualErr)
+		}
+
+		assert.Equal(t, testCase.expectedKitName, track.Kit.Name)
+	}
+}
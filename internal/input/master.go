@@ -0,0 +1,90 @@
+package input
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/jcfox412/logarhythms/internal/audio"
+	"github.com/jcfox412/logarhythms/internal/models"
+	"github.com/jcfox412/logarhythms/internal/utils"
+)
+
+// MasterMenu prints out the user menu for modifying the compressor and gain of
+// the master bus a track is played through. Returns an error if invalid input
+// is given.
+func (u *UserInput) MasterMenu(iface interface{}) error {
+	track := iface.(*models.Track)
+
+	params := audio.DefaultMasterParams()
+	if track.Master != nil {
+		params = *track.Master
+	}
+
+	fmt.Print(utils.Bold(fmt.Sprintf("\nWhich master bus setting would you like to change? Current settings: %s\n", describeMasterParams(params))))
+	fmt.Print(masterMenuOptions)
+	fmt.Print(utils.Bold("\nWhat would you like to do? (Please enter number 1-8): "))
+
+	c := &params.Compressor
+
+	var err error
+
+	switch userInput := getUserInput(u.Reader); userInput {
+	case "1":
+		params.Gain, err = u.promptBoundedFloat("Please enter a master gain in decibels", audio.MinMasterGain, audio.MaxMasterGain)
+	case "2":
+		c.Enabled = !c.Enabled
+	case "3":
+		c.Threshold, err = u.promptBoundedFloat("Please enter a compressor threshold in decibels", audio.MinCompressorThreshold, 0)
+	case "4":
+		c.Ratio, err = u.promptBoundedFloat("Please enter a compressor ratio", audio.MinCompressorRatio, audio.MaxCompressorRatio)
+	case "5":
+		c.Attack, err = u.promptDuration("Please enter a compressor attack in milliseconds", audio.MinCompressorAttack, audio.MaxCompressorAttack)
+	case "6":
+		c.Release, err = u.promptDuration("Please enter a compressor release in milliseconds", audio.MinCompressorRelease, audio.MaxCompressorRelease)
+	case "7":
+		c.Makeup, err = u.promptBoundedFloat("Please enter a compressor makeup gain in decibels", 0, audio.MaxCompressorMakeup)
+	case "8":
+		return nil
+	default:
+		err = errors.New("I'm sorry, I didn't understand your input")
+		fmt.Println(err.Error())
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if err := params.Validate(); err != nil {
+		fmt.Println(err.Error())
+		return err
+	}
+
+	track.Master = &params
+	fmt.Printf("Master bus set to %s!\n", describeMasterParams(params))
+
+	return nil
+}
+
+// promptDuration reads a duration between the bounds from the user, as a
+// fractional number of milliseconds.
+func (u *UserInput) promptDuration(prompt string, lowerBound, upperBound time.Duration) (time.Duration, error) {
+	ms, err := u.promptBoundedFloat(prompt, lowerBound.Seconds()*1000, upperBound.Seconds()*1000)
+	if err != nil {
+		return 0, err
+	}
+
+	return milliseconds(ms), nil
+}
+
+func describeMasterParams(params audio.MasterParams) string {
+	c := params.Compressor
+
+	compressor := "compressor off"
+	if c.Enabled {
+		compressor = fmt.Sprintf("compressor %.1fdB %.1f:1 attack %v release %v makeup %+.1fdB", c.Threshold, c.Ratio, c.Attack, c.Release, c.Makeup)
+	}
+
+	return fmt.Sprintf("gain %+.1fdB, %s", params.Gain, compressor)
+}
@@ -0,0 +1,97 @@
+package input_test
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jcfox412/logarhythms/internal/audio"
+	"github.com/jcfox412/logarhythms/internal/input"
+	"github.com/jcfox412/logarhythms/internal/models"
+	_ "github.com/jcfox412/logarhythms/testing"
+)
+
+func TestMasterMenu(t *testing.T) {
+	type testCase struct {
+		description     string
+		input           []string
+		expectedMaster  func(*audio.MasterParams)
+		expectedToError bool
+	}
+
+	testCases := []testCase{
+		{
+			description:     "Sets master gain",
+			input:           []string{"1", "-6.5"},
+			expectedMaster:  func(p *audio.MasterParams) { p.Gain = -6.5 },
+			expectedToError: false,
+		},
+		{
+			description:     "Turns compressor on",
+			input:           []string{"2"},
+			expectedMaster:  func(p *audio.MasterParams) { p.Compressor.Enabled = true },
+			expectedToError: false,
+		},
+		{
+			description:     "Sets compressor ratio",
+			input:           []string{"4", "8"},
+			expectedMaster:  func(p *audio.MasterParams) { p.Compressor.Ratio = 8 },
+			expectedToError: false,
+		},
+		{
+			description:     "Sets compressor attack",
+			input:           []string{"5", "0.5"},
+			expectedMaster:  func(p *audio.MasterParams) { p.Compressor.Attack = 500 * time.Microsecond },
+			expectedToError: false,
+		},
+		{
+			description:     "Returns to settings menu",
+			input:           []string{"8"},
+			expectedMaster:  func(p *audio.MasterParams) {},
+			expectedToError: false,
+		},
+		{
+			description:     "Errors on threshold above 0",
+			input:           []string{"3", "3"},
+			expectedMaster:  func(p *audio.MasterParams) {},
+			expectedToError: true,
+		},
+		{
+			description:     "Errors on non-integer input",
+			input:           []string{"help"},
+			expectedMaster:  func(p *audio.MasterParams) {},
+			expectedToError: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		var stdin bytes.Buffer
+		for _, i := range testCase.input {
+			stdin.Write([]byte(fmt.Sprintf("%s\n", i)))
+		}
+
+		userInput := input.UserInput{
+			Reader: &stdin,
+		}
+
+		master := audio.DefaultMasterParams()
+		track := &models.Track{Master: &master}
+
+		expectedMaster := audio.DefaultMasterParams()
+		testCase.expectedMaster(&expectedMaster)
+
+		actualErr := userInput.MasterMenu(track)
+		if testCase.expectedToError {
+			assert.NotNil(t, actualErr)
+		} else {
+			assert.Nil(t, actualErr)
+		}
+
+		assert.Equal(t, expectedMaster, *track.Master, testCase.description)
+	}
+}
@@ -0,0 +1,446 @@
+package input
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/jcfox412/logarhythms/internal/audio"
+	"github.com/jcfox412/logarhythms/internal/models"
+)
+
+// Volume kit voices are played at when the kit doesn't set one, matching the
+// volume sample files are loaded at
+const defaultVolume = 50
+
+// Q filters are given when none is set, which gives a flat response
+const defaultQ = 0.707
+
+// Channel MIDI notes are sent on when none is set, the General MIDI drum
+// channel
+const defaultMIDIChannel = 10
+
+type humanizeMetadata struct {
+	TimingMilliseconds float64 `json:"timing_ms"`
+	TimingFraction     float64 `json:"timing_fraction"`
+	AroundBeat         bool    `json:"around_beat"`
+	Velocity           float64 `json:"velocity"`
+}
+
+type envelopeMetadata struct {
+	AttackMilliseconds float64 `json:"attack_ms"`
+	HoldMilliseconds   float64 `json:"hold_ms"`
+	DecayMilliseconds  float64 `json:"decay_ms"`
+}
+
+type sampleMetadata struct {
+	StartMilliseconds float64           `json:"start_ms"`
+	EndMilliseconds   float64           `json:"end_ms"`
+	Reverse           bool              `json:"reverse"`
+	Semitones         int               `json:"semitones"`
+	Cents             int               `json:"cents"`
+	Envelope          *envelopeMetadata `json:"envelope"`
+}
+
+// synthParamsMetadata overrides the defaults of a synthesised voice. Fields
+// left out keep the voice's default.
+type synthParamsMetadata struct {
+	Pitch             *float64 `json:"pitch"`
+	DecayMilliseconds *float64 `json:"decay_ms"`
+	Tone              *float64 `json:"tone"`
+	Noise             *float64 `json:"noise"`
+}
+
+type effectMetadata struct {
+	Type       string  `json:"type"`
+	Frequency  float64 `json:"frequency"`
+	Q          float64 `json:"q"`
+	Gain       float64 `json:"gain_db"`
+	Bits       int     `json:"bits"`
+	Downsample int     `json:"downsample"`
+	Drive      float64 `json:"drive"`
+	Attack     float64 `json:"attack"`
+	Sustain    float64 `json:"sustain"`
+}
+
+// midiMetadata is the MIDI note an instrument's hits are sent as.
+type midiMetadata struct {
+	Channel            int     `json:"channel"`
+	Note               int     `json:"note"`
+	LengthMilliseconds float64 `json:"length_ms"`
+}
+
+type sendsMetadata struct {
+	Reverb float64 `json:"reverb"`
+	Delay  float64 `json:"delay"`
+}
+
+type reverbMetadata struct {
+	RoomSize *float64 `json:"room_size"`
+	Damping  *float64 `json:"damping"`
+	Return   *float64 `json:"return"`
+}
+
+type delayMetadata struct {
+	Note     string   `json:"note"`
+	Feedback *float64 `json:"feedback"`
+	Return   *float64 `json:"return"`
+}
+
+// compressorMetadata turns on the master bus' compressor. Fields left out keep
+// the compressor's defaults.
+type compressorMetadata struct {
+	Threshold           *float64 `json:"threshold_db"`
+	Ratio               *float64 `json:"ratio"`
+	AttackMilliseconds  *float64 `json:"attack_ms"`
+	ReleaseMilliseconds *float64 `json:"release_ms"`
+	Makeup              *float64 `json:"makeup_db"`
+}
+
+type masterMetadata struct {
+	Gain       float64             `json:"gain_db"`
+	Compressor *compressorMetadata `json:"compressor"`
+}
+
+type voiceMetadata struct {
+	Filename string               `json:"filename"`
+	Synth    string               `json:"synth"`
+	Params   *synthParamsMetadata `json:"params"`
+	Volume   *float64             `json:"volume"`
+	Pan      float64              `json:"pan"`
+	Effects  []effectMetadata     `json:"effects"`
+}
+
+type kitMetadata struct {
+	Name   string                   `json:"name"`
+	Voices map[string]voiceMetadata `json:"voices"`
+}
+
+// stepMetadata is a single entry of an instrument's pattern. It can be written
+// either as a plain beat subdivision index, or as an object describing how the
+// beat subdivision is articulated.
+type stepMetadata struct {
+	Step     int      `json:"step"`
+	Velocity *float64 `json:"velocity"`
+	Repeat   int      `json:"repeat"`
+	Ornament string   `json:"ornament"`
+	// Plain marks steps written as a bare index, which need no articulation
+	Plain bool `json:"-"`
+}
+
+type instrumentMetadata struct {
+	Name       string               `json:"name"`
+	Filename   string               `json:"filename"`
+	Synth      string               `json:"synth"`
+	Voice      string               `json:"voice"`
+	Params     *synthParamsMetadata `json:"params"`
+	Pattern    []stepMetadata       `json:"pattern"`
+	Steps      string               `json:"steps"`
+	Humanize   *humanizeMetadata    `json:"humanize"`
+	ChokeGroup int                  `json:"choke_group"`
+	Sample     *sampleMetadata      `json:"sample"`
+	Effects    []effectMetadata     `json:"effects"`
+	Sends      *sendsMetadata       `json:"sends"`
+	// Whether the instrument's audio is played, which defaults to true
+	Audio *bool         `json:"audio"`
+	MIDI  *midiMetadata `json:"midi"`
+}
+
+type trackMetadata struct {
+	Instruments      []instrumentMetadata `json:"instruments"`
+	Title            string               `json:"title"`
+	BeatsPerMeasure  int                  `json:"beats_per_measure"`
+	DivisionsPerBeat int                  `json:"divisions_per_beat"`
+	SuggestedBPM     float64              `json:"suggested_bpm"`
+	HumanizeSeed     int64                `json:"humanize_seed"`
+	Kit              string               `json:"kit"`
+	Reverb           *reverbMetadata      `json:"reverb"`
+	Delay            *delayMetadata       `json:"delay"`
+	Master           *masterMetadata      `json:"master"`
+}
+
+// UnmarshalJSON accepts either a beat subdivision index or a step object.
+func (s *stepMetadata) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &s.Step); err == nil {
+		s.Plain = true
+		return nil
+	}
+
+	type step stepMetadata
+	return json.Unmarshal(data, (*step)(s))
+}
+
+func (h *humanizeMetadata) humanize() *models.Humanize {
+	if h == nil {
+		return nil
+	}
+
+	return &models.Humanize{
+		Timing:         milliseconds(h.TimingMilliseconds),
+		TimingFraction: h.TimingFraction,
+		AroundBeat:     h.AroundBeat,
+		Velocity:       h.Velocity,
+	}
+}
+
+func (m *midiMetadata) note() *models.MIDINote {
+	if m == nil {
+		return nil
+	}
+
+	channel := m.Channel
+	if channel == 0 {
+		channel = defaultMIDIChannel
+	}
+
+	return &models.MIDINote{
+		Channel: channel,
+		Note:    m.Note,
+		Length:  milliseconds(m.LengthMilliseconds),
+	}
+}
+
+func (s *sampleMetadata) params() audio.SampleParams {
+	params := audio.SampleParams{
+		Start:     milliseconds(s.StartMilliseconds),
+		End:       milliseconds(s.EndMilliseconds),
+		Reverse:   s.Reverse,
+		Semitones: s.Semitones,
+		Cents:     s.Cents,
+	}
+
+	if s.Envelope != nil {
+		params.Envelope = &audio.Envelope{
+			Attack: milliseconds(s.Envelope.AttackMilliseconds),
+			Hold:   milliseconds(s.Envelope.HoldMilliseconds),
+			Decay:  milliseconds(s.Envelope.DecayMilliseconds),
+		}
+	}
+
+	return params
+}
+
+// instrument builds the instrument from either its sample file, its
+// synthesised voice or its voice in the track's kit.
+func (i *instrumentMetadata) instrument(pattern []int, kit *models.Kit) (*models.Instrument, error) {
+	sounds := 0
+	for _, sound := range []string{i.Filename, i.Synth, i.Voice} {
+		if sound != "" {
+			sounds++
+		}
+	}
+
+	if sounds > 1 {
+		return nil, errors.New("instrument must have only one of a filename, a synth or a voice")
+	}
+
+	switch {
+	case i.Voice != "":
+		if kit == nil {
+			return nil, fmt.Errorf("instrument plays the %s voice, but no kit was given", i.Voice)
+		}
+
+		return models.NewKitInstrument(i.Name, kit, i.Voice, pattern)
+	case i.Synth != "":
+		params, err := synthParams(i.Synth, i.Params)
+		if err != nil {
+			return nil, err
+		}
+
+		return models.NewSynthInstrument(i.Name, i.Synth, params, pattern)
+	default:
+		return models.NewInstrument(i.Name, i.Filename, pattern)
+	}
+}
+
+// kit builds the kit, playing voices at the default volume unless they set
+// their own.
+func (k *kitMetadata) kit() (*models.Kit, error) {
+	voices := make(map[string]models.Voice, len(k.Voices))
+
+	for name, v := range k.Voices {
+		voice := models.Voice{
+			Filename: v.Filename,
+			Synth:    v.Synth,
+			Volume:   defaultVolume,
+			Pan:      v.Pan,
+			Effects:  effects(v.Effects),
+		}
+
+		if v.Volume != nil {
+			voice.Volume = *v.Volume
+		}
+
+		if v.Synth != "" {
+			params, err := synthParams(v.Synth, v.Params)
+			if err != nil {
+				return nil, errors.Wrap(err, fmt.Sprintf("error reading %s voice", name))
+			}
+
+			voice.SynthParams = params
+		}
+
+		voices[name] = voice
+	}
+
+	return models.NewKit(k.Name, voices)
+}
+
+// effects builds an effects chain. Filters without a Q are given a flat
+// response.
+func effects(metadata []effectMetadata) []audio.Effect {
+	if len(metadata) == 0 {
+		return nil
+	}
+
+	chain := make([]audio.Effect, 0, len(metadata))
+	for _, e := range metadata {
+		effect := audio.Effect{
+			Type:       audio.EffectType(e.Type),
+			Frequency:  e.Frequency,
+			Q:          e.Q,
+			Gain:       e.Gain,
+			Bits:       e.Bits,
+			Downsample: e.Downsample,
+			Drive:      e.Drive,
+			Attack:     e.Attack,
+			Sustain:    e.Sustain,
+		}
+
+		isFilter := effect.Type == audio.LowPass || effect.Type == audio.HighPass || effect.Type == audio.Peaking
+		if isFilter && effect.Q == 0 {
+			effect.Q = defaultQ
+		}
+
+		chain = append(chain, effect)
+	}
+
+	return chain
+}
+
+// busParams overlays any reverb and delay params given on the defaults.
+func (t *trackMetadata) busParams() audio.BusParams {
+	params := audio.DefaultBusParams()
+
+	if r := t.Reverb; r != nil {
+		overlay(&params.Reverb.RoomSize, r.RoomSize)
+		overlay(&params.Reverb.Damping, r.Damping)
+		overlay(&params.Reverb.Return, r.Return)
+	}
+
+	if d := t.Delay; d != nil {
+		if d.Note != "" {
+			params.Delay.Note = audio.NoteValue(d.Note)
+		}
+
+		overlay(&params.Delay.Feedback, d.Feedback)
+		overlay(&params.Delay.Return, d.Return)
+	}
+
+	return params
+}
+
+// masterParams overlays any master bus params given on the defaults.
+func (t *trackMetadata) masterParams() audio.MasterParams {
+	params := audio.DefaultMasterParams()
+
+	m := t.Master
+	if m == nil {
+		return params
+	}
+
+	params.Gain = m.Gain
+
+	if c := m.Compressor; c != nil {
+		params.Compressor.Enabled = true
+
+		overlay(&params.Compressor.Threshold, c.Threshold)
+		overlay(&params.Compressor.Ratio, c.Ratio)
+		overlay(&params.Compressor.Makeup, c.Makeup)
+
+		if c.AttackMilliseconds != nil {
+			params.Compressor.Attack = milliseconds(*c.AttackMilliseconds)
+		}
+
+		if c.ReleaseMilliseconds != nil {
+			params.Compressor.Release = milliseconds(*c.ReleaseMilliseconds)
+		}
+	}
+
+	return params
+}
+
+func overlay(value, override *float64) {
+	if override != nil {
+		*value = *override
+	}
+}
+
+// synthParams overlays any params given on the synthesised voice's defaults.
+func synthParams(voice string, p *synthParamsMetadata) (audio.SynthParams, error) {
+	params, err := audio.DefaultSynthParams(voice)
+	if err != nil {
+		return audio.SynthParams{}, err
+	}
+
+	if p == nil {
+		return params, nil
+	}
+
+	overlay(&params.Pitch, p.Pitch)
+	overlay(&params.Tone, p.Tone)
+	overlay(&params.Noise, p.Noise)
+
+	if p.DecayMilliseconds != nil {
+		params.Decay = milliseconds(*p.DecayMilliseconds)
+	}
+
+	return params, nil
+}
+
+// pattern returns the instrument's pattern and the articulations of its beat
+// subdivisions, from either its pattern or its step string.
+func (i *instrumentMetadata) pattern() ([]int, map[int]models.Articulation, error) {
+	if i.Steps != "" {
+		if len(i.Pattern) > 0 {
+			return nil, nil, errors.New("instrument must have either a pattern or steps, not both")
+		}
+
+		return models.ParseSteps(i.Steps)
+	}
+
+	pattern := make([]int, 0, len(i.Pattern))
+	articulations := map[int]models.Articulation{}
+
+	for _, step := range i.Pattern {
+		pattern = append(pattern, step.Step)
+
+		if step.Plain {
+			continue
+		}
+
+		ornament, err := models.ParseOrnament(step.Ornament)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "error parsing step ornament")
+		}
+
+		velocity := 1.0
+		if step.Velocity != nil {
+			velocity = *step.Velocity
+		}
+
+		articulations[step.Step] = models.Articulation{
+			Velocity: velocity,
+			Repeat:   step.Repeat,
+			Ornament: ornament,
+		}
+	}
+
+	return pattern, articulations, nil
+}
+
+func milliseconds(ms float64) time.Duration {
+	return time.Duration(ms * float64(time.Millisecond))
+}
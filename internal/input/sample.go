@@ -0,0 +1,144 @@
+package input
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/jcfox412/logarhythms/internal/audio"
+	"github.com/jcfox412/logarhythms/internal/models"
+	"github.com/jcfox412/logarhythms/internal/utils"
+)
+
+const (
+	// Longest sample offset or envelope time that can be entered, in milliseconds
+	maxSampleMilliseconds = 10000
+)
+
+// AllInstrumentsSampleMenu prints out the user menu for viewing all
+// instruments' sample settings and choosing one to modify. Returns an error if
+// invalid input is given.
+func (u *UserInput) AllInstrumentsSampleMenu(iface interface{}) error {
+	track := iface.(*models.Track)
+
+	instrument, err := u.selectInstrument(track, "sample settings", func(instrument *models.Instrument) string {
+		return describeSampleParams(instrument.Audio.GetSampleParams())
+	})
+	if err != nil {
+		return err
+	}
+
+	if instrument == nil {
+		return u.PrintSettingsMenu(track)
+	}
+
+	return retry(3, instrument, u.InstrumentSampleMenu)
+}
+
+// InstrumentSampleMenu prints out the user menu for modifying an instrument's
+// sample settings: which part of the sample is played, whether it's reversed,
+// its tuning and its envelope. Returns an error if invalid input is given or
+// if instrument's Audio object is nil.
+func (u *UserInput) InstrumentSampleMenu(iface interface{}) error {
+	instrument := iface.(*models.Instrument)
+
+	if instrument.Audio == nil {
+		return errors.New("instrument audio must be set to change sample settings")
+	}
+
+	params := instrument.Audio.GetSampleParams()
+
+	fmt.Print(utils.Bold(fmt.Sprintf("\nWhich of the %s's sample settings would you like to change? Current settings: %s\n", instrument.Name, describeSampleParams(params))))
+	fmt.Print(sampleMenuOptions)
+	fmt.Print(utils.Bold("\nWhat would you like to do? (Please enter number 1-6): "))
+
+	var err error
+
+	switch userInput := getUserInput(u.Reader); userInput {
+	case "1":
+		params.Start, err = u.promptMilliseconds("Please enter a start offset in milliseconds")
+	case "2":
+		params.End, err = u.promptMilliseconds("Please enter an end offset in milliseconds (0 plays to the end)")
+	case "3":
+		params.Reverse = !params.Reverse
+	case "4":
+		params.Semitones, err = u.promptBoundedInteger("Please enter a tuning in semitones", -audio.MaxSemitones, audio.MaxSemitones)
+		if err == nil {
+			params.Cents, err = u.promptBoundedInteger("Please enter a fine tuning in cents", -audio.MaxCents, audio.MaxCents)
+		}
+	case "5":
+		params.Envelope, err = u.promptEnvelope()
+	case "6":
+		return nil
+	default:
+		err = errors.New("I'm sorry, I didn't understand your input")
+		fmt.Println(err.Error())
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if err := instrument.Audio.SetSampleParams(params); err != nil {
+		fmt.Println(err.Error())
+		return err
+	}
+
+	fmt.Printf("Sample settings set to %s!\n", describeSampleParams(params))
+
+	return nil
+}
+
+func (u *UserInput) promptMilliseconds(prompt string) (time.Duration, error) {
+	ms, err := u.promptBoundedInteger(prompt, 0, maxSampleMilliseconds)
+	if err != nil {
+		return 0, err
+	}
+
+	return time.Duration(ms) * time.Millisecond, nil
+}
+
+// promptEnvelope reads an envelope's attack, hold and decay from the user. An
+// envelope of all zeroes removes the envelope.
+func (u *UserInput) promptEnvelope() (*audio.Envelope, error) {
+	attack, err := u.promptMilliseconds("Please enter an attack time in milliseconds")
+	if err != nil {
+		return nil, err
+	}
+
+	hold, err := u.promptMilliseconds("Please enter a hold time in milliseconds")
+	if err != nil {
+		return nil, err
+	}
+
+	decay, err := u.promptMilliseconds("Please enter a decay time in milliseconds (0 never decays)")
+	if err != nil {
+		return nil, err
+	}
+
+	if attack == 0 && hold == 0 && decay == 0 {
+		return nil, nil
+	}
+
+	return &audio.Envelope{Attack: attack, Hold: hold, Decay: decay}, nil
+}
+
+func describeSampleParams(params audio.SampleParams) string {
+	end := "end"
+	if params.End > 0 {
+		end = fmt.Sprint(params.End)
+	}
+
+	direction := "forwards"
+	if params.Reverse {
+		direction = "reversed"
+	}
+
+	envelope := "no envelope"
+	if e := params.Envelope; e != nil {
+		envelope = fmt.Sprintf("envelope %v/%v/%v", e.Attack, e.Hold, e.Decay)
+	}
+
+	return fmt.Sprintf("%v to %s, %s, %+d semitones %+d cents, %s", params.Start, end, direction, params.Semitones, params.Cents, envelope)
+}
@@ -0,0 +1,181 @@
+package input_test
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jcfox412/logarhythms/internal/audio"
+	audiomocks "github.com/jcfox412/logarhythms/internal/audio/mocks"
+	"github.com/jcfox412/logarhythms/internal/input"
+	"github.com/jcfox412/logarhythms/internal/models"
+	_ "github.com/jcfox412/logarhythms/testing"
+)
+
+// TODO: test more than error path
+func TestAllInstrumentsSampleMenu(t *testing.T) {
+	type testCase struct {
+		description     string
+		input           string
+		expectedToError bool
+	}
+
+	testCases := []testCase{
+		{
+			description:     "Errors on user input out of range",
+			input:           "3",
+			expectedToError: true,
+		},
+		{
+			description:     "Errors on non-integer input",
+			input:           "help",
+			expectedToError: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		var stdin bytes.Buffer
+		stdin.Write([]byte(fmt.Sprintf("%s\n", testCase.input)))
+
+		userInput := input.UserInput{
+			Reader: &stdin,
+		}
+
+		m := &audiomocks.Manager{}
+		m.On("GetSampleParams").Return(audio.SampleParams{}).Once()
+
+		instrument := &models.Instrument{Name: "testInstrument", Audio: m}
+
+		track := &models.Track{Instruments: []*models.Instrument{instrument}}
+
+		actualErr := userInput.AllInstrumentsSampleMenu(track)
+		if testCase.expectedToError {
+			assert.NotNil(t, actualErr)
+		} else {
+			assert.Nil(t, actualErr)
+		}
+
+		m.AssertExpectations(t)
+	}
+}
+
+func TestInstrumentSampleMenu(t *testing.T) {
+	type testCase struct {
+		description        string
+		input              []string
+		shouldIncludeAudio bool
+		expectedParams     *audio.SampleParams
+		expectedToError    bool
+	}
+
+	initialParams := audio.SampleParams{Start: 10 * time.Millisecond}
+
+	testCases := []testCase{
+		{
+			description:        "Sets start offset",
+			input:              []string{"1", "25"},
+			shouldIncludeAudio: true,
+			expectedParams:     &audio.SampleParams{Start: 25 * time.Millisecond},
+			expectedToError:    false,
+		},
+		{
+			description:        "Sets end offset",
+			input:              []string{"2", "300"},
+			shouldIncludeAudio: true,
+			expectedParams:     &audio.SampleParams{Start: 10 * time.Millisecond, End: 300 * time.Millisecond},
+			expectedToError:    false,
+		},
+		{
+			description:        "Toggles reverse",
+			input:              []string{"3"},
+			shouldIncludeAudio: true,
+			expectedParams:     &audio.SampleParams{Start: 10 * time.Millisecond, Reverse: true},
+			expectedToError:    false,
+		},
+		{
+			description:        "Sets tuning",
+			input:              []string{"4", "-5", "30"},
+			shouldIncludeAudio: true,
+			expectedParams:     &audio.SampleParams{Start: 10 * time.Millisecond, Semitones: -5, Cents: 30},
+			expectedToError:    false,
+		},
+		{
+			description:        "Sets envelope",
+			input:              []string{"5", "1", "20", "150"},
+			shouldIncludeAudio: true,
+			expectedParams: &audio.SampleParams{
+				Start:    10 * time.Millisecond,
+				Envelope: &audio.Envelope{Attack: time.Millisecond, Hold: 20 * time.Millisecond, Decay: 150 * time.Millisecond},
+			},
+			expectedToError: false,
+		},
+		{
+			description:        "Returns to settings menu",
+			input:              []string{"6"},
+			shouldIncludeAudio: true,
+			expectedParams:     nil,
+			expectedToError:    false,
+		},
+		{
+			description:        "Errors on tuning out of range",
+			input:              []string{"4", "25"},
+			shouldIncludeAudio: true,
+			expectedParams:     nil,
+			expectedToError:    true,
+		},
+		{
+			description:        "Errors on non-integer input",
+			input:              []string{"help"},
+			shouldIncludeAudio: true,
+			expectedParams:     nil,
+			expectedToError:    true,
+		},
+		{
+			description:        "Errors on nil instrument Audio",
+			input:              []string{""},
+			shouldIncludeAudio: false,
+			expectedParams:     nil,
+			expectedToError:    true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		var stdin bytes.Buffer
+		for _, i := range testCase.input {
+			stdin.Write([]byte(fmt.Sprintf("%s\n", i)))
+		}
+
+		userInput := input.UserInput{
+			Reader: &stdin,
+		}
+
+		m := &audiomocks.Manager{}
+		instrument := &models.Instrument{Name: "testInstrument", Audio: m}
+
+		if testCase.shouldIncludeAudio {
+			m.On("GetSampleParams").Return(initialParams).Once()
+		} else {
+			instrument.Audio = nil
+		}
+
+		if testCase.expectedParams != nil {
+			m.On("SetSampleParams", *testCase.expectedParams).Return(nil).Once()
+		}
+
+		actualErr := userInput.InstrumentSampleMenu(instrument)
+		if testCase.expectedToError {
+			assert.NotNil(t, actualErr)
+		} else {
+			assert.Nil(t, actualErr)
+		}
+
+		m.AssertExpectations(t)
+	}
+}
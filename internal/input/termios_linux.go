@@ -0,0 +1,9 @@
+package input
+
+import "syscall"
+
+// Requests which get and set a terminal's settings
+const (
+	ioctlGetTermios = syscall.TCGETS
+	ioctlSetTermios = syscall.TCSETS
+)
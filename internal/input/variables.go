@@ -1,5 +1,19 @@
 package input
 
+const (
+	// Range of BPM tracks can be played at
+	minBeatsPerMinute = 1
+	maxBeatsPerMinute = 1000
+	// Entered in the BPM menu to tap the tempo instead
+	tapTempoOption = "t"
+)
+
+const (
+	// Most bars or loops, and seconds, a track's length can be set to
+	maxTrackCount   = 9999
+	maxTrackSeconds = 24 * 60 * 60
+)
+
 const (
 	mainMenuOptions = "\n" +
 		"1) Play Four on the floor (a pattern in 4/4 time)\n" +
@@ -11,9 +25,69 @@ const (
 	settingsMenuOptions = "\n" +
 		"1) Beats per minute (BPM)\n" +
 		"2) Instrument volume(s)\n" +
-		"3) Track length\n" +
-		"4) I'm done, play track!\n" +
-		"5) Back to main menu\n"
+		"3) Instrument sample(s)\n" +
+		"4) Instrument effect(s)\n" +
+		"5) Instrument send(s)\n" +
+		"6) Reverb and delay\n" +
+		"7) Master bus\n" +
+		"8) Kit\n" +
+		"9) Track length\n" +
+		"10) I'm done, play track!\n" +
+		"11) Back to main menu\n"
+
+	playbackKeys = "\nWhile the track plays:\n" +
+		"1-9) Play an instrument, in the order they're listed\n" +
+		"r) Start or stop recording the instruments played (a metronome clicks while recording)\n" +
+		"m) Switch between overdubbing and replacing the pattern\n" +
+		"u) Undo the last bar recorded\n" +
+		"t) Tap the tempo, at least 4 times, to play the track at it once the taps stop\n" +
+		"q) Stop the track\n"
+
+	kitMenuOptions = "\n" +
+		"1) Electronic\n" +
+		"2) Acoustic\n" +
+		"3) Synthesised\n" +
+		"4) Return to settings menu\n"
+
+	busMenuOptions = "\n" +
+		"1) Reverb room size\n" +
+		"2) Reverb damping\n" +
+		"3) Reverb return\n" +
+		"4) Delay time\n" +
+		"5) Delay feedback\n" +
+		"6) Delay return\n" +
+		"7) Return to settings menu\n"
+
+	trackLengthMenuOptions = "\n" +
+		"1) A number of bars\n" +
+		"2) A number of loops through the pattern\n" +
+		"3) A number of seconds, rounded up to the end of the bar\n" +
+		"4) Until stopped\n" +
+		"5) Return to settings menu\n"
+
+	masterMenuOptions = "\n" +
+		"1) Master gain\n" +
+		"2) Turn compressor on or off\n" +
+		"3) Compressor threshold\n" +
+		"4) Compressor ratio\n" +
+		"5) Compressor attack\n" +
+		"6) Compressor release\n" +
+		"7) Compressor makeup gain\n" +
+		"8) Return to settings menu\n"
+
+	effectsMenuOptions = "\n" +
+		"1) Add effect\n" +
+		"2) Edit effect\n" +
+		"3) Remove effect\n" +
+		"4) Return to settings menu\n"
+
+	sampleMenuOptions = "\n" +
+		"1) Start offset\n" +
+		"2) End offset\n" +
+		"3) Reverse\n" +
+		"4) Tuning\n" +
+		"5) Envelope\n" +
+		"6) Return to settings menu\n"
 )
 
 var (
@@ -22,4 +96,10 @@ var (
 		"2": "assets/tracks/gravity.json",
 		"3": "assets/tracks/take_five.json",
 	}
+
+	inputKitMap = map[string]string{
+		"1": "assets/kits/electronic.json",
+		"2": "assets/kits/acoustic.json",
+		"3": "assets/kits/synthesised.json",
+	}
 )
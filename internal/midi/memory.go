@@ -0,0 +1,80 @@
+package midi
+
+import (
+	"io"
+	"sync"
+)
+
+// Memory is a port kept in memory, so tests can check what's sent to a port
+// and deliver messages to be received from it.
+type Memory struct {
+	mu     sync.Mutex
+	sent   [][]byte
+	closed bool
+	// Messages delivered, handed over to Receive one at a time
+	delivered chan []byte
+	done      chan struct{}
+}
+
+var _ Port = new(Memory)
+
+// NewMemory returns an open Memory port.
+func NewMemory() *Memory {
+	return &Memory{delivered: make(chan []byte), done: make(chan struct{})}
+}
+
+// Send records the message, returning an error once the port is closed.
+func (m *Memory) Send(message []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return io.ErrClosedPipe
+	}
+
+	m.sent = append(m.sent, append([]byte(nil), message...))
+
+	return nil
+}
+
+// Sent returns the messages sent so far, in order.
+func (m *Memory) Sent() [][]byte {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([][]byte(nil), m.sent...)
+}
+
+// Deliver blocks until the message is received, or the port is closed. A
+// receiver which handles each message before receiving the next has handled
+// every message delivered before it once Deliver returns.
+func (m *Memory) Deliver(message []byte) {
+	select {
+	case m.delivered <- message:
+	case <-m.done:
+	}
+}
+
+// Receive blocks until a message is delivered, returning io.EOF once the port
+// is closed.
+func (m *Memory) Receive() ([]byte, error) {
+	select {
+	case message := <-m.delivered:
+		return message, nil
+	case <-m.done:
+		return nil, io.EOF
+	}
+}
+
+// Close closes the port, unblocking Deliver and Receive.
+func (m *Memory) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.closed {
+		m.closed = true
+		close(m.done)
+	}
+
+	return nil
+}
@@ -0,0 +1,229 @@
+// Package midi sends and receives MIDI messages through ports, such as the raw
+// MIDI device files of a sound card or USB interface, or the ALSA sequencer.
+package midi
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// System real-time messages, which are a single status byte and can be sent in
+// the middle of other messages
+const (
+	// Sent PulsesPerQuarterNote times to the beat while a clock is running
+	TimingClock byte = 0xF8
+	// Starts the transport from the beginning of the song
+	Start byte = 0xFA
+	// Starts the transport from where it was stopped
+	Continue byte = 0xFB
+	// Stops the transport
+	Stop byte = 0xFC
+)
+
+// PulsesPerQuarterNote is the number of timing clock messages sent to the beat.
+const PulsesPerQuarterNote = 24
+
+// Channel messages, whose low four bits are the channel
+const (
+	noteOff byte = 0x80
+	noteOn  byte = 0x90
+)
+
+const (
+	systemExclusive    byte = 0xF0
+	endOfExclusive     byte = 0xF7
+	firstRealTime      byte = 0xF8
+	firstSystemCommon  byte = 0xF0
+	firstStatus        byte = 0x80
+	maxExclusiveLength      = 1 << 16
+)
+
+// Port sends and receives MIDI messages. It's safe to send and receive at the
+// same time.
+type Port interface {
+	// Send sends a single message
+	Send(message []byte) error
+	// Receive blocks until a whole message is received, returning an error once
+	// the port is closed
+	Receive() ([]byte, error)
+	// Close closes the port, unblocking Receive
+	Close() error
+}
+
+// Open opens a port by name: a raw MIDI device file if it's a path, such as
+// /dev/snd/midiC1D0, or else an ALSA sequencer port, such as 20:0 or the name
+// of a client, e.g. "TD-17".
+func Open(name string) (Port, error) {
+	if strings.HasPrefix(name, "/") || strings.HasPrefix(name, ".") {
+		return OpenDevice(name)
+	}
+
+	return OpenSequencer(name)
+}
+
+// OpenDevice opens a raw MIDI device file, e.g. /dev/snd/midiC1D0 on Linux.
+// Devices which can only send or only receive are opened for the one they can
+// do.
+func OpenDevice(path string) (Port, error) {
+	var (
+		f   *os.File
+		err error
+	)
+
+	for _, flag := range []int{os.O_RDWR, os.O_WRONLY, os.O_RDONLY} {
+		if f, err = os.OpenFile(path, flag, 0); err == nil {
+			return NewPort(f), nil
+		}
+	}
+
+	return nil, errors.Wrapf(err, "error opening MIDI device %s", path)
+}
+
+// NewPort returns a port which sends messages to rw as they are, and receives
+// them from the byte stream it reads.
+func NewPort(rw io.ReadWriteCloser) Port {
+	return &streamPort{rw: rw, r: bufio.NewReader(rw)}
+}
+
+// streamPort is a port over a MIDI byte stream, as read from and written to a
+// raw MIDI device.
+type streamPort struct {
+	rw io.ReadWriteCloser
+	// Guards writes, so messages aren't interleaved
+	mu sync.Mutex
+	r  *bufio.Reader
+	// Message being read, held while real-time messages interrupt it
+	message []byte
+	// Number of data bytes the message being read takes, or -1 for a system
+	// exclusive message which runs until its end byte
+	length int
+	// Status of the last channel message, which following data bytes are read
+	// as if they repeat
+	running byte
+}
+
+func (p *streamPort) Send(message []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, err := p.rw.Write(message); err != nil {
+		return errors.Wrap(err, "error sending MIDI message")
+	}
+
+	return nil
+}
+
+func (p *streamPort) Receive() ([]byte, error) {
+	for {
+		b, err := p.r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case b >= firstRealTime:
+			return []byte{b}, nil
+		case b == endOfExclusive:
+			if len(p.message) > 0 && p.message[0] == systemExclusive {
+				return p.complete(b), nil
+			}
+		case b == systemExclusive:
+			p.message, p.length, p.running = []byte{b}, -1, 0
+		case b >= firstStatus:
+			p.message, p.length = []byte{b}, dataLength(b)
+			if b < firstSystemCommon {
+				p.running = b
+			} else {
+				p.running = 0
+			}
+
+			if p.length == 0 {
+				return p.complete(), nil
+			}
+		default:
+			if len(p.message) == 0 {
+				if p.running == 0 {
+					// a data byte without a status to belong to
+					continue
+				}
+
+				p.message, p.length = []byte{p.running}, dataLength(p.running)
+			}
+
+			if p.length < 0 && len(p.message) >= maxExclusiveLength {
+				p.message = nil
+				continue
+			}
+
+			p.message = append(p.message, b)
+			if len(p.message)-1 == p.length {
+				return p.complete(), nil
+			}
+		}
+	}
+}
+
+// complete returns the message being read with any final bytes, and starts
+// reading the next one.
+func (p *streamPort) complete(b ...byte) []byte {
+	message := append(p.message, b...)
+	p.message = nil
+
+	return message
+}
+
+func (p *streamPort) Close() error {
+	return p.rw.Close()
+}
+
+// NoteOn returns a note-on message for a note from 0 to 127, on a channel from
+// 1 to 16, with a velocity from 1 to 127.
+func NoteOn(channel, note, velocity int) []byte {
+	return []byte{noteOn | channelBits(channel), byte(note) & 0x7F, byte(velocity) & 0x7F}
+}
+
+// NoteOff returns a note-off message for a note from 0 to 127, on a channel
+// from 1 to 16.
+func NoteOff(channel, note int) []byte {
+	return []byte{noteOff | channelBits(channel), byte(note) & 0x7F, 0}
+}
+
+// ValidateNote returns an error unless the channel is from 1 to 16 and the note
+// from 0 to 127.
+func ValidateNote(channel, note int) error {
+	if channel < 1 || channel > 16 {
+		return fmt.Errorf("MIDI channel must be between 1 and 16, got %d", channel)
+	}
+
+	if note < 0 || note > 127 {
+		return fmt.Errorf("MIDI note must be between 0 and 127, got %d", note)
+	}
+
+	return nil
+}
+
+func channelBits(channel int) byte {
+	return byte(channel-1) & 0x0F
+}
+
+// dataLength returns the number of data bytes following a status byte.
+func dataLength(status byte) int {
+	switch {
+	case status < 0xC0, status >= 0xE0 && status < firstSystemCommon:
+		return 2
+	case status < 0xE0:
+		return 1
+	case status == 0xF1, status == 0xF3:
+		return 1
+	case status == 0xF2:
+		return 2
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,114 @@
+package midi_test
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jcfox412/logarhythms/internal/midi"
+)
+
+// stream is a MIDI byte stream which records what's written to it.
+type stream struct {
+	io.Reader
+	bytes.Buffer
+}
+
+func (s *stream) Read(p []byte) (int, error) {
+	return s.Reader.Read(p)
+}
+
+func (s *stream) Close() error {
+	return nil
+}
+
+func TestReceive(t *testing.T) {
+	type testCase struct {
+		description    string
+		input          []byte
+		expectedOutput [][]byte
+	}
+
+	testCases := []testCase{
+		{
+			description:    "Receives real-time messages",
+			input:          []byte{midi.Start, midi.TimingClock, midi.Stop},
+			expectedOutput: [][]byte{{midi.Start}, {midi.TimingClock}, {midi.Stop}},
+		},
+		{
+			description:    "Receives channel messages",
+			input:          []byte{0x90, 36, 100, 0xC0, 5, 0x80, 36, 0},
+			expectedOutput: [][]byte{{0x90, 36, 100}, {0xC0, 5}, {0x80, 36, 0}},
+		},
+		{
+			description:    "Receives channel messages with running status",
+			input:          []byte{0x90, 36, 100, 38, 90},
+			expectedOutput: [][]byte{{0x90, 36, 100}, {0x90, 38, 90}},
+		},
+		{
+			description:    "Receives real-time messages in the middle of other messages",
+			input:          []byte{0x90, 36, midi.TimingClock, 100},
+			expectedOutput: [][]byte{{midi.TimingClock}, {0x90, 36, 100}},
+		},
+		{
+			description:    "Receives system exclusive and common messages",
+			input:          []byte{0xF0, 0x7E, 0x01, 0xF7, 0xF2, 0x10, 0x00, 0xF6},
+			expectedOutput: [][]byte{{0xF0, 0x7E, 0x01, 0xF7}, {0xF2, 0x10, 0x00}, {0xF6}},
+		},
+		{
+			description:    "Skips data bytes without a status",
+			input:          []byte{36, 100, midi.Continue},
+			expectedOutput: [][]byte{{midi.Continue}},
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		port := midi.NewPort(&stream{Reader: bytes.NewReader(testCase.input)})
+
+		var actualOutput [][]byte
+		for {
+			message, err := port.Receive()
+			if err != nil {
+				assert.Equal(t, io.EOF, err, testCase.description)
+				break
+			}
+
+			actualOutput = append(actualOutput, message)
+		}
+
+		assert.Equal(t, testCase.expectedOutput, actualOutput, testCase.description)
+	}
+}
+
+func TestSend(t *testing.T) {
+	s := &stream{Reader: bytes.NewReader(nil)}
+	port := midi.NewPort(s)
+
+	assert.Nil(t, port.Send([]byte{midi.Start}))
+	assert.Nil(t, port.Send([]byte{midi.TimingClock}))
+	assert.Equal(t, []byte{midi.Start, midi.TimingClock}, s.Bytes())
+	assert.Nil(t, port.Close())
+}
+
+func TestMemory(t *testing.T) {
+	port := midi.NewMemory()
+
+	assert.Nil(t, port.Send([]byte{midi.Start}))
+	assert.Equal(t, [][]byte{{midi.Start}}, port.Sent())
+
+	go port.Deliver([]byte{midi.Stop})
+
+	message, err := port.Receive()
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{midi.Stop}, message)
+
+	assert.Nil(t, port.Close())
+
+	_, err = port.Receive()
+	assert.Equal(t, io.EOF, err)
+	assert.NotNil(t, port.Send([]byte{midi.Stop}))
+}
@@ -0,0 +1,244 @@
+//go:build linux && cgo
+// +build linux,cgo
+
+package midi
+
+/*
+#cgo LDFLAGS: -lasound
+#include <alsa/asoundlib.h>
+#include <poll.h>
+
+// Largest number of poll descriptors a sequencer is waited on with
+#define LR_MAX_POLL_DESCRIPTORS 8
+
+// lr_seq_open opens a sequencer client named LogaRhythms, with a port other
+// clients can send to and receive from.
+static int lr_seq_open(snd_seq_t **seq, int *port) {
+	int err = snd_seq_open(seq, "default", SND_SEQ_OPEN_DUPLEX, SND_SEQ_NONBLOCK);
+	if (err < 0) {
+		return err;
+	}
+
+	snd_seq_set_client_name(*seq, "LogaRhythms");
+
+	*port = snd_seq_create_simple_port(*seq, "LogaRhythms",
+		SND_SEQ_PORT_CAP_READ | SND_SEQ_PORT_CAP_SUBS_READ | SND_SEQ_PORT_CAP_WRITE | SND_SEQ_PORT_CAP_SUBS_WRITE,
+		SND_SEQ_PORT_TYPE_MIDI_GENERIC | SND_SEQ_PORT_TYPE_APPLICATION);
+	if (*port < 0) {
+		err = *port;
+		snd_seq_close(*seq);
+		return err;
+	}
+
+	return 0;
+}
+
+// lr_seq_connect connects the port to and from the address, e.g. 20:0, or
+// just one way if the other port only sends or receives.
+static int lr_seq_connect(snd_seq_t *seq, int port, const char *address) {
+	snd_seq_addr_t addr;
+	int err = snd_seq_parse_address(seq, &addr, address);
+	if (err < 0) {
+		return err;
+	}
+
+	int to = snd_seq_connect_to(seq, port, addr.client, addr.port);
+	int from = snd_seq_connect_from(seq, port, addr.client, addr.port);
+	if (to < 0 && from < 0) {
+		return to;
+	}
+
+	return 0;
+}
+
+// lr_seq_send sends the MIDI bytes from the port as a single event. Returns 0
+// without sending if they aren't a whole message.
+static int lr_seq_send(snd_seq_t *seq, int port, snd_midi_event_t *encoder, const unsigned char *message, long length) {
+	snd_seq_event_t ev;
+	snd_seq_ev_clear(&ev);
+	snd_midi_event_reset_encode(encoder);
+
+	long n = snd_midi_event_encode(encoder, message, length, &ev);
+	if (n < 0) {
+		return n;
+	}
+
+	if (ev.type == SND_SEQ_EVENT_NONE) {
+		return 0;
+	}
+
+	snd_seq_ev_set_source(&ev, port);
+	snd_seq_ev_set_subs(&ev);
+	snd_seq_ev_set_direct(&ev);
+
+	int err = snd_seq_event_output_direct(seq, &ev);
+	return err < 0 ? err : 0;
+}
+
+// lr_seq_receive waits up to timeout milliseconds for an event, decoding it
+// into MIDI bytes. Returns the number of bytes decoded, which is 0 if none
+// arrived or the event isn't a MIDI message.
+static long lr_seq_receive(snd_seq_t *seq, snd_midi_event_t *decoder, unsigned char *message, long length, int timeout) {
+	struct pollfd fds[LR_MAX_POLL_DESCRIPTORS];
+	int count = snd_seq_poll_descriptors(seq, fds, LR_MAX_POLL_DESCRIPTORS, POLLIN);
+	if (poll(fds, count, timeout) <= 0) {
+		return 0;
+	}
+
+	snd_seq_event_t *ev;
+	int err = snd_seq_event_input(seq, &ev);
+	if (err == -EAGAIN || err == -ENOSPC) {
+		return 0;
+	}
+
+	if (err < 0) {
+		return err;
+	}
+
+	long n = snd_midi_event_decode(decoder, message, length, ev);
+	if (n == -ENOENT) {
+		return 0;
+	}
+
+	return n;
+}
+*/
+import "C"
+
+import (
+	"io"
+	"sync"
+	"unsafe"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// Largest message sent or received through the sequencer, which longer
+	// system exclusive messages are split up by
+	sequencerBufferSize = 256
+	// Time Receive waits for an event before checking whether the port has
+	// been closed
+	sequencerPollMilliseconds = 100
+)
+
+// sequencerPort is a port of the ALSA sequencer, connected to another client's
+// port.
+type sequencerPort struct {
+	seq     *C.snd_seq_t
+	port    C.int
+	encoder *C.snd_midi_event_t
+	decoder *C.snd_midi_event_t
+
+	// Guards sending, and closing
+	mu sync.Mutex
+	// Held while receiving, so the sequencer isn't closed under it
+	receiving sync.Mutex
+	closed    bool
+}
+
+// OpenSequencer opens an ALSA sequencer port connected to the given client's
+// port, such as 20:0, or the name of a client, e.g. "TD-17". The port can be
+// listed with aconnect -l.
+func OpenSequencer(address string) (Port, error) {
+	p := &sequencerPort{}
+
+	if err := C.lr_seq_open(&p.seq, &p.port); err < 0 {
+		return nil, sequencerError(err, "error opening ALSA sequencer")
+	}
+
+	caddress := C.CString(address)
+	defer C.free(unsafe.Pointer(caddress))
+
+	if err := C.lr_seq_connect(p.seq, p.port, caddress); err < 0 {
+		C.snd_seq_close(p.seq)
+		return nil, sequencerError(err, "error connecting to ALSA sequencer port "+address)
+	}
+
+	if err := C.snd_midi_event_new(sequencerBufferSize, &p.encoder); err < 0 {
+		C.snd_seq_close(p.seq)
+		return nil, sequencerError(err, "error creating MIDI encoder")
+	}
+
+	if err := C.snd_midi_event_new(sequencerBufferSize, &p.decoder); err < 0 {
+		C.snd_midi_event_free(p.encoder)
+		C.snd_seq_close(p.seq)
+		return nil, sequencerError(err, "error creating MIDI decoder")
+	}
+
+	C.snd_midi_event_no_status(p.decoder, 1)
+
+	return p, nil
+}
+
+func (p *sequencerPort) Send(message []byte) error {
+	if len(message) == 0 {
+		return nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return io.ErrClosedPipe
+	}
+
+	if err := C.lr_seq_send(p.seq, p.port, p.encoder, (*C.uchar)(unsafe.Pointer(&message[0])), C.long(len(message))); err < 0 {
+		return sequencerError(err, "error sending MIDI message")
+	}
+
+	return nil
+}
+
+func (p *sequencerPort) Receive() ([]byte, error) {
+	p.receiving.Lock()
+	defer p.receiving.Unlock()
+
+	message := make([]byte, sequencerBufferSize)
+	for {
+		p.mu.Lock()
+		closed := p.closed
+		p.mu.Unlock()
+
+		if closed {
+			return nil, io.EOF
+		}
+
+		n := C.lr_seq_receive(p.seq, p.decoder, (*C.uchar)(unsafe.Pointer(&message[0])), C.long(len(message)), sequencerPollMilliseconds)
+		if n < 0 {
+			return nil, sequencerError(C.int(n), "error receiving MIDI message")
+		}
+
+		if n > 0 {
+			return message[:n], nil
+		}
+	}
+}
+
+func (p *sequencerPort) Close() error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil
+	}
+
+	p.closed = true
+	p.mu.Unlock()
+
+	// wait for Receive to see the port is closed
+	p.receiving.Lock()
+	defer p.receiving.Unlock()
+
+	C.snd_midi_event_free(p.encoder)
+	C.snd_midi_event_free(p.decoder)
+
+	if err := C.snd_seq_close(p.seq); err < 0 {
+		return sequencerError(err, "error closing ALSA sequencer")
+	}
+
+	return nil
+}
+
+func sequencerError(err C.int, message string) error {
+	return errors.Wrap(errors.New(C.GoString(C.snd_strerror(err))), message)
+}
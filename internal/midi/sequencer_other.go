@@ -0,0 +1,12 @@
+//go:build !linux || !cgo
+// +build !linux !cgo
+
+package midi
+
+import "github.com/pkg/errors"
+
+// OpenSequencer can't open ALSA sequencer ports on this platform, which only
+// Linux has.
+func OpenSequencer(address string) (Port, error) {
+	return nil, errors.New("the ALSA sequencer is only available on Linux, use a raw MIDI device instead")
+}
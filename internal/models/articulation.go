@@ -0,0 +1,258 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// Velocity of a ghost note, relative to a full-velocity hit
+	ghostVelocity = 0.4
+	// Velocity of grace notes, relative to the hit they decorate
+	graceVelocityRatio = 0.5
+	// How far ahead of the hit a flam's grace note is played
+	flamLead = 15 * time.Millisecond
+	// Time between each of a drag's grace notes, and between the last grace
+	// note and the hit
+	dragSpacing = 15 * time.Millisecond
+	// Number of hits in a roll when no repeat count is given
+	defaultRollHits = 4
+	// Highest number of hits a single step can be divided into
+	maxRepeat = 9
+	// Furthest a hit can be moved from its step, as a fraction of the step
+	maxOffset = 0.5
+)
+
+// Ornament decorates a hit with extra grace notes.
+type Ornament int
+
+const (
+	// NoOrnament plays a hit without decoration.
+	NoOrnament Ornament = iota
+	// Flam plays a single quiet grace note just ahead of the hit.
+	Flam
+	// Drag plays two quiet grace notes just ahead of the hit.
+	Drag
+	// Roll spreads hits evenly across the step, building from grace note
+	// velocity up to the hit's velocity.
+	Roll
+)
+
+var ornamentNames = map[Ornament]string{
+	NoOrnament: "none",
+	Flam:       "flam",
+	Drag:       "drag",
+	Roll:       "roll",
+}
+
+// String returns the name of the ornament, as used in track files.
+func (o Ornament) String() string {
+	if name, ok := ornamentNames[o]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("Ornament(%d)", int(o))
+}
+
+// ParseOrnament returns the ornament with the given name. An empty name is
+// treated as NoOrnament.
+func ParseOrnament(name string) (Ornament, error) {
+	if name == "" {
+		return NoOrnament, nil
+	}
+
+	for ornament, ornamentName := range ornamentNames {
+		if ornamentName == name {
+			return ornament, nil
+		}
+	}
+
+	return NoOrnament, fmt.Errorf("unknown ornament %q", name)
+}
+
+// Articulation describes how a single step of an instrument's pattern is
+// played.
+type Articulation struct {
+	// Velocity of the hit, between 0 and 1
+	Velocity float64
+	// Number of hits spread evenly across the step (a ratchet). 0 and 1 both
+	// play a single hit.
+	Repeat int
+	// Grace notes played around the hit
+	Ornament Ornament
+	// How far the hit is played from its step, as a fraction of the step from
+	// -0.5 to 0.5, negative to play it early. Left by recording hits without
+	// fully quantising them.
+	Offset float64
+}
+
+// hit is a single trigger of an instrument, relative to the start of its step.
+type hit struct {
+	offset   time.Duration
+	velocity float64
+}
+
+func defaultArticulation() Articulation {
+	return Articulation{Velocity: defaultVelocity, Repeat: 1}
+}
+
+func (a Articulation) validate() error {
+	if a.Velocity < 0 || a.Velocity > 1 {
+		return errors.New("articulation velocity must be between 0 and 1")
+	}
+
+	if a.Repeat < 0 || a.Repeat > maxRepeat {
+		return fmt.Errorf("articulation repeat must be between 0 and %d", maxRepeat)
+	}
+
+	if _, ok := ornamentNames[a.Ornament]; !ok {
+		return fmt.Errorf("unknown articulation ornament %d", int(a.Ornament))
+	}
+
+	if a.Offset < -maxOffset || a.Offset > maxOffset {
+		return fmt.Errorf("articulation offset must be between %v and %v", -maxOffset, maxOffset)
+	}
+
+	return nil
+}
+
+// lead returns how far ahead of its step, of the given duration, the
+// articulation's first hit or grace note is played.
+func (a Articulation) lead(stepDuration time.Duration) time.Duration {
+	lead := -a.offset(stepDuration)
+
+	switch a.Ornament {
+	case Flam:
+		lead += flamLead
+	case Drag:
+		lead += 2 * dragSpacing
+	}
+
+	if lead < 0 {
+		return 0
+	}
+
+	return lead
+}
+
+// offset returns how far the articulation's hits are moved from a step of the
+// given duration.
+func (a Articulation) offset(stepDuration time.Duration) time.Duration {
+	return time.Duration(a.Offset * float64(stepDuration))
+}
+
+// hits expands the articulation into the individual hits played for a step
+// of the given duration.
+func (a Articulation) hits(stepDuration time.Duration) []hit {
+	graceVelocity := a.Velocity * graceVelocityRatio
+	offset := a.offset(stepDuration)
+
+	var hits []hit
+
+	switch a.Ornament {
+	case Flam:
+		hits = append(hits, hit{offset: offset - flamLead, velocity: graceVelocity})
+	case Drag:
+		hits = append(hits,
+			hit{offset: offset - 2*dragSpacing, velocity: graceVelocity},
+			hit{offset: offset - dragSpacing, velocity: graceVelocity},
+		)
+	}
+
+	repeat := a.Repeat
+	if a.Ornament == Roll && repeat < 2 {
+		repeat = defaultRollHits
+	}
+
+	if repeat < 1 {
+		repeat = 1
+	}
+
+	for i := 0; i < repeat; i++ {
+		velocity := a.Velocity
+		if a.Ornament == Roll {
+			velocity = graceVelocity + (a.Velocity-graceVelocity)*float64(i)/float64(repeat-1)
+		}
+
+		hits = append(hits, hit{
+			offset:   offset + stepDuration*time.Duration(i)/time.Duration(repeat),
+			velocity: velocity,
+		})
+	}
+
+	return hits
+}
+
+// glyph returns the character used to draw the articulation, both in the
+// playback grid and in step strings.
+func (a Articulation) glyph() string {
+	switch {
+	case a.Ornament == Flam:
+		return "f"
+	case a.Ornament == Drag:
+		return "d"
+	case a.Ornament == Roll:
+		return "r"
+	case a.Repeat > 1:
+		return fmt.Sprint(a.Repeat)
+	case a.Velocity <= ghostVelocity:
+		return "o"
+	default:
+		return "X"
+	}
+}
+
+// ParseSteps parses a step string into a pattern and the articulations of its
+// steps. Each character is a single step:
+//
+//	. or -  rest
+//	x or X  hit
+//	o       ghost note
+//	2 to 9  ratchet of that many hits
+//	f       flam
+//	d       drag
+//	r       roll
+//
+// Spaces and bar lines (|) are ignored, so that steps can be grouped for
+// readability.
+func ParseSteps(steps string) ([]int, map[int]Articulation, error) {
+	pattern := []int{}
+	articulations := map[int]Articulation{}
+
+	step := 0
+	for _, glyph := range steps {
+		articulation := defaultArticulation()
+
+		switch {
+		case glyph == ' ' || glyph == '|':
+			continue
+		case glyph == '.' || glyph == '-':
+			step++
+			continue
+		case glyph == 'x' || glyph == 'X':
+		case glyph == 'o':
+			articulation.Velocity = ghostVelocity
+		case glyph >= '2' && glyph <= '9':
+			articulation.Repeat = int(glyph - '0')
+		case glyph == 'f':
+			articulation.Ornament = Flam
+		case glyph == 'd':
+			articulation.Ornament = Drag
+		case glyph == 'r':
+			articulation.Ornament = Roll
+		default:
+			return nil, nil, fmt.Errorf("unknown step %q at step %d", glyph, step)
+		}
+
+		pattern = append(pattern, step)
+		if articulation != defaultArticulation() {
+			articulations[step] = articulation
+		}
+
+		step++
+	}
+
+	return pattern, articulations, nil
+}
@@ -0,0 +1,187 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestArticulationValidate(t *testing.T) {
+	type testCase struct {
+		description     string
+		input           Articulation
+		expectedToError bool
+	}
+
+	testCases := []testCase{
+		{
+			description:     "Succeeds with valid articulation",
+			input:           Articulation{Velocity: 0.5, Repeat: 3, Ornament: Flam},
+			expectedToError: false,
+		},
+		{
+			description:     "Errors with velocity above 1",
+			input:           Articulation{Velocity: 1.5},
+			expectedToError: true,
+		},
+		{
+			description:     "Errors with too many repeats",
+			input:           Articulation{Velocity: 1, Repeat: maxRepeat + 1},
+			expectedToError: true,
+		},
+		{
+			description:     "Errors with unknown ornament",
+			input:           Articulation{Velocity: 1, Ornament: Ornament(42)},
+			expectedToError: true,
+		},
+		{
+			description:     "Errors with offset beyond half a step",
+			input:           Articulation{Velocity: 1, Offset: -0.6},
+			expectedToError: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		actualErr := testCase.input.validate()
+		if testCase.expectedToError {
+			assert.NotNil(t, actualErr)
+		} else {
+			assert.Nil(t, actualErr)
+		}
+	}
+}
+
+func TestArticulationHits(t *testing.T) {
+	type testCase struct {
+		description    string
+		input          Articulation
+		expectedOutput []hit
+	}
+
+	stepDuration := 120 * time.Millisecond
+
+	testCases := []testCase{
+		{
+			description:    "Plays a single hit",
+			input:          Articulation{Velocity: 1},
+			expectedOutput: []hit{{offset: 0, velocity: 1}},
+		},
+		{
+			description: "Spreads a ratchet across the step",
+			input:       Articulation{Velocity: 1, Repeat: 3},
+			expectedOutput: []hit{
+				{offset: 0, velocity: 1},
+				{offset: 40 * time.Millisecond, velocity: 1},
+				{offset: 80 * time.Millisecond, velocity: 1},
+			},
+		},
+		{
+			description: "Plays a flam's grace note early",
+			input:       Articulation{Velocity: 1, Ornament: Flam},
+			expectedOutput: []hit{
+				{offset: -flamLead, velocity: 0.5},
+				{offset: 0, velocity: 1},
+			},
+		},
+		{
+			description: "Plays a drag's grace notes early",
+			input:       Articulation{Velocity: 1, Ornament: Drag},
+			expectedOutput: []hit{
+				{offset: -2 * dragSpacing, velocity: 0.5},
+				{offset: -dragSpacing, velocity: 0.5},
+				{offset: 0, velocity: 1},
+			},
+		},
+		{
+			description: "Builds a roll up to the hit's velocity",
+			input:       Articulation{Velocity: 1, Repeat: 3, Ornament: Roll},
+			expectedOutput: []hit{
+				{offset: 0, velocity: 0.5},
+				{offset: 40 * time.Millisecond, velocity: 0.75},
+				{offset: 80 * time.Millisecond, velocity: 1},
+			},
+		},
+		{
+			description: "Defaults a roll's number of hits",
+			input:       Articulation{Velocity: 1, Ornament: Roll},
+			expectedOutput: []hit{
+				{offset: 0, velocity: 0.5},
+				{offset: 30 * time.Millisecond, velocity: 0.5 + 0.5/3},
+				{offset: 60 * time.Millisecond, velocity: 0.5 + 1.0/3},
+				{offset: 90 * time.Millisecond, velocity: 1},
+			},
+		},
+		{
+			description: "Moves every hit by the offset",
+			input:       Articulation{Velocity: 1, Repeat: 2, Ornament: Flam, Offset: -0.25},
+			expectedOutput: []hit{
+				{offset: -30*time.Millisecond - flamLead, velocity: 0.5},
+				{offset: -30 * time.Millisecond, velocity: 1},
+				{offset: 30 * time.Millisecond, velocity: 1},
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		actualOutput := testCase.input.hits(stepDuration)
+		assert.Equal(t, len(testCase.expectedOutput), len(actualOutput))
+
+		for i := range actualOutput {
+			assert.Equal(t, testCase.expectedOutput[i].offset, actualOutput[i].offset)
+			assert.InDelta(t, testCase.expectedOutput[i].velocity, actualOutput[i].velocity, 1e-9)
+		}
+	}
+}
+
+func TestArticulationGlyph(t *testing.T) {
+	type testCase struct {
+		description    string
+		input          Articulation
+		expectedOutput string
+	}
+
+	testCases := []testCase{
+		{
+			description:    "Draws a plain hit",
+			input:          defaultArticulation(),
+			expectedOutput: "X",
+		},
+		{
+			description:    "Draws a ghost note",
+			input:          Articulation{Velocity: ghostVelocity},
+			expectedOutput: "o",
+		},
+		{
+			description:    "Draws a ratchet",
+			input:          Articulation{Velocity: 1, Repeat: 3},
+			expectedOutput: "3",
+		},
+		{
+			description:    "Draws a flam",
+			input:          Articulation{Velocity: 1, Ornament: Flam},
+			expectedOutput: "f",
+		},
+		{
+			description:    "Draws a drag",
+			input:          Articulation{Velocity: 1, Ornament: Drag},
+			expectedOutput: "d",
+		},
+		{
+			description:    "Draws a roll",
+			input:          Articulation{Velocity: 1, Repeat: 6, Ornament: Roll},
+			expectedOutput: "r",
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		actualOutput := testCase.input.glyph()
+		assert.Equal(t, testCase.expectedOutput, actualOutput)
+	}
+}
@@ -0,0 +1,117 @@
+package models_test
+
+import (
+	"testing"
+
+	"github.com/jcfox412/logarhythms/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSteps(t *testing.T) {
+	type output struct {
+		pattern       []int
+		articulations map[int]models.Articulation
+	}
+
+	type testCase struct {
+		description     string
+		input           string
+		expectedOutput  output
+		expectedToError bool
+	}
+
+	testCases := []testCase{
+		{
+			description: "Succeeds with empty steps",
+			input:       "",
+			expectedOutput: output{
+				pattern:       []int{},
+				articulations: map[int]models.Articulation{},
+			},
+			expectedToError: false,
+		},
+		{
+			description: "Succeeds with plain hits and rests",
+			input:       "x.-X",
+			expectedOutput: output{
+				pattern:       []int{0, 3},
+				articulations: map[int]models.Articulation{},
+			},
+			expectedToError: false,
+		},
+		{
+			description: "Succeeds with articulations, ignoring spaces and bar lines",
+			input:       "o3.. | f.dr",
+			expectedOutput: output{
+				pattern: []int{0, 1, 4, 6, 7},
+				articulations: map[int]models.Articulation{
+					0: {Velocity: 0.4, Repeat: 1},
+					1: {Velocity: 1, Repeat: 3},
+					4: {Velocity: 1, Repeat: 1, Ornament: models.Flam},
+					6: {Velocity: 1, Repeat: 1, Ornament: models.Drag},
+					7: {Velocity: 1, Repeat: 1, Ornament: models.Roll},
+				},
+			},
+			expectedToError: false,
+		},
+		{
+			description:     "Errors on unknown step",
+			input:           "x.y.",
+			expectedToError: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		actualPattern, actualArticulations, actualErr := models.ParseSteps(testCase.input)
+		if testCase.expectedToError {
+			assert.NotNil(t, actualErr)
+		} else {
+			assert.Nil(t, actualErr)
+			assert.Equal(t, testCase.expectedOutput.pattern, actualPattern)
+			assert.Equal(t, testCase.expectedOutput.articulations, actualArticulations)
+		}
+	}
+}
+
+func TestParseOrnament(t *testing.T) {
+	type testCase struct {
+		description     string
+		input           string
+		expectedOutput  models.Ornament
+		expectedToError bool
+	}
+
+	testCases := []testCase{
+		{
+			description:     "Treats empty name as no ornament",
+			input:           "",
+			expectedOutput:  models.NoOrnament,
+			expectedToError: false,
+		},
+		{
+			description:     "Parses named ornament",
+			input:           "drag",
+			expectedOutput:  models.Drag,
+			expectedToError: false,
+		},
+		{
+			description:     "Errors on unknown ornament",
+			input:           "paradiddle",
+			expectedToError: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		actualOutput, actualErr := models.ParseOrnament(testCase.input)
+		if testCase.expectedToError {
+			assert.NotNil(t, actualErr)
+		} else {
+			assert.Nil(t, actualErr)
+			assert.Equal(t, testCase.expectedOutput, actualOutput)
+		}
+	}
+}
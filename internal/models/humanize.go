@@ -0,0 +1,79 @@
+package models
+
+import (
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Humanize describes the random variation applied to an instrument's hits, so
+// that playback doesn't land machine-perfectly on every beat subdivision.
+type Humanize struct {
+	// Maximum timing offset of a hit, as an absolute duration
+	Timing time.Duration
+	// Maximum timing offset of a hit, as a fraction of a beat subdivision. Added
+	// to Timing if both are set.
+	TimingFraction float64
+	// Whether hits can land ahead of the beat as well as behind it. If false,
+	// hits are only ever delayed.
+	AroundBeat bool
+	// Maximum amount a hit's velocity can vary by, between 0 and 1
+	Velocity float64
+}
+
+func (h *Humanize) validate() error {
+	if h.Timing < 0 {
+		return errors.New("humanize timing must not be negative")
+	}
+
+	if h.TimingFraction < 0 || h.TimingFraction > 1 {
+		return errors.New("humanize timing fraction must be between 0 and 1")
+	}
+
+	if h.Velocity < 0 || h.Velocity > 1 {
+		return errors.New("humanize velocity must be between 0 and 1")
+	}
+
+	return nil
+}
+
+// maxTimingOffset returns the furthest a hit can be moved from its beat
+// subdivision, in either direction.
+func (h *Humanize) maxTimingOffset(stepDuration time.Duration) time.Duration {
+	if h == nil {
+		return 0
+	}
+
+	return h.Timing + time.Duration(h.TimingFraction*float64(stepDuration))
+}
+
+// maxEarlyOffset returns the furthest ahead of its beat subdivision a hit can
+// land.
+func (h *Humanize) maxEarlyOffset(stepDuration time.Duration) time.Duration {
+	if h == nil || !h.AroundBeat {
+		return 0
+	}
+
+	return h.maxTimingOffset(stepDuration)
+}
+
+// apply randomly varies a hit's timing and velocity. The returned offset is
+// relative to the hit's beat subdivision, and is negative for early hits.
+func (h *Humanize) apply(rng *rand.Rand, stepDuration time.Duration, velocity float64) (time.Duration, float64) {
+	if h == nil {
+		return 0, velocity
+	}
+
+	maxOffset := float64(h.maxTimingOffset(stepDuration))
+
+	offset := rng.Float64() * maxOffset
+	if h.AroundBeat {
+		offset = (rng.Float64()*2 - 1) * maxOffset
+	}
+
+	velocity += (rng.Float64()*2 - 1) * h.Velocity
+
+	return time.Duration(offset), math.Max(0, math.Min(1, velocity))
+}
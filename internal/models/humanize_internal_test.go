@@ -0,0 +1,162 @@
+package models
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHumanizeValidate(t *testing.T) {
+	type testCase struct {
+		description     string
+		input           *Humanize
+		expectedToError bool
+	}
+
+	testCases := []testCase{
+		{
+			description:     "Succeeds with valid settings",
+			input:           &Humanize{Timing: 10 * time.Millisecond, TimingFraction: 0.5, Velocity: 0.2},
+			expectedToError: false,
+		},
+		{
+			description:     "Errors with negative timing",
+			input:           &Humanize{Timing: -time.Millisecond},
+			expectedToError: true,
+		},
+		{
+			description:     "Errors with timing fraction above 1",
+			input:           &Humanize{TimingFraction: 1.5},
+			expectedToError: true,
+		},
+		{
+			description:     "Errors with velocity above 1",
+			input:           &Humanize{Velocity: 2},
+			expectedToError: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		actualErr := testCase.input.validate()
+		if testCase.expectedToError {
+			assert.NotNil(t, actualErr)
+		} else {
+			assert.Nil(t, actualErr)
+		}
+	}
+}
+
+func TestHumanizeMaxEarlyOffset(t *testing.T) {
+	type testCase struct {
+		description    string
+		input          *Humanize
+		expectedOutput time.Duration
+	}
+
+	stepDuration := 100 * time.Millisecond
+
+	testCases := []testCase{
+		{
+			description:    "Handles nil humanize",
+			input:          nil,
+			expectedOutput: 0,
+		},
+		{
+			description:    "Never lands early when only behind the beat",
+			input:          &Humanize{Timing: 10 * time.Millisecond},
+			expectedOutput: 0,
+		},
+		{
+			description:    "Combines timing and timing fraction around the beat",
+			input:          &Humanize{Timing: 10 * time.Millisecond, TimingFraction: 0.1, AroundBeat: true},
+			expectedOutput: 20 * time.Millisecond,
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		actualOutput := testCase.input.maxEarlyOffset(stepDuration)
+		assert.Equal(t, testCase.expectedOutput, actualOutput)
+	}
+}
+
+func TestHumanizeApply(t *testing.T) {
+	type testCase struct {
+		description       string
+		input             *Humanize
+		minExpectedOffset time.Duration
+		maxExpectedOffset time.Duration
+		minVelocity       float64
+		maxVelocity       float64
+	}
+
+	stepDuration := 100 * time.Millisecond
+
+	testCases := []testCase{
+		{
+			description:       "Leaves hits untouched with nil humanize",
+			input:             nil,
+			minExpectedOffset: 0,
+			maxExpectedOffset: 0,
+			minVelocity:       0.8,
+			maxVelocity:       0.8,
+		},
+		{
+			description:       "Only delays hits behind the beat",
+			input:             &Humanize{TimingFraction: 0.1},
+			minExpectedOffset: 0,
+			maxExpectedOffset: 10 * time.Millisecond,
+			minVelocity:       0.8,
+			maxVelocity:       0.8,
+		},
+		{
+			description:       "Moves hits either side of the beat",
+			input:             &Humanize{Timing: 5 * time.Millisecond, AroundBeat: true},
+			minExpectedOffset: -5 * time.Millisecond,
+			maxExpectedOffset: 5 * time.Millisecond,
+			minVelocity:       0.8,
+			maxVelocity:       0.8,
+		},
+		{
+			description:       "Varies velocity within bounds",
+			input:             &Humanize{Velocity: 0.5},
+			minExpectedOffset: 0,
+			maxExpectedOffset: 0,
+			minVelocity:       0.3,
+			maxVelocity:       1,
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		rng := rand.New(rand.NewSource(1))
+
+		for i := 0; i < 100; i++ {
+			actualOffset, actualVelocity := testCase.input.apply(rng, stepDuration, 0.8)
+			assert.GreaterOrEqual(t, int64(actualOffset), int64(testCase.minExpectedOffset))
+			assert.LessOrEqual(t, int64(actualOffset), int64(testCase.maxExpectedOffset))
+			assert.GreaterOrEqual(t, actualVelocity, testCase.minVelocity)
+			assert.LessOrEqual(t, actualVelocity, testCase.maxVelocity)
+		}
+	}
+}
+
+func TestHumanizeApplyIsSeeded(t *testing.T) {
+	humanize := &Humanize{Timing: 10 * time.Millisecond, AroundBeat: true, Velocity: 0.2}
+
+	rng1 := rand.New(rand.NewSource(42))
+	rng2 := rand.New(rand.NewSource(42))
+
+	for i := 0; i < 10; i++ {
+		offset1, velocity1 := humanize.apply(rng1, 0, 1)
+		offset2, velocity2 := humanize.apply(rng2, 0, 1)
+		assert.Equal(t, offset1, offset2)
+		assert.Equal(t, velocity1, velocity2)
+	}
+}
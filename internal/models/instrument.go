@@ -1,6 +1,9 @@
 package models
 
 import (
+	"fmt"
+	"time"
+
 	"github.com/pkg/errors"
 
 	"github.com/jcfox412/logarhythms/internal/audio"
@@ -10,12 +13,31 @@ import (
 type Instrument struct {
 	// Name of the instrument, e.g. Snare
 	Name string
-	// File location of the instrument's audio sample (relative to root of project)
-	// Filename string
+	// Name of the kit voice the instrument is played on, e.g. kick, or empty if
+	// the instrument has its own sample file or synth
+	Voice string
 	// Beat subdivisions where the instrument should be triggered
 	Pattern []int
+	// How individual beat subdivisions of Pattern are played, keyed by beat
+	// subdivision. Subdivisions without an articulation play a single hit at
+	// full velocity.
+	Articulations map[int]Articulation
 	// Manager for audio of instrument
 	Audio audio.Manager
+	// Random variation applied to the instrument's hits, or nil to play them
+	// exactly on the beat
+	Humanize *Humanize
+	// Whether the instrument is silenced, leaving its pattern as it is
+	Muted bool
+	// Whether the instrument's audio is turned off, such as when only its MIDI
+	// notes are wanted
+	AudioOff bool
+	// Note the instrument's hits are sent as to the track's MIDI port, or nil to
+	// send none
+	MIDI *MIDINote
+	// Whether the instrument's MIDI notes are turned off, leaving its note as it
+	// is
+	MIDIOff bool
 }
 
 // NewInstrument builds an Instrument object with Audio support.
@@ -32,10 +54,66 @@ func NewInstrument(name, filename string, pattern []int) (*Instrument, error) {
 	}, nil
 }
 
+// NewSynthInstrument builds an Instrument object whose audio is synthesised
+// from params rather than loaded from a sample file.
+func NewSynthInstrument(name, voice string, params audio.SynthParams, pattern []int) (*Instrument, error) {
+	audioManager, err := audio.NewSynth(voice, params)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Instrument{
+		Name:    name,
+		Pattern: pattern,
+		Audio:   audioManager,
+	}, nil
+}
+
 func (i *Instrument) validate() error {
 	if i.Audio == nil {
 		return errors.New("instrument audio manager must not be nil")
 	}
 
+	if i.Humanize != nil {
+		if err := i.Humanize.validate(); err != nil {
+			return errors.Wrap(err, "error validating humanize settings")
+		}
+	}
+
+	if i.MIDI != nil {
+		if err := i.MIDI.validate(); err != nil {
+			return errors.Wrap(err, "error validating MIDI note")
+		}
+	}
+
+	for step, articulation := range i.Articulations {
+		if err := articulation.validate(); err != nil {
+			return errors.Wrap(err, fmt.Sprintf("error validating articulation of step %d", step))
+		}
+	}
+
 	return nil
 }
+
+// articulation returns how the instrument is played at the given beat
+// subdivision.
+func (i *Instrument) articulation(step int) Articulation {
+	if articulation, ok := i.Articulations[step]; ok {
+		return articulation
+	}
+
+	return defaultArticulation()
+}
+
+// lead returns how far ahead of its beat subdivision the instrument can be
+// played, either through ornaments, early offsets or humanization.
+func (i *Instrument) lead(stepDuration time.Duration) time.Duration {
+	lead := time.Duration(0)
+	for _, articulation := range i.Articulations {
+		if articulation.lead(stepDuration) > lead {
+			lead = articulation.lead(stepDuration)
+		}
+	}
+
+	return lead + i.Humanize.maxEarlyOffset(stepDuration)
+}
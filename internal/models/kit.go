@@ -0,0 +1,158 @@
+package models
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+
+	"github.com/jcfox412/logarhythms/internal/audio"
+)
+
+// Kit is a named set of voices which a track's instruments can be played on.
+// Swapping a track's kit changes how it sounds without changing its patterns.
+type Kit struct {
+	// Name of the kit, e.g. Acoustic
+	Name string
+	// Voices of the kit, keyed by abstract voice name, e.g. kick
+	Voices map[string]Voice
+}
+
+// Voice describes the sound a kit plays for one of its voice names. A voice is
+// either a sample file or a synthesised voice.
+type Voice struct {
+	// File location of the voice's audio sample (relative to root of project)
+	Filename string
+	// Name of the synthesised voice, e.g. kick, used if Filename is empty
+	Synth string
+	// Params the synthesised voice is rendered with
+	SynthParams audio.SynthParams
+	// Default volume of the voice, from 0 to 100
+	Volume float64
+	// Default stereo position of the voice, from -1 (left) to 1 (right)
+	Pan float64
+	// Insert effects applied to the voice, in order
+	Effects []audio.Effect
+}
+
+// NewKit builds a Kit, checking that every voice has a sound.
+func NewKit(name string, voices map[string]Voice) (*Kit, error) {
+	if len(voices) == 0 {
+		return nil, errors.New("kit must have at least one voice")
+	}
+
+	for voiceName, voice := range voices {
+		if voice.Filename == "" && voice.Synth == "" {
+			return nil, fmt.Errorf("kit voice %s must have either a filename or a synth", voiceName)
+		}
+
+		if voice.Filename != "" && voice.Synth != "" {
+			return nil, fmt.Errorf("kit voice %s must have either a filename or a synth, not both", voiceName)
+		}
+	}
+
+	return &Kit{
+		Name:   name,
+		Voices: voices,
+	}, nil
+}
+
+// newAudio builds the audio for the kit's voice, set to the voice's default
+// volume, pan and effects.
+func (k *Kit) newAudio(voiceName string) (audio.Manager, error) {
+	voice, ok := k.Voices[voiceName]
+	if !ok {
+		return nil, fmt.Errorf("kit %s has no %s voice", k.Name, voiceName)
+	}
+
+	var (
+		audioManager audio.Manager
+		err          error
+	)
+
+	if voice.Synth != "" {
+		audioManager, err = audio.NewSynth(voice.Synth, voice.SynthParams)
+	} else {
+		audioManager, err = audio.New(voice.Filename)
+	}
+
+	if err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("error creating %s voice", voiceName))
+	}
+
+	if _, err := audioManager.SetVolume(voice.Volume); err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("error setting %s voice volume", voiceName))
+	}
+
+	if err := audioManager.SetPan(voice.Pan); err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("error setting %s voice pan", voiceName))
+	}
+
+	if err := audioManager.SetEffects(voice.Effects); err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("error setting %s voice effects", voiceName))
+	}
+
+	return audioManager, nil
+}
+
+// NewKitInstrument builds an Instrument object played on one of the kit's
+// voices.
+func NewKitInstrument(name string, kit *Kit, voice string, pattern []int) (*Instrument, error) {
+	audioManager, err := kit.newAudio(voice)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Instrument{
+		Name:    name,
+		Voice:   voice,
+		Pattern: pattern,
+		Audio:   audioManager,
+	}, nil
+}
+
+// SetKit plays the track's kit instruments on the given kit instead. Each
+// instrument's volume, pan, sample settings and effects are reset to the new
+// kit's, but its choke group and sends are kept. Instruments with their own
+// sample file or synth are unchanged. Returns an error, leaving the track
+// unchanged, if the kit is missing a voice the track needs or a voice can't be
+// created.
+func (t *Track) SetKit(kit *Kit) error {
+	if kit == nil {
+		return errors.New("kit must not be nil")
+	}
+
+	audioManagers := make([]audio.Manager, len(t.Instruments))
+
+	for i, instrument := range t.Instruments {
+		if instrument.Voice == "" {
+			continue
+		}
+
+		audioManager, err := kit.newAudio(instrument.Voice)
+		if err != nil {
+			return errors.Wrap(err, fmt.Sprintf("error swapping %s to kit %s", instrument.Name, kit.Name))
+		}
+
+		if instrument.Audio != nil {
+			if err := audioManager.SetChokeGroup(instrument.Audio.GetChokeGroup()); err != nil {
+				return errors.Wrap(err, fmt.Sprintf("error keeping %s choke group", instrument.Name))
+			}
+
+			if err := audioManager.SetSends(instrument.Audio.GetSends()); err != nil {
+				return errors.Wrap(err, fmt.Sprintf("error keeping %s sends", instrument.Name))
+			}
+		}
+
+		audioManagers[i] = audioManager
+	}
+
+	for i, instrument := range t.Instruments {
+		if audioManagers[i] != nil {
+			instrument.Audio = audioManagers[i]
+		}
+	}
+
+	t.Kit = kit
+
+	return nil
+}
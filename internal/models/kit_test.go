@@ -0,0 +1,157 @@
+package models_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jcfox412/logarhythms/internal/audio"
+	"github.com/jcfox412/logarhythms/internal/models"
+)
+
+func synthVoice(t *testing.T, synth string, volume, pan float64) models.Voice {
+	params, err := audio.DefaultSynthParams(synth)
+	assert.Nil(t, err)
+
+	return models.Voice{Synth: synth, SynthParams: params, Volume: volume, Pan: pan}
+}
+
+func TestNewKit(t *testing.T) {
+	type testCase struct {
+		description     string
+		input           map[string]models.Voice
+		expectedToError bool
+	}
+
+	testCases := []testCase{
+		{
+			description: "Successfully creates kit",
+			input: map[string]models.Voice{
+				"kick":  {Filename: "assets/sounds/kick.wav"},
+				"snare": {Synth: "snare"},
+			},
+			expectedToError: false,
+		},
+		{
+			description:     "Fails with no voices",
+			input:           map[string]models.Voice{},
+			expectedToError: true,
+		},
+		{
+			description: "Fails with voice without a sound",
+			input: map[string]models.Voice{
+				"kick": {Volume: 50},
+			},
+			expectedToError: true,
+		},
+		{
+			description: "Fails with voice with both a filename and a synth",
+			input: map[string]models.Voice{
+				"kick": {Filename: "assets/sounds/kick.wav", Synth: "kick"},
+			},
+			expectedToError: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		actualOutput, actualErr := models.NewKit("Test", testCase.input)
+		if testCase.expectedToError {
+			assert.Nil(t, actualOutput)
+			assert.NotNil(t, actualErr)
+		} else {
+			assert.NotNil(t, actualOutput)
+			assert.Nil(t, actualErr)
+		}
+	}
+}
+
+func TestNewKitInstrument(t *testing.T) {
+	kit, err := models.NewKit("Test", map[string]models.Voice{
+		"kick": synthVoice(t, "kick", 70, -0.5),
+	})
+	assert.Nil(t, err)
+
+	instrument, err := models.NewKitInstrument("Kick", kit, "kick", []int{0})
+	assert.Nil(t, err)
+	assert.Equal(t, "kick", instrument.Voice)
+	assert.Equal(t, 70.0, instrument.Audio.GetVolume())
+	assert.Equal(t, -0.5, instrument.Audio.GetPan())
+
+	instrument, err = models.NewKitInstrument("Snare", kit, "snare", []int{0})
+	assert.Nil(t, instrument)
+	assert.NotNil(t, err)
+}
+
+func TestSetKit(t *testing.T) {
+	type testCase struct {
+		description     string
+		input           map[string]models.Voice
+		expectedVolume  float64
+		expectedToError bool
+	}
+
+	testCases := []testCase{
+		{
+			description: "Swaps kit instruments to new kit",
+			input: map[string]models.Voice{
+				"kick":  synthVoice(t, "kick", 80, 0),
+				"snare": synthVoice(t, "snare", 80, 0),
+			},
+			expectedVolume:  80,
+			expectedToError: false,
+		},
+		{
+			description: "Fails with kit missing a voice",
+			input: map[string]models.Voice{
+				"kick": synthVoice(t, "kick", 80, 0),
+			},
+			expectedVolume:  50,
+			expectedToError: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		originalKit, err := models.NewKit("Original", map[string]models.Voice{
+			"kick":  synthVoice(t, "kick", 50, 0),
+			"snare": synthVoice(t, "snare", 50, 0),
+		})
+		assert.Nil(t, err)
+
+		kick, err := models.NewKitInstrument("Kick", originalKit, "kick", []int{0})
+		assert.Nil(t, err)
+		assert.Nil(t, kick.Audio.SetChokeGroup(2))
+		assert.Nil(t, kick.Audio.SetSends(audio.Sends{Reverb: 0.4}))
+
+		snare, err := models.NewKitInstrument("Snare", originalKit, "snare", []int{1})
+		assert.Nil(t, err)
+
+		clap, err := models.NewSynthInstrument("Clap", "clap", synthVoice(t, "clap", 0, 0).SynthParams, []int{1})
+		assert.Nil(t, err)
+
+		track, err := models.NewTrack("Test", []*models.Instrument{kick, snare, clap}, 120, 4, 1)
+		assert.Nil(t, err)
+		track.Kit = originalKit
+
+		kit, err := models.NewKit("New", testCase.input)
+		assert.Nil(t, err)
+
+		actualErr := track.SetKit(kit)
+		if testCase.expectedToError {
+			assert.NotNil(t, actualErr)
+			assert.Equal(t, originalKit, track.Kit)
+		} else {
+			assert.Nil(t, actualErr)
+			assert.Equal(t, kit, track.Kit)
+		}
+
+		assert.Equal(t, testCase.expectedVolume, kick.Audio.GetVolume())
+		assert.Equal(t, testCase.expectedVolume, snare.Audio.GetVolume())
+		assert.Equal(t, 2, kick.Audio.GetChokeGroup())
+		assert.Equal(t, audio.Sends{Reverb: 0.4}, kick.Audio.GetSends())
+		assert.Equal(t, 50.0, clap.Audio.GetVolume())
+	}
+}
@@ -0,0 +1,113 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// LengthUnit is what a track's length is measured in.
+type LengthUnit string
+
+const (
+	// LengthBars plays the track for Count bars
+	LengthBars LengthUnit = "bars"
+	// LengthDuration plays the track for Duration, rounded up to the end of the
+	// bar
+	LengthDuration LengthUnit = "duration"
+	// LengthInfinite plays the track until it's stopped
+	LengthInfinite LengthUnit = "infinite"
+)
+
+// Length is how long a track plays for.
+type Length struct {
+	Unit LengthUnit
+	// Number of bars to play, used by LengthBars
+	Count int
+	// Minimum time to play for, used by LengthDuration
+	Duration time.Duration
+}
+
+// Bars returns a Length of the given number of bars.
+func Bars(count int) Length {
+	return Length{Unit: LengthBars, Count: count}
+}
+
+// Duration returns a Length of at least the given duration, rounded up to the
+// end of the bar.
+func Duration(duration time.Duration) Length {
+	return Length{Unit: LengthDuration, Duration: duration}
+}
+
+// Infinite returns a Length which plays until stopped.
+func Infinite() Length {
+	return Length{Unit: LengthInfinite}
+}
+
+// Validate checks that the length has a unit and a positive amount.
+func (l Length) Validate() error {
+	switch l.Unit {
+	case LengthBars:
+		if l.Count <= 0 {
+			return fmt.Errorf("number of %s must be greater than 0", l.Unit)
+		}
+	case LengthDuration:
+		if l.Duration <= 0 {
+			return errors.New("track duration must be greater than 0")
+		}
+	case LengthInfinite:
+	default:
+		return fmt.Errorf("unknown track length unit %q", l.Unit)
+	}
+
+	return nil
+}
+
+// String describes the length.
+func (l Length) String() string {
+	switch l.Unit {
+	case LengthBars:
+		return fmt.Sprintf("%d %s", l.Count, l.Unit)
+	case LengthDuration:
+		return fmt.Sprintf("%v, rounded up to the bar", l.Duration)
+	case LengthInfinite:
+		return "until stopped"
+	default:
+		return string(l.Unit)
+	}
+}
+
+// steps returns how many beat subdivisions a track with the given bar length
+// plays for, each step lasting stepDuration. Returns 0 if the track plays until
+// stopped.
+func (l Length) steps(stepsPerBar int, stepDuration time.Duration) int {
+	switch l.Unit {
+	case LengthBars:
+		return l.Count * stepsPerBar
+	case LengthDuration:
+		barDuration := time.Duration(stepsPerBar) * stepDuration
+		bars := (l.Duration + barDuration - 1) / barDuration
+
+		return int(bars) * stepsPerBar
+	default:
+		return 0
+	}
+}
+
+// retimed returns how many beat subdivisions a track plays for in all, out of
+// totalSteps, once its steps start lasting stepDuration, having played played
+// steps lasting elapsed. Only a duration changes with the tempo, its time left
+// being played at the new tempo and rounded up to the end of the bar.
+func (l Length) retimed(totalSteps, stepsPerBar, played int, elapsed, stepDuration time.Duration) int {
+	if l.Unit != LengthDuration {
+		return totalSteps
+	}
+
+	steps := played
+	if left := l.Duration - elapsed; left > 0 {
+		steps += int((left + stepDuration - 1) / stepDuration)
+	}
+
+	return (steps + stepsPerBar - 1) / stepsPerBar * stepsPerBar
+}
@@ -0,0 +1,144 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLengthValidate(t *testing.T) {
+	type testCase struct {
+		description     string
+		input           Length
+		expectedToError bool
+	}
+
+	testCases := []testCase{
+		{
+			description:     "Validates bars",
+			input:           Bars(4),
+			expectedToError: false,
+		},
+		{
+			description:     "Validates until stopped",
+			input:           Infinite(),
+			expectedToError: false,
+		},
+		{
+			description:     "Errors with no bars",
+			input:           Bars(0),
+			expectedToError: true,
+		},
+		{
+			description:     "Errors with negative duration",
+			input:           Duration(-time.Second),
+			expectedToError: true,
+		},
+		{
+			description:     "Errors with no unit",
+			input:           Length{},
+			expectedToError: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		actualErr := testCase.input.Validate()
+		if testCase.expectedToError {
+			assert.NotNil(t, actualErr, testCase.description)
+		} else {
+			assert.Nil(t, actualErr, testCase.description)
+		}
+	}
+}
+
+func TestLengthSteps(t *testing.T) {
+	type testCase struct {
+		description    string
+		input          Length
+		expectedOutput int
+	}
+
+	// bars of 8 steps, and steps of 250ms, so each bar is 2 seconds long
+	testCases := []testCase{
+		{
+			description:    "Counts bars",
+			input:          Bars(3),
+			expectedOutput: 24,
+		},
+		{
+			description:    "Rounds a duration up to the end of the bar",
+			input:          Duration(4100 * time.Millisecond),
+			expectedOutput: 24,
+		},
+		{
+			description:    "Keeps a duration which ends on a bar",
+			input:          Duration(4 * time.Second),
+			expectedOutput: 16,
+		},
+		{
+			description:    "Plays until stopped",
+			input:          Infinite(),
+			expectedOutput: 0,
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		actualOutput := testCase.input.steps(8, 250*time.Millisecond)
+		assert.Equal(t, testCase.expectedOutput, actualOutput, testCase.description)
+	}
+}
+
+func TestLengthRetimed(t *testing.T) {
+	type testCase struct {
+		description    string
+		input          Length
+		stepDuration   time.Duration
+		expectedOutput int
+	}
+
+	// bars of 8 steps, retimed after a bar of steps of 250ms, out of 24 steps
+	testCases := []testCase{
+		{
+			description:    "Keeps the bars to play",
+			input:          Bars(3),
+			stepDuration:   125 * time.Millisecond,
+			expectedOutput: 24,
+		},
+		{
+			description:    "Plays the time left of a duration at the new tempo",
+			input:          Duration(4 * time.Second),
+			stepDuration:   125 * time.Millisecond,
+			expectedOutput: 24,
+		},
+		{
+			description:    "Rounds the time left up to the end of the bar",
+			input:          Duration(3 * time.Second),
+			stepDuration:   300 * time.Millisecond,
+			expectedOutput: 16,
+		},
+		{
+			description:    "Ends at the bar once a duration has been played",
+			input:          Duration(time.Second),
+			stepDuration:   125 * time.Millisecond,
+			expectedOutput: 8,
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		actualOutput := testCase.input.retimed(24, 8, 8, 2*time.Second, testCase.stepDuration)
+		assert.Equal(t, testCase.expectedOutput, actualOutput, testCase.description)
+	}
+}
+
+func TestLengthString(t *testing.T) {
+	assert.Equal(t, "8 bars", Bars(8).String())
+	assert.Equal(t, "1m30s, rounded up to the bar", Duration(90*time.Second).String())
+	assert.Equal(t, "until stopped", Infinite().String())
+}
@@ -0,0 +1,97 @@
+package models
+
+import (
+	"math"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/jcfox412/logarhythms/internal/audio"
+	"github.com/jcfox412/logarhythms/internal/clock"
+	"github.com/jcfox412/logarhythms/internal/midi"
+)
+
+// MIDINote is the MIDI note an instrument's hits are sent as, with the
+// velocity of each hit.
+type MIDINote struct {
+	// Channel from 1 to 16, e.g. 10 for General MIDI drums
+	Channel int
+	// Note number from 0 to 127, e.g. 36 for a General MIDI bass drum
+	Note int
+	// Time each note is held before it's released, or 0 to hold it for half a
+	// beat subdivision, so it's released before the next subdivision plays
+	Length time.Duration
+}
+
+func (n *MIDINote) validate() error {
+	if err := midi.ValidateNote(n.Channel, n.Note); err != nil {
+		return errors.Wrap(err, "error validating MIDI note")
+	}
+
+	if n.Length < 0 {
+		return errors.New("MIDI note length must not be negative")
+	}
+
+	return nil
+}
+
+// output is where an instrument's hits are played, fixed as each beat
+// subdivision is triggered so that hits played later don't race with changes
+// to the instrument.
+type output struct {
+	clock clock.Clock
+	// Audio the hits are played on, or nil if the instrument's audio is off
+	audio audio.Manager
+	// Port the hits are sent to as notes, or nil if none are sent
+	port midi.Port
+	note MIDINote
+}
+
+// outputOf returns where the instrument's hits are played, as it's set now,
+// sending its notes to port, if not nil. Notes without a length are released
+// halfway to the next hit, hits being spacing apart, so that a synth never
+// has a note struck again before it's released.
+func outputOf(c clock.Clock, port midi.Port, instrument *Instrument, spacing time.Duration) output {
+	o := output{clock: c}
+
+	if !instrument.AudioOff {
+		o.audio = instrument.Audio
+	}
+
+	if port != nil && instrument.MIDI != nil && !instrument.MIDIOff {
+		o.port = port
+		o.note = *instrument.MIDI
+
+		if o.note.Length == 0 {
+			o.note.Length = spacing / 2
+		}
+	}
+
+	return o
+}
+
+// play plays a hit, sending it as a note which is released once the note's
+// length has passed. Notes which can't be sent are dropped, rather than
+// stopping the track.
+func (o output) play(velocity float64) {
+	if o.audio != nil {
+		o.audio.Play(velocity)
+	}
+
+	if o.port == nil {
+		return
+	}
+
+	note := o.note
+	_ = o.port.Send(midi.NoteOn(note.Channel, note.Note, midiVelocity(velocity)))
+
+	o.clock.AfterFunc(note.Length, func() {
+		_ = o.port.Send(midi.NoteOff(note.Channel, note.Note))
+	})
+}
+
+// midiVelocity converts a velocity from 0 to 1 to a MIDI velocity from 1 to
+// 127, as a velocity of 0 would release the note instead.
+func midiVelocity(velocity float64) int {
+	return int(math.Max(1, math.Min(127, math.Round(velocity*127))))
+}
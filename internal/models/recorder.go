@@ -0,0 +1,362 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/jcfox412/logarhythms/internal/audio"
+	"github.com/jcfox412/logarhythms/internal/clock"
+	"github.com/jcfox412/logarhythms/internal/render"
+)
+
+// Velocities the metronome clicks at, on the first beat of the bar and on
+// every other beat
+const (
+	metronomeAccent = 1.0
+	metronomeBeat   = 0.5
+)
+
+// RecordMode is how hits recorded into an instrument's pattern combine with
+// the pattern already there.
+type RecordMode int
+
+const (
+	// Overdub adds recorded hits to the pattern.
+	Overdub RecordMode = iota
+	// Replace clears an instrument's pattern on its first hit of each pass,
+	// so that the pass's hits take its place.
+	Replace
+)
+
+var recordModeNames = map[RecordMode]string{
+	Overdub: "overdub",
+	Replace: "replace",
+}
+
+// String returns the name of the record mode.
+func (m RecordMode) String() string {
+	if name, ok := recordModeNames[m]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("RecordMode(%d)", int(m))
+}
+
+// ParseRecordMode returns the record mode with the given name.
+func ParseRecordMode(name string) (RecordMode, error) {
+	for mode, modeName := range recordModeNames {
+		if modeName == name {
+			return mode, nil
+		}
+	}
+
+	return Overdub, fmt.Errorf("unknown record mode %q", name)
+}
+
+// RecordSettings are how a Recorder writes hits into a track.
+type RecordSettings struct {
+	Mode RecordMode
+	// How far hits are moved onto their nearest beat subdivision, from 0 to
+	// keep them where they were played to 1 to move them onto it
+	Strength float64
+	// Time between a hit being played and it reaching the recorder, such as
+	// the delay of the audio output, taken off every hit
+	Latency time.Duration
+	// Click played on each beat while recording, accented on the first beat of
+	// the bar, or nil to play none
+	Metronome audio.Manager
+}
+
+// Validate returns an error if the settings are out of range.
+func (s RecordSettings) Validate() error {
+	if _, ok := recordModeNames[s.Mode]; !ok {
+		return fmt.Errorf("unknown record mode %d", int(s.Mode))
+	}
+
+	if s.Strength < 0 || s.Strength > 1 {
+		return errors.New("quantise strength must be between 0 and 1")
+	}
+
+	if s.Latency < 0 {
+		return errors.New("record latency must not be negative")
+	}
+
+	return nil
+}
+
+// Recorder writes hits played live into the patterns of a playing track,
+// quantised to its beat subdivisions. It follows the track as one of its
+// renderers, and each bar played is a pass which can be undone.
+type Recorder struct {
+	track *Track
+	clock clock.Clock
+
+	mu        sync.Mutex
+	settings  RecordSettings
+	recording bool
+	// Whether the track is playing, and the time, bar and position in the bar
+	// of the beat subdivision playing
+	playing  bool
+	stepTime time.Time
+	bar      int
+	division int
+	// Timing of the track playing
+	stepDuration     time.Duration
+	stepsPerBar      int
+	divisionsPerBeat int
+	// Bar the last hit was recorded in, and the instruments whose patterns
+	// have been replaced in it
+	recordedBar int
+	replaced    map[int]bool
+	// Patterns from before each pass that recorded hits, the last pass last
+	undo [][]instrumentPattern
+}
+
+// instrumentPattern is an instrument's pattern as it was before a pass.
+type instrumentPattern struct {
+	pattern       []int
+	articulations map[int]Articulation
+}
+
+// NewRecorder returns a recorder of hits into the track, which is timed by
+// the clock. A nil clock follows real time. The recorder must be one of the
+// track's renderers while it plays. Returns an error if the settings are
+// invalid.
+func NewRecorder(track *Track, c clock.Clock, settings RecordSettings) (*Recorder, error) {
+	if err := settings.Validate(); err != nil {
+		return nil, errors.Wrap(err, "error validating record settings")
+	}
+
+	if c == nil {
+		c = clock.New()
+	}
+
+	return &Recorder{track: track, clock: c, settings: settings}, nil
+}
+
+// Recording returns whether hits are being recorded.
+func (r *Recorder) Recording() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.recording
+}
+
+// SetRecording starts or stops recording hits. Hits played while not recording
+// are heard but not written.
+func (r *Recorder) SetRecording(recording bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.recording = recording
+	r.recordedBar = 0
+}
+
+// Mode returns how recorded hits combine with the patterns already there.
+func (r *Recorder) Mode() RecordMode {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.settings.Mode
+}
+
+// SetMode changes how recorded hits combine with the patterns already there,
+// from the next pass.
+func (r *Recorder) SetMode(mode RecordMode) error {
+	if _, ok := recordModeNames[mode]; !ok {
+		return fmt.Errorf("unknown record mode %d", int(mode))
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.settings.Mode = mode
+	r.recordedBar = 0
+
+	return nil
+}
+
+// Hit plays the instrument at the given index in the track, on its audio and
+// as its MIDI note, recording it at the nearest beat subdivision if recording.
+// Hits played while the track isn't playing are only heard.
+func (r *Recorder) Hit(instrument int) error {
+	now := r.clock.Now()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.track.Update(func(t *Track) error {
+		if instrument < 0 || instrument >= len(t.Instruments) {
+			return fmt.Errorf("no instrument at index %d", instrument)
+		}
+
+		stepDuration := r.stepDuration
+		if !r.playing {
+			var err error
+			if stepDuration, err = t.calculateBeatDuration(); err != nil {
+				return errors.Wrap(err, "error calculating beat duration")
+			}
+		}
+
+		outputOf(r.clock, t.MIDI, t.Instruments[instrument], stepDuration).play(defaultVelocity)
+
+		if !r.playing || !r.recording {
+			return nil
+		}
+
+		return r.record(t, instrument, now)
+	})
+}
+
+// record writes a hit played at the given time into the instrument's pattern.
+// Must be called with both the recorder and track locked.
+func (r *Recorder) record(t *Track, instrument int, played time.Time) error {
+	// the subdivisions played between the pass's first subdivision and the
+	// hit, which is negative if the hit landed before it
+	position := float64(r.division) + float64(played.Sub(r.stepTime)-r.settings.Latency)/float64(r.stepDuration)
+	nearest := math.Round(position)
+	offset := (position - nearest) * (1 - r.settings.Strength)
+
+	step := int(nearest) % r.stepsPerBar
+	if step < 0 {
+		step += r.stepsPerBar
+	}
+
+	if r.recordedBar != r.bar {
+		r.recordedBar = r.bar
+		r.replaced = map[int]bool{}
+		r.undo = append(r.undo, patternsOf(t))
+	}
+
+	i := t.Instruments[instrument]
+
+	if r.settings.Mode == Replace && !r.replaced[instrument] {
+		r.replaced[instrument] = true
+		i.Pattern = nil
+		i.Articulations = nil
+	}
+
+	articulation := i.articulation(step)
+	if articulation.Offset != offset {
+		articulation.Offset = offset
+
+		articulations := make(map[int]Articulation, len(i.Articulations)+1)
+		for s, a := range i.Articulations {
+			articulations[s] = a
+		}
+
+		articulations[step] = articulation
+		i.Articulations = articulations
+	}
+
+	return t.SetStep(instrument, step, true)
+}
+
+// Undo restores the patterns from before the last pass that recorded hits.
+// Returns an error if there's no pass to undo.
+func (r *Recorder) Undo() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.undo) == 0 {
+		return errors.New("no recorded pass to undo")
+	}
+
+	patterns := r.undo[len(r.undo)-1]
+	r.undo = r.undo[:len(r.undo)-1]
+	r.recordedBar = 0
+
+	return r.track.Update(func(t *Track) error {
+		if len(patterns) != len(t.Instruments) {
+			return errors.New("track's instruments have changed since the pass was recorded")
+		}
+
+		for n, i := range t.Instruments {
+			i.Pattern = patterns[n].pattern
+			i.Articulations = patterns[n].articulations
+		}
+
+		t.Patterns = makePattern(t.BeatsPerMeasure*t.DivisionsPerBeat, t.Instruments)
+
+		return nil
+	})
+}
+
+// Start times the hits played from the start of the track, whose pattern is a
+// bar long.
+func (r *Recorder) Start(track render.Track) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.stepDuration = track.StepDuration
+	r.stepsPerBar = len(track.Pattern)
+	r.divisionsPerBeat = track.DivisionsPerBeat
+	r.recordedBar = 0
+}
+
+// Step times the hits played during the beat subdivision, clicking the
+// metronome on each beat while recording.
+func (r *Recorder) Step(step render.Step) {
+	now := r.clock.Now()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.playing = true
+	r.stepTime = now
+	r.bar = step.Bar
+	r.division = step.Division
+
+	if !r.recording || r.settings.Metronome == nil || r.divisionsPerBeat <= 0 || step.Division%r.divisionsPerBeat != 0 {
+		return
+	}
+
+	if step.Division == 0 {
+		r.settings.Metronome.Play(metronomeAccent)
+	} else {
+		r.settings.Metronome.Play(metronomeBeat)
+	}
+}
+
+// Retime times the hits played from the next beat subdivision at the track's
+// new tempo.
+func (r *Recorder) Retime(beatsPerMinute float64, stepDuration time.Duration, totalSteps int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.stepDuration = stepDuration
+}
+
+// Clip does nothing, as clipping doesn't change what's recorded.
+func (r *Recorder) Clip() {}
+
+// Stop stops recording hits into the track, which are only heard until it
+// plays again.
+func (r *Recorder) Stop() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.playing = false
+}
+
+// patternsOf returns a copy of the patterns of each of the track's
+// instruments.
+func patternsOf(t *Track) []instrumentPattern {
+	patterns := make([]instrumentPattern, len(t.Instruments))
+	for n, i := range t.Instruments {
+		patterns[n].pattern = append([]int(nil), i.Pattern...)
+
+		if i.Articulations != nil {
+			patterns[n].articulations = make(map[int]Articulation, len(i.Articulations))
+			for step, articulation := range i.Articulations {
+				patterns[n].articulations[step] = articulation
+			}
+		}
+	}
+
+	return patterns
+}
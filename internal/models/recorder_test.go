@@ -0,0 +1,289 @@
+package models_test
+
+import (
+	"testing"
+	"time"
+
+	audiomocks "github.com/jcfox412/logarhythms/internal/audio/mocks"
+	"github.com/jcfox412/logarhythms/internal/clock"
+	"github.com/jcfox412/logarhythms/internal/midi"
+	"github.com/jcfox412/logarhythms/internal/models"
+	"github.com/jcfox412/logarhythms/internal/render"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestNewRecorder(t *testing.T) {
+	type testCase struct {
+		description     string
+		input           models.RecordSettings
+		expectedToError bool
+	}
+
+	testCases := []testCase{
+		{
+			description:     "Succeeds with valid settings",
+			input:           models.RecordSettings{Mode: models.Replace, Strength: 0.5, Latency: 20 * time.Millisecond},
+			expectedToError: false,
+		},
+		{
+			description:     "Errors with unknown mode",
+			input:           models.RecordSettings{Mode: models.RecordMode(42)},
+			expectedToError: true,
+		},
+		{
+			description:     "Errors with strength above 1",
+			input:           models.RecordSettings{Strength: 1.5},
+			expectedToError: true,
+		},
+		{
+			description:     "Errors with negative latency",
+			input:           models.RecordSettings{Strength: 1, Latency: -time.Millisecond},
+			expectedToError: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		_, actualErr := models.NewRecorder(&models.Track{}, nil, testCase.input)
+		assert.Equal(t, testCase.expectedToError, actualErr != nil, testCase.description)
+	}
+}
+
+func TestParseRecordMode(t *testing.T) {
+	type testCase struct {
+		description     string
+		input           string
+		expectedOutput  models.RecordMode
+		expectedToError bool
+	}
+
+	testCases := []testCase{
+		{
+			description:     "Parses overdub",
+			input:           "overdub",
+			expectedOutput:  models.Overdub,
+			expectedToError: false,
+		},
+		{
+			description:     "Parses replace",
+			input:           "replace",
+			expectedOutput:  models.Replace,
+			expectedToError: false,
+		},
+		{
+			description:     "Errors on unknown mode",
+			input:           "punch",
+			expectedToError: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		actualOutput, actualErr := models.ParseRecordMode(testCase.input)
+		if testCase.expectedToError {
+			assert.NotNil(t, actualErr, testCase.description)
+		} else {
+			assert.Nil(t, actualErr, testCase.description)
+			assert.Equal(t, testCase.expectedOutput, actualOutput, testCase.description)
+		}
+	}
+}
+
+func TestRecorderHit(t *testing.T) {
+	type input struct {
+		settings  models.RecordSettings
+		recording bool
+		// Position in the bar of the beat subdivision playing, and the time
+		// after it the hit is played
+		division int
+		played   time.Duration
+	}
+
+	type testCase struct {
+		description     string
+		input           input
+		expectedPattern []int
+		expectedOffset  float64
+	}
+
+	// 4 subdivisions of 250ms to the bar, the snare playing on the third
+	testCases := []testCase{
+		{
+			description:     "Quantises a late hit back onto its subdivision",
+			input:           input{settings: models.RecordSettings{Strength: 1}, recording: true, division: 1, played: 50 * time.Millisecond},
+			expectedPattern: []int{1, 2},
+			expectedOffset:  0,
+		},
+		{
+			description:     "Quantises an early hit forward onto its subdivision",
+			input:           input{settings: models.RecordSettings{Strength: 1}, recording: true, division: 0, played: 200 * time.Millisecond},
+			expectedPattern: []int{1, 2},
+			expectedOffset:  0,
+		},
+		{
+			description:     "Keeps some of the hit's timing below full strength",
+			input:           input{settings: models.RecordSettings{Strength: 0.5}, recording: true, division: 1, played: 50 * time.Millisecond},
+			expectedPattern: []int{1, 2},
+			expectedOffset:  0.1,
+		},
+		{
+			description:     "Keeps all of the hit's timing at no strength",
+			input:           input{settings: models.RecordSettings{Strength: 0}, recording: true, division: 0, played: 200 * time.Millisecond},
+			expectedPattern: []int{1, 2},
+			expectedOffset:  -0.2,
+		},
+		{
+			description:     "Takes the latency off the hit",
+			input:           input{settings: models.RecordSettings{Strength: 1, Latency: 200 * time.Millisecond}, recording: true, division: 1, played: 0},
+			expectedPattern: []int{0, 2},
+			expectedOffset:  0,
+		},
+		{
+			description:     "Wraps a hit at the end of the bar onto its first subdivision",
+			input:           input{settings: models.RecordSettings{Strength: 1}, recording: true, division: 3, played: 200 * time.Millisecond},
+			expectedPattern: []int{0, 2},
+			expectedOffset:  0,
+		},
+		{
+			description:     "Replaces the pattern with the pass's hits",
+			input:           input{settings: models.RecordSettings{Mode: models.Replace, Strength: 1}, recording: true, division: 1, played: 0},
+			expectedPattern: []int{1},
+			expectedOffset:  0,
+		},
+		{
+			description:     "Only plays the hit while not recording",
+			input:           input{settings: models.RecordSettings{Strength: 1}, recording: false, division: 1, played: 0},
+			expectedPattern: []int{2},
+			expectedOffset:  0,
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		m := &audiomocks.Manager{}
+		m.On("Play", 1.0).Return().Once()
+
+		kick := &models.Instrument{Name: "Kick", Pattern: []int{0}, Audio: &audiomocks.Manager{}}
+		snare := &models.Instrument{Name: "Snare", Pattern: []int{2}, Audio: m}
+
+		track, err := models.NewTrack("testTitle", []*models.Instrument{kick, snare}, 120, 2, 2)
+		assert.Nil(t, err)
+
+		c := clock.NewManual(time.Unix(0, 0))
+
+		recorder, err := models.NewRecorder(track, c, testCase.input.settings)
+		assert.Nil(t, err)
+
+		recorder.SetRecording(testCase.input.recording)
+		recorder.Start(render.Track{Pattern: make([]render.Step, 4), DivisionsPerBeat: 2, StepDuration: 250 * time.Millisecond})
+		recorder.Step(render.Step{Bar: 1, Division: testCase.input.division})
+		c.Advance(testCase.input.played)
+
+		assert.Nil(t, recorder.Hit(1), testCase.description)
+		assert.Equal(t, testCase.expectedPattern, snare.Pattern, testCase.description)
+		assert.Equal(t, []int{0}, kick.Pattern, testCase.description)
+
+		for step, instruments := range track.Patterns {
+			assert.Equal(t, contains(snare.Pattern, step), instruments[1] == snare, testCase.description)
+		}
+
+		offset := 0.0
+		if articulation, ok := snare.Articulations[testCase.expectedPattern[0]]; ok {
+			offset = articulation.Offset
+		}
+
+		assert.InDelta(t, testCase.expectedOffset, offset, 1e-9, testCase.description)
+		m.AssertExpectations(t)
+	}
+}
+
+func TestRecorderHitMIDI(t *testing.T) {
+	m := &audiomocks.Manager{}
+	m.On("Play", 1.0).Return().Once()
+
+	snare := &models.Instrument{Name: "Snare", Audio: m, MIDI: &models.MIDINote{Channel: 10, Note: 38}}
+
+	track, err := models.NewTrack("testTitle", []*models.Instrument{snare}, 120, 2, 2)
+	assert.Nil(t, err)
+
+	port := midi.NewMemory()
+	track.MIDI = port
+
+	c := clock.NewManual(time.Unix(0, 0))
+
+	recorder, err := models.NewRecorder(track, c, models.RecordSettings{Strength: 1})
+	assert.Nil(t, err)
+
+	// the note is released halfway through a 250ms beat subdivision
+	assert.Nil(t, recorder.Hit(0))
+	assert.Equal(t, [][]byte{{0x99, 38, 127}}, port.Sent())
+
+	c.Advance(125 * time.Millisecond)
+	assert.Equal(t, [][]byte{{0x99, 38, 127}, {0x89, 38, 0}}, port.Sent())
+
+	m.AssertExpectations(t)
+}
+
+func TestRecorderUndo(t *testing.T) {
+	m := &audiomocks.Manager{}
+	m.On("Play", 1.0).Return()
+
+	snare := &models.Instrument{Name: "Snare", Pattern: []int{2}, Audio: m}
+
+	track, err := models.NewTrack("testTitle", []*models.Instrument{snare}, 120, 2, 2)
+	assert.Nil(t, err)
+
+	recorder, err := models.NewRecorder(track, clock.NewManual(time.Unix(0, 0)), models.RecordSettings{Mode: models.Replace, Strength: 1})
+	assert.Nil(t, err)
+
+	recorder.SetRecording(true)
+	recorder.Start(render.Track{Pattern: make([]render.Step, 4), DivisionsPerBeat: 2, StepDuration: 250 * time.Millisecond})
+
+	// each pass replaces the snare's pattern with a single hit
+	recorder.Step(render.Step{Bar: 1, Division: 0})
+	assert.Nil(t, recorder.Hit(0))
+	recorder.Step(render.Step{Bar: 2, Division: 3})
+	assert.Nil(t, recorder.Hit(0))
+	assert.Equal(t, []int{3}, snare.Pattern)
+
+	assert.Nil(t, recorder.Undo())
+	assert.Equal(t, []int{0}, snare.Pattern)
+	assert.Equal(t, snare, track.Patterns[0][0])
+
+	assert.Nil(t, recorder.Undo())
+	assert.Equal(t, []int{2}, snare.Pattern)
+	assert.Nil(t, track.Patterns[0][0])
+
+	assert.NotNil(t, recorder.Undo())
+}
+
+func TestRecorderMetronome(t *testing.T) {
+	metronome := &audiomocks.Manager{}
+	metronome.On("Play", mock.AnythingOfType("float64")).Return()
+
+	track, err := models.NewTrack("testTitle", []*models.Instrument{{Name: "Snare", Audio: &audiomocks.Manager{}}}, 120, 2, 2)
+	assert.Nil(t, err)
+
+	recorder, err := models.NewRecorder(track, nil, models.RecordSettings{Strength: 1, Metronome: metronome})
+	assert.Nil(t, err)
+
+	recorder.Start(render.Track{Pattern: make([]render.Step, 4), DivisionsPerBeat: 2, StepDuration: 250 * time.Millisecond})
+
+	// the metronome only clicks while recording
+	recorder.Step(render.Step{Bar: 1, Division: 0})
+	recorder.SetRecording(true)
+
+	for division := 1; division < 4; division++ {
+		recorder.Step(render.Step{Bar: 1, Division: division})
+	}
+
+	recorder.Step(render.Step{Bar: 2, Division: 0})
+
+	metronome.AssertNumberOfCalls(t, "Play", 2)
+	metronome.AssertCalled(t, "Play", 0.5)
+	metronome.AssertCalled(t, "Play", 1.0)
+}
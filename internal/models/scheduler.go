@@ -0,0 +1,137 @@
+package models
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/jcfox412/logarhythms/internal/clock"
+	"github.com/jcfox412/logarhythms/internal/midi"
+)
+
+// scheduler plays instrument hits at their offsets within a beat subdivision,
+// applying each instrument's humanization using a seeded random number
+// generator.
+type scheduler struct {
+	clock clock.Clock
+	rng   *rand.Rand
+	// Duration of a single beat subdivision
+	stepDuration time.Duration
+	// Delay applied to every hit, so that grace notes and humanized hits can
+	// land ahead of the beat subdivision which triggered them
+	lookahead time.Duration
+	// Port instruments' MIDI notes are sent to, or nil to send none
+	midi midi.Port
+
+	mu sync.Mutex
+	// Delayed hits yet to be played, by the order they were scheduled in
+	pending map[int]clock.Timer
+	next    int
+}
+
+func newScheduler(c clock.Clock, seed int64, stepDuration time.Duration, instruments []*Instrument, port midi.Port) *scheduler {
+	s := &scheduler{
+		clock:   c,
+		rng:     rand.New(rand.NewSource(seed)),
+		midi:    port,
+		pending: map[int]clock.Timer{},
+	}
+
+	s.retime(stepDuration, instruments)
+
+	return s
+}
+
+// retime schedules hits from now on with beat subdivisions lasting
+// stepDuration, delaying them enough for the instruments' earliest hits.
+func (s *scheduler) retime(stepDuration time.Duration, instruments []*Instrument) {
+	s.stepDuration = stepDuration
+	s.prepare(instruments)
+}
+
+// prepare delays hits from now on enough for the instruments' earliest hits,
+// as their patterns are set now, so that grace notes and humanization added
+// while the track plays can still land ahead of the beat.
+func (s *scheduler) prepare(instruments []*Instrument) {
+	lookahead := time.Duration(0)
+
+	for _, instrument := range instruments {
+		if lead := instrument.lead(s.stepDuration); lead > lookahead {
+			lookahead = lead
+		}
+	}
+
+	s.lookahead = lookahead
+}
+
+// trigger plays each hit of the articulation on the instrument's audio, and
+// sends it as the instrument's MIDI note. The articulation is humanized as a
+// whole, so that its hits keep their spacing. Hits which don't need delaying
+// are played immediately.
+func (s *scheduler) trigger(instrument *Instrument, articulation Articulation) {
+	offset, velocity := instrument.Humanize.apply(s.rng, s.stepDuration, articulation.Velocity)
+	articulation.Velocity = velocity
+
+	hits := articulation.hits(s.stepDuration)
+	output := outputOf(s.clock, s.midi, instrument, spacing(hits, s.stepDuration))
+
+	for _, hit := range hits {
+		hit := hit
+
+		delay := s.lookahead + offset + hit.offset
+		if delay <= 0 {
+			output.play(hit.velocity)
+			continue
+		}
+
+		s.after(delay, func() {
+			output.play(hit.velocity)
+		})
+	}
+}
+
+// after calls play once delay has passed, unless the scheduler is stopped
+// first.
+func (s *scheduler) after(delay time.Duration, play func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := s.next
+	s.next++
+
+	s.pending[id] = s.clock.AfterFunc(delay, func() {
+		s.mu.Lock()
+		_, ok := s.pending[id]
+		delete(s.pending, id)
+		s.mu.Unlock()
+
+		if ok {
+			play()
+		}
+	})
+}
+
+// stop drops every delayed hit yet to be played, so that none sound once the
+// track has stopped. Notes already struck are still released.
+func (s *scheduler) stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, timer := range s.pending {
+		timer.Stop()
+		delete(s.pending, id)
+	}
+}
+
+// spacing returns the shortest time between the hits, in the order they're
+// played, or stepDuration if there's only one.
+func spacing(hits []hit, stepDuration time.Duration) time.Duration {
+	shortest := stepDuration
+	for i := 1; i < len(hits); i++ {
+		if gap := hits[i].offset - hits[i-1].offset; gap < shortest {
+			shortest = gap
+		}
+	}
+
+	return shortest
+}
@@ -0,0 +1,192 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	audiomocks "github.com/jcfox412/logarhythms/internal/audio/mocks"
+	"github.com/jcfox412/logarhythms/internal/clock"
+	"github.com/jcfox412/logarhythms/internal/midi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestNewScheduler(t *testing.T) {
+	type testCase struct {
+		description       string
+		input             []*Instrument
+		expectedLookahead time.Duration
+	}
+
+	testCases := []testCase{
+		{
+			description:       "Has no lookahead for plain instruments",
+			input:             []*Instrument{{}},
+			expectedLookahead: 0,
+		},
+		{
+			description: "Looks ahead by the earliest grace note",
+			input: []*Instrument{
+				{Articulations: map[int]Articulation{0: {Velocity: 1, Ornament: Flam}}},
+				{Articulations: map[int]Articulation{2: {Velocity: 1, Ornament: Drag}}},
+			},
+			expectedLookahead: 2 * dragSpacing,
+		},
+		{
+			description: "Looks ahead by the earliest offset hit",
+			input: []*Instrument{
+				{Articulations: map[int]Articulation{0: {Velocity: 1, Offset: -0.1}}},
+				{Articulations: map[int]Articulation{1: {Velocity: 1, Offset: 0.3}}},
+			},
+			expectedLookahead: 100 * time.Millisecond,
+		},
+		{
+			description: "Looks ahead by the earliest humanized hit",
+			input: []*Instrument{
+				{Humanize: &Humanize{Timing: 5 * time.Millisecond, AroundBeat: true}},
+				{Humanize: &Humanize{Timing: 20 * time.Millisecond}},
+				{Humanize: &Humanize{Timing: 10 * time.Millisecond, AroundBeat: true}},
+			},
+			expectedLookahead: 10 * time.Millisecond,
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		actualScheduler := newScheduler(clock.New(), 0, time.Second, testCase.input, nil)
+		assert.Equal(t, testCase.expectedLookahead, actualScheduler.lookahead)
+	}
+}
+
+func TestSchedulerTrigger(t *testing.T) {
+	type input struct {
+		instrument   *Instrument
+		articulation Articulation
+	}
+
+	type testCase struct {
+		description        string
+		input              input
+		expectedVelocities []float64
+		expectedMIDI       [][]byte
+	}
+
+	testCases := []testCase{
+		{
+			description: "Plays unhumanized hits immediately",
+			input: input{
+				instrument:   &Instrument{},
+				articulation: Articulation{Velocity: 0.5},
+			},
+			expectedVelocities: []float64{0.5},
+		},
+		{
+			description: "Plays humanized hits after a delay",
+			input: input{
+				instrument:   &Instrument{Humanize: &Humanize{Timing: time.Millisecond}},
+				articulation: Articulation{Velocity: 0.5},
+			},
+			expectedVelocities: []float64{0.5},
+		},
+		{
+			description: "Plays grace notes before the hit",
+			input: input{
+				instrument: &Instrument{
+					Articulations: map[int]Articulation{0: {Velocity: 0.8, Ornament: Flam}},
+				},
+				articulation: Articulation{Velocity: 0.8, Ornament: Flam},
+			},
+			expectedVelocities: []float64{0.4, 0.8},
+		},
+		{
+			description: "Sends hits as MIDI notes, released halfway through the beat subdivision",
+			input: input{
+				instrument:   &Instrument{MIDI: &MIDINote{Channel: 10, Note: 36}},
+				articulation: Articulation{Velocity: 0.5},
+			},
+			expectedVelocities: []float64{0.5},
+			expectedMIDI:       [][]byte{{0x99, 36, 64}, {0x89, 36, 0}},
+		},
+		{
+			description: "Releases each ratcheted note before it's struck again",
+			input: input{
+				instrument:   &Instrument{MIDI: &MIDINote{Channel: 10, Note: 38}},
+				articulation: Articulation{Velocity: 1, Repeat: 3},
+			},
+			expectedVelocities: []float64{1, 1, 1},
+			expectedMIDI: [][]byte{
+				{0x99, 38, 127}, {0x89, 38, 0},
+				{0x99, 38, 127}, {0x89, 38, 0},
+				{0x99, 38, 127}, {0x89, 38, 0},
+			},
+		},
+		{
+			description: "Sends only MIDI notes with audio turned off",
+			input: input{
+				instrument:   &Instrument{AudioOff: true, MIDI: &MIDINote{Channel: 1, Note: 38, Length: time.Millisecond}},
+				articulation: Articulation{Velocity: 1},
+			},
+			expectedVelocities: nil,
+			expectedMIDI:       [][]byte{{0x90, 38, 127}, {0x80, 38, 0}},
+		},
+		{
+			description: "Plays only audio with MIDI turned off",
+			input: input{
+				instrument:   &Instrument{MIDIOff: true, MIDI: &MIDINote{Channel: 10, Note: 36}},
+				articulation: Articulation{Velocity: 0.5},
+			},
+			expectedVelocities: []float64{0.5},
+			expectedMIDI:       nil,
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+		instrument := testCase.input.instrument
+
+		var played []float64
+
+		m := &audiomocks.Manager{}
+		if len(testCase.expectedVelocities) > 0 {
+			m.On("Play", mock.AnythingOfType("float64")).Run(func(args mock.Arguments) {
+				played = append(played, args.Get(0).(float64))
+			}).Return().Times(len(testCase.expectedVelocities))
+		}
+
+		instrument.Audio = m
+		port := midi.NewMemory()
+
+		c := clock.NewManual(time.Time{})
+		newScheduler(c, 0, 10*time.Millisecond, []*Instrument{instrument}, port).trigger(instrument, testCase.input.articulation)
+		c.Advance(time.Second)
+
+		assert.Equal(t, testCase.expectedVelocities, played, testCase.description)
+		assert.Equal(t, testCase.expectedMIDI, port.Sent(), testCase.description)
+
+		m.AssertExpectations(t)
+	}
+}
+
+func TestSchedulerPrepare(t *testing.T) {
+	m := &audiomocks.Manager{}
+	m.On("Play", mock.AnythingOfType("float64")).Return()
+
+	instrument := &Instrument{Pattern: []int{0}, Audio: m}
+	track := &Track{
+		DivisionsPerBeat: 1,
+		Instruments:      []*Instrument{instrument},
+		Patterns:         [][]*Instrument{{instrument}},
+	}
+
+	s := newScheduler(clock.NewManual(time.Time{}), 0, time.Second, track.Instruments, nil)
+	assert.Equal(t, time.Duration(0), s.lookahead)
+
+	// grace notes added while the track plays are looked ahead for from the
+	// next beat subdivision
+	instrument.Articulations = map[int]Articulation{0: {Velocity: 1, Ornament: Drag}}
+
+	_, err := track.triggerBeat(0, s)
+	assert.Nil(t, err)
+	assert.Equal(t, 2*dragSpacing, s.lookahead)
+}
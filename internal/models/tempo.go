@@ -0,0 +1,87 @@
+package models
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/jcfox412/logarhythms/internal/clock"
+)
+
+const (
+	// MinTaps is the fewest taps a tempo is worked out from.
+	MinTaps = 4
+	// TapTimeout is the longest pause between taps of the same tempo, after
+	// which tapping starts again.
+	TapTimeout = 2 * time.Second
+	// Most recent taps the tempo is worked out from
+	maxTaps = 16
+	// Furthest an interval between taps can be from the median interval, as a
+	// fraction of it, before it's thrown out
+	tapOutlierRatio = 0.25
+	// Fractions of a BPM the tapped tempo is rounded to, as taps can't be
+	// timed any finer
+	tapResolution = 10
+)
+
+// TapTempo works out a tempo from taps on each beat.
+type TapTempo struct {
+	clock clock.Clock
+	taps  []time.Time
+}
+
+// NewTapTempo returns a tap tempo whose taps are timed by the clock. A nil
+// clock follows real time.
+func NewTapTempo(c clock.Clock) *TapTempo {
+	if c == nil {
+		c = clock.New()
+	}
+
+	return &TapTempo{clock: c}
+}
+
+// Tap records a tap now, returning the tempo tapped in BPM once there have
+// been at least MinTaps. The tempo is the average interval between the taps,
+// leaving out intervals far from the rest, such as a missed or doubled tap,
+// rounded to a tenth of a BPM.
+func (t *TapTempo) Tap() (float64, bool) {
+	now := t.clock.Now()
+
+	if n := len(t.taps); n > 0 && now.Sub(t.taps[n-1]) > TapTimeout {
+		t.taps = nil
+	}
+
+	t.taps = append(t.taps, now)
+	if len(t.taps) > maxTaps {
+		t.taps = t.taps[1:]
+	}
+
+	if len(t.taps) < MinTaps {
+		return 0, false
+	}
+
+	intervals := make([]time.Duration, len(t.taps)-1)
+	for i := range intervals {
+		intervals[i] = t.taps[i+1].Sub(t.taps[i])
+	}
+
+	sorted := append([]time.Duration(nil), intervals...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	median := sorted[(len(sorted)-1)/2]
+
+	total, count := time.Duration(0), 0
+	for _, interval := range intervals {
+		if math.Abs(float64(interval-median)) <= tapOutlierRatio*float64(median) {
+			total += interval
+			count++
+		}
+	}
+
+	if total <= 0 {
+		return 0, false
+	}
+
+	beatsPerMinute := float64(time.Minute) * float64(count) / float64(total)
+
+	return math.Round(beatsPerMinute*tapResolution) / tapResolution, true
+}
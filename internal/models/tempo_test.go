@@ -0,0 +1,74 @@
+package models_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jcfox412/logarhythms/internal/clock"
+	"github.com/jcfox412/logarhythms/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTapTempo(t *testing.T) {
+	type testCase struct {
+		description    string
+		input          []time.Duration
+		expectedOutput float64
+		expectedOK     bool
+	}
+
+	// each input is the time before each tap after the first
+	testCases := []testCase{
+		{
+			description: "Waits for enough taps",
+			input:       []time.Duration{500 * time.Millisecond, 500 * time.Millisecond},
+			expectedOK:  false,
+		},
+		{
+			description:    "Works out the tempo from steady taps",
+			input:          []time.Duration{500 * time.Millisecond, 500 * time.Millisecond, 500 * time.Millisecond},
+			expectedOutput: 120,
+			expectedOK:     true,
+		},
+		{
+			description:    "Averages uneven taps",
+			input:          []time.Duration{640 * time.Millisecond, 660 * time.Millisecond, 650 * time.Millisecond, 644 * time.Millisecond},
+			expectedOutput: 92.5,
+			expectedOK:     true,
+		},
+		{
+			description:    "Throws out missed and doubled taps",
+			input:          []time.Duration{500 * time.Millisecond, time.Second, 500 * time.Millisecond, 250 * time.Millisecond, 500 * time.Millisecond},
+			expectedOutput: 120,
+			expectedOK:     true,
+		},
+		{
+			description:    "Starts again after a pause",
+			input:          []time.Duration{time.Second, time.Second, 3 * time.Second, 600 * time.Millisecond, 600 * time.Millisecond, 600 * time.Millisecond},
+			expectedOutput: 100,
+			expectedOK:     true,
+		},
+		{
+			description:    "Starts again only once there are enough taps",
+			input:          []time.Duration{time.Second, time.Second, time.Second, 3 * time.Second, 600 * time.Millisecond},
+			expectedOutput: 0,
+			expectedOK:     false,
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		c := clock.NewManual(time.Unix(0, 0))
+		tapper := models.NewTapTempo(c)
+
+		actualOutput, actualOK := tapper.Tap()
+		for _, interval := range testCase.input {
+			c.Advance(interval)
+			actualOutput, actualOK = tapper.Tap()
+		}
+
+		assert.Equal(t, testCase.expectedOK, actualOK, testCase.description)
+		assert.InDelta(t, testCase.expectedOutput, actualOutput, 1e-6, testCase.description)
+	}
+}
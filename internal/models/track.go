@@ -1,39 +1,81 @@
 package models
 
 import (
+	"context"
 	"fmt"
+	"math"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/pkg/errors"
 
+	"github.com/jcfox412/logarhythms/internal/audio"
+	"github.com/jcfox412/logarhythms/internal/clock"
+	"github.com/jcfox412/logarhythms/internal/midi"
+	"github.com/jcfox412/logarhythms/internal/render"
 	"github.com/jcfox412/logarhythms/internal/utils"
 )
 
 const (
-	headerPadding      = 2
-	defaultTrackLength = 10 * time.Second
+	defaultVelocity = 1.0
+	// Time tracks play for unless set, rounded up to the end of the bar
+	defaultTrackDuration = 10 * time.Second
 )
 
-// Track is an object which can be played.
+// Track is an object which can be played. Its settings can be changed while
+// it plays through Update.
 type Track struct {
+	mu sync.Mutex
 	// Title of the track
 	Title string
-	// Amount of time the track should play
-	Length time.Duration
-	// Beats per minute (BPM) of the track
-	BeatsPerMinute int
+	// How long the track should play
+	Length Length
+	// Beats per minute (BPM) of the track, which can be fractional, e.g. 92.5
+	BeatsPerMinute float64
 	// Number of beats per measure
 	BeatsPerMeasure int
 	// Number of times to divide each beat (e.g. 1 for quarter notes, 2 for eighth notes)
 	DivisionsPerBeat int
 	// Instruments used in the track.
 	Instruments []*Instrument
+	// Kit the track's kit instruments are played on, or nil if it has none
+	Kit *Kit
+	// Shared reverb and delay the instruments' sends are played through, or nil
+	// to leave them as they are
+	Bus *audio.BusParams
+	// Compressor and gain of the master bus the track is played through, or nil
+	// to leave them as they are
+	Master *audio.MasterParams
 	// Sequence of instruments to be played in the track.
 	Patterns [][]*Instrument
+	// Seed for the random number generator used to humanize instruments' hits
+	Seed int64
+	// Clock the track is played by, or nil to play in real time
+	Clock clock.Clock
+	// Renderer the track is drawn by as it plays, or nil to draw nothing
+	Renderer render.Renderer
+	// Port the MIDI notes of instruments are sent to as they're played, or nil
+	// to send none
+	MIDI midi.Port
+	// External clock the track's beat subdivisions follow, or nil to play them
+	// at the track's BPM
+	Sync Sync
+}
+
+// Sync is an external clock a track can follow in place of its BPM, such as
+// MIDI clock from a sequencer.
+type Sync interface {
+	// Follow calls step for each beat subdivision of the external clock, with
+	// divisionsPerBeat to the beat, from the next beat after its transport
+	// starts. step is given the number of subdivisions since the transport
+	// started, which the track keeps in phase with. done is closed once its
+	// transport stops.
+	Follow(divisionsPerBeat int, step func(position int)) (ticker clock.Ticker, done <-chan struct{})
 }
 
 // NewTrack creates a new track with calculated track pattern.
-func NewTrack(title string, instruments []*Instrument, beatsPerMinute, beatsPerMeasure, divisionsPerBeat int) (*Track, error) {
+func NewTrack(title string, instruments []*Instrument, beatsPerMinute float64, beatsPerMeasure, divisionsPerBeat int) (*Track, error) {
 	for _, instrument := range instruments {
 		if err := instrument.validate(); err != nil {
 			return nil, errors.Wrap(err, "error validating instruments")
@@ -44,143 +86,458 @@ func NewTrack(title string, instruments []*Instrument, beatsPerMinute, beatsPerM
 		return nil, errors.Wrap(err, "error validating integer inputs")
 	}
 
+	if err := validatePatterns(beatsPerMeasure*divisionsPerBeat, instruments); err != nil {
+		return nil, errors.Wrap(err, "error validating instrument patterns")
+	}
+
+	busParams := audio.DefaultBusParams()
+	masterParams := audio.DefaultMasterParams()
+
 	return &Track{
 		Title:            title,
-		Length:           defaultTrackLength,
+		Length:           Duration(defaultTrackDuration),
 		BeatsPerMinute:   beatsPerMinute,
 		BeatsPerMeasure:  beatsPerMeasure,
 		DivisionsPerBeat: divisionsPerBeat,
 		Instruments:      instruments,
 		Patterns:         makePattern(beatsPerMeasure*divisionsPerBeat, instruments),
+		Seed:             time.Now().UnixNano(),
+		Bus:              &busParams,
+		Master:           &masterParams,
 	}, nil
 }
 
-// Play plays a track. This entails printing out the track's pattern as it is
-// played, and playing the audio for the instruments of the track.
+// Play plays a track. This entails drawing the track's pattern with its
+// renderer as it is played, and playing the audio for the instruments of the
+// track.
 func (t *Track) Play() error {
+	return t.PlayContext(context.Background())
+}
+
+// PlayContext plays a track like Play, stopping early if the context is
+// cancelled. Returns the first error hit while playing, including the audio
+// backend failing, or the context's error if it was cancelled. Either way, any
+// hits still sounding are faded out and the renderer is stopped. A track following an external clock also stops
+// once the clock's transport does.
+func (t *Track) PlayContext(ctx context.Context) error {
+	p, err := t.prepare()
+	if err != nil {
+		return err
+	}
+
 	// delay allows for cleaner audio
-	time.Sleep(200 * time.Millisecond)
+	select {
+	case <-p.clock.After(200 * time.Millisecond):
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 
-	fmt.Printf("Playing track at BPM: %v\n\n", t.BeatsPerMinute)
+	if p.bus != nil {
+		if err := audio.SetBus(*p.bus); err != nil {
+			return errors.Wrap(err, "error setting reverb and delay")
+		}
+	}
 
-	header, headerWidth := t.printHeaders()
-	fmt.Print(header)
+	if p.master != nil {
+		if err := audio.SetMaster(*p.master); err != nil {
+			return errors.Wrap(err, "error setting master bus")
+		}
+	}
 
-	beatDuration, err := t.calculateBeatDuration()
-	if err != nil {
-		return errors.Wrap(err, "error calculating beat duration")
+	// clear any clipping from before the track started
+	audio.Clipped()
+
+	if err := audio.SetTempo(p.description.BeatsPerMinute); err != nil {
+		return errors.Wrap(err, "error syncing delay to tempo")
 	}
 
-	beatTicker := time.NewTicker(beatDuration)
-	done := make(chan bool)
+	if err := audio.Start(); err != nil {
+		return errors.Wrap(err, "error starting audio output")
+	}
 
-	go func() {
-		beatDivisionCount := 0
-		fmt.Print(utils.ClearLine(headerWidth))
+	c := p.clock
+	renderer := p.renderer
+	stepsPerBar := p.stepsPerBar
+	beatsPerMinute := p.description.BeatsPerMinute
+	stepDuration := p.description.StepDuration
+	totalSteps := p.description.TotalSteps
+	scheduler := newScheduler(c, p.seed, stepDuration, t.Instruments, p.midi)
+
+	// subdivision of the bar last played, and the bar it's in
+	beatDivisionCount := -1
+	bar := 1
+	stepsPlayed := 0
+	// time the steps played so far lasted, which a duration is measured by
+	// when the tempo changes
+	elapsed := time.Duration(0)
+	clipped := false
+	// the ticker can't return errors, so it hands over the first one, or nil
+	// once the track has played for its length, instead
+	ended := make(chan error, 1)
+	stopped := false
+
+	var (
+		// held while the ticker is started, so that the first step can't
+		// retime it before then
+		tickerMu   sync.Mutex
+		beatTicker clock.Ticker
+		// closed once an external clock's transport stops, ending the track
+		syncDone <-chan struct{}
+	)
+
+	renderer.Start(p.description)
+
+	// retime plays the track from the next step at its BPM if it has changed,
+	// unless an external clock sets its tempo, returning whether it has. Must
+	// be called with the track locked.
+	retime := func() (bool, error) {
+		if p.sync != nil || t.BeatsPerMinute == beatsPerMinute {
+			return false, nil
+		}
 
-		for {
-			select {
-			case <-done:
-				return
-			case <-beatTicker.C:
-				if beatDivisionCount == t.BeatsPerMeasure*t.DivisionsPerBeat {
-					beatDivisionCount = 0
-					fmt.Print(utils.ClearLine(headerWidth))
-				}
+		duration, err := t.calculateBeatDuration()
+		if err != nil {
+			return false, errors.Wrap(err, "error calculating beat duration")
+		}
 
-				fmt.Print(utils.CursorToNextColumn(len(t.Instruments) + 1))
+		if err := audio.SetTempo(t.BeatsPerMinute); err != nil {
+			return false, errors.Wrap(err, "error syncing delay to tempo")
+		}
 
-				beatStr, err := t.triggerBeat(beatDivisionCount)
-				if err != nil {
-					// not great way to surface this error (since we're in a goroutine)
-					fmt.Print(err.Error())
-					return
-				}
+		beatsPerMinute = t.BeatsPerMinute
+		stepDuration = duration
+		totalSteps = p.length.retimed(totalSteps, stepsPerBar, stepsPlayed, elapsed, stepDuration)
+		scheduler.retime(stepDuration, t.Instruments)
 
-				fmt.Print(beatStr)
+		tickerMu.Lock()
+		beatTicker.Reset(stepDuration)
+		tickerMu.Unlock()
 
-				beatDivisionCount++
-			}
+		return true, nil
+	}
+
+	// tick plays the beat subdivision at the position since the track, or the
+	// external clock it follows, started
+	tick := func(position int) {
+		if stopped {
+			return
+		}
+
+		// the last beat subdivision has been given its full length
+		if totalSteps > 0 && stepsPlayed == totalSteps {
+			stopped = true
+			ended <- nil
+			return
+		}
+
+		t.mu.Lock()
+		retimed, err := retime()
+		t.mu.Unlock()
+
+		if err != nil {
+			stopped = true
+			ended <- errors.Wrap(err, "error changing tempo")
+			return
+		}
+
+		// renderers are never called with the track locked, so that they can
+		// read it as they draw
+		if retimed {
+			render.Retime(renderer, beatsPerMinute, stepDuration, totalSteps)
 		}
-	}()
 
-	time.Sleep(t.Length)
+		division := position % stepsPerBar
+		if stepsPlayed > 0 && division <= beatDivisionCount {
+			bar++
+		}
+
+		beatDivisionCount = division
+
+		t.mu.Lock()
+		step, err := t.triggerBeat(beatDivisionCount, scheduler)
+		t.mu.Unlock()
+
+		if err != nil {
+			stopped = true
+			ended <- errors.Wrap(err, "error playing beat")
+			return
+		}
+
+		step.Index = stepsPlayed
+		step.Bar = bar
+		renderer.Step(step)
+
+		// the indicator stays lit once the mix has clipped
+		if !clipped && audio.Clipped() {
+			clipped = true
+			renderer.Clip()
+		}
 
+		stepsPlayed++
+		elapsed += stepDuration
+	}
+
+	tickerMu.Lock()
+	if p.sync != nil {
+		beatTicker, syncDone = p.sync.Follow(p.description.DivisionsPerBeat, tick)
+	} else {
+		beatTicker = c.Every(stepDuration, func() {
+			tick(stepsPlayed)
+		})
+	}
+	tickerMu.Unlock()
+
+	select {
+	case err = <-ended:
+	case <-syncDone:
+	case <-audio.Failed():
+		err = errors.Wrap(audio.Err(), "error playing audio")
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	// stopping waits for any beat in progress, so the renderer has drawn its
+	// last step before it's stopped
 	beatTicker.Stop()
-	done <- true
+	scheduler.stop()
+
+	if err != nil {
+		audio.Silence()
+	}
+
+	renderer.Stop()
+
+	return err
+}
+
+// Update calls f to change the track's settings, which is safe to do while
+// the track plays. A new BPM is heard from the next beat subdivision, unless
+// the track follows an external clock, while other changes to how it's
+// played, such as its length, are heard the next time it's played.
+func (t *Track) Update(f func(*Track) error) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return f(t)
+}
+
+// SetStep turns an instrument's hit at a beat subdivision of the bar on or
+// off, by the positions of both in the track. A playing track hears the change
+// from its next beat subdivision, so it should be called through Update.
+func (t *Track) SetStep(instrument, step int, on bool) error {
+	if instrument < 0 || instrument >= len(t.Instruments) {
+		return fmt.Errorf("no instrument at index %d", instrument)
+	}
+
+	divisionsPerMeasure := t.BeatsPerMeasure * t.DivisionsPerBeat
+	if step < 0 || step >= divisionsPerMeasure {
+		return fmt.Errorf("step %d is outside of a %d subdivision measure", step, divisionsPerMeasure)
+	}
+
+	i := t.Instruments[instrument]
+
+	pattern := make([]int, 0, len(i.Pattern)+1)
+	for _, beat := range i.Pattern {
+		if beat != step {
+			pattern = append(pattern, beat)
+		}
+	}
+
+	if on {
+		pattern = append(pattern, step)
+		sort.Ints(pattern)
+	}
 
-	fmt.Println()
+	i.Pattern = pattern
+	t.Patterns = makePattern(divisionsPerMeasure, t.Instruments)
 
 	return nil
 }
 
-func (t *Track) triggerBeat(beatDivisionCount int) (string, error) {
-	beatStr := ""
+// playback is how a track is played, fixed as it starts playing.
+type playback struct {
+	clock       clock.Clock
+	renderer    render.Renderer
+	sync        Sync
+	midi        midi.Port
+	bus         *audio.BusParams
+	master      *audio.MasterParams
+	seed        int64
+	stepsPerBar int
+	length      Length
+	description render.Track
+}
 
-	beatCount, err := utils.BeatCount(beatDivisionCount, t.DivisionsPerBeat)
+// prepare validates the track's settings, returning how it's played.
+func (t *Track) prepare() (playback, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	beatDuration, err := t.calculateBeatDuration()
 	if err != nil {
-		return "", errors.Wrap(err, "error determining beat count")
+		return playback{}, errors.Wrap(err, "error calculating beat duration")
 	}
 
-	beatStr += beatCount
-	beatStr += utils.CursorToNextRow()
+	if err := t.Length.Validate(); err != nil {
+		return playback{}, errors.Wrap(err, "error validating track length")
+	}
 
-	if beatDivisionCount >= len(t.Patterns) {
-		return "", errors.New("beat counter higher than length of patterns - something went wrong")
+	p := playback{
+		clock:       t.Clock,
+		renderer:    t.Renderer,
+		sync:        t.Sync,
+		midi:        t.MIDI,
+		seed:        t.Seed,
+		stepsPerBar: t.BeatsPerMeasure * t.DivisionsPerBeat,
+		length:      t.Length,
 	}
 
-	instruments := t.Patterns[beatDivisionCount]
+	if p.clock == nil {
+		p.clock = clock.New()
+	}
 
-	for _, instrument := range instruments {
-		if instrument != nil {
-			instrument.Audio.Play()
-			beatStr += fmt.Sprint("X|")
+	if p.renderer == nil {
+		p.renderer = render.NewSilent()
+	}
+
+	if t.Bus != nil {
+		bus := *t.Bus
+		p.bus = &bus
+	}
+
+	if t.Master != nil {
+		master := *t.Master
+		p.master = &master
+	}
+
+	totalSteps := t.Length.steps(p.stepsPerBar, beatDuration)
+	p.description = t.describe(beatDuration, totalSteps)
+
+	return p, nil
+}
+
+// triggerBeat plays the instruments of a beat subdivision, returning the step
+// to draw for it. The step's index and bar are left for the caller to fill in.
+func (t *Track) triggerBeat(beatDivisionCount int, s *scheduler) (render.Step, error) {
+	step, err := t.step(beatDivisionCount)
+	if err != nil {
+		return render.Step{}, err
+	}
 
-		} else {
-			beatStr += fmt.Sprint("_|")
+	s.prepare(t.Instruments)
+
+	for _, instrument := range t.Patterns[beatDivisionCount] {
+		if instrument != nil && !instrument.Muted {
+			s.trigger(instrument, instrument.articulation(beatDivisionCount))
 		}
+	}
+
+	return step, nil
+}
 
-		beatStr += utils.CursorToNextRow()
+// step returns the step drawn for a beat subdivision of the track's pattern.
+func (t *Track) step(beatDivisionCount int) (render.Step, error) {
+	beatCount, err := utils.BeatCount(beatDivisionCount, t.DivisionsPerBeat)
+	if err != nil {
+		return render.Step{}, errors.Wrap(err, "error determining beat count")
+	}
+
+	if beatDivisionCount >= len(t.Patterns) {
+		return render.Step{}, errors.New("beat counter higher than length of patterns - something went wrong")
 	}
 
-	beatStr += utils.BeatTracker()
+	instruments := t.Patterns[beatDivisionCount]
+	hits := make([]render.Hit, len(instruments))
+
+	for i, instrument := range instruments {
+		if instrument != nil && !instrument.Muted {
+			articulation := instrument.articulation(beatDivisionCount)
+			hits[i] = render.Hit{Glyph: articulation.glyph(), Velocity: articulation.Velocity}
+		}
+	}
 
-	return beatStr, nil
+	return render.Step{
+		Division: beatDivisionCount,
+		Count:    beatCount,
+		Hits:     hits,
+	}, nil
 }
 
-func (t *Track) calculateBeatDuration() (time.Duration, error) {
-	if t.BeatsPerMinute <= 0 {
-		return time.Duration(0), errors.New("beats per minute must be greater than 0")
+// describe returns the track as it's given to renderers, playing for
+// totalSteps of stepDuration each.
+func (t *Track) describe(stepDuration time.Duration, totalSteps int) render.Track {
+	instruments := make([]string, len(t.Instruments))
+	for i, instrument := range t.Instruments {
+		instruments[i] = instrument.Name
 	}
 
-	if t.DivisionsPerBeat <= 0 {
-		return time.Duration(0), errors.New("divisions per beat must be greater than 0")
+	return render.Track{
+		Title:            fmt.Sprintf("Playing track at BPM: %v", t.BeatsPerMinute),
+		BeatsPerMinute:   t.BeatsPerMinute,
+		Instruments:      instruments,
+		Pattern:          t.pattern(),
+		DivisionsPerBeat: t.DivisionsPerBeat,
+		StepDuration:     stepDuration,
+		TotalSteps:       totalSteps,
+		CurrentPattern:   t.currentPattern,
+		Levels:           t.levels,
 	}
+}
+
+// pattern returns each beat subdivision of the track's pattern as it's drawn.
+func (t *Track) pattern() []render.Step {
+	pattern := make([]render.Step, 0, len(t.Patterns))
+	for i := range t.Patterns {
+		// the pattern has been checked to have valid beat counts by now
+		step, _ := t.step(i)
+		pattern = append(pattern, step)
+	}
+
+	return pattern
+}
 
-	beatDuration := 60.0 * 1000 / (t.BeatsPerMinute * t.DivisionsPerBeat)
+// currentPattern returns the track's pattern as it's set now, while it plays.
+func (t *Track) currentPattern() []render.Step {
+	t.mu.Lock()
+	defer t.mu.Unlock()
 
-	return time.Duration(beatDuration) * time.Millisecond, nil
+	return t.pattern()
 }
 
-func (t *Track) printHeaders() (string, int) {
-	longestInstrument := 0
-	header := ""
+// levels returns the current level of each of the track's instruments, or 0
+// for any whose level can't be measured.
+func (t *Track) levels() []float64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
 
-	for _, instrument := range t.Instruments {
-		if len(instrument.Name) > longestInstrument {
-			longestInstrument = len(instrument.Name)
+	levels := make([]float64, len(t.Instruments))
+	for i, instrument := range t.Instruments {
+		if m, ok := instrument.Audio.(audio.Metered); ok {
+			levels[i] = m.Level()
 		}
 	}
 
-	for _, instrument := range t.Instruments {
-		header += fmt.Sprintf("%*s: |\n", longestInstrument, instrument.Name)
+	return levels
+}
+
+// calculateBeatDuration returns the duration of a beat subdivision, to the
+// nearest nanosecond so that tracks keep in time over long playbacks.
+func (t *Track) calculateBeatDuration() (time.Duration, error) {
+	if !(t.BeatsPerMinute > 0) || math.IsInf(t.BeatsPerMinute, 1) {
+		return time.Duration(0), errors.New("beats per minute must be greater than 0")
+	}
+
+	if t.DivisionsPerBeat <= 0 {
+		return time.Duration(0), errors.New("divisions per beat must be greater than 0")
 	}
 
-	return header, longestInstrument + headerPadding
+	beatDuration := float64(time.Minute) / (t.BeatsPerMinute * float64(t.DivisionsPerBeat))
+
+	return time.Duration(math.Round(beatDuration)), nil
 }
 
-func validatePositiveInputs(beatsPerMinute, beatsPerMeasure, divisionsPerBeat int) error {
-	if beatsPerMinute <= 0 {
+func validatePositiveInputs(beatsPerMinute float64, beatsPerMeasure, divisionsPerBeat int) error {
+	if !(beatsPerMinute > 0) || math.IsInf(beatsPerMinute, 1) {
 		return errors.New("BeatsPerMinute must be greater than 0")
 	}
 
@@ -195,6 +552,18 @@ func validatePositiveInputs(beatsPerMinute, beatsPerMeasure, divisionsPerBeat in
 	return nil
 }
 
+func validatePatterns(divisionsPerMeasure int, instruments []*Instrument) error {
+	for _, instrument := range instruments {
+		for _, beat := range instrument.Pattern {
+			if beat < 0 || beat >= divisionsPerMeasure {
+				return fmt.Errorf("%s pattern has beat subdivision %d outside of a %d subdivision measure", instrument.Name, beat, divisionsPerMeasure)
+			}
+		}
+	}
+
+	return nil
+}
+
 func makePattern(divisionsPerMeasure int, instruments []*Instrument) [][]*Instrument {
 	pattern := make([][]*Instrument, divisionsPerMeasure)
 	for i := 0; i < divisionsPerMeasure; i++ {
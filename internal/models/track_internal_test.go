@@ -1,70 +1,16 @@
 package models
 
 import (
+	"math"
 	"testing"
 	"time"
 
 	audiomocks "github.com/jcfox412/logarhythms/internal/audio/mocks"
+	"github.com/jcfox412/logarhythms/internal/clock"
+	"github.com/jcfox412/logarhythms/internal/render"
 	"github.com/stretchr/testify/assert"
 )
 
-func TestPrintHeaders(t *testing.T) {
-	type output struct {
-		header      string
-		headerWidth int
-	}
-
-	type testCase struct {
-		description    string
-		input          *Track
-		expectedOutput output
-	}
-
-	testCases := []testCase{
-		{
-			description: "Handles no instruments",
-			input:       &Track{},
-			expectedOutput: output{
-				header:      "",
-				headerWidth: 2,
-			},
-		},
-		{
-			description: "Handles one instrument",
-			input: &Track{
-				Instruments: []*Instrument{
-					{Name: "Snare"},
-				},
-			},
-			expectedOutput: output{
-				header:      "Snare: |\n",
-				headerWidth: 7,
-			},
-		},
-		{
-			description: "Handles multiple instrument",
-			input: &Track{
-				Instruments: []*Instrument{
-					{Name: "Kick"},
-					{Name: "Snare"},
-				},
-			},
-			expectedOutput: output{
-				header:      " Kick: |\nSnare: |\n",
-				headerWidth: 7,
-			},
-		},
-	}
-
-	for _, testCase := range testCases {
-		testCase := testCase
-
-		actualHeader, actualHeaderWidth := testCase.input.printHeaders()
-		assert.Equal(t, testCase.expectedOutput.header, actualHeader)
-		assert.Equal(t, testCase.expectedOutput.headerWidth, actualHeaderWidth)
-	}
-}
-
 func TestCalculateBeatDuration(t *testing.T) {
 	type testCase struct {
 		description     string
@@ -92,6 +38,24 @@ func TestCalculateBeatDuration(t *testing.T) {
 			expectedOutput:  time.Duration(1000) * time.Millisecond,
 			expectedToError: false,
 		},
+		{
+			description:     "Keeps subdivisions which don't divide a millisecond to the nanosecond",
+			input:           &Track{BeatsPerMinute: 133, DivisionsPerBeat: 7},
+			expectedOutput:  64446831 * time.Nanosecond,
+			expectedToError: false,
+		},
+		{
+			description:     "Succeeds with fractional beats per minute",
+			input:           &Track{BeatsPerMinute: 92.5, DivisionsPerBeat: 1},
+			expectedOutput:  648648649 * time.Nanosecond,
+			expectedToError: false,
+		},
+		{
+			description:     "Errors when beats per minute isn't a number",
+			input:           &Track{BeatsPerMinute: math.NaN(), DivisionsPerBeat: 1},
+			expectedOutput:  time.Duration(0),
+			expectedToError: true,
+		},
 	}
 
 	for _, testCase := range testCases {
@@ -169,12 +133,17 @@ func TestTriggerBeat(t *testing.T) {
 		description     string
 		input           input
 		setupMocks      func(*audiomocks.Manager)
-		expectedOutput  string
+		expectedOutput  render.Step
 		expectedToError bool
 	}
 
 	testInstrument1 := &Instrument{Name: "testInstrument1"}
 	testInstrument2 := &Instrument{Name: "testInstrument2"}
+	testRatchetInstrument := &Instrument{
+		Name:          "testRatchetInstrument",
+		Articulations: map[int]Articulation{0: {Velocity: 1, Repeat: 3}},
+	}
+	testMutedInstrument := &Instrument{Name: "testMutedInstrument", Muted: true}
 
 	testCases := []testCase{
 		{
@@ -184,7 +153,7 @@ func TestTriggerBeat(t *testing.T) {
 				beatCount: 0,
 			},
 			setupMocks:      func(m *audiomocks.Manager) {},
-			expectedOutput:  "",
+			expectedOutput:  render.Step{},
 			expectedToError: true,
 		},
 		{
@@ -199,7 +168,7 @@ func TestTriggerBeat(t *testing.T) {
 				beatCount: 0,
 			},
 			setupMocks:      func(m *audiomocks.Manager) {},
-			expectedOutput:  "1 \x1b[1B\x1b[2D\x1b[3D   *",
+			expectedOutput:  render.Step{Count: "1 ", Hits: []render.Hit{}},
 			expectedToError: false,
 		},
 		{
@@ -221,9 +190,9 @@ func TestTriggerBeat(t *testing.T) {
 				beatCount: 0,
 			},
 			setupMocks: func(m *audiomocks.Manager) {
-				m.On("Play").Return().Once()
+				m.On("Play", 1.0).Return().Once()
 			},
-			expectedOutput:  "1 \x1b[1B\x1b[2DX|\x1b[1B\x1b[2D_|\x1b[1B\x1b[2D\x1b[3D   *",
+			expectedOutput:  render.Step{Count: "1 ", Hits: []render.Hit{{Glyph: "X", Velocity: 1}, {}}},
 			expectedToError: false,
 		},
 		{
@@ -245,9 +214,51 @@ func TestTriggerBeat(t *testing.T) {
 				beatCount: 0,
 			},
 			setupMocks: func(m *audiomocks.Manager) {
-				m.On("Play").Return().Once()
+				m.On("Play", 1.0).Return().Once()
 			},
-			expectedOutput:  "1 \x1b[1B\x1b[2DX|\x1b[1B\x1b[2DX|\x1b[1B\x1b[2D\x1b[3D   *",
+			expectedOutput:  render.Step{Count: "1 ", Hits: []render.Hit{{Glyph: "X", Velocity: 1}, {Glyph: "X", Velocity: 1}}},
+			expectedToError: false,
+		},
+		{
+			description: "Succeeds with articulated instrument",
+			input: input{
+				track: &Track{
+					Instruments: []*Instrument{
+						testRatchetInstrument,
+					},
+					Patterns: [][]*Instrument{
+						{
+							testRatchetInstrument,
+						},
+					},
+					DivisionsPerBeat: 1,
+				},
+				beatCount: 0,
+			},
+			setupMocks: func(m *audiomocks.Manager) {
+				m.On("Play", 1.0).Return().Times(3)
+			},
+			expectedOutput:  render.Step{Count: "1 ", Hits: []render.Hit{{Glyph: "3", Velocity: 1}}},
+			expectedToError: false,
+		},
+		{
+			description: "Succeeds with muted instrument, which isn't played",
+			input: input{
+				track: &Track{
+					Instruments: []*Instrument{
+						testMutedInstrument,
+					},
+					Patterns: [][]*Instrument{
+						{
+							testMutedInstrument,
+						},
+					},
+					DivisionsPerBeat: 1,
+				},
+				beatCount: 0,
+			},
+			setupMocks:      func(m *audiomocks.Manager) {},
+			expectedOutput:  render.Step{Count: "1 ", Hits: []render.Hit{{}}},
 			expectedToError: false,
 		},
 	}
@@ -270,12 +281,12 @@ func TestTriggerBeat(t *testing.T) {
 			mockManagers = append(mockManagers, m)
 		}
 
-		actualBeatStr, actualErr := track.triggerBeat(testCase.input.beatCount)
+		actualStep, actualErr := track.triggerBeat(testCase.input.beatCount, newScheduler(clock.New(), 0, 0, track.Instruments, nil))
 		if testCase.expectedToError {
 			assert.NotNil(t, actualErr)
 		} else {
 			assert.Nil(t, actualErr)
-			assert.Equal(t, testCase.expectedOutput, actualBeatStr)
+			assert.Equal(t, testCase.expectedOutput, actualStep)
 		}
 
 		for _, m := range mockManagers {
@@ -283,3 +294,52 @@ func TestTriggerBeat(t *testing.T) {
 		}
 	}
 }
+
+// meteredManager is audio whose level can be measured.
+type meteredManager struct {
+	*audiomocks.Manager
+	*audiomocks.Metered
+}
+
+func TestDescribe(t *testing.T) {
+	kickAudio := &audiomocks.Metered{}
+	kickAudio.On("Level").Return(0.5).Once()
+
+	// the snare's level can't be measured
+	snareAudio := &audiomocks.Manager{}
+
+	kick := &Instrument{Name: "Kick", Audio: meteredManager{Manager: &audiomocks.Manager{}, Metered: kickAudio}}
+	snare := &Instrument{
+		Name:          "Snare",
+		Audio:         snareAudio,
+		Articulations: map[int]Articulation{1: {Velocity: ghostVelocity, Repeat: 1}},
+	}
+
+	track := &Track{
+		BeatsPerMinute:   90,
+		DivisionsPerBeat: 2,
+		Instruments:      []*Instrument{kick, snare},
+		Patterns:         [][]*Instrument{{kick, nil}, {nil, snare}},
+	}
+
+	actualOutput := track.describe(time.Second/3, 12)
+
+	assert.Equal(t, "Playing track at BPM: 90", actualOutput.Title)
+	assert.Equal(t, 90.0, actualOutput.BeatsPerMinute)
+	assert.Equal(t, []string{"Kick", "Snare"}, actualOutput.Instruments)
+	assert.Equal(t, []render.Step{
+		{Division: 0, Count: "1 ", Hits: []render.Hit{{Glyph: "X", Velocity: 1}, {}}},
+		{Division: 1, Count: "  ", Hits: []render.Hit{{}, {Glyph: "o", Velocity: ghostVelocity}}},
+	}, actualOutput.Pattern)
+	assert.Equal(t, 2, actualOutput.DivisionsPerBeat)
+	assert.Equal(t, time.Second/3, actualOutput.StepDuration)
+	assert.Equal(t, 12, actualOutput.TotalSteps)
+	assert.Equal(t, []float64{0.5, 0}, actualOutput.Levels())
+
+	// steps changed as the track plays are drawn from the current pattern
+	track.Patterns[1][0] = kick
+	assert.Equal(t, []render.Hit{{Glyph: "X", Velocity: 1}, {Glyph: "o", Velocity: ghostVelocity}}, actualOutput.CurrentPattern()[1].Hits)
+
+	kickAudio.AssertExpectations(t)
+	snareAudio.AssertExpectations(t)
+}
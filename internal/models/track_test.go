@@ -1,19 +1,27 @@
 package models_test
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"testing"
 	"time"
 
+	"github.com/faiface/beep"
 	"github.com/jcfox412/logarhythms/internal/audio"
 	audiomocks "github.com/jcfox412/logarhythms/internal/audio/mocks"
+	"github.com/jcfox412/logarhythms/internal/clock"
 	"github.com/jcfox412/logarhythms/internal/models"
+	"github.com/jcfox412/logarhythms/internal/render"
+	_ "github.com/jcfox412/logarhythms/testing"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 )
 
 func TestNewTrack(t *testing.T) {
 	type input struct {
 		instruments      []*models.Instrument
-		beatsPerMinute   int
+		beatsPerMinute   float64
 		beatsPerMeasure  int
 		divisionsPerBeat int
 	}
@@ -49,6 +57,30 @@ func TestNewTrack(t *testing.T) {
 			},
 			expectedToError: true,
 		},
+		{
+			description: "Fails with invalid MIDI note",
+			input: input{
+				instruments: []*models.Instrument{
+					{Audio: &audio.BeepManager{}, MIDI: &models.MIDINote{Channel: 0, Note: 36}},
+				},
+				beatsPerMinute:   120,
+				beatsPerMeasure:  4,
+				divisionsPerBeat: 2,
+			},
+			expectedToError: true,
+		},
+		{
+			description: "Fails with pattern outside of measure",
+			input: input{
+				instruments: []*models.Instrument{
+					{Audio: &audio.BeepManager{}, Pattern: []int{0, 8}},
+				},
+				beatsPerMinute:   120,
+				beatsPerMeasure:  4,
+				divisionsPerBeat: 2,
+			},
+			expectedToError: true,
+		},
 		{
 			description: "Fails with invalid beatsPerMinute",
 			input: input{
@@ -102,73 +134,608 @@ func TestNewTrack(t *testing.T) {
 	}
 }
 
-// TODO: this test does not cover the Play functionality super effectively. If
-// I spent more time on this, I would change Play to make it more testable.
 func TestPlay(t *testing.T) {
 	type testCase struct {
-		description     string
-		input           *models.Track
-		setupMocks      func(*audiomocks.Manager)
+		description string
+		// patterns of each instrument, in beat subdivisions
+		patterns        [][]int
+		trackPatterns   int
+		beatsPerMinute  float64
+		length          models.Length
+		expectedSteps   [][]int
 		expectedToError bool
 	}
 
-	testInstrument1 := &models.Instrument{Name: "testInstrument1", Pattern: []int{0, 1, 2}}
-	testInstrument2 := &models.Instrument{Name: "testInstrument2", Pattern: []int{1, 2}}
-
 	testCases := []testCase{
 		{
-			description: "Successfully plays track",
-			input: &models.Track{
-				Length: 100 * time.Millisecond,
-				Instruments: []*models.Instrument{
-					testInstrument1,
-					testInstrument2,
-				},
-				Patterns: [][]*models.Instrument{
-					{
-						testInstrument1,
-						testInstrument2,
-					},
-					{},
-				},
-				BeatsPerMinute:   1000,
-				BeatsPerMeasure:  2,
-				DivisionsPerBeat: 1,
-			},
-			setupMocks: func(m *audiomocks.Manager) {
-				m.On("Play").Return().Once()
-			},
+			description:     "Plays each instrument at its subdivisions, wrapping around each bar",
+			patterns:        [][]int{{0, 3}, {1}},
+			trackPatterns:   4,
+			beatsPerMinute:  60,
+			length:          models.Bars(2),
+			expectedSteps:   [][]int{{0, 3, 4, 7}, {1, 5}},
+			expectedToError: false,
+		},
+		{
+			description:    "Rounds a duration up to the end of the bar",
+			patterns:       [][]int{{0, 3}, {1}},
+			trackPatterns:  4,
+			beatsPerMinute: 60,
+			// a bar and a quarter
+			length:          models.Duration(2500 * time.Millisecond),
+			expectedSteps:   [][]int{{0, 3, 4, 7}, {1, 5}},
 			expectedToError: false,
 		},
+		{
+			description:     "Errors when the bar is longer than the track's patterns",
+			patterns:        [][]int{{0}},
+			trackPatterns:   2,
+			beatsPerMinute:  60,
+			length:          models.Bars(1),
+			expectedSteps:   [][]int{{0}},
+			expectedToError: true,
+		},
 	}
 
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
 	for _, testCase := range testCases {
 		testCase := testCase
-		track := testCase.input
 
-		mockManagers := make([]*audiomocks.Manager, 0, len(track.Instruments))
+		c := clock.NewManual(start)
+
+		track := &models.Track{
+			Length:           testCase.length,
+			BeatsPerMinute:   testCase.beatsPerMinute,
+			BeatsPerMeasure:  2,
+			DivisionsPerBeat: 2,
+			Patterns:         make([][]*models.Instrument, testCase.trackPatterns),
+			Clock:            c,
+		}
+
+		// the track starts ticking after a short delay, a subdivision at a time
+		// (half a beat at 60 BPM)
+		var playStart time.Time
+		stepDuration := 500 * time.Millisecond
+
+		actualSteps := make([][]int, len(testCase.patterns))
+
+		for i, pattern := range testCase.patterns {
+			i := i
 
-		for i, instrument := range track.Instruments {
 			m := &audiomocks.Manager{}
+			m.On("Play", 1.0).Run(func(mock.Arguments) {
+				step := int(c.Now().Sub(playStart)/stepDuration) - 1
+				actualSteps[i] = append(actualSteps[i], step)
+			}).Return()
+
+			instrument := &models.Instrument{Name: "testInstrument", Pattern: pattern, Audio: m}
+			track.Instruments = append(track.Instruments, instrument)
+		}
 
-			// only mock play for instruments whose pattern says they should play
-			if len(track.Patterns) > 0 && track.Patterns[0][i] != nil {
-				testCase.setupMocks(m)
+		for i := range track.Patterns {
+			track.Patterns[i] = make([]*models.Instrument, len(track.Instruments))
+		}
+
+		for i, instrument := range track.Instruments {
+			for _, step := range instrument.Pattern {
+				if step < len(track.Patterns) {
+					track.Patterns[step][i] = instrument
+				}
 			}
+		}
+
+		done := make(chan error)
+		go func() {
+			done <- track.Play()
+		}()
+
+		c.BlockUntil(1)
+		c.Advance(200 * time.Millisecond)
+		playStart = c.Now()
+
+		// wait for the track to start ticking
+		c.BlockUntil(1)
 
-			instrument.Audio = m
-			mockManagers = append(mockManagers, m)
+		// step through well past the end of the track, a subdivision at a time
+		for i := 0; i < 20; i++ {
+			c.Advance(stepDuration)
 		}
 
-		actualErr := track.Play()
+		actualErr := <-done
 		if testCase.expectedToError {
-			assert.NotNil(t, actualErr)
+			assert.NotNil(t, actualErr, testCase.description)
 		} else {
-			assert.Nil(t, actualErr)
+			assert.Nil(t, actualErr, testCase.description)
 		}
 
-		for _, m := range mockManagers {
-			m.AssertExpectations(t)
+		assert.Equal(t, testCase.expectedSteps, actualSteps, testCase.description)
+	}
+}
+
+func TestPlayValidates(t *testing.T) {
+	type testCase struct {
+		description string
+		input       *models.Track
+	}
+
+	testCases := []testCase{
+		{
+			description: "Errors with no length",
+			input:       &models.Track{BeatsPerMinute: 60, DivisionsPerBeat: 1, Length: models.Bars(0)},
+		},
+		{
+			description: "Errors with no beats per minute",
+			input:       &models.Track{BeatsPerMinute: 0, DivisionsPerBeat: 1, Length: models.Bars(1)},
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		// nothing is played, so the clock never needs advancing
+		testCase.input.Clock = clock.NewManual(time.Time{})
+
+		assert.NotNil(t, testCase.input.Play(), testCase.description)
+	}
+}
+
+func TestPlayContext(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := clock.NewManual(start)
+
+	played := 0
+
+	m := &audiomocks.Manager{}
+	m.On("Play", 1.0).Run(func(mock.Arguments) {
+		played++
+	}).Return()
+
+	instrument := &models.Instrument{Name: "testInstrument", Pattern: []int{0, 1}, Audio: m}
+
+	track := &models.Track{
+		Length:           models.Infinite(),
+		BeatsPerMinute:   60,
+		BeatsPerMeasure:  2,
+		DivisionsPerBeat: 1,
+		Instruments:      []*models.Instrument{instrument},
+		Patterns:         [][]*models.Instrument{{instrument}, {instrument}},
+		Clock:            c,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan error)
+	go func() {
+		done <- track.PlayContext(ctx)
+	}()
+
+	c.BlockUntil(1)
+	c.Advance(200 * time.Millisecond)
+	c.BlockUntil(1)
+	c.Advance(2 * time.Second)
+
+	cancel()
+
+	actualErr := <-done
+	assert.True(t, errors.Is(actualErr, context.Canceled))
+	assert.Equal(t, 2, played)
+
+	// the track stops ticking once cancelled
+	c.Advance(time.Minute)
+	assert.Equal(t, 2, played)
+}
+
+func TestPlayContextDropsPendingHits(t *testing.T) {
+	c := clock.NewManual(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	var played []float64
+
+	m := &audiomocks.Manager{}
+	m.On("Play", mock.AnythingOfType("float64")).Run(func(args mock.Arguments) {
+		played = append(played, args.Get(0).(float64))
+	}).Return()
+
+	instrument := &models.Instrument{
+		Name:          "testInstrument",
+		Pattern:       []int{1},
+		Articulations: map[int]models.Articulation{0: {Velocity: 1, Ornament: models.Flam}},
+		Audio:         m,
+	}
+
+	track := &models.Track{
+		Length:           models.Infinite(),
+		BeatsPerMinute:   60,
+		BeatsPerMeasure:  1,
+		DivisionsPerBeat: 1,
+		Instruments:      []*models.Instrument{instrument},
+		Patterns:         [][]*models.Instrument{{instrument}},
+		Clock:            c,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan error)
+	go func() {
+		done <- track.PlayContext(ctx)
+	}()
+
+	// the flam's grace note is played on the first beat, with its hit still to
+	// come
+	c.BlockUntil(1)
+	c.Advance(200 * time.Millisecond)
+	c.BlockUntil(1)
+	c.Advance(time.Second)
+
+	cancel()
+
+	actualErr := <-done
+	assert.True(t, errors.Is(actualErr, context.Canceled))
+	assert.Equal(t, []float64{0.5}, played)
+
+	// the hit isn't played once the track has stopped
+	c.Advance(time.Minute)
+	assert.Equal(t, []float64{0.5}, played)
+}
+
+// failingBackend is an audio backend which fails as soon as it starts.
+type failingBackend struct {
+	failed chan struct{}
+}
+
+func (f *failingBackend) Start(beep.Streamer, beep.SampleRate) error {
+	close(f.failed)
+	return nil
+}
+
+func (f *failingBackend) Failed() <-chan struct{} { return f.failed }
+func (f *failingBackend) Err() error              { return errors.New("disk full") }
+func (f *failingBackend) Close() error            { return nil }
+
+func TestPlayContextStopsWhenAudioFails(t *testing.T) {
+	assert.Nil(t, audio.Close())
+	assert.Nil(t, audio.SetBackend(&failingBackend{failed: make(chan struct{})}))
+
+	defer func() {
+		assert.Nil(t, audio.Close())
+
+		backend, err := audio.NewBackend(audio.NullBackend, "")
+		assert.Nil(t, err)
+		assert.Nil(t, audio.SetBackend(backend))
+	}()
+
+	c := clock.NewManual(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	instrument := &models.Instrument{Name: "testInstrument", Pattern: []int{1}, Audio: &audiomocks.Manager{}}
+
+	track := &models.Track{
+		Length:           models.Infinite(),
+		BeatsPerMinute:   60,
+		BeatsPerMeasure:  1,
+		DivisionsPerBeat: 1,
+		Instruments:      []*models.Instrument{instrument},
+		Patterns:         [][]*models.Instrument{{instrument}},
+		Clock:            c,
+	}
+
+	done := make(chan error)
+	go func() {
+		done <- track.Play()
+	}()
+
+	c.BlockUntil(1)
+	c.Advance(200 * time.Millisecond)
+
+	actualErr := <-done
+	assert.EqualError(t, actualErr, "error playing audio: disk full")
+}
+
+// retimes is a renderer which records the tempos it's retimed to.
+type retimes struct {
+	render.Renderer
+	stepDurations []time.Duration
+	totalSteps    []int
+}
+
+func (r *retimes) Retime(beatsPerMinute float64, stepDuration time.Duration, totalSteps int) {
+	r.stepDurations = append(r.stepDurations, stepDuration)
+	r.totalSteps = append(r.totalSteps, totalSteps)
+}
+
+func TestPlayRetimes(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := clock.NewManual(start)
+
+	var hits []time.Duration
+
+	m := &audiomocks.Manager{}
+	m.On("Play", 1.0).Run(func(mock.Arguments) {
+		hits = append(hits, c.Now().Sub(start))
+	}).Return()
+
+	instrument := &models.Instrument{Name: "testInstrument", Pattern: []int{0}, Audio: m}
+	renderer := &retimes{Renderer: render.NewSilent()}
+
+	// a step a second, for 4 seconds
+	track := &models.Track{
+		Length:           models.Duration(4 * time.Second),
+		BeatsPerMinute:   60,
+		BeatsPerMeasure:  1,
+		DivisionsPerBeat: 1,
+		Instruments:      []*models.Instrument{instrument},
+		Patterns:         [][]*models.Instrument{{instrument}},
+		Clock:            c,
+		Renderer:         renderer,
+	}
+
+	done := make(chan error)
+	go func() {
+		done <- track.Play()
+	}()
+
+	c.BlockUntil(1)
+	c.Advance(200 * time.Millisecond)
+	c.BlockUntil(1)
+	c.Advance(2500 * time.Millisecond)
+
+	assert.Nil(t, track.Update(func(t *models.Track) error {
+		t.BeatsPerMinute = 120
+		return nil
+	}))
+
+	for i := 0; i < 10; i++ {
+		c.Advance(500 * time.Millisecond)
+	}
+
+	assert.Nil(t, <-done)
+
+	// the 2 seconds left are played at the new tempo from the next step
+	expected := []time.Duration{}
+	for _, ms := range []int{1200, 2200, 3200, 3700, 4200, 4700} {
+		expected = append(expected, time.Duration(ms)*time.Millisecond)
+	}
+
+	assert.Equal(t, expected, hits)
+	assert.Equal(t, []time.Duration{500 * time.Millisecond}, renderer.stepDurations)
+	assert.Equal(t, []int{6}, renderer.totalSteps)
+}
+
+func TestPlayRenders(t *testing.T) {
+	c := clock.NewManual(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	m := &audiomocks.Manager{}
+	m.On("Play", 1.0).Return()
+
+	kick := &models.Instrument{Name: "Kick", Pattern: []int{0}, Audio: m}
+	snare := &models.Instrument{Name: "Snare", Pattern: []int{1}, Audio: m}
+
+	w := &bytes.Buffer{}
+
+	track := &models.Track{
+		Length:           models.Bars(2),
+		BeatsPerMinute:   120,
+		BeatsPerMeasure:  2,
+		DivisionsPerBeat: 1,
+		Instruments:      []*models.Instrument{kick, snare},
+		Patterns:         [][]*models.Instrument{{kick, nil}, {nil, snare}},
+		Clock:            c,
+		Renderer:         render.NewPlain(w),
+	}
+
+	done := make(chan error)
+	go func() {
+		done <- track.Play()
+	}()
+
+	c.BlockUntil(1)
+	c.Advance(200 * time.Millisecond)
+	c.BlockUntil(1)
+
+	for i := 0; i < 5; i++ {
+		c.Advance(500 * time.Millisecond)
+	}
+
+	assert.Nil(t, <-done)
+	assert.Equal(t, "Playing track at BPM: 120\n\n"+
+		"Bar Beat Kick Snare\n"+
+		"  1    1    X     _\n"+
+		"  1    2    _     X\n"+
+		"  2    1    X     _\n"+
+		"  2    2    _     X\n", w.String())
+}
+
+// fakeSync is an external clock whose beat subdivisions and transport stop
+// are driven by the test.
+type fakeSync struct {
+	divisionsPerBeat int
+	step             func(position int)
+	following        chan struct{}
+	done             chan struct{}
+}
+
+func (s *fakeSync) Follow(divisionsPerBeat int, step func(position int)) (clock.Ticker, <-chan struct{}) {
+	s.divisionsPerBeat = divisionsPerBeat
+	s.step = step
+	close(s.following)
+
+	return s, s.done
+}
+
+func (s *fakeSync) Reset(time.Duration) {}
+
+func (s *fakeSync) Stop() {}
+
+func TestPlayFollowsSync(t *testing.T) {
+	type testCase struct {
+		description string
+		input       []int
+		expected    string
+	}
+
+	testCases := []testCase{
+		{
+			description: "in phase",
+			input:       []int{0, 1, 2},
+			expected: "Playing track at BPM: 60\n\n" +
+				"Bar Beat testInstrument\n" +
+				"  1    1              X\n" +
+				"  1                   _\n" +
+				"  2    1              X\n",
+		},
+		{
+			description: "joins the external clock mid bar",
+			input:       []int{5, 6, 7},
+			expected: "Playing track at BPM: 60\n\n" +
+				"Bar Beat testInstrument\n" +
+				"  1                   _\n" +
+				"  2    1              X\n" +
+				"  2                   _\n",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.description, func(t *testing.T) {
+			c := clock.NewManual(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+
+			m := &audiomocks.Manager{}
+			m.On("Play", 1.0).Return()
+
+			instrument := &models.Instrument{Name: "testInstrument", Pattern: []int{0}, Audio: m}
+			sync := &fakeSync{following: make(chan struct{}), done: make(chan struct{})}
+			w := &bytes.Buffer{}
+
+			track := &models.Track{
+				Length:           models.Infinite(),
+				BeatsPerMinute:   60,
+				BeatsPerMeasure:  1,
+				DivisionsPerBeat: 2,
+				Instruments:      []*models.Instrument{instrument},
+				Patterns:         [][]*models.Instrument{{instrument}, {nil}},
+				Clock:            c,
+				Renderer:         render.NewPlain(w),
+				Sync:             sync,
+			}
+
+			done := make(chan error)
+			go func() {
+				done <- track.Play()
+			}()
+
+			c.BlockUntil(1)
+			c.Advance(200 * time.Millisecond)
+			<-sync.following
+
+			assert.Equal(t, 2, sync.divisionsPerBeat)
+
+			// the track's own BPM is ignored, with subdivisions played as
+			// they're followed
+			c.Advance(time.Minute)
+
+			for _, position := range testCase.input {
+				sync.step(position)
+			}
+
+			close(sync.done)
+			assert.Nil(t, <-done)
+			assert.Equal(t, testCase.expected, w.String())
+		})
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	track := &models.Track{BeatsPerMinute: 60}
+
+	assert.Nil(t, track.Update(func(track *models.Track) error {
+		track.BeatsPerMinute = 90
+		return nil
+	}))
+	assert.Equal(t, 90.0, track.BeatsPerMinute)
+
+	assert.NotNil(t, track.Update(func(*models.Track) error {
+		return errors.New("invalid setting")
+	}))
+}
+
+func TestSetStep(t *testing.T) {
+	type input struct {
+		instrument int
+		step       int
+		on         bool
+	}
+
+	type testCase struct {
+		description     string
+		input           input
+		expectedPattern []int
+		expectedToError bool
+	}
+
+	// each step is set in turn on the same track
+	testCases := []testCase{
+		{
+			description:     "Turns a step on",
+			input:           input{instrument: 1, step: 1, on: true},
+			expectedPattern: []int{1, 2},
+			expectedToError: false,
+		},
+		{
+			description:     "Leaves a step that's already on",
+			input:           input{instrument: 1, step: 2, on: true},
+			expectedPattern: []int{1, 2},
+			expectedToError: false,
+		},
+		{
+			description:     "Turns a step off",
+			input:           input{instrument: 1, step: 2, on: false},
+			expectedPattern: []int{1},
+			expectedToError: false,
+		},
+		{
+			description:     "Leaves a step that's already off",
+			input:           input{instrument: 1, step: 3, on: false},
+			expectedPattern: []int{1},
+			expectedToError: false,
+		},
+		{
+			description:     "Errors setting a step outside of the bar",
+			input:           input{instrument: 1, step: 4, on: true},
+			expectedPattern: []int{1},
+			expectedToError: true,
+		},
+		{
+			description:     "Errors setting a step of an instrument the track doesn't have",
+			input:           input{instrument: 2, step: 0, on: true},
+			expectedPattern: []int{1},
+			expectedToError: true,
+		},
+	}
+
+	kick := &models.Instrument{Name: "Kick", Pattern: []int{0, 2}, Audio: &audiomocks.Manager{}}
+	snare := &models.Instrument{Name: "Snare", Pattern: []int{2}, Audio: &audiomocks.Manager{}}
+
+	track, err := models.NewTrack("testTitle", []*models.Instrument{kick, snare}, 120, 2, 2)
+	assert.Nil(t, err)
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		actualErr := track.Update(func(track *models.Track) error {
+			return track.SetStep(testCase.input.instrument, testCase.input.step, testCase.input.on)
+		})
+		assert.Equal(t, testCase.expectedToError, actualErr != nil, testCase.description)
+		assert.Equal(t, testCase.expectedPattern, snare.Pattern, testCase.description)
+
+		for step, instruments := range track.Patterns {
+			assert.Equal(t, contains(snare.Pattern, step), instruments[1] == snare, testCase.description)
+		}
+	}
+}
+
+func contains(steps []int, step int) bool {
+	for _, s := range steps {
+		if s == step {
+			return true
 		}
 	}
+
+	return false
 }
@@ -0,0 +1,243 @@
+// Package osc reads and writes Open Sound Control 1.0 packets.
+package osc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const bundleTag = "#bundle"
+
+// Message is a single OSC message: an address and its arguments. Arguments
+// are read and written as int32, float32, string, []byte (a blob), bool, int64
+// or float64, and nil is written and read as OSC's nil argument.
+type Message struct {
+	Address   string
+	Arguments []interface{}
+}
+
+// Parse returns the messages in an OSC packet, in order. The messages of
+// bundles, including nested bundles, are returned as though they'd each been
+// sent alone; their time tags are ignored.
+func Parse(packet []byte) ([]Message, error) {
+	if strings.HasPrefix(string(packet), bundleTag+"\x00") {
+		return parseBundle(packet)
+	}
+
+	message, err := parseMessage(packet)
+	if err != nil {
+		return nil, err
+	}
+
+	return []Message{message}, nil
+}
+
+func parseBundle(packet []byte) ([]Message, error) {
+	if len(packet) < len(bundleTag)+1+8 {
+		return nil, errors.New("bundle is missing its time tag")
+	}
+
+	// skip the tag and time tag
+	r := bytes.NewReader(packet[len(bundleTag)+1+8:])
+
+	var messages []Message
+	for r.Len() > 0 {
+		var size int32
+		if err := binary.Read(r, binary.BigEndian, &size); err != nil {
+			return nil, errors.Wrap(err, "error reading bundle element size")
+		}
+
+		if size < 0 || int(size) > r.Len() || size%4 != 0 {
+			return nil, fmt.Errorf("invalid bundle element size %d", size)
+		}
+
+		element := make([]byte, size)
+		_, _ = r.Read(element)
+
+		elementMessages, err := Parse(element)
+		if err != nil {
+			return nil, err
+		}
+
+		messages = append(messages, elementMessages...)
+	}
+
+	return messages, nil
+}
+
+func parseMessage(packet []byte) (Message, error) {
+	r := bytes.NewReader(packet)
+
+	address, err := readString(r)
+	if err != nil {
+		return Message{}, errors.Wrap(err, "error reading address")
+	}
+
+	if !strings.HasPrefix(address, "/") {
+		return Message{}, fmt.Errorf("invalid address %q", address)
+	}
+
+	message := Message{Address: address}
+
+	// old implementations may leave out the type tags of messages without
+	// arguments
+	if r.Len() == 0 {
+		return message, nil
+	}
+
+	tags, err := readString(r)
+	if err != nil {
+		return Message{}, errors.Wrap(err, "error reading type tags")
+	}
+
+	if !strings.HasPrefix(tags, ",") {
+		return Message{}, fmt.Errorf("invalid type tags %q", tags)
+	}
+
+	for _, tag := range tags[1:] {
+		argument, err := readArgument(r, tag)
+		if err != nil {
+			return Message{}, errors.Wrapf(err, "error reading %s argument", address)
+		}
+
+		message.Arguments = append(message.Arguments, argument)
+	}
+
+	return message, nil
+}
+
+func readArgument(r *bytes.Reader, tag rune) (interface{}, error) {
+	switch tag {
+	case 'i':
+		var i int32
+		err := binary.Read(r, binary.BigEndian, &i)
+		return i, err
+	case 'f':
+		var f float32
+		err := binary.Read(r, binary.BigEndian, &f)
+		return f, err
+	case 'h':
+		var h int64
+		err := binary.Read(r, binary.BigEndian, &h)
+		return h, err
+	case 'd':
+		var d float64
+		err := binary.Read(r, binary.BigEndian, &d)
+		return d, err
+	case 's':
+		return readString(r)
+	case 'b':
+		return readBlob(r)
+	case 'T':
+		return true, nil
+	case 'F':
+		return false, nil
+	case 'N':
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unsupported type tag %q", tag)
+	}
+}
+
+// readString reads a null-terminated string, padded to a multiple of 4 bytes.
+func readString(r *bytes.Reader) (string, error) {
+	var s strings.Builder
+
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return "", errors.New("string isn't null-terminated")
+		}
+
+		if b == 0 {
+			break
+		}
+
+		s.WriteByte(b)
+	}
+
+	// the terminator and padding take the string to a multiple of 4 bytes
+	for padding := (4 - (s.Len()+1)%4) % 4; padding > 0; padding-- {
+		if _, err := r.ReadByte(); err != nil {
+			return "", errors.New("string isn't padded")
+		}
+	}
+
+	return s.String(), nil
+}
+
+func readBlob(r *bytes.Reader) ([]byte, error) {
+	var size int32
+	if err := binary.Read(r, binary.BigEndian, &size); err != nil {
+		return nil, err
+	}
+
+	padded := int(size) + (4-int(size)%4)%4
+	if size < 0 || padded > r.Len() {
+		return nil, fmt.Errorf("invalid blob size %d", size)
+	}
+
+	blob := make([]byte, padded)
+	_, _ = r.Read(blob)
+
+	return blob[:size], nil
+}
+
+// MarshalBinary returns the message as an OSC packet. Returns an error if it
+// has an argument of an unsupported type.
+func (m Message) MarshalBinary() ([]byte, error) {
+	tags := ","
+	arguments := &bytes.Buffer{}
+
+	for _, argument := range m.Arguments {
+		switch a := argument.(type) {
+		case int32:
+			tags += "i"
+			_ = binary.Write(arguments, binary.BigEndian, a)
+		case float32:
+			tags += "f"
+			_ = binary.Write(arguments, binary.BigEndian, math.Float32bits(a))
+		case int64:
+			tags += "h"
+			_ = binary.Write(arguments, binary.BigEndian, a)
+		case float64:
+			tags += "d"
+			_ = binary.Write(arguments, binary.BigEndian, math.Float64bits(a))
+		case string:
+			tags += "s"
+			writeString(arguments, a)
+		case []byte:
+			tags += "b"
+			_ = binary.Write(arguments, binary.BigEndian, int32(len(a)))
+			arguments.Write(a)
+			arguments.Write(make([]byte, (4-len(a)%4)%4))
+		case bool:
+			if a {
+				tags += "T"
+			} else {
+				tags += "F"
+			}
+		case nil:
+			tags += "N"
+		default:
+			return nil, fmt.Errorf("unsupported argument type %T", argument)
+		}
+	}
+
+	packet := &bytes.Buffer{}
+	writeString(packet, m.Address)
+	writeString(packet, tags)
+	packet.Write(arguments.Bytes())
+
+	return packet.Bytes(), nil
+}
+
+func writeString(b *bytes.Buffer, s string) {
+	b.WriteString(s)
+	b.Write(make([]byte, 4-len(s)%4))
+}
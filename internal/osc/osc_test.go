@@ -0,0 +1,156 @@
+package osc_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jcfox412/logarhythms/internal/osc"
+)
+
+func TestParse(t *testing.T) {
+	type testCase struct {
+		description     string
+		input           []byte
+		expectedOutput  []osc.Message
+		expectedToError bool
+	}
+
+	testCases := []testCase{
+		{
+			description:     "Parses message without arguments",
+			input:           []byte("/transport/play\x00,\x00\x00\x00"),
+			expectedOutput:  []osc.Message{{Address: "/transport/play"}},
+			expectedToError: false,
+		},
+		{
+			description:     "Parses message without type tags",
+			input:           []byte("/transport/stop\x00"),
+			expectedOutput:  []osc.Message{{Address: "/transport/stop"}},
+			expectedToError: false,
+		},
+		{
+			description:     "Parses int argument",
+			input:           []byte("/tempo\x00\x00,i\x00\x00\x00\x00\x00\x80"),
+			expectedOutput:  []osc.Message{{Address: "/tempo", Arguments: []interface{}{int32(128)}}},
+			expectedToError: false,
+		},
+		{
+			description: "Parses float, string, blob and bool arguments",
+			input: []byte("/a\x00\x00,fsbTFN\x00" +
+				"\x42\x8c\x00\x00" +
+				"snare\x00\x00\x00" +
+				"\x00\x00\x00\x03\x01\x02\x03\x00"),
+			expectedOutput: []osc.Message{{Address: "/a", Arguments: []interface{}{
+				float32(70), "snare", []byte{1, 2, 3}, true, false, nil,
+			}}},
+			expectedToError: false,
+		},
+		{
+			description: "Parses messages of a nested bundle",
+			input: []byte("#bundle\x00\x00\x00\x00\x00\x00\x00\x00\x01" +
+				"\x00\x00\x00\x10/transport/play\x00" +
+				"\x00\x00\x00\x1c#bundle\x00\x00\x00\x00\x00\x00\x00\x00\x01" +
+				"\x00\x00\x00\x08/a\x00\x00,\x00\x00\x00"),
+			expectedOutput:  []osc.Message{{Address: "/transport/play"}, {Address: "/a"}},
+			expectedToError: false,
+		},
+		{
+			description:     "Errors with unterminated address",
+			input:           []byte("/tempo"),
+			expectedOutput:  nil,
+			expectedToError: true,
+		},
+		{
+			description:     "Errors with address not starting with a slash",
+			input:           []byte("tempo\x00\x00\x00"),
+			expectedOutput:  nil,
+			expectedToError: true,
+		},
+		{
+			description:     "Errors with missing argument",
+			input:           []byte("/tempo\x00\x00,i\x00\x00"),
+			expectedOutput:  nil,
+			expectedToError: true,
+		},
+		{
+			description:     "Errors with unsupported type tag",
+			input:           []byte("/tempo\x00\x00,m\x00\x00\x00\x00\x00\x80"),
+			expectedOutput:  nil,
+			expectedToError: true,
+		},
+		{
+			description:     "Errors with bundle element longer than the bundle",
+			input:           []byte("#bundle\x00\x00\x00\x00\x00\x00\x00\x00\x01\x00\x00\x00\x40/a\x00\x00"),
+			expectedOutput:  nil,
+			expectedToError: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		actualOutput, actualErr := osc.Parse(testCase.input)
+		if testCase.expectedToError {
+			assert.NotNil(t, actualErr, testCase.description)
+		} else {
+			assert.Nil(t, actualErr, testCase.description)
+			assert.Equal(t, testCase.expectedOutput, actualOutput, testCase.description)
+		}
+	}
+}
+
+func TestMarshalBinary(t *testing.T) {
+	type testCase struct {
+		description     string
+		input           osc.Message
+		expectedOutput  []byte
+		expectedToError bool
+	}
+
+	testCases := []testCase{
+		{
+			description:     "Marshals message without arguments",
+			input:           osc.Message{Address: "/transport/play"},
+			expectedOutput:  []byte("/transport/play\x00,\x00\x00\x00"),
+			expectedToError: false,
+		},
+		{
+			description: "Marshals arguments of every type",
+			input: osc.Message{Address: "/a", Arguments: []interface{}{
+				int32(128), float32(70), "snare", []byte{1, 2, 3}, true, false, nil, int64(1), float64(0.5),
+			}},
+			expectedOutput: []byte("/a\x00\x00,ifsbTFNhd\x00\x00" +
+				"\x00\x00\x00\x80" +
+				"\x42\x8c\x00\x00" +
+				"snare\x00\x00\x00" +
+				"\x00\x00\x00\x03\x01\x02\x03\x00" +
+				"\x00\x00\x00\x00\x00\x00\x00\x01" +
+				"\x3f\xe0\x00\x00\x00\x00\x00\x00"),
+			expectedToError: false,
+		},
+		{
+			description:     "Errors with unsupported argument type",
+			input:           osc.Message{Address: "/a", Arguments: []interface{}{128}},
+			expectedOutput:  nil,
+			expectedToError: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		actualOutput, actualErr := testCase.input.MarshalBinary()
+		if testCase.expectedToError {
+			assert.NotNil(t, actualErr, testCase.description)
+		} else {
+			assert.Nil(t, actualErr, testCase.description)
+			assert.Equal(t, testCase.expectedOutput, actualOutput, testCase.description)
+
+			// marshalled messages can be parsed back
+			parsed, err := osc.Parse(actualOutput)
+			assert.Nil(t, err, testCase.description)
+			assert.Equal(t, []osc.Message{testCase.input}, parsed, testCase.description)
+		}
+	}
+}
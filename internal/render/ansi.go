@@ -0,0 +1,148 @@
+package render
+
+import (
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/jcfox412/logarhythms/internal/utils"
+)
+
+const (
+	headerPadding = 2
+	clipIndicator = " CLIP"
+)
+
+// ansiRenderer redraws a bar of the track's pattern in place using ANSI escape
+// codes, with a row per instrument and a marker following the playing beat.
+type ansiRenderer struct {
+	// Guards drawing, as messages can arrive between steps
+	mu sync.Mutex
+	w  io.Writer
+	// Whether the clip indicator is drawn in bold
+	styled      bool
+	title       string
+	instruments int
+	headerWidth int
+	steps       int
+	// Whether the grid is drawn, so messages can be drawn above it
+	started bool
+}
+
+// NewANSI returns a renderer which draws the track as a grid, redrawn in place
+// every bar. Styled renderers draw the clip indicator in bold.
+func NewANSI(w io.Writer, styled bool) Renderer {
+	return &ansiRenderer{w: w, styled: styled}
+}
+
+func (a *ansiRenderer) Start(track Track) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.started = true
+	a.title = track.Title
+	a.instruments = len(track.Instruments)
+	a.steps = 0
+
+	fmt.Fprintf(a.w, "%s\n\n", track.Title)
+
+	header, headerWidth := printHeaders(track.Instruments)
+	a.headerWidth = headerWidth
+
+	fmt.Fprint(a.w, header)
+	fmt.Fprint(a.w, utils.HideCursor())
+	fmt.Fprint(a.w, utils.ClearLine(a.headerWidth))
+}
+
+func (a *ansiRenderer) Step(step Step) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if step.Division == 0 && a.steps > 0 {
+		fmt.Fprint(a.w, utils.ClearLine(a.headerWidth))
+	}
+
+	fmt.Fprint(a.w, utils.CursorToNextColumn(a.instruments+1))
+	fmt.Fprint(a.w, printStep(step))
+
+	a.steps++
+}
+
+func (a *ansiRenderer) Clip() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	indicator := clipIndicator
+	if a.styled {
+		indicator = utils.Bold(indicator)
+	}
+
+	// the indicator sits beside the title, above the instruments and beat count
+	fmt.Fprint(a.w, utils.Indicator(indicator, a.instruments+2, len(a.title)))
+}
+
+func (a *ansiRenderer) Stop() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.started = false
+
+	fmt.Fprint(a.w, utils.ShowCursor())
+	fmt.Fprintln(a.w)
+}
+
+// Message draws the text on the blank line between the title and the
+// instruments, while the grid is drawn.
+func (a *ansiRenderer) Message(text string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.started {
+		fmt.Fprint(a.w, utils.ReplaceLine(text, a.instruments+1))
+	}
+}
+
+// printStep returns the column drawn for a step, from the beat count down
+// through each instrument, finishing with the beat marker.
+func printStep(step Step) string {
+	stepStr := step.Count
+	stepStr += utils.CursorToNextRow()
+
+	for _, hit := range step.Hits {
+		stepStr += fmt.Sprintf("%s|", glyph(hit))
+		stepStr += utils.CursorToNextRow()
+	}
+
+	stepStr += utils.BeatTracker()
+
+	return stepStr
+}
+
+// printHeaders returns a row naming each instrument, right aligned, and the
+// width the rows take up.
+func printHeaders(instruments []string) (string, int) {
+	longestInstrument := 0
+	header := ""
+
+	for _, instrument := range instruments {
+		if len(instrument) > longestInstrument {
+			longestInstrument = len(instrument)
+		}
+	}
+
+	for _, instrument := range instruments {
+		header += fmt.Sprintf("%*s: |\n", longestInstrument, instrument)
+	}
+
+	return header, longestInstrument + headerPadding
+}
+
+// glyph returns the character a hit is drawn with, or an underscore if the
+// instrument doesn't play.
+func glyph(hit Hit) string {
+	if hit.Glyph == "" {
+		return "_"
+	}
+
+	return hit.Glyph
+}
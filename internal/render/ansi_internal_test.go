@@ -0,0 +1,148 @@
+package render
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrintHeaders(t *testing.T) {
+	type output struct {
+		header      string
+		headerWidth int
+	}
+
+	type testCase struct {
+		description    string
+		input          []string
+		expectedOutput output
+	}
+
+	testCases := []testCase{
+		{
+			description: "Handles no instruments",
+			input:       nil,
+			expectedOutput: output{
+				header:      "",
+				headerWidth: 2,
+			},
+		},
+		{
+			description: "Handles one instrument",
+			input:       []string{"Snare"},
+			expectedOutput: output{
+				header:      "Snare: |\n",
+				headerWidth: 7,
+			},
+		},
+		{
+			description: "Handles multiple instrument",
+			input:       []string{"Kick", "Snare"},
+			expectedOutput: output{
+				header:      " Kick: |\nSnare: |\n",
+				headerWidth: 7,
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		actualHeader, actualHeaderWidth := printHeaders(testCase.input)
+		assert.Equal(t, testCase.expectedOutput.header, actualHeader)
+		assert.Equal(t, testCase.expectedOutput.headerWidth, actualHeaderWidth)
+	}
+}
+
+func TestPrintStep(t *testing.T) {
+	type testCase struct {
+		description    string
+		input          Step
+		expectedOutput string
+	}
+
+	testCases := []testCase{
+		{
+			description:    "Handles no instruments",
+			input:          Step{Count: "1 "},
+			expectedOutput: "1 \x1b[1B\x1b[2D\x1b[3D   *",
+		},
+		{
+			description:    "Handles one instrument playing and one not",
+			input:          Step{Count: "1 ", Hits: []Hit{{Glyph: "X", Velocity: 1}, {}}},
+			expectedOutput: "1 \x1b[1B\x1b[2DX|\x1b[1B\x1b[2D_|\x1b[1B\x1b[2D\x1b[3D   *",
+		},
+		{
+			description:    "Handles articulated instrument between beats",
+			input:          Step{Count: "  ", Hits: []Hit{{Glyph: "3", Velocity: 1}}},
+			expectedOutput: "  \x1b[1B\x1b[2D3|\x1b[1B\x1b[2D\x1b[3D   *",
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		assert.Equal(t, testCase.expectedOutput, printStep(testCase.input), testCase.description)
+	}
+}
+
+func TestANSIRenderer(t *testing.T) {
+	type testCase struct {
+		description    string
+		styled         bool
+		expectedOutput string
+	}
+
+	testCases := []testCase{
+		{
+			description: "Draws bold clip indicator when styled",
+			styled:      true,
+			expectedOutput: "BPM\n\nKick: |\n\x1b[?25l\x1b[G\x1b[K\x1b[6C" +
+				"\x1b[2A\x1b[1C1 \x1b[1B\x1b[2DX|\x1b[1B\x1b[2D\x1b[3D   *" +
+				"\x1b[G\x1b[K\x1b[6C\x1b[2A\x1b[1C1 \x1b[1B\x1b[2D_|\x1b[1B\x1b[2D\x1b[3D   *" +
+				"\x1b7\x1b[3A\x1b[G\x1b[3C\x1b[1m CLIP\x1b[0m\x1b8" +
+				"\x1b[?25h\n",
+		},
+		{
+			description: "Draws plain clip indicator when unstyled",
+			styled:      false,
+			expectedOutput: "BPM\n\nKick: |\n\x1b[?25l\x1b[G\x1b[K\x1b[6C" +
+				"\x1b[2A\x1b[1C1 \x1b[1B\x1b[2DX|\x1b[1B\x1b[2D\x1b[3D   *" +
+				"\x1b[G\x1b[K\x1b[6C\x1b[2A\x1b[1C1 \x1b[1B\x1b[2D_|\x1b[1B\x1b[2D\x1b[3D   *" +
+				"\x1b7\x1b[3A\x1b[G\x1b[3C CLIP\x1b8" +
+				"\x1b[?25h\n",
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+		w := &bytes.Buffer{}
+
+		renderer := NewANSI(w, testCase.styled)
+		renderer.Start(Track{Title: "BPM", Instruments: []string{"Kick"}})
+		renderer.Step(Step{Bar: 1, Division: 0, Count: "1 ", Hits: []Hit{{Glyph: "X", Velocity: 1}}})
+		// a new bar clears the last one
+		renderer.Step(Step{Bar: 2, Division: 0, Count: "1 ", Hits: []Hit{{}}})
+		renderer.Clip()
+		renderer.Stop()
+
+		assert.Equal(t, testCase.expectedOutput, w.String(), testCase.description)
+	}
+}
+
+func TestANSIRendererMessage(t *testing.T) {
+	w := &bytes.Buffer{}
+
+	renderer := NewANSI(w, false).(*ansiRenderer)
+
+	// messages are only drawn above the grid while it's drawn
+	renderer.Message("Before")
+	renderer.Start(Track{Title: "BPM", Instruments: []string{"Kick"}})
+	w.Reset()
+	renderer.Message("Tapped 92 BPM")
+	renderer.Stop()
+	renderer.Message("After")
+
+	assert.Equal(t, "\x1b7\x1b[2A\x1b[GTapped 92 BPM\x1b[K\x1b8\x1b[?25h\n", w.String())
+}
@@ -0,0 +1,57 @@
+package render
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// plainRenderer writes a line per step, for terminals without ANSI escape
+// codes and output which is piped or redirected.
+type plainRenderer struct {
+	w      io.Writer
+	widths []int
+}
+
+// NewPlain returns a renderer which writes each step as a line of text, with a
+// column per instrument.
+func NewPlain(w io.Writer) Renderer {
+	return &plainRenderer{w: w}
+}
+
+func (p *plainRenderer) Start(track Track) {
+	p.widths = make([]int, len(track.Instruments))
+
+	header := "Bar Beat"
+	for i, instrument := range track.Instruments {
+		p.widths[i] = len(instrument)
+		header += " " + instrument
+	}
+
+	fmt.Fprintf(p.w, "%s\n\n%s\n", track.Title, header)
+}
+
+func (p *plainRenderer) Step(step Step) {
+	line := fmt.Sprintf("%3d %4s", step.Bar, strings.TrimSpace(step.Count))
+
+	for i, hit := range step.Hits {
+		width := 1
+		if i < len(p.widths) {
+			width = p.widths[i]
+		}
+
+		line += fmt.Sprintf(" %*s", width, glyph(hit))
+	}
+
+	fmt.Fprintln(p.w, line)
+}
+
+func (p *plainRenderer) Clip() {
+	fmt.Fprintln(p.w, "CLIP")
+}
+
+func (p *plainRenderer) Stop() {}
+
+func (p *plainRenderer) Message(text string) {
+	fmt.Fprintln(p.w, text)
+}
@@ -0,0 +1,28 @@
+package render_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/jcfox412/logarhythms/internal/render"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlainRenderer(t *testing.T) {
+	w := &bytes.Buffer{}
+
+	renderer := render.NewPlain(w)
+	renderer.Start(render.Track{Title: "Playing track at BPM: 120", Instruments: []string{"Kick", "Hi-Hat"}})
+	renderer.Step(render.Step{Bar: 1, Division: 0, Count: "1 ", Hits: []render.Hit{{Glyph: "X", Velocity: 1}, {Glyph: "o", Velocity: 1}}})
+	renderer.Step(render.Step{Bar: 1, Division: 1, Count: "  ", Hits: []render.Hit{{}, {Glyph: "X", Velocity: 1}}})
+	renderer.Clip()
+	renderer.Step(render.Step{Bar: 12, Division: 0, Count: "10", Hits: []render.Hit{{Glyph: "f", Velocity: 1}, {}}})
+	renderer.Stop()
+
+	assert.Equal(t, "Playing track at BPM: 120\n\n"+
+		"Bar Beat Kick Hi-Hat\n"+
+		"  1    1    X      o\n"+
+		"  1         _      X\n"+
+		"CLIP\n"+
+		" 12   10    f      _\n", w.String())
+}
@@ -0,0 +1,212 @@
+package render
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// Names of the available renderers
+const (
+	Auto   = "auto"
+	ANSI   = "ansi"
+	TUI    = "tui"
+	Plain  = "plain"
+	Silent = "silent"
+)
+
+// Renderers lists the name of every renderer, in the order they're offered to
+// users.
+var Renderers = []string{Auto, ANSI, TUI, Plain, Silent}
+
+// Renderer draws a track as it's played.
+type Renderer interface {
+	// Start draws the track, before its first step
+	Start(track Track)
+	// Step draws a beat subdivision as it's played
+	Step(step Step)
+	// Clip shows that the mix has clipped, at most once per track
+	Clip()
+	// Stop finishes drawing the track, however it stopped
+	Stop()
+}
+
+// Messenger is a renderer which can show a short message as the track plays,
+// such as the tempo being tapped.
+type Messenger interface {
+	// Message shows text in place of any message shown before it
+	Message(text string)
+}
+
+// Message shows text with the renderer, if it can show messages.
+func Message(r Renderer, text string) {
+	if m, ok := r.(Messenger); ok {
+		m.Message(text)
+	}
+}
+
+// Retimer is a renderer which follows changes to the tempo of a playing
+// track, such as a new BPM set while it plays.
+type Retimer interface {
+	// Retime is called before the first step played at the new tempo, with
+	// the track's new BPM, duration of a step, and number of steps it plays
+	// for in all
+	Retime(beatsPerMinute float64, stepDuration time.Duration, totalSteps int)
+}
+
+// Retime tells the renderer the track's tempo has changed, if it follows
+// tempo changes.
+func Retime(r Renderer, beatsPerMinute float64, stepDuration time.Duration, totalSteps int) {
+	if t, ok := r.(Retimer); ok {
+		t.Retime(beatsPerMinute, stepDuration, totalSteps)
+	}
+}
+
+// Track describes the track being drawn.
+type Track struct {
+	Title          string
+	BeatsPerMinute float64
+	// Name of each instrument
+	Instruments []string
+	// Each beat subdivision of the track's pattern, without bars or indexes
+	Pattern          []Step
+	DivisionsPerBeat int
+	// Duration of a single beat subdivision
+	StepDuration time.Duration
+	// Number of beat subdivisions the track plays for, or 0 if it plays until
+	// stopped
+	TotalSteps int
+	// CurrentPattern returns each beat subdivision of the track's pattern as
+	// it's set now, with any changes made while it plays, or is nil if the
+	// pattern can't change
+	CurrentPattern func() []Step
+	// Levels returns the current level of each instrument, from 0 to 1, or is
+	// nil if they can't be measured
+	Levels func() []float64
+}
+
+// Step is a single beat subdivision of a track.
+type Step struct {
+	// Number of subdivisions played before this one
+	Index int
+	// Bar the subdivision is in, counting from 1
+	Bar int
+	// Position of the subdivision within its bar, counting from 0
+	Division int
+	// Beat count drawn above the subdivision, blank between beats
+	Count string
+	// Each instrument's hit, in the order of the track's instruments
+	Hits []Hit
+}
+
+// Hit is an instrument's articulation in a beat subdivision.
+type Hit struct {
+	// Character the articulation is drawn with, or empty if the instrument
+	// doesn't play
+	Glyph string
+	// How hard the instrument is played, from 0 to 1
+	Velocity float64
+}
+
+// New creates the renderer with the given name, writing to w. Auto picks a
+// renderer to suit w and the TERM and NO_COLOR environment variables. Returns
+// an error if no renderer has the name.
+func New(name string, w io.Writer) (Renderer, error) {
+	switch name {
+	case Auto:
+		return detect(w, isTerminal(w), os.Getenv("TERM"), os.Getenv("NO_COLOR") != ""), nil
+	case ANSI:
+		return NewANSI(w, true), nil
+	case TUI:
+		return NewTUI(w, os.Getenv("NO_COLOR") == ""), nil
+	case Plain:
+		return NewPlain(w), nil
+	case Silent:
+		return silent{}, nil
+	default:
+		return nil, fmt.Errorf("unknown renderer %q, must be one of %v", name, Renderers)
+	}
+}
+
+// NewSilent returns a renderer which draws nothing.
+func NewSilent() Renderer {
+	return silent{}
+}
+
+// detect draws the grid on terminals which understand ANSI escape codes, left
+// unstyled if noColor is set, and plain lines anywhere else.
+func detect(w io.Writer, terminal bool, term string, noColor bool) Renderer {
+	if !terminal || term == "" || term == "dumb" {
+		return NewPlain(w)
+	}
+
+	return NewANSI(w, !noColor)
+}
+
+// isTerminal reports whether w is a terminal, rather than a file or pipe.
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// silent draws nothing.
+type silent struct{}
+
+func (silent) Start(Track) {}
+func (silent) Step(Step)   {}
+func (silent) Clip()       {}
+func (silent) Stop()       {}
+
+// multi draws with every one of its renderers in turn.
+type multi []Renderer
+
+// Multi returns a renderer which draws with each of the renderers, in order.
+func Multi(renderers ...Renderer) Renderer {
+	return multi(renderers)
+}
+
+func (m multi) Start(track Track) {
+	for _, r := range m {
+		r.Start(track)
+	}
+}
+
+func (m multi) Step(step Step) {
+	for _, r := range m {
+		r.Step(step)
+	}
+}
+
+func (m multi) Clip() {
+	for _, r := range m {
+		r.Clip()
+	}
+}
+
+func (m multi) Stop() {
+	for _, r := range m {
+		r.Stop()
+	}
+}
+
+func (m multi) Message(text string) {
+	for _, r := range m {
+		Message(r, text)
+	}
+}
+
+func (m multi) Retime(beatsPerMinute float64, stepDuration time.Duration, totalSteps int) {
+	for _, r := range m {
+		Retime(r, beatsPerMinute, stepDuration, totalSteps)
+	}
+}
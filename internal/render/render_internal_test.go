@@ -0,0 +1,129 @@
+package render
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew(t *testing.T) {
+	type testCase struct {
+		description     string
+		input           string
+		expectedOutput  Renderer
+		expectedToError bool
+	}
+
+	w := &bytes.Buffer{}
+
+	testCases := []testCase{
+		{
+			description:     "Creates ANSI renderer",
+			input:           ANSI,
+			expectedOutput:  &ansiRenderer{w: w, styled: true},
+			expectedToError: false,
+		},
+		{
+			description:     "Creates plain renderer",
+			input:           Plain,
+			expectedOutput:  &plainRenderer{w: w},
+			expectedToError: false,
+		},
+		{
+			description:     "Creates silent renderer",
+			input:           Silent,
+			expectedOutput:  silent{},
+			expectedToError: false,
+		},
+		{
+			description:     "Detects plain renderer when not writing to a terminal",
+			input:           Auto,
+			expectedOutput:  &plainRenderer{w: w},
+			expectedToError: false,
+		},
+		{
+			description:     "Errors with unknown renderer",
+			input:           "fancy",
+			expectedOutput:  nil,
+			expectedToError: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		actualOutput, actualErr := New(testCase.input, w)
+		if testCase.expectedToError {
+			assert.NotNil(t, actualErr, testCase.description)
+		} else {
+			assert.Nil(t, actualErr, testCase.description)
+		}
+
+		assert.Equal(t, testCase.expectedOutput, actualOutput, testCase.description)
+	}
+}
+
+func TestDetect(t *testing.T) {
+	type input struct {
+		terminal bool
+		term     string
+		noColor  bool
+	}
+
+	type testCase struct {
+		description    string
+		input          input
+		expectedOutput Renderer
+	}
+
+	w := &bytes.Buffer{}
+
+	testCases := []testCase{
+		{
+			description:    "Draws plain lines when not a terminal",
+			input:          input{terminal: false, term: "xterm-256color"},
+			expectedOutput: &plainRenderer{w: w},
+		},
+		{
+			description:    "Draws plain lines on a dumb terminal",
+			input:          input{terminal: true, term: "dumb"},
+			expectedOutput: &plainRenderer{w: w},
+		},
+		{
+			description:    "Draws plain lines when TERM isn't set",
+			input:          input{terminal: true, term: ""},
+			expectedOutput: &plainRenderer{w: w},
+		},
+		{
+			description:    "Draws styled grid on a terminal",
+			input:          input{terminal: true, term: "xterm-256color"},
+			expectedOutput: &ansiRenderer{w: w, styled: true},
+		},
+		{
+			description:    "Draws unstyled grid when NO_COLOR is set",
+			input:          input{terminal: true, term: "xterm-256color", noColor: true},
+			expectedOutput: &ansiRenderer{w: w, styled: false},
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		actualOutput := detect(w, testCase.input.terminal, testCase.input.term, testCase.input.noColor)
+		assert.Equal(t, testCase.expectedOutput, actualOutput, testCase.description)
+	}
+}
+
+func TestIsTerminal(t *testing.T) {
+	file, err := ioutil.TempFile("", "render")
+	assert.Nil(t, err)
+
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	assert.False(t, isTerminal(file))
+	assert.False(t, isTerminal(&bytes.Buffer{}))
+}
@@ -0,0 +1,58 @@
+package render_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/jcfox412/logarhythms/internal/render"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMulti(t *testing.T) {
+	first := &bytes.Buffer{}
+	second := &bytes.Buffer{}
+
+	renderer := render.Multi(render.NewPlain(first), render.NewSilent(), render.NewPlain(second))
+	renderer.Start(render.Track{Title: "Playing track at BPM: 120", Instruments: []string{"Kick"}})
+	renderer.Step(render.Step{Bar: 1, Division: 0, Count: "1 ", Hits: []render.Hit{{Glyph: "X", Velocity: 1}}})
+	renderer.Clip()
+	render.Message(renderer, "Tapped 92 BPM")
+	renderer.Stop()
+
+	expected := "Playing track at BPM: 120\n\nBar Beat Kick\n  1    1    X\nCLIP\nTapped 92 BPM\n"
+	assert.Equal(t, expected, first.String())
+	assert.Equal(t, expected, second.String())
+}
+
+// retimer records the tempos it's retimed to.
+type retimer struct {
+	render.Renderer
+	tempos []float64
+}
+
+func (r *retimer) Retime(beatsPerMinute float64, stepDuration time.Duration, totalSteps int) {
+	r.tempos = append(r.tempos, beatsPerMinute)
+}
+
+func TestRetime(t *testing.T) {
+	first := &retimer{Renderer: render.NewSilent()}
+	second := &retimer{Renderer: render.NewSilent()}
+
+	// renderers which don't follow tempo changes are left alone
+	render.Retime(render.Multi(first, render.NewSilent(), second), 92.5, 324*time.Millisecond, 16)
+	render.Retime(render.NewSilent(), 92.5, 324*time.Millisecond, 16)
+
+	assert.Equal(t, []float64{92.5}, first.tempos)
+	assert.Equal(t, []float64{92.5}, second.tempos)
+}
+
+func TestMessage(t *testing.T) {
+	w := &bytes.Buffer{}
+
+	// renderers which can't show messages are left alone
+	render.Message(render.NewSilent(), "Tapped 92 BPM")
+	render.Message(render.NewPlain(w), "Tapped 92 BPM")
+
+	assert.Equal(t, "Tapped 92 BPM\n", w.String())
+}
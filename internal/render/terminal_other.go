@@ -0,0 +1,18 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd
+
+package render
+
+import (
+	"io"
+	"os"
+)
+
+// terminalSize can't find the size of the terminal on this platform, so the
+// default size is always used.
+func terminalSize(io.Writer) (int, int, bool) {
+	return 0, 0, false
+}
+
+// notifyResize never sends, as resizes can't be watched for on this platform.
+func notifyResize(chan<- os.Signal) {}
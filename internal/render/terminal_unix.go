@@ -0,0 +1,37 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+// +build darwin dragonfly freebsd linux netbsd openbsd
+
+package render
+
+import (
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"unsafe"
+)
+
+// terminalSize returns the width and height of the terminal w writes to.
+// Returns false if w isn't a terminal.
+func terminalSize(w io.Writer) (int, int, bool) {
+	f, ok := w.(*os.File)
+	if !ok {
+		return 0, 0, false
+	}
+
+	var size struct {
+		rows, columns, xPixels, yPixels uint16
+	}
+
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), uintptr(syscall.TIOCGWINSZ), uintptr(unsafe.Pointer(&size)))
+	if errno != 0 || size.columns == 0 || size.rows == 0 {
+		return 0, 0, false
+	}
+
+	return int(size.columns), int(size.rows), true
+}
+
+// notifyResize sends on c whenever the terminal is resized.
+func notifyResize(c chan<- os.Signal) {
+	signal.Notify(c, syscall.SIGWINCH)
+}
@@ -0,0 +1,409 @@
+package render
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jcfox412/logarhythms/internal/utils"
+)
+
+const (
+	// Size of the screen when the terminal's size can't be found
+	defaultWidth  = 80
+	defaultHeight = 24
+	// Number of characters in a level meter, and the quietest level it shows,
+	// in decibels
+	meterWidth = 16
+	meterFloor = -48.0
+	// Rows drawn around the instruments: the title, transport, a gap, the beat
+	// count and the playhead
+	tuiChromeRows = 5
+	// Velocities at or below which hits are drawn dim, and at or above which
+	// they're drawn bold
+	ghostVelocity  = 0.5
+	accentVelocity = 0.9
+)
+
+// SGR codes each instrument's hits are coloured with, in turn
+var instrumentColours = []string{"36", "33", "35", "32", "34", "31"}
+
+// tuiRenderer takes over the whole terminal, redrawing the track's full
+// pattern with a moving playhead, transport and level meters every step, and
+// whenever the terminal is resized.
+type tuiRenderer struct {
+	mu sync.Mutex
+	w  io.Writer
+	// Whether the screen is drawn in colour
+	styled  bool
+	track   Track
+	step    *Step
+	clipped bool
+	// Message shown beside the transport, or empty for none
+	message string
+	// Index of the first step played at the current tempo, and the time
+	// played before it
+	retimedAt int
+	retimed   time.Duration
+	resized   chan os.Signal
+	done      chan struct{}
+	wait      sync.WaitGroup
+}
+
+// NewTUI returns a renderer which draws the track full screen. Styled
+// renderers draw in colour.
+func NewTUI(w io.Writer, styled bool) Renderer {
+	return &tuiRenderer{w: w, styled: styled}
+}
+
+func (t *tuiRenderer) Start(track Track) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	// the pattern is copied, as each step drawn updates it
+	t.track = track
+	t.track.Pattern = append([]Step(nil), track.Pattern...)
+	t.step = nil
+	t.clipped = false
+	t.message = ""
+	t.retimedAt = 0
+	t.retimed = 0
+
+	fmt.Fprint(t.w, utils.EnterFullScreen())
+	fmt.Fprint(t.w, utils.HideCursor())
+	t.draw()
+
+	t.resized = make(chan os.Signal, 1)
+	t.done = make(chan struct{})
+	notifyResize(t.resized)
+
+	t.wait.Add(1)
+	go t.redrawOnResize()
+}
+
+func (t *tuiRenderer) Step(step Step) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.step = &step
+
+	// keeps the pattern drawn up to date with steps changed as it plays, the
+	// step playing drawn as it was played
+	if t.track.CurrentPattern != nil {
+		t.track.Pattern = t.track.CurrentPattern()
+	}
+
+	if step.Hits != nil && step.Division >= 0 && step.Division < len(t.track.Pattern) {
+		t.track.Pattern[step.Division].Hits = step.Hits
+	}
+
+	t.draw()
+}
+
+func (t *tuiRenderer) Clip() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.clipped = true
+	t.draw()
+}
+
+// Message shows the text beside the transport from the next step drawn.
+func (t *tuiRenderer) Message(text string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.message = text
+}
+
+// Retime shows the track's new tempo, timing the steps from the next one at
+// it.
+func (t *tuiRenderer) Retime(beatsPerMinute float64, stepDuration time.Duration, totalSteps int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	next := 0
+	if t.step != nil {
+		next = t.step.Index + 1
+	}
+
+	t.retimed = t.elapsed(next)
+	t.retimedAt = next
+	t.track.BeatsPerMinute = beatsPerMinute
+	t.track.StepDuration = stepDuration
+	t.track.TotalSteps = totalSteps
+}
+
+// Stop hands the terminal back, leaving the transport where the track stopped
+// in the shell's scrollback.
+func (t *tuiRenderer) Stop() {
+	signal.Stop(t.resized)
+	close(t.done)
+	t.wait.Wait()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	fmt.Fprint(t.w, utils.ShowCursor())
+	fmt.Fprint(t.w, utils.ExitFullScreen())
+	fmt.Fprintf(t.w, "%s\n%s\n", t.track.Title, t.transport())
+}
+
+func (t *tuiRenderer) redrawOnResize() {
+	defer t.wait.Done()
+
+	for {
+		select {
+		case <-t.done:
+			return
+		case <-t.resized:
+			t.mu.Lock()
+			fmt.Fprint(t.w, utils.ClearScreen())
+			t.draw()
+			t.mu.Unlock()
+		}
+	}
+}
+
+// draw redraws the whole screen over the last one.
+func (t *tuiRenderer) draw() {
+	width, height, ok := terminalSize(t.w)
+	if !ok {
+		width, height = defaultWidth, defaultHeight
+	}
+
+	fmt.Fprint(t.w, utils.CursorHome())
+	fmt.Fprint(t.w, t.frame(width, height))
+	fmt.Fprint(t.w, utils.ClearToEndOfScreen())
+}
+
+// frame returns the screen for the current step, fitted to the given size.
+// Level meters are dropped if there isn't room for them, and patterns too
+// wide to fit are shown a page at a time.
+func (t *tuiRenderer) frame(width, height int) string {
+	title := truncate(t.track.Title, width)
+	if t.clipped && len(title)+len(clipIndicator) <= width {
+		title += t.style(clipIndicator, "1", "31")
+	}
+
+	transport := t.transport()
+	if t.message != "" {
+		transport += "   " + t.message
+	}
+
+	lines := []string{title, truncate(transport, width), ""}
+
+	nameWidth := 0
+	for _, instrument := range t.track.Instruments {
+		if len(instrument) > nameWidth {
+			nameWidth = len(instrument)
+		}
+	}
+
+	var levels []float64
+	if t.track.Levels != nil {
+		levels = t.track.Levels()
+	}
+
+	cellsWidth := width - nameWidth - headerPadding
+	if levels != nil {
+		cellsWidth -= meterWidth + 3
+	}
+
+	if cellsWidth < 2 && levels != nil {
+		levels = nil
+		cellsWidth += meterWidth + 3
+	}
+
+	first, last := t.page(cellsWidth / 2)
+	playing := -1
+	if t.step != nil {
+		playing = t.step.Division
+	}
+
+	counts := strings.Repeat(" ", nameWidth+headerPadding)
+	for i := first; i < last; i++ {
+		counts += fmt.Sprintf("%-2s", t.track.Pattern[i].Count)
+	}
+	lines = append(lines, counts)
+
+	rows := len(t.track.Instruments)
+	if rows > height-tuiChromeRows {
+		rows = max(height-tuiChromeRows, 0)
+	}
+
+	for row := 0; row < rows; row++ {
+		line := fmt.Sprintf("%*s: ", nameWidth, t.track.Instruments[row])
+
+		for i := first; i < last; i++ {
+			var hit Hit
+			if row < len(t.track.Pattern[i].Hits) {
+				hit = t.track.Pattern[i].Hits[row]
+			}
+
+			line += t.cell(hit, row, i == playing)
+		}
+
+		if row < len(levels) {
+			line += " " + t.meter(levels[row])
+		}
+
+		lines = append(lines, line)
+	}
+
+	playhead := ""
+	if playing >= first && playing < last {
+		playhead = strings.Repeat(" ", nameWidth+headerPadding+(playing-first)*2) + "^"
+	}
+	lines = append(lines, playhead)
+
+	if len(lines) > height {
+		lines = lines[:height]
+	}
+
+	return strings.Join(lines, utils.ClearToEndOfLine()+"\n") + utils.ClearToEndOfLine()
+}
+
+// page returns the first and last (exclusive) steps of the pattern shown, when
+// only visible steps fit on the screen. Pages are whole beats where they fit.
+func (t *tuiRenderer) page(visible int) (int, int) {
+	steps := len(t.track.Pattern)
+	if visible <= 0 {
+		return 0, 0
+	}
+
+	if divisionsPerBeat := t.track.DivisionsPerBeat; divisionsPerBeat > 0 && visible >= divisionsPerBeat {
+		visible -= visible % divisionsPerBeat
+	}
+
+	if visible >= steps || t.step == nil {
+		return 0, min(visible, steps)
+	}
+
+	first := t.step.Division / visible * visible
+
+	return first, min(first+visible, steps)
+}
+
+// cell returns a hit drawn in its instrument's colour, shaded by velocity.
+// The playing step is drawn in reverse.
+func (t *tuiRenderer) cell(hit Hit, instrument int, playing bool) string {
+	var codes []string
+
+	text := "."
+	switch {
+	case hit.Glyph == "":
+		codes = append(codes, "2")
+	default:
+		text = hit.Glyph
+		codes = append(codes, instrumentColours[instrument%len(instrumentColours)])
+
+		if hit.Velocity <= ghostVelocity {
+			codes = append(codes, "2")
+		} else if hit.Velocity >= accentVelocity {
+			codes = append(codes, "1")
+		}
+	}
+
+	if playing {
+		codes = append(codes, "7")
+	}
+
+	return t.style(text, codes...) + " "
+}
+
+// meter returns a bar showing level on a decibel scale, coloured by how close
+// it is to full scale.
+func (t *tuiRenderer) meter(level float64) string {
+	decibels := meterFloor
+	if level > 0 {
+		decibels = math.Max(20*math.Log10(level), meterFloor)
+	}
+
+	filled := int(math.Round(math.Min(1, (decibels-meterFloor)/-meterFloor) * meterWidth))
+
+	colour := "32"
+	switch {
+	case decibels > -3:
+		colour = "31"
+	case decibels > -12:
+		colour = "33"
+	}
+
+	return "[" + t.style(strings.Repeat("#", filled), colour) + strings.Repeat("-", meterWidth-filled) + "]"
+}
+
+// transport returns the position of the playing step as bar:beat:step, with
+// the time played and left to play.
+func (t *tuiRenderer) transport() string {
+	step := Step{Bar: 1}
+	if t.step != nil {
+		step = *t.step
+	}
+
+	divisionsPerBeat := t.track.DivisionsPerBeat
+	if divisionsPerBeat <= 0 {
+		divisionsPerBeat = 1
+	}
+
+	elapsed := t.elapsed(step.Index)
+
+	left := "until stopped"
+	if t.track.TotalSteps > 0 {
+		left = formatTime(time.Duration(t.track.TotalSteps-step.Index) * t.track.StepDuration)
+	}
+
+	return fmt.Sprintf("Bar %d:%d:%d   Elapsed %s   Left %s   %v BPM",
+		step.Bar, step.Division/divisionsPerBeat+1, step.Division%divisionsPerBeat+1,
+		formatTime(elapsed), left, t.track.BeatsPerMinute)
+}
+
+// elapsed returns the time played before the step at index, at the current
+// tempo since it last changed.
+func (t *tuiRenderer) elapsed(index int) time.Duration {
+	return t.retimed + time.Duration(index-t.retimedAt)*t.track.StepDuration
+}
+
+func (t *tuiRenderer) style(text string, codes ...string) string {
+	if !t.styled {
+		return text
+	}
+
+	return utils.Style(text, codes...)
+}
+
+// formatTime returns d as minutes and seconds, to a tenth of a second.
+func formatTime(d time.Duration) string {
+	d = d.Round(100 * time.Millisecond)
+
+	return fmt.Sprintf("%d:%04.1f", int(d/time.Minute), (d % time.Minute).Seconds())
+}
+
+func truncate(text string, width int) string {
+	if len(text) > width {
+		return text[:max(width, 0)]
+	}
+
+	return text
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+
+	return b
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+
+	return b
+}
@@ -0,0 +1,306 @@
+package render
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testTrack returns a bar of 4/4 in eighth notes, with a kick on each beat and
+// a ghosted snare between them.
+func testTrack() Track {
+	kick := Hit{Glyph: "X", Velocity: 1}
+	snare := Hit{Glyph: "o", Velocity: 0.4}
+
+	pattern := make([]Step, 8)
+	for i := range pattern {
+		pattern[i] = Step{Division: i, Count: "  ", Hits: []Hit{{}, snare}}
+		if i%2 == 0 {
+			pattern[i] = Step{Division: i, Count: string(rune('1'+i/2)) + " ", Hits: []Hit{kick, {}}}
+		}
+	}
+
+	return Track{
+		Title:            "Playing track at BPM: 120",
+		BeatsPerMinute:   120,
+		Instruments:      []string{"Kick", "Snare"},
+		Pattern:          pattern,
+		DivisionsPerBeat: 2,
+		StepDuration:     250 * time.Millisecond,
+		TotalSteps:       16,
+		Levels: func() []float64 {
+			return []float64{1, 0}
+		},
+	}
+}
+
+// lines returns the lines of a frame, without the escape codes clearing each
+// line.
+func lines(frame string) []string {
+	return strings.Split(strings.ReplaceAll(frame, "\x1b[K", ""), "\n")
+}
+
+func TestTUIFrame(t *testing.T) {
+	type input struct {
+		step    *Step
+		clipped bool
+		width   int
+		height  int
+	}
+
+	type testCase struct {
+		description    string
+		input          input
+		expectedOutput []string
+	}
+
+	testCases := []testCase{
+		{
+			description: "Draws the pattern before the first step",
+			input:       input{width: 80, height: 24},
+			expectedOutput: []string{
+				"Playing track at BPM: 120",
+				"Bar 1:1:1   Elapsed 0:00.0   Left 0:04.0   120 BPM",
+				"",
+				"       1   2   3   4   ",
+				" Kick: X . X . X . X .  [################]",
+				"Snare: . o . o . o . o  [----------------]",
+				"",
+			},
+		},
+		{
+			description: "Draws the playhead, transport and clip indicator",
+			input: input{
+				step:    &Step{Index: 11, Bar: 2, Division: 3},
+				clipped: true,
+				width:   80,
+				height:  24,
+			},
+			expectedOutput: []string{
+				"Playing track at BPM: 120 CLIP",
+				"Bar 2:2:2   Elapsed 0:02.8   Left 0:01.3   120 BPM",
+				"",
+				"       1   2   3   4   ",
+				" Kick: X . X . X . X .  [################]",
+				"Snare: . o . o . o . o  [----------------]",
+				"             ^",
+			},
+		},
+		{
+			description: "Drops the meters and pages the pattern on narrow screens",
+			input: input{
+				step:   &Step{Index: 5, Bar: 1, Division: 5},
+				width:  15,
+				height: 24,
+			},
+			expectedOutput: []string{
+				"Playing track a",
+				"Bar 1:3:2   Ela",
+				"",
+				"       3   4   ",
+				" Kick: X . X . ",
+				"Snare: . o . o ",
+				"         ^",
+			},
+		},
+		{
+			description: "Drops instruments and the playhead on short screens",
+			input:       input{width: 80, height: 6},
+			expectedOutput: []string{
+				"Playing track at BPM: 120",
+				"Bar 1:1:1   Elapsed 0:00.0   Left 0:04.0   120 BPM",
+				"",
+				"       1   2   3   4   ",
+				" Kick: X . X . X . X .  [################]",
+				"",
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		renderer := &tuiRenderer{
+			track:   testTrack(),
+			step:    testCase.input.step,
+			clipped: testCase.input.clipped,
+		}
+
+		actualOutput := renderer.frame(testCase.input.width, testCase.input.height)
+		assert.Equal(t, testCase.expectedOutput, lines(actualOutput), testCase.description)
+	}
+}
+
+func TestTUICell(t *testing.T) {
+	type input struct {
+		hit        Hit
+		instrument int
+		playing    bool
+	}
+
+	type testCase struct {
+		description    string
+		input          input
+		expectedOutput string
+	}
+
+	testCases := []testCase{
+		{
+			description:    "Dims rests",
+			input:          input{hit: Hit{}},
+			expectedOutput: "\x1b[2m.\x1b[0m ",
+		},
+		{
+			description:    "Colours hits by instrument, bolding accents",
+			input:          input{hit: Hit{Glyph: "X", Velocity: 1}, instrument: 1},
+			expectedOutput: "\x1b[33;1mX\x1b[0m ",
+		},
+		{
+			description:    "Dims ghost notes",
+			input:          input{hit: Hit{Glyph: "o", Velocity: 0.4}, instrument: 0},
+			expectedOutput: "\x1b[36;2mo\x1b[0m ",
+		},
+		{
+			description:    "Leaves normal velocities unshaded",
+			input:          input{hit: Hit{Glyph: "X", Velocity: 0.7}, instrument: 6},
+			expectedOutput: "\x1b[36mX\x1b[0m ",
+		},
+		{
+			description:    "Reverses the playing step",
+			input:          input{hit: Hit{Glyph: "X", Velocity: 0.7}, instrument: 2, playing: true},
+			expectedOutput: "\x1b[35;7mX\x1b[0m ",
+		},
+	}
+
+	renderer := &tuiRenderer{styled: true}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		actualOutput := renderer.cell(testCase.input.hit, testCase.input.instrument, testCase.input.playing)
+		assert.Equal(t, testCase.expectedOutput, actualOutput, testCase.description)
+	}
+}
+
+func TestTUIMeter(t *testing.T) {
+	type testCase struct {
+		description    string
+		input          float64
+		expectedOutput string
+	}
+
+	testCases := []testCase{
+		{
+			description:    "Empty when silent",
+			input:          0,
+			expectedOutput: "[\x1b[32m\x1b[0m----------------]",
+		},
+		{
+			description:    "Half full and green at -24dB",
+			input:          0.063,
+			expectedOutput: "[\x1b[32m########\x1b[0m--------]",
+		},
+		{
+			description:    "Yellow over -12dB",
+			input:          0.5,
+			expectedOutput: "[\x1b[33m##############\x1b[0m--]",
+		},
+		{
+			description:    "Full and red at full scale",
+			input:          1,
+			expectedOutput: "[\x1b[31m################\x1b[0m]",
+		},
+	}
+
+	renderer := &tuiRenderer{styled: true}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		assert.Equal(t, testCase.expectedOutput, renderer.meter(testCase.input), testCase.description)
+	}
+}
+
+func TestTUIRenderer(t *testing.T) {
+	w := &bytes.Buffer{}
+
+	renderer := NewTUI(w, false)
+	renderer.Start(testTrack())
+	renderer.Step(Step{Index: 0, Bar: 1, Division: 0})
+	renderer.Clip()
+	renderer.Stop()
+
+	output := w.String()
+
+	// the screen is taken over, and handed back with the transport left behind
+	assert.True(t, strings.HasPrefix(output, "\x1b[?1049h\x1b[2J\x1b[H\x1b[?25l"))
+	assert.True(t, strings.HasSuffix(output, "\x1b[?25h\x1b[?1049l"+
+		"Playing track at BPM: 120\nBar 1:1:1   Elapsed 0:00.0   Left 0:04.0   120 BPM\n"))
+	assert.Contains(t, output, "Playing track at BPM: 120 CLIP")
+}
+
+func TestTUIRendererMessage(t *testing.T) {
+	renderer := NewTUI(&bytes.Buffer{}, false).(*tuiRenderer)
+	renderer.Start(testTrack())
+	renderer.Message("Tapped 92 BPM")
+
+	assert.Equal(t, "Bar 1:1:1   Elapsed 0:00.0   Left 0:04.0   120 BPM   Tapped 92 BPM", lines(renderer.frame(100, 24))[1])
+
+	renderer.Stop()
+}
+
+func TestTUIRendererRetime(t *testing.T) {
+	renderer := NewTUI(&bytes.Buffer{}, false).(*tuiRenderer)
+	renderer.Start(testTrack())
+	renderer.Step(Step{Index: 3, Bar: 1, Division: 3})
+
+	// the steps played before the tempo changed keep their time
+	renderer.Retime(60, 500*time.Millisecond, 12)
+	renderer.Step(Step{Index: 4, Bar: 1, Division: 4})
+	renderer.Step(Step{Index: 5, Bar: 1, Division: 5})
+
+	assert.Equal(t, "Bar 1:3:2   Elapsed 0:01.5   Left 0:03.5   60 BPM", lines(renderer.frame(100, 24))[1])
+
+	renderer.Stop()
+}
+
+func TestTUIRendererUpdatesPattern(t *testing.T) {
+	track := testTrack()
+
+	renderer := NewTUI(&bytes.Buffer{}, false).(*tuiRenderer)
+	renderer.Start(track)
+	renderer.Step(Step{Index: 1, Bar: 1, Division: 1, Hits: []Hit{{Glyph: "X", Velocity: 1}, {}}})
+	renderer.Stop()
+
+	// the step played is drawn as it was played, leaving the track as it was
+	assert.Equal(t, []Hit{{Glyph: "X", Velocity: 1}, {}}, renderer.track.Pattern[1].Hits)
+	assert.Equal(t, []Hit{{}, {Glyph: "o", Velocity: 0.4}}, track.Pattern[1].Hits)
+}
+
+func TestTUIRendererFollowsPattern(t *testing.T) {
+	track := testTrack()
+	current := testTrack().Pattern
+	track.CurrentPattern = func() []Step {
+		return current
+	}
+
+	renderer := NewTUI(&bytes.Buffer{}, false).(*tuiRenderer)
+	renderer.Start(track)
+
+	// a step toggled ahead of the playhead is drawn from the next step
+	current[4].Hits = []Hit{{}, {Glyph: "X", Velocity: 1}}
+	renderer.Step(Step{Index: 0, Bar: 1, Division: 0, Hits: current[0].Hits})
+
+	assert.Equal(t, "Snare: . o . o X o . o  [----------------]", lines(renderer.frame(100, 24))[5])
+
+	renderer.Stop()
+}
+
+func TestFormatTime(t *testing.T) {
+	assert.Equal(t, "0:00.0", formatTime(0))
+	assert.Equal(t, "0:05.3", formatTime(5260*time.Millisecond))
+	assert.Equal(t, "90:00.0", formatTime(90*time.Minute))
+}
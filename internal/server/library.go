@@ -0,0 +1,63 @@
+package server
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/jcfox412/logarhythms/internal/input"
+)
+
+const trackExtension = ".json"
+
+// trackSummary is a track in the library, which can be loaded by its ID.
+type trackSummary struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+}
+
+// listTracks returns every track in the library directory, sorted by ID. A
+// track's ID is its filename without the extension.
+func listTracks(library string) ([]trackSummary, error) {
+	files, err := ioutil.ReadDir(library)
+	if err != nil {
+		return nil, errors.Wrap(err, "error reading track library")
+	}
+
+	tracks := []trackSummary{}
+	for _, file := range files {
+		if file.IsDir() || filepath.Ext(file.Name()) != trackExtension {
+			continue
+		}
+
+		title, err := input.ReadTrackTitle(filepath.Join(library, file.Name()))
+		if err != nil {
+			return nil, errors.Wrapf(err, "error reading %s", file.Name())
+		}
+
+		tracks = append(tracks, trackSummary{
+			ID:    strings.TrimSuffix(file.Name(), trackExtension),
+			Title: title,
+		})
+	}
+
+	sort.Slice(tracks, func(i, j int) bool {
+		return tracks[i].ID < tracks[j].ID
+	})
+
+	return tracks, nil
+}
+
+// trackFilename returns the metadata file of the track in the library with
+// the given ID. Returns false if the ID could name a file outside of the
+// library.
+func trackFilename(library, id string) (string, bool) {
+	if id == "" || strings.ContainsAny(id, `/\`) || id == "." || id == ".." {
+		return "", false
+	}
+
+	return filepath.Join(library, id+trackExtension), true
+}
@@ -0,0 +1,60 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrackFilename(t *testing.T) {
+	type output struct {
+		filename string
+		ok       bool
+	}
+
+	type testCase struct {
+		description    string
+		input          string
+		expectedOutput output
+	}
+
+	testCases := []testCase{
+		{
+			description:    "Finds track in library",
+			input:          "gravity",
+			expectedOutput: output{filename: "assets/tracks/gravity.json", ok: true},
+		},
+		{
+			description:    "Rejects empty ID",
+			input:          "",
+			expectedOutput: output{},
+		},
+		{
+			description:    "Rejects ID in another directory",
+			input:          "../kits/acoustic",
+			expectedOutput: output{},
+		},
+		{
+			description:    "Rejects ID with a backslash",
+			input:          `..\kits\acoustic`,
+			expectedOutput: output{},
+		},
+		{
+			description:    "Rejects parent directory",
+			input:          "..",
+			expectedOutput: output{},
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		actualFilename, actualOk := trackFilename("assets/tracks", testCase.input)
+		assert.Equal(t, testCase.expectedOutput, output{filename: actualFilename, ok: actualOk}, testCase.description)
+	}
+}
+
+func TestListTracksErrors(t *testing.T) {
+	_, err := listTracks("testfiles/missing")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,320 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/jcfox412/logarhythms/internal/clock"
+	"github.com/jcfox412/logarhythms/internal/events"
+	"github.com/jcfox412/logarhythms/internal/input"
+	"github.com/jcfox412/logarhythms/internal/models"
+	"github.com/jcfox412/logarhythms/internal/render"
+)
+
+// statusError is an error caused by a request, returned over HTTP with its
+// status.
+type statusError struct {
+	status int
+	err    error
+}
+
+func (e *statusError) Error() string {
+	return e.err.Error()
+}
+
+func withStatus(status int, err error) error {
+	return &statusError{status: status, err: err}
+}
+
+// status returns the HTTP status an operation's error is returned with.
+func status(err error) int {
+	var statusErr *statusError
+	if errors.As(err, &statusErr) {
+		return statusErr.status
+	}
+
+	return http.StatusInternalServerError
+}
+
+// trackSettings are changes to the loaded track. Settings left nil are left as
+// they are. A new BPM is heard from the next beat subdivision of a playing
+// track, and a new length the next time it's played.
+type trackSettings struct {
+	BeatsPerMinute *float64    `json:"bpm"`
+	Length         *lengthJSON `json:"length"`
+}
+
+// instrumentSettings are changes to an instrument of the loaded track.
+// Settings left nil are left as they are.
+type instrumentSettings struct {
+	// Volume from 0 to 100
+	Volume *float64 `json:"volume"`
+	Muted  *bool    `json:"muted"`
+	// Whether the instrument's audio is played, and its MIDI note sent
+	Audio *bool `json:"audio"`
+	MIDI  *bool `json:"midi"`
+}
+
+// load replaces the loaded track with the library's track with the given ID,
+// stopping the last one if it's playing.
+func (s *Server) load(id string) (trackState, error) {
+	filename, ok := trackFilename(s.Library, id)
+	if !ok {
+		return trackState{}, withStatus(http.StatusBadRequest, fmt.Errorf("invalid track ID %q", id))
+	}
+
+	if _, err := os.Stat(filename); err != nil {
+		return trackState{}, withStatus(http.StatusNotFound, fmt.Errorf("no track with ID %q", id))
+	}
+
+	track, err := input.PrepareTrack(filename, s.KitFilename)
+	if err != nil {
+		return trackState{}, withStatus(http.StatusUnprocessableEntity, errors.Wrap(err, "could not prepare track"))
+	}
+
+	track.Clock = s.Clock
+	track.Sync = s.Sync
+	track.MIDI = s.MIDI
+
+	renderers := []render.Renderer{&s.transport, events.NewRenderer(s.Events.Publish, s.Clock)}
+	if s.Renderer != nil {
+		renderers = append(renderers, s.Renderer)
+	}
+
+	track.Renderer = render.Multi(renderers...)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.stop()
+	s.run = nil
+	s.trackID = id
+	s.track = track
+	s.changed()
+
+	return s.trackState(), nil
+}
+
+// updateTrack changes the loaded track's settings, which are heard the next
+// time it's played.
+func (s *Server) updateTrack(settings trackSettings) (trackState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.track == nil {
+		return trackState{}, withStatus(http.StatusNotFound, errors.New("no track loaded"))
+	}
+
+	err := s.track.Update(func(track *models.Track) error {
+		if settings.BeatsPerMinute != nil {
+			if !(*settings.BeatsPerMinute >= minBeatsPerMinute && *settings.BeatsPerMinute <= maxBeatsPerMinute) {
+				return fmt.Errorf("bpm must be between %d and %d", minBeatsPerMinute, maxBeatsPerMinute)
+			}
+		}
+
+		var length models.Length
+		if settings.Length != nil {
+			var err error
+			if length, err = settings.Length.length(); err != nil {
+				return err
+			}
+		}
+
+		if settings.BeatsPerMinute != nil {
+			track.BeatsPerMinute = *settings.BeatsPerMinute
+		}
+
+		if settings.Length != nil {
+			track.Length = length
+		}
+
+		return nil
+	})
+	if err != nil {
+		return trackState{}, withStatus(http.StatusBadRequest, err)
+	}
+
+	s.changed()
+
+	return s.trackState(), nil
+}
+
+// updateInstrument changes the settings of one of the loaded track's
+// instruments, by its index or name, which are heard straight away.
+func (s *Server) updateInstrument(instrument string, settings instrumentSettings) (trackState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i, err := s.instrumentIndex(instrument)
+	if err != nil {
+		return trackState{}, err
+	}
+
+	err = s.track.Update(func(track *models.Track) error {
+		if settings.MIDI != nil && track.Instruments[i].MIDI == nil {
+			return errors.New("instrument has no MIDI note")
+		}
+
+		if settings.Volume != nil {
+			if _, err := track.Instruments[i].Audio.SetVolume(*settings.Volume); err != nil {
+				return err
+			}
+		}
+
+		if settings.Muted != nil {
+			track.Instruments[i].Muted = *settings.Muted
+		}
+
+		if settings.Audio != nil {
+			track.Instruments[i].AudioOff = !*settings.Audio
+		}
+
+		if settings.MIDI != nil {
+			track.Instruments[i].MIDIOff = !*settings.MIDI
+		}
+
+		return nil
+	})
+	if err != nil {
+		return trackState{}, withStatus(http.StatusBadRequest, err)
+	}
+
+	s.changed()
+
+	return s.trackState(), nil
+}
+
+// setStep turns the hit of one of the loaded track's instruments, by its index
+// or name, at a beat subdivision of the bar on or off. It's heard from the
+// next beat subdivision played.
+func (s *Server) setStep(instrument, step string, on bool) (trackState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i, err := s.instrumentIndex(instrument)
+	if err != nil {
+		return trackState{}, err
+	}
+
+	j, err := strconv.Atoi(step)
+	if err != nil {
+		return trackState{}, withStatus(http.StatusNotFound, fmt.Errorf("no step at index %q", step))
+	}
+
+	err = s.track.Update(func(track *models.Track) error {
+		return track.SetStep(i, j, on)
+	})
+	if err != nil {
+		return trackState{}, withStatus(http.StatusNotFound, err)
+	}
+
+	s.changed()
+
+	return s.trackState(), nil
+}
+
+// instrumentIndex returns the position in the loaded track of the instrument
+// with the given index or name. Names are matched ignoring case, with
+// underscores and hyphens matching spaces. The server must be locked.
+func (s *Server) instrumentIndex(instrument string) (int, error) {
+	if s.track == nil {
+		return 0, withStatus(http.StatusNotFound, errors.New("no track loaded"))
+	}
+
+	if i, err := strconv.Atoi(instrument); err == nil {
+		if i < 0 || i >= len(s.track.Instruments) {
+			return 0, withStatus(http.StatusNotFound, fmt.Errorf("no instrument at index %q", instrument))
+		}
+
+		return i, nil
+	}
+
+	for i, candidate := range s.track.Instruments {
+		if instrumentKey(candidate.Name) == instrumentKey(instrument) {
+			return i, nil
+		}
+	}
+
+	return 0, withStatus(http.StatusNotFound, fmt.Errorf("no instrument named %q", instrument))
+}
+
+func instrumentKey(name string) string {
+	return strings.NewReplacer("_", " ", "-", " ").Replace(strings.ToLower(name))
+}
+
+// play starts the loaded track playing in the background.
+func (s *Server) play() (transportState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.track == nil {
+		return transportState{}, withStatus(http.StatusConflict, errors.New("no track loaded"))
+	}
+
+	if s.playing() {
+		return transportState{}, withStatus(http.StatusConflict, errors.New("track is already playing"))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &run{cancel: cancel, done: make(chan struct{})}
+	s.run = r
+
+	go func(track *models.Track) {
+		defer close(r.done)
+		r.err = track.PlayContext(ctx)
+	}(s.track)
+
+	return s.transportState(), nil
+}
+
+// stopPlaying stops the track if it's playing.
+func (s *Server) stopPlaying() transportState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.stop()
+
+	return s.transportState()
+}
+
+// stop stops the track if it's playing, waiting for it to finish. The server
+// must be locked.
+func (s *Server) stop() {
+	if s.run == nil {
+		return
+	}
+
+	s.run.cancel()
+	<-s.run.done
+}
+
+// playing reports whether the track is playing. The server must be locked.
+func (s *Server) playing() bool {
+	if s.run == nil {
+		return false
+	}
+
+	select {
+	case <-s.run.done:
+		return false
+	default:
+		return true
+	}
+}
+
+// changed tells clients of the event stream that the loaded track or its
+// settings have changed.
+func (s *Server) changed() {
+	c := s.Clock
+	if c == nil {
+		c = clock.New()
+	}
+
+	s.Events.Publish(events.Event{Time: c.Now(), Type: events.Changed})
+}
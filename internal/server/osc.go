@@ -0,0 +1,289 @@
+package server
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/jcfox412/logarhythms/internal/events"
+	"github.com/jcfox412/logarhythms/internal/osc"
+)
+
+// Largest OSC packet read, which is the largest UDP payload
+const maxPacketSize = 65507
+
+// ServeOSC controls the server with the OSC messages received on conn, until
+// it's closed:
+//
+//	/track <id>                      load a track from the library by ID
+//	/tempo <bpm>                     change the loaded track's BPM
+//	/transport/play                  start playing the loaded track
+//	/transport/stop                  stop playing
+//	/instrument/{i}/volume <volume>  change the volume of the track's ith instrument
+//	/instrument/{i}/mute <0|1>       mute or unmute the ith instrument
+//	/instrument/{i}/audio <0|1>      turn the ith instrument's audio on or off
+//	/instrument/{i}/midi <0|1>       turn the ith instrument's MIDI note on or off
+//	/pattern/{i}/step/{s} <0|1>      turn the ith instrument's hit at the sth beat subdivision of the bar on or off
+//
+// Instruments are given by index, or by name. Transport messages with an
+// argument of 0, as sent when a button is released, are ignored.
+//
+// If feedback is set, the loaded track's settings and where playback is are
+// sent to it from conn as they change, along with an /error message for each
+// message which can't be carried out.
+func (s *Server) ServeOSC(conn net.PacketConn, feedback net.Addr) error {
+	if feedback != nil {
+		subscription, unsubscribe := s.Events.Subscribe()
+		defer unsubscribe()
+
+		go func() {
+			for event := range subscription {
+				s.sendOSC(conn, feedback, s.oscFeedback(event)...)
+			}
+		}()
+
+		s.sendOSC(conn, feedback, s.oscFeedback(events.Event{Type: events.Changed})...)
+	}
+
+	packet := make([]byte, maxPacketSize)
+	for {
+		n, _, err := conn.ReadFrom(packet)
+		if err != nil {
+			return err
+		}
+
+		messages, err := osc.Parse(packet[:n])
+		if err != nil {
+			s.sendOSC(conn, feedback, osc.Message{Address: "/error", Arguments: []interface{}{err.Error()}})
+			continue
+		}
+
+		for _, message := range messages {
+			if err := s.handleOSC(message); err != nil {
+				s.sendOSC(conn, feedback, osc.Message{
+					Address:   "/error",
+					Arguments: []interface{}{fmt.Sprintf("%s: %s", message.Address, err)},
+				})
+			}
+		}
+	}
+}
+
+// handleOSC carries out a single OSC message.
+func (s *Server) handleOSC(message osc.Message) error {
+	parts := strings.Split(strings.Trim(message.Address, "/"), "/")
+
+	switch {
+	case message.Address == "/track":
+		id, err := stringArgument(message)
+		if err != nil {
+			return err
+		}
+
+		_, err = s.load(id)
+		return err
+	case message.Address == "/tempo":
+		bpm, err := numberArgument(message)
+		if err != nil {
+			return err
+		}
+
+		// float32 arguments can't hold most fractions exactly, so 92.3 arrives
+		// as 92.30000305 and is rounded back to a thousandth of a BPM
+		beatsPerMinute := math.Round(bpm*1000) / 1000
+		_, err = s.updateTrack(trackSettings{BeatsPerMinute: &beatsPerMinute})
+		return err
+	case message.Address == "/transport/play":
+		if !triggered(message) {
+			return nil
+		}
+
+		_, err := s.play()
+		return err
+	case message.Address == "/transport/stop":
+		if triggered(message) {
+			s.stopPlaying()
+		}
+
+		return nil
+	case len(parts) == 3 && parts[0] == "instrument" && parts[2] == "volume":
+		volume, err := numberArgument(message)
+		if err != nil {
+			return err
+		}
+
+		_, err = s.updateInstrument(parts[1], instrumentSettings{Volume: &volume})
+		return err
+	case len(parts) == 3 && parts[0] == "instrument" && parts[2] == "mute":
+		mute, err := numberArgument(message)
+		if err != nil {
+			return err
+		}
+
+		muted := mute != 0
+		_, err = s.updateInstrument(parts[1], instrumentSettings{Muted: &muted})
+		return err
+	case len(parts) == 3 && parts[0] == "instrument" && (parts[2] == "audio" || parts[2] == "midi"):
+		n, err := numberArgument(message)
+		if err != nil {
+			return err
+		}
+
+		on := n != 0
+		settings := instrumentSettings{Audio: &on}
+		if parts[2] == "midi" {
+			settings = instrumentSettings{MIDI: &on}
+		}
+
+		_, err = s.updateInstrument(parts[1], settings)
+		return err
+	case len(parts) == 4 && parts[0] == "pattern" && parts[2] == "step":
+		hit, err := numberArgument(message)
+		if err != nil {
+			return err
+		}
+
+		_, err = s.setStep(parts[1], parts[3], hit != 0)
+		return err
+	default:
+		return errors.New("unknown address")
+	}
+}
+
+// oscFeedback returns the messages sent to the feedback address for an event.
+// Changes are sent as the loaded track's full settings.
+func (s *Server) oscFeedback(event events.Event) []osc.Message {
+	switch event.Type {
+	case events.Changed:
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		if s.track == nil {
+			return nil
+		}
+
+		state := s.trackState()
+		messages := []osc.Message{
+			{Address: "/track", Arguments: []interface{}{state.ID}},
+			{Address: "/tempo", Arguments: []interface{}{float32(state.BeatsPerMinute)}},
+		}
+
+		steps := state.BeatsPerMeasure * state.DivisionsPerBeat
+		for i, instrument := range state.Instruments {
+			messages = append(messages,
+				osc.Message{Address: fmt.Sprintf("/instrument/%d/volume", i), Arguments: []interface{}{float32(instrument.Volume)}},
+				osc.Message{Address: fmt.Sprintf("/instrument/%d/mute", i), Arguments: []interface{}{boolInt(instrument.Muted)}},
+				osc.Message{Address: fmt.Sprintf("/instrument/%d/audio", i), Arguments: []interface{}{boolInt(instrument.Audio)}},
+			)
+
+			if instrument.MIDI != nil {
+				messages = append(messages,
+					osc.Message{Address: fmt.Sprintf("/instrument/%d/midi", i), Arguments: []interface{}{boolInt(instrument.MIDI.On)}})
+			}
+
+			hits := make([]bool, steps)
+			for _, step := range instrument.Pattern {
+				hits[step] = true
+			}
+
+			for step, hit := range hits {
+				messages = append(messages,
+					osc.Message{Address: fmt.Sprintf("/pattern/%d/step/%d", i, step), Arguments: []interface{}{boolInt(hit)}})
+			}
+		}
+
+		return messages
+	case events.Start:
+		return []osc.Message{{Address: "/transport/playing", Arguments: []interface{}{int32(1)}}}
+	case events.Stop:
+		return []osc.Message{{Address: "/transport/playing", Arguments: []interface{}{int32(0)}}}
+	case events.Step:
+		return []osc.Message{{Address: "/transport/position", Arguments: []interface{}{
+			int32(event.Bar), int32(event.Beat), int32(event.Step),
+		}}}
+	case events.Clip:
+		return []osc.Message{{Address: "/transport/clip", Arguments: []interface{}{int32(1)}}}
+	default:
+		return nil
+	}
+}
+
+// sendOSC sends the messages to addr from conn, one packet each. Messages
+// which can't be sent are dropped, as UDP would drop them anyway.
+func (s *Server) sendOSC(conn net.PacketConn, addr net.Addr, messages ...osc.Message) {
+	if addr == nil {
+		return
+	}
+
+	for _, message := range messages {
+		packet, err := message.MarshalBinary()
+		if err != nil {
+			continue
+		}
+
+		_, _ = conn.WriteTo(packet, addr)
+	}
+}
+
+func stringArgument(message osc.Message) (string, error) {
+	if len(message.Arguments) != 1 {
+		return "", errors.New("expected a single string argument")
+	}
+
+	s, ok := message.Arguments[0].(string)
+	if !ok {
+		return "", errors.New("expected a single string argument")
+	}
+
+	return s, nil
+}
+
+// numberArgument returns the message's single numeric argument, with true and
+// false read as 1 and 0.
+func numberArgument(message osc.Message) (float64, error) {
+	if len(message.Arguments) != 1 {
+		return 0, errors.New("expected a single number argument")
+	}
+
+	switch a := message.Arguments[0].(type) {
+	case int32:
+		return float64(a), nil
+	case float32:
+		return float64(a), nil
+	case int64:
+		return float64(a), nil
+	case float64:
+		return a, nil
+	case bool:
+		if a {
+			return 1, nil
+		}
+
+		return 0, nil
+	default:
+		return 0, errors.New("expected a single number argument")
+	}
+}
+
+// triggered reports whether a transport message should be acted on, which it
+// should unless its argument is 0.
+func triggered(message osc.Message) bool {
+	if len(message.Arguments) == 0 {
+		return true
+	}
+
+	n, err := numberArgument(message)
+
+	return err != nil || n != 0
+}
+
+func boolInt(b bool) int32 {
+	if b {
+		return 1
+	}
+
+	return 0
+}
@@ -0,0 +1,182 @@
+package server_test
+
+import (
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jcfox412/logarhythms/internal/clock"
+	"github.com/jcfox412/logarhythms/internal/osc"
+	"github.com/jcfox412/logarhythms/internal/server"
+)
+
+// sendOSC sends a message from the client to the server's OSC address.
+func sendOSC(t *testing.T, client net.PacketConn, addr net.Addr, address string, arguments ...interface{}) {
+	packet, err := osc.Message{Address: address, Arguments: arguments}.MarshalBinary()
+	assert.Nil(t, err)
+
+	_, err = client.WriteTo(packet, addr)
+	assert.Nil(t, err)
+}
+
+// expectOSC waits for the message to be sent to the client, skipping any
+// others. Each change sends the track's full settings, so messages matching
+// the address but not the arguments are from earlier changes.
+func expectOSC(t *testing.T, client net.PacketConn, address string, arguments ...interface{}) {
+	expected := osc.Message{Address: address, Arguments: arguments}
+	packet := make([]byte, 1024)
+	assert.Nil(t, client.SetReadDeadline(time.Now().Add(5*time.Second)))
+
+	for {
+		n, _, err := client.ReadFrom(packet)
+		if !assert.Nil(t, err, "waiting for %v", expected) {
+			return
+		}
+
+		messages, err := osc.Parse(packet[:n])
+		assert.Nil(t, err)
+
+		for _, message := range messages {
+			if assert.ObjectsAreEqual(expected, message) {
+				return
+			}
+		}
+	}
+}
+
+func TestServeOSC(t *testing.T) {
+	s := server.New(library, "")
+	defer s.Close()
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	client, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer client.Close()
+
+	served := make(chan error, 1)
+	go func() {
+		served <- s.ServeOSC(conn, client.LocalAddr())
+	}()
+
+	addr := conn.LocalAddr()
+
+	sendOSC(t, client, addr, "/track", "synth_beat")
+	expectOSC(t, client, "/track", "synth_beat")
+	expectOSC(t, client, "/tempo", float32(60))
+	expectOSC(t, client, "/instrument/1/volume", float32(50))
+	expectOSC(t, client, "/instrument/1/mute", int32(0))
+	expectOSC(t, client, "/pattern/1/step/0", int32(0))
+	expectOSC(t, client, "/pattern/1/step/1", int32(1))
+
+	// 128.4 can't be held exactly as a float32, but is kept to a thousandth
+	sendOSC(t, client, addr, "/tempo", float32(128.4))
+	expectOSC(t, client, "/tempo", float32(128.4))
+
+	sendOSC(t, client, addr, "/instrument/snare/volume", int32(70))
+	expectOSC(t, client, "/instrument/1/volume", float32(70))
+
+	sendOSC(t, client, addr, "/instrument/0/mute", true)
+	expectOSC(t, client, "/instrument/0/mute", int32(1))
+
+	sendOSC(t, client, addr, "/instrument/kick/audio", int32(0))
+	expectOSC(t, client, "/instrument/0/audio", int32(0))
+
+	sendOSC(t, client, addr, "/instrument/kick/audio", int32(1))
+	expectOSC(t, client, "/instrument/0/audio", int32(1))
+
+	sendOSC(t, client, addr, "/instrument/kick/midi", int32(1))
+	expectOSC(t, client, "/error", "/instrument/kick/midi: instrument has no MIDI note")
+
+	sendOSC(t, client, addr, "/pattern/snare/step/0", int32(1))
+	expectOSC(t, client, "/pattern/1/step/0", int32(1))
+
+	sendOSC(t, client, addr, "/pattern/snare/step/4", int32(1))
+	expectOSC(t, client, "/error", "/pattern/snare/step/4: step 4 is outside of a 4 subdivision measure")
+
+	sendOSC(t, client, addr, "/instrument/cowbell/volume", int32(70))
+	expectOSC(t, client, "/error", `/instrument/cowbell/volume: no instrument named "cowbell"`)
+
+	sendOSC(t, client, addr, "/tempo", "fast")
+	expectOSC(t, client, "/error", "/tempo: expected a single number argument")
+
+	sendOSC(t, client, addr, "/cowbell")
+	expectOSC(t, client, "/error", "/cowbell: unknown address")
+
+	// releasing the play button doesn't play the track
+	sendOSC(t, client, addr, "/transport/play", int32(0))
+	sendOSC(t, client, addr, "/transport/play", int32(1))
+	expectOSC(t, client, "/transport/playing", int32(1))
+
+	sendOSC(t, client, addr, "/transport/stop")
+	expectOSC(t, client, "/transport/playing", int32(0))
+
+	// the changes are made to the same track the HTTP API controls
+	w := request(s, http.MethodGet, "/track", "")
+	assert.JSONEq(t, `{"id":"synth_beat","title":"Synth Beat","bpm":128.4,"beats_per_measure":2,"divisions_per_beat":2,`+
+		`"length":{"unit":"duration","seconds":10},"instruments":[{"name":"Kick","volume":50,"muted":true,"audio":true,"pattern":[0,2]},`+
+		`{"name":"Snare","volume":70,"muted":false,"audio":true,"pattern":[0,1,3]}]}`, w.Body.String())
+
+	assert.Nil(t, conn.Close())
+	assert.NotNil(t, <-served)
+}
+
+func TestServeOSCTempoWhilePlaying(t *testing.T) {
+	c := clock.NewManual(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	s := server.New(library, "")
+	s.Clock = c
+	defer s.Close()
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	client, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer client.Close()
+
+	served := make(chan error, 1)
+	go func() {
+		served <- s.ServeOSC(conn, client.LocalAddr())
+	}()
+
+	assert.Equal(t, http.StatusOK, request(s, http.MethodPut, "/track", `{"id":"synth_beat"}`).Code)
+	assert.Equal(t, http.StatusOK, request(s, http.MethodPatch, "/track", `{"length":{"unit":"bars","count":4}}`).Code)
+	assert.Equal(t, http.StatusAccepted, request(s, http.MethodPost, "/transport/play", "").Code)
+
+	// a beat subdivision lasts half a second at 60 BPM
+	c.BlockUntil(1)
+	c.Advance(200 * time.Millisecond)
+	c.BlockUntil(1)
+	c.Advance(500 * time.Millisecond)
+	c.Advance(500 * time.Millisecond)
+
+	sendOSC(t, client, conn.LocalAddr(), "/tempo", float32(120))
+	expectOSC(t, client, "/tempo", float32(120))
+
+	// the next beat subdivision is played at 120 BPM, and those after it last
+	// a quarter of a second
+	c.Advance(500 * time.Millisecond)
+	c.Advance(250 * time.Millisecond)
+	c.Advance(250 * time.Millisecond)
+
+	w := request(s, http.MethodGet, "/transport", "")
+	assert.JSONEq(t, `{"playing":true,"track_id":"synth_beat","bpm":120,"bar":2,"beat":1,"step":1,`+
+		`"elapsed_ms":1500,"left_ms":3000,"clipped":false}`, w.Body.String())
+
+	assert.Equal(t, http.StatusOK, request(s, http.MethodPost, "/transport/stop", "").Code)
+	assert.Nil(t, conn.Close())
+	assert.NotNil(t, <-served)
+}
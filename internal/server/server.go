@@ -0,0 +1,461 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/jcfox412/logarhythms/internal/clock"
+	"github.com/jcfox412/logarhythms/internal/events"
+	"github.com/jcfox412/logarhythms/internal/midi"
+	"github.com/jcfox412/logarhythms/internal/models"
+	"github.com/jcfox412/logarhythms/internal/render"
+)
+
+const (
+	// Range of BPMs tracks can be set to, matching the settings menu
+	minBeatsPerMinute = 1
+	maxBeatsPerMinute = 1000
+	// Largest request body accepted
+	maxBodySize = 1 << 20
+)
+
+// Server controls LogaRhythms over HTTP and OSC, with one track loaded and
+// played at a time. It's safe for concurrent requests.
+type Server struct {
+	// Directory of track metadata files which can be loaded
+	Library string
+	// File location of a kit to play every track on, overriding each track's own
+	// kit. Empty keeps each track's kit.
+	KitFilename string
+	// Clock tracks are played by, or nil to play in real time
+	Clock clock.Clock
+	// Events of the tracks played, streamed to WebSocket clients of /events
+	Events *events.Hub
+	// Renderer tracks are also drawn by as they play, such as to send MIDI
+	// clock, or nil for none
+	Renderer render.Renderer
+	// External clock tracks follow in place of their BPM, or nil to play them
+	// at their BPM
+	Sync models.Sync
+	// Port the MIDI notes of tracks' instruments are sent to, or nil to send
+	// none
+	MIDI midi.Port
+
+	mu        sync.Mutex
+	trackID   string
+	track     *models.Track
+	transport transport
+	// Playback of the track, or nil if it has never been played
+	run *run
+}
+
+// run is a single playback of the loaded track.
+type run struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+	// Error the track stopped with, set before done is closed
+	err error
+}
+
+// New creates a server playing tracks from the library directory.
+func New(library, kitFilename string) *Server {
+	return &Server{Library: library, KitFilename: kitFilename, Events: events.NewHub()}
+}
+
+// Close stops any track playing, and disconnects clients of the event stream.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.stop()
+	s.Events.Close()
+
+	return nil
+}
+
+// ServeHTTP routes requests to the web UI and the API's endpoints:
+//
+//	GET    /                        the web UI
+//	GET    /tracks                  tracks in the library
+//	GET    /track                   the loaded track
+//	PUT    /track                   load a track from the library by ID
+//	PATCH  /track                   change the loaded track's BPM or length
+//	PATCH  /track/instruments/{i}   change the volume of the track's ith instrument, mute it, or turn its audio or MIDI on or off
+//	PUT    /track/instruments/{i}/steps/{s}
+//	                                turn the ith instrument's hit at the sth beat subdivision of the bar on or off
+//
+// Instruments are given by index, or by name.
+//
+//	GET    /transport               where playback is
+//	POST   /transport/play          start playing the loaded track
+//	POST   /transport/stop          stop playing
+//	GET    /events                  WebSocket streaming the events of tracks as they play
+//
+// Requests which change the server's state are refused if a browser sends them
+// from a page on another origin.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.Trim(r.URL.Path, "/")
+
+	if r.Method != http.MethodGet && r.Method != http.MethodHead && !sameOrigin(r) {
+		writeError(w, http.StatusForbidden, errors.New("requests from another origin can't change the server's state"))
+		return
+	}
+
+	switch {
+	case path == "":
+		if allow(w, r, http.MethodGet) {
+			serveIndex(w)
+		}
+	case path == "tracks":
+		if allow(w, r, http.MethodGet) {
+			s.getTracks(w)
+		}
+	case path == "track":
+		switch r.Method {
+		case http.MethodGet:
+			s.getTrack(w)
+		case http.MethodPut:
+			s.putTrack(w, r)
+		case http.MethodPatch:
+			s.patchTrack(w, r)
+		default:
+			allow(w, r, http.MethodGet, http.MethodPut, http.MethodPatch)
+		}
+	case strings.HasPrefix(path, "track/instruments/"):
+		parts := strings.Split(strings.TrimPrefix(path, "track/instruments/"), "/")
+
+		switch {
+		case len(parts) == 1:
+			if allow(w, r, http.MethodPatch) {
+				s.patchInstrument(w, r, parts[0])
+			}
+		case len(parts) == 3 && parts[1] == "steps":
+			if allow(w, r, http.MethodPut) {
+				s.putStep(w, r, parts[0], parts[2])
+			}
+		default:
+			writeError(w, http.StatusNotFound, fmt.Errorf("no endpoint at %s", r.URL.Path))
+		}
+	case path == "transport":
+		if allow(w, r, http.MethodGet) {
+			s.getTransport(w)
+		}
+	case path == "transport/play":
+		if allow(w, r, http.MethodPost) {
+			s.postPlay(w)
+		}
+	case path == "transport/stop":
+		if allow(w, r, http.MethodPost) {
+			s.postStop(w)
+		}
+	case path == "events":
+		if allow(w, r, http.MethodGet) {
+			s.Events.ServeHTTP(w, r)
+		}
+	default:
+		writeError(w, http.StatusNotFound, fmt.Errorf("no endpoint at %s", r.URL.Path))
+	}
+}
+
+func (s *Server) getTracks(w http.ResponseWriter) {
+	tracks, err := listTracks(s.Library)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, tracks)
+}
+
+func (s *Server) getTrack(w http.ResponseWriter) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.track == nil {
+		writeError(w, http.StatusNotFound, errors.New("no track loaded"))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, s.trackState())
+}
+
+func (s *Server) putTrack(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		ID string `json:"id"`
+	}
+
+	if err := readJSON(w, r, &body); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	state, err := s.load(body.ID)
+	if err != nil {
+		writeError(w, status(err), err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, state)
+}
+
+func (s *Server) patchTrack(w http.ResponseWriter, r *http.Request) {
+	var settings trackSettings
+	if err := readJSON(w, r, &settings); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	state, err := s.updateTrack(settings)
+	if err != nil {
+		writeError(w, status(err), err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, state)
+}
+
+func (s *Server) patchInstrument(w http.ResponseWriter, r *http.Request, instrument string) {
+	var settings instrumentSettings
+	if err := readJSON(w, r, &settings); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	state, err := s.updateInstrument(instrument, settings)
+	if err != nil {
+		writeError(w, status(err), err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, state)
+}
+
+func (s *Server) putStep(w http.ResponseWriter, r *http.Request, instrument, step string) {
+	var body struct {
+		On *bool `json:"on"`
+	}
+
+	if err := readJSON(w, r, &body); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if body.On == nil {
+		writeError(w, http.StatusBadRequest, errors.New("on must be set"))
+		return
+	}
+
+	state, err := s.setStep(instrument, step, *body.On)
+	if err != nil {
+		writeError(w, status(err), err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, state)
+}
+
+func (s *Server) getTransport(w http.ResponseWriter) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, s.transportState())
+}
+
+func (s *Server) postPlay(w http.ResponseWriter) {
+	state, err := s.play()
+	if err != nil {
+		writeError(w, status(err), err)
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, state)
+}
+
+func (s *Server) postStop(w http.ResponseWriter) {
+	writeJSON(w, http.StatusOK, s.stopPlaying())
+}
+
+// transportState returns where playback is. The server must be locked.
+func (s *Server) transportState() transportState {
+	state := s.transport.state()
+	state.Playing = s.playing()
+	state.TrackID = s.trackID
+
+	if s.run != nil && !state.Playing && s.run.err != nil && !errors.Is(s.run.err, context.Canceled) {
+		state.Error = s.run.err.Error()
+	}
+
+	return state
+}
+
+// trackState is the loaded track, as returned by the API.
+type trackState struct {
+	ID               string            `json:"id"`
+	Title            string            `json:"title"`
+	BeatsPerMinute   float64           `json:"bpm"`
+	BeatsPerMeasure  int               `json:"beats_per_measure"`
+	DivisionsPerBeat int               `json:"divisions_per_beat"`
+	Length           lengthJSON        `json:"length"`
+	Instruments      []instrumentState `json:"instruments"`
+}
+
+type instrumentState struct {
+	Name string `json:"name"`
+	// Volume from 0 to 100
+	Volume float64 `json:"volume"`
+	Muted  bool    `json:"muted"`
+	// Whether the instrument's audio is played
+	Audio bool `json:"audio"`
+	// Note the instrument is sent as, or nil if it has none
+	MIDI *midiState `json:"midi,omitempty"`
+	// Beat subdivisions of the bar the instrument is triggered at, counting
+	// from 0
+	Pattern []int `json:"pattern"`
+}
+
+type midiState struct {
+	Channel int `json:"channel"`
+	Note    int `json:"note"`
+	// Whether the note is sent
+	On bool `json:"on"`
+}
+
+// trackState returns the loaded track. The server must be locked, and a track
+// loaded.
+func (s *Server) trackState() trackState {
+	var state trackState
+
+	// nolint: errcheck
+	s.track.Update(func(track *models.Track) error {
+		state = trackState{
+			ID:               s.trackID,
+			Title:            track.Title,
+			BeatsPerMinute:   track.BeatsPerMinute,
+			BeatsPerMeasure:  track.BeatsPerMeasure,
+			DivisionsPerBeat: track.DivisionsPerBeat,
+			Length:           toLengthJSON(track.Length),
+			Instruments:      make([]instrumentState, len(track.Instruments)),
+		}
+
+		for i, instrument := range track.Instruments {
+			state.Instruments[i] = instrumentState{
+				Name:    instrument.Name,
+				Volume:  instrument.Audio.GetVolume(),
+				Muted:   instrument.Muted,
+				Audio:   !instrument.AudioOff,
+				Pattern: append([]int{}, instrument.Pattern...),
+			}
+
+			if instrument.MIDI != nil {
+				state.Instruments[i].MIDI = &midiState{
+					Channel: instrument.MIDI.Channel,
+					Note:    instrument.MIDI.Note,
+					On:      !instrument.MIDIOff,
+				}
+			}
+		}
+
+		return nil
+	})
+
+	return state
+}
+
+// lengthJSON is a track length, as read and returned by the API.
+type lengthJSON struct {
+	// One of bars, duration or infinite
+	Unit string `json:"unit"`
+	// Number of bars
+	Count int `json:"count,omitempty"`
+	// Minimum time to play for, for the duration unit
+	Seconds float64 `json:"seconds,omitempty"`
+}
+
+func toLengthJSON(length models.Length) lengthJSON {
+	return lengthJSON{
+		Unit:    string(length.Unit),
+		Count:   length.Count,
+		Seconds: length.Duration.Seconds(),
+	}
+}
+
+// length returns the length described. Returns an error if it's invalid.
+func (l lengthJSON) length() (models.Length, error) {
+	length := models.Length{
+		Unit:     models.LengthUnit(l.Unit),
+		Count:    l.Count,
+		Duration: time.Duration(l.Seconds * float64(time.Second)),
+	}
+
+	if err := length.Validate(); err != nil {
+		return models.Length{}, err
+	}
+
+	return length, nil
+}
+
+// allow reports whether the request uses one of the methods, responding that
+// the method isn't allowed if not.
+func allow(w http.ResponseWriter, r *http.Request, methods ...string) bool {
+	for _, method := range methods {
+		if r.Method == method {
+			return true
+		}
+	}
+
+	w.Header().Set("Allow", strings.Join(methods, ", "))
+	writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+
+	return false
+}
+
+// sameOrigin returns whether the request comes from a page served by the
+// server, or has no origin, as with clients other than browsers. Like the
+// event stream's check, the origin's host must match the request's.
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+
+	return strings.EqualFold(u.Host, r.Host)
+}
+
+// readJSON decodes the request's body into v, rejecting unknown fields.
+func readJSON(w http.ResponseWriter, r *http.Request, v interface{}) error {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodySize))
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(v); err != nil {
+		return errors.Wrap(err, "error reading request body")
+	}
+
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	// nolint: errcheck
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, struct {
+		Error string `json:"error"`
+	}{err.Error()})
+}
@@ -0,0 +1,514 @@
+package server_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/faiface/beep"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jcfox412/logarhythms/internal/audio"
+	"github.com/jcfox412/logarhythms/internal/clock"
+	"github.com/jcfox412/logarhythms/internal/events"
+	"github.com/jcfox412/logarhythms/internal/midi"
+	"github.com/jcfox412/logarhythms/internal/server"
+	_ "github.com/jcfox412/logarhythms/testing"
+)
+
+const library = "internal/server/testfiles"
+
+// unplayed is an audio backend which never pulls the mix, so that hits the
+// manual clock plays at once, which would sum past full scale, can't clip it.
+type unplayed struct{}
+
+func (unplayed) Start(beep.Streamer, beep.SampleRate) error { return nil }
+
+func (unplayed) Close() error { return nil }
+
+func TestMain(m *testing.M) {
+	if err := audio.SetBackend(unplayed{}); err != nil {
+		panic(err)
+	}
+
+	os.Exit(m.Run())
+}
+
+// request sends a request to the server, returning the response.
+func request(s *server.Server, method, path, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(method, path, strings.NewReader(body)))
+
+	return w
+}
+
+func TestServer(t *testing.T) {
+	type testCase struct {
+		description    string
+		method         string
+		path           string
+		body           string
+		expectedStatus int
+		expectedBody   string
+	}
+
+	// each request is sent in turn to the same server
+	testCases := []testCase{
+		{
+			description:    "Lists the library's tracks",
+			method:         http.MethodGet,
+			path:           "/tracks",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `[{"id":"another_beat","title":"Another Beat"},{"id":"synth_beat","title":"Synth Beat"}]`,
+		},
+		{
+			description:    "Has no track before one is loaded",
+			method:         http.MethodGet,
+			path:           "/track",
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"error":"no track loaded"}`,
+		},
+		{
+			description:    "Can't play before a track is loaded",
+			method:         http.MethodPost,
+			path:           "/transport/play",
+			expectedStatus: http.StatusConflict,
+			expectedBody:   `{"error":"no track loaded"}`,
+		},
+		{
+			description:    "Errors loading a track not in the library",
+			method:         http.MethodPut,
+			path:           "/track",
+			body:           `{"id":"missing_beat"}`,
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"error":"no track with ID \"missing_beat\""}`,
+		},
+		{
+			description:    "Errors loading a track outside of the library",
+			method:         http.MethodPut,
+			path:           "/track",
+			body:           `{"id":"../testfiles/synth_beat"}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"invalid track ID \"../testfiles/synth_beat\""}`,
+		},
+		{
+			description:    "Loads a track",
+			method:         http.MethodPut,
+			path:           "/track",
+			body:           `{"id":"synth_beat"}`,
+			expectedStatus: http.StatusOK,
+			expectedBody: `{"id":"synth_beat","title":"Synth Beat","bpm":60,"beats_per_measure":2,"divisions_per_beat":2,` +
+				`"length":{"unit":"duration","seconds":10},"instruments":[{"name":"Kick","volume":50,"muted":false,"audio":true,"pattern":[0,2]},{"name":"Snare","volume":50,"muted":false,"audio":true,"pattern":[1,3]}]}`,
+		},
+		{
+			description:    "Changes the track's BPM to a fractional tempo",
+			method:         http.MethodPatch,
+			path:           "/track",
+			body:           `{"bpm":92.5}`,
+			expectedStatus: http.StatusOK,
+			expectedBody: `{"id":"synth_beat","title":"Synth Beat","bpm":92.5,"beats_per_measure":2,"divisions_per_beat":2,` +
+				`"length":{"unit":"duration","seconds":10},"instruments":[{"name":"Kick","volume":50,"muted":false,"audio":true,"pattern":[0,2]},{"name":"Snare","volume":50,"muted":false,"audio":true,"pattern":[1,3]}]}`,
+		},
+		{
+			description:    "Changes the track's BPM and length",
+			method:         http.MethodPatch,
+			path:           "/track",
+			body:           `{"bpm":120,"length":{"unit":"bars","count":4}}`,
+			expectedStatus: http.StatusOK,
+			expectedBody: `{"id":"synth_beat","title":"Synth Beat","bpm":120,"beats_per_measure":2,"divisions_per_beat":2,` +
+				`"length":{"unit":"bars","count":4},"instruments":[{"name":"Kick","volume":50,"muted":false,"audio":true,"pattern":[0,2]},{"name":"Snare","volume":50,"muted":false,"audio":true,"pattern":[1,3]}]}`,
+		},
+		{
+			description:    "Rejects an invalid BPM",
+			method:         http.MethodPatch,
+			path:           "/track",
+			body:           `{"bpm":0}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"bpm must be between 1 and 1000"}`,
+		},
+		{
+			description:    "Rejects an invalid length, leaving the BPM alone",
+			method:         http.MethodPatch,
+			path:           "/track",
+			body:           `{"bpm":90,"length":{"unit":"weeks","count":1}}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"unknown track length unit \"weeks\""}`,
+		},
+		{
+			description:    "Rejects unknown settings",
+			method:         http.MethodPatch,
+			path:           "/track",
+			body:           `{"tempo":90}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"error reading request body: json: unknown field \"tempo\""}`,
+		},
+		{
+			description:    "Changes an instrument's volume",
+			method:         http.MethodPatch,
+			path:           "/track/instruments/1",
+			body:           `{"volume":80}`,
+			expectedStatus: http.StatusOK,
+			expectedBody: `{"id":"synth_beat","title":"Synth Beat","bpm":120,"beats_per_measure":2,"divisions_per_beat":2,` +
+				`"length":{"unit":"bars","count":4},"instruments":[{"name":"Kick","volume":50,"muted":false,"audio":true,"pattern":[0,2]},{"name":"Snare","volume":80,"muted":false,"audio":true,"pattern":[1,3]}]}`,
+		},
+		{
+			description:    "Rejects an invalid volume",
+			method:         http.MethodPatch,
+			path:           "/track/instruments/0",
+			body:           `{"volume":101}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"volume must be between 0 and 100"}`,
+		},
+		{
+			description:    "Errors changing an instrument the track doesn't have",
+			method:         http.MethodPatch,
+			path:           "/track/instruments/2",
+			body:           `{"volume":50}`,
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"error":"no instrument at index \"2\""}`,
+		},
+		{
+			description:    "Mutes an instrument by name",
+			method:         http.MethodPatch,
+			path:           "/track/instruments/SNARE",
+			body:           `{"muted":true}`,
+			expectedStatus: http.StatusOK,
+			expectedBody: `{"id":"synth_beat","title":"Synth Beat","bpm":120,"beats_per_measure":2,"divisions_per_beat":2,` +
+				`"length":{"unit":"bars","count":4},"instruments":[{"name":"Kick","volume":50,"muted":false,"audio":true,"pattern":[0,2]},` +
+				`{"name":"Snare","volume":80,"muted":true,"audio":true,"pattern":[1,3]}]}`,
+		},
+		{
+			description:    "Errors changing an instrument by a name the track doesn't have",
+			method:         http.MethodPatch,
+			path:           "/track/instruments/cowbell",
+			body:           `{"muted":true}`,
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"error":"no instrument named \"cowbell\""}`,
+		},
+		{
+			description:    "Turns an instrument's audio off",
+			method:         http.MethodPatch,
+			path:           "/track/instruments/1",
+			body:           `{"audio":false}`,
+			expectedStatus: http.StatusOK,
+			expectedBody: `{"id":"synth_beat","title":"Synth Beat","bpm":120,"beats_per_measure":2,"divisions_per_beat":2,` +
+				`"length":{"unit":"bars","count":4},"instruments":[{"name":"Kick","volume":50,"muted":false,"audio":true,"pattern":[0,2]},` +
+				`{"name":"Snare","volume":80,"muted":true,"audio":false,"pattern":[1,3]}]}`,
+		},
+		{
+			description:    "Errors turning MIDI on for an instrument without a note",
+			method:         http.MethodPatch,
+			path:           "/track/instruments/1",
+			body:           `{"midi":true,"volume":10}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"instrument has no MIDI note"}`,
+		},
+		{
+			description:    "Turns a step on",
+			method:         http.MethodPut,
+			path:           "/track/instruments/0/steps/1",
+			body:           `{"on":true}`,
+			expectedStatus: http.StatusOK,
+			expectedBody: `{"id":"synth_beat","title":"Synth Beat","bpm":120,"beats_per_measure":2,"divisions_per_beat":2,` +
+				`"length":{"unit":"bars","count":4},"instruments":[{"name":"Kick","volume":50,"muted":false,"audio":true,"pattern":[0,1,2]},{"name":"Snare","volume":80,"muted":true,"audio":false,"pattern":[1,3]}]}`,
+		},
+		{
+			description:    "Turns a step off",
+			method:         http.MethodPut,
+			path:           "/track/instruments/1/steps/3",
+			body:           `{"on":false}`,
+			expectedStatus: http.StatusOK,
+			expectedBody: `{"id":"synth_beat","title":"Synth Beat","bpm":120,"beats_per_measure":2,"divisions_per_beat":2,` +
+				`"length":{"unit":"bars","count":4},"instruments":[{"name":"Kick","volume":50,"muted":false,"audio":true,"pattern":[0,1,2]},{"name":"Snare","volume":80,"muted":true,"audio":false,"pattern":[1]}]}`,
+		},
+		{
+			description:    "Errors setting a step outside of the bar",
+			method:         http.MethodPut,
+			path:           "/track/instruments/1/steps/4",
+			body:           `{"on":true}`,
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"error":"step 4 is outside of a 4 subdivision measure"}`,
+		},
+		{
+			description:    "Errors setting a step without saying whether it's on",
+			method:         http.MethodPut,
+			path:           "/track/instruments/1/steps/0",
+			body:           `{}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"on must be set"}`,
+		},
+		{
+			description:    "Has nothing below an instrument's steps",
+			method:         http.MethodPut,
+			path:           "/track/instruments/1/steps",
+			body:           `{"on":true}`,
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"error":"no endpoint at /track/instruments/1/steps"}`,
+		},
+		{
+			description:    "Isn't playing once loaded",
+			method:         http.MethodGet,
+			path:           "/transport",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"playing":false,"track_id":"synth_beat","elapsed_ms":0,"left_ms":null,"clipped":false}`,
+		},
+		{
+			description:    "Rejects methods an endpoint doesn't allow",
+			method:         http.MethodDelete,
+			path:           "/track",
+			expectedStatus: http.StatusMethodNotAllowed,
+			expectedBody:   `{"error":"method DELETE not allowed"}`,
+		},
+		{
+			description:    "Has nothing at unknown paths",
+			method:         http.MethodGet,
+			path:           "/instruments",
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"error":"no endpoint at /instruments"}`,
+		},
+	}
+
+	s := server.New(library, "")
+	defer s.Close()
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		w := request(s, testCase.method, testCase.path, testCase.body)
+		assert.Equal(t, testCase.expectedStatus, w.Code, testCase.description)
+		assert.JSONEq(t, testCase.expectedBody, w.Body.String(), testCase.description)
+		assert.Equal(t, "application/json", w.Header().Get("Content-Type"), testCase.description)
+	}
+}
+
+func TestServerOrigin(t *testing.T) {
+	type testCase struct {
+		description    string
+		method         string
+		path           string
+		body           string
+		origin         string
+		expectedStatus int
+	}
+
+	testCases := []testCase{
+		{
+			description:    "Allows changes without an origin, as from curl",
+			method:         http.MethodPut,
+			path:           "/track",
+			body:           `{"id":"missing_beat"}`,
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			description:    "Allows changes from the web UI",
+			method:         http.MethodPut,
+			path:           "/track",
+			body:           `{"id":"missing_beat"}`,
+			origin:         "http://example.com",
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			description:    "Refuses changes from another origin",
+			method:         http.MethodPut,
+			path:           "/track",
+			body:           `{"id":"missing_beat"}`,
+			origin:         "http://elsewhere.com",
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			description:    "Refuses playback from another origin",
+			method:         http.MethodPost,
+			path:           "/transport/play",
+			origin:         "http://example.com:8080",
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			description:    "Allows reads from another origin",
+			method:         http.MethodGet,
+			path:           "/tracks",
+			origin:         "http://elsewhere.com",
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	s := server.New(library, "")
+	defer s.Close()
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		r := httptest.NewRequest(testCase.method, testCase.path, strings.NewReader(testCase.body))
+		if testCase.origin != "" {
+			r.Header.Set("Origin", testCase.origin)
+		}
+
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, r)
+
+		assert.Equal(t, testCase.expectedStatus, w.Code, testCase.description)
+	}
+}
+
+func TestServerWebUI(t *testing.T) {
+	s := server.New(library, "")
+	defer s.Close()
+
+	w := request(s, http.MethodGet, "/", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/html; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Body.String(), "<title>LogaRhythms</title>")
+}
+
+func TestServerPlayback(t *testing.T) {
+	c := clock.NewManual(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	s := server.New(library, "")
+	s.Clock = c
+	defer s.Close()
+
+	assert.Equal(t, http.StatusOK, request(s, http.MethodPut, "/track", `{"id":"synth_beat"}`).Code)
+	assert.Equal(t, http.StatusOK, request(s, http.MethodPatch, "/track", `{"length":{"unit":"bars","count":2}}`).Code)
+
+	w := request(s, http.MethodPost, "/transport/play", "")
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	assert.JSONEq(t, `{"playing":true,"track_id":"synth_beat","elapsed_ms":0,"left_ms":null,"clipped":false}`, w.Body.String())
+
+	w = request(s, http.MethodPost, "/transport/play", "")
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.JSONEq(t, `{"error":"track is already playing"}`, w.Body.String())
+
+	// a beat subdivision lasts half a second at 60 BPM
+	c.BlockUntil(1)
+	c.Advance(200 * time.Millisecond)
+	c.BlockUntil(1)
+	c.Advance(500 * time.Millisecond)
+	c.Advance(500 * time.Millisecond)
+	c.Advance(500 * time.Millisecond)
+
+	w = request(s, http.MethodGet, "/transport", "")
+	assert.JSONEq(t, `{"playing":true,"track_id":"synth_beat","bpm":60,"bar":1,"beat":2,"step":1,`+
+		`"elapsed_ms":1000,"left_ms":3000,"clipped":false}`, w.Body.String())
+
+	w = request(s, http.MethodPost, "/transport/stop", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"playing":false,"track_id":"synth_beat","bpm":60,"bar":1,"beat":2,"step":1,`+
+		`"elapsed_ms":1000,"left_ms":3000,"clipped":false}`, w.Body.String())
+
+	// played again, the track plays to its end
+	assert.Equal(t, http.StatusAccepted, request(s, http.MethodPost, "/transport/play", "").Code)
+
+	c.BlockUntil(1)
+	c.Advance(200 * time.Millisecond)
+	c.BlockUntil(1)
+
+	// the last beat subdivision is given its full length before the track ends
+	for i := 0; i < 9; i++ {
+		c.Advance(500 * time.Millisecond)
+	}
+
+	// the track finishes in the background
+	w = request(s, http.MethodGet, "/transport", "")
+	for strings.Contains(w.Body.String(), `"playing":true`) {
+		w = request(s, http.MethodGet, "/transport", "")
+	}
+
+	assert.JSONEq(t, `{"playing":false,"track_id":"synth_beat","bpm":60,"bar":2,"beat":2,"step":2,`+
+		`"elapsed_ms":3500,"left_ms":500,"clipped":false}`, w.Body.String())
+}
+
+func TestServerEvents(t *testing.T) {
+	c := clock.NewManual(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	s := server.New(library, "")
+	s.Clock = c
+
+	subscription, unsubscribe := s.Events.Subscribe()
+	defer unsubscribe()
+
+	assert.Equal(t, http.StatusOK, request(s, http.MethodPut, "/track", `{"id":"synth_beat"}`).Code)
+	assert.Equal(t, http.StatusAccepted, request(s, http.MethodPost, "/transport/play", "").Code)
+
+	c.BlockUntil(1)
+	c.Advance(200 * time.Millisecond)
+	c.BlockUntil(1)
+	c.Advance(500 * time.Millisecond)
+
+	assert.Equal(t, http.StatusOK, request(s, http.MethodPost, "/transport/stop", "").Code)
+
+	// closing the server ends the stream
+	assert.Nil(t, s.Close())
+
+	var types []string
+	for event := range subscription {
+		types = append(types, event.Type)
+	}
+
+	assert.Equal(t, []string{events.Changed, events.Start, events.Tempo, events.Bar, events.Step, events.Hit, events.Stop}, types)
+
+	w := request(s, http.MethodGet, "/events", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code, "Requires a WebSocket upgrade")
+}
+
+func TestServerMIDI(t *testing.T) {
+	c := clock.NewManual(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	port := midi.NewMemory()
+
+	s := server.New(library, "")
+	s.Clock = c
+	s.MIDI = port
+	defer s.Close()
+
+	w := request(s, http.MethodPut, "/track", `{"id":"another_beat"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `{"name":"HiHat","volume":50,"muted":false,"audio":false,"midi":{"channel":10,"note":42,"on":true}`)
+
+	w = request(s, http.MethodPatch, "/track/instruments/hihat", `{"midi":false}`)
+	assert.Contains(t, w.Body.String(), `"midi":{"channel":10,"note":42,"on":false}`)
+
+	request(s, http.MethodPatch, "/track/instruments/hihat", `{"midi":true}`)
+	assert.Equal(t, http.StatusAccepted, request(s, http.MethodPost, "/transport/play", "").Code)
+
+	c.BlockUntil(1)
+	c.Advance(200 * time.Millisecond)
+	c.BlockUntil(1)
+
+	// a note is sent each beat at 90 BPM, and released halfway through it
+	c.Advance(1500 * time.Millisecond)
+
+	assert.Equal(t, http.StatusOK, request(s, http.MethodPost, "/transport/stop", "").Code)
+	assert.Equal(t, [][]byte{{0x99, 42, 127}, {0x89, 42, 0}, {0x99, 42, 127}}, port.Sent())
+}
+
+func TestServerConcurrentRequests(t *testing.T) {
+	s := server.New(library, "")
+	defer s.Close()
+
+	assert.Equal(t, http.StatusOK, request(s, http.MethodPut, "/track", `{"id":"synth_beat"}`).Code)
+	assert.Equal(t, http.StatusOK, request(s, http.MethodPatch, "/track", `{"bpm":1000,"length":{"unit":"bars","count":1}}`).Code)
+
+	var wait sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wait.Add(1)
+
+		go func() {
+			defer wait.Done()
+
+			request(s, http.MethodPost, "/transport/play", "")
+			request(s, http.MethodPatch, "/track/instruments/0", `{"volume":60}`)
+			request(s, http.MethodPatch, "/track", `{"bpm":900}`)
+			request(s, http.MethodGet, "/transport", "")
+			request(s, http.MethodGet, "/track", "")
+			request(s, http.MethodPost, "/transport/stop", "")
+		}()
+	}
+
+	wait.Wait()
+
+	w := request(s, http.MethodGet, "/transport", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"playing":false`)
+}
@@ -0,0 +1,127 @@
+package server
+
+import (
+	"sync"
+	"time"
+
+	"github.com/jcfox412/logarhythms/internal/render"
+)
+
+// transport follows a track as it plays, by being the renderer it's drawn by.
+type transport struct {
+	mu      sync.Mutex
+	track   render.Track
+	step    *render.Step
+	clipped bool
+	// Index of the first step played at the current tempo, and the time
+	// played before it
+	retimedAt int
+	retimed   time.Duration
+}
+
+var _ render.Renderer = new(transport)
+
+func (t *transport) Start(track render.Track) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.track = track
+	t.step = nil
+	t.clipped = false
+	t.retimedAt = 0
+	t.retimed = 0
+}
+
+func (t *transport) Step(step render.Step) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.step = &step
+}
+
+func (t *transport) Clip() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.clipped = true
+}
+
+func (t *transport) Retime(beatsPerMinute float64, stepDuration time.Duration, totalSteps int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	next := 0
+	if t.step != nil {
+		next = t.step.Index + 1
+	}
+
+	t.retimed = t.elapsed(next)
+	t.retimedAt = next
+	t.track.BeatsPerMinute = beatsPerMinute
+	t.track.StepDuration = stepDuration
+	t.track.TotalSteps = totalSteps
+}
+
+func (t *transport) Stop() {}
+
+// transportState is where playback is, as returned by the API.
+type transportState struct {
+	Playing bool   `json:"playing"`
+	TrackID string `json:"track_id,omitempty"`
+	// BPM the track is playing at, which follows a change to the track's BPM
+	// from the next beat subdivision played
+	BeatsPerMinute float64 `json:"bpm,omitempty"`
+	// Position of the playing beat subdivision, each counting from 1
+	Bar  int `json:"bar,omitempty"`
+	Beat int `json:"beat,omitempty"`
+	Step int `json:"step,omitempty"`
+	// Time played, and left to play or nil if playing until stopped
+	ElapsedMilliseconds int64  `json:"elapsed_ms"`
+	LeftMilliseconds    *int64 `json:"left_ms"`
+	Clipped             bool   `json:"clipped"`
+	// Why playback last stopped early, if it failed
+	Error string `json:"error,omitempty"`
+}
+
+// state returns the position of the last step played. Position fields are
+// left empty before the first step.
+func (t *transport) state() transportState {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	state := transportState{
+		BeatsPerMinute: t.track.BeatsPerMinute,
+		Clipped:        t.clipped,
+	}
+
+	if t.step == nil {
+		return state
+	}
+
+	divisionsPerBeat := t.track.DivisionsPerBeat
+	if divisionsPerBeat <= 0 {
+		divisionsPerBeat = 1
+	}
+
+	state.Bar = t.step.Bar
+	state.Beat = t.step.Division/divisionsPerBeat + 1
+	state.Step = t.step.Division%divisionsPerBeat + 1
+	state.ElapsedMilliseconds = milliseconds(t.elapsed(t.step.Index))
+
+	if t.track.TotalSteps > 0 {
+		left := milliseconds(time.Duration(t.track.TotalSteps-t.step.Index) * t.track.StepDuration)
+		state.LeftMilliseconds = &left
+	}
+
+	return state
+}
+
+// elapsed returns the time played before the step at index, at the current
+// tempo since it last changed.
+func (t *transport) elapsed(index int) time.Duration {
+	return t.retimed + time.Duration(index-t.retimedAt)*t.track.StepDuration
+}
+
+func milliseconds(d time.Duration) int64 {
+	return int64(d / time.Millisecond)
+}
@@ -0,0 +1,18 @@
+package server
+
+import (
+	// embeds the web UI
+	_ "embed"
+	"net/http"
+)
+
+// index is the web UI, a single page controlling the server through its API.
+//
+//go:embed web/index.html
+var index []byte
+
+func serveIndex(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(index)
+}
@@ -2,6 +2,7 @@ package utils
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/pkg/errors"
 )
@@ -11,6 +12,15 @@ const (
 	cursorAbsoluteLeft = "\033[G"
 	setBold            = "\033[1m"
 	setUnbold          = "\033[0m"
+	saveCursor         = "\0337"
+	restoreCursor      = "\0338"
+	hideCursor         = "\033[?25l"
+	showCursor         = "\033[?25h"
+	enterAltScreen     = "\033[?1049h"
+	leaveAltScreen     = "\033[?1049l"
+	clearScreen        = "\033[2J"
+	clearToEnd         = "\033[J"
+	cursorHome         = "\033[H"
 )
 
 // BeatCount returns a string representation of whole-beat increments at the top
@@ -81,6 +91,94 @@ func ClearLine(headerWidth int) string {
 	return out
 }
 
+// Indicator returns an ANSI-enabled string for printing text at the given
+// column of the row rowsUp rows above the cursor, leaving the cursor where it
+// was.
+func Indicator(text string, rowsUp, column int) string {
+	out := ""
+	out += fmt.Sprint(saveCursor)
+	out += fmt.Sprint(cursorUp(rowsUp))
+	out += fmt.Sprint(cursorAbsoluteLeft)
+	out += fmt.Sprint(cursorRight(column))
+	out += text
+	out += fmt.Sprint(restoreCursor)
+
+	return out
+}
+
+// ReplaceLine returns an ANSI-enabled string for printing text over the row
+// rowsUp rows above the cursor, clearing the rest of the row, and leaving the
+// cursor where it was.
+func ReplaceLine(text string, rowsUp int) string {
+	out := ""
+	out += fmt.Sprint(saveCursor)
+	out += fmt.Sprint(cursorUp(rowsUp))
+	out += fmt.Sprint(cursorAbsoluteLeft)
+	out += text
+	out += fmt.Sprint(clearLine)
+	out += fmt.Sprint(restoreCursor)
+
+	return out
+}
+
+// HideCursor returns an ANSI-enabled string for hiding the cursor while a
+// track plays.
+func HideCursor() string {
+	return hideCursor
+}
+
+// ShowCursor returns an ANSI-enabled string for showing the cursor again.
+func ShowCursor() string {
+	return showCursor
+}
+
+// EnterFullScreen returns an ANSI-enabled string for switching to the
+// terminal's alternate screen and clearing it, leaving the shell's scrollback
+// untouched.
+func EnterFullScreen() string {
+	return enterAltScreen + ClearScreen()
+}
+
+// ExitFullScreen returns an ANSI-enabled string for switching back from the
+// alternate screen.
+func ExitFullScreen() string {
+	return leaveAltScreen
+}
+
+// ClearScreen returns an ANSI-enabled string for clearing the screen and
+// moving the cursor to its top left.
+func ClearScreen() string {
+	return clearScreen + cursorHome
+}
+
+// CursorHome returns an ANSI-enabled string for moving the cursor to the top
+// left of the screen.
+func CursorHome() string {
+	return cursorHome
+}
+
+// ClearToEndOfLine returns an ANSI-enabled string for clearing the rest of the
+// cursor's line.
+func ClearToEndOfLine() string {
+	return clearLine
+}
+
+// ClearToEndOfScreen returns an ANSI-enabled string for clearing everything
+// after the cursor.
+func ClearToEndOfScreen() string {
+	return clearToEnd
+}
+
+// Style returns an ANSI-supported styling of the input text, using the given
+// SGR codes (e.g. "1" for bold, "31" for red).
+func Style(text string, codes ...string) string {
+	if len(codes) == 0 {
+		return text
+	}
+
+	return fmt.Sprintf("\033[%sm%s%s", strings.Join(codes, ";"), text, setUnbold)
+}
+
 func cursorUp(spaces int) string {
 	return moveCursor(spaces, "A")
 }
@@ -173,3 +173,89 @@ func TestClearLine(t *testing.T) {
 		assert.Equal(t, testCase.expectedOutput, actualOutput)
 	}
 }
+
+func TestIndicator(t *testing.T) {
+	type input struct {
+		text   string
+		rowsUp int
+		column int
+	}
+
+	type testCase struct {
+		description    string
+		input          input
+		expectedOutput string
+	}
+
+	testCases := []testCase{
+		{
+			description:    "Succeeds",
+			input:          input{text: "CLIP", rowsUp: 4, column: 25},
+			expectedOutput: "\x1b7\x1b[4A\x1b[G\x1b[25CCLIP\x1b8",
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		actualOutput := utils.Indicator(testCase.input.text, testCase.input.rowsUp, testCase.input.column)
+		assert.Equal(t, testCase.expectedOutput, actualOutput)
+	}
+}
+
+func TestReplaceLine(t *testing.T) {
+	assert.Equal(t, "\x1b7\x1b[3A\x1b[G120 BPM\x1b[K\x1b8", utils.ReplaceLine("120 BPM", 3))
+}
+
+func TestHideCursor(t *testing.T) {
+	assert.Equal(t, "\x1b[?25l", utils.HideCursor())
+}
+
+func TestShowCursor(t *testing.T) {
+	assert.Equal(t, "\x1b[?25h", utils.ShowCursor())
+}
+
+func TestEnterFullScreen(t *testing.T) {
+	assert.Equal(t, "\x1b[?1049h\x1b[2J\x1b[H", utils.EnterFullScreen())
+}
+
+func TestExitFullScreen(t *testing.T) {
+	assert.Equal(t, "\x1b[?1049l", utils.ExitFullScreen())
+}
+
+func TestStyle(t *testing.T) {
+	type input struct {
+		text  string
+		codes []string
+	}
+
+	type testCase struct {
+		description    string
+		input          input
+		expectedOutput string
+	}
+
+	testCases := []testCase{
+		{
+			description:    "Leaves text without codes alone",
+			input:          input{text: "X"},
+			expectedOutput: "X",
+		},
+		{
+			description:    "Styles with one code",
+			input:          input{text: "X", codes: []string{"31"}},
+			expectedOutput: "\x1b[31mX\x1b[0m",
+		},
+		{
+			description:    "Styles with several codes",
+			input:          input{text: "X", codes: []string{"1", "31"}},
+			expectedOutput: "\x1b[1;31mX\x1b[0m",
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		assert.Equal(t, testCase.expectedOutput, utils.Style(testCase.input.text, testCase.input.codes...), testCase.description)
+	}
+}
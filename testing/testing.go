@@ -4,6 +4,8 @@ import (
 	"os"
 	"path"
 	"runtime"
+
+	"github.com/jcfox412/logarhythms/internal/audio"
 )
 
 // This function allows all tests to be run from the root of the project,
@@ -16,3 +18,16 @@ func init() {
 		panic(err)
 	}
 }
+
+// This function plays all tests' audio through the null backend, so that tests
+// can be run on machines without a sound card.
+func init() {
+	backend, err := audio.NewBackend(audio.NullBackend, "")
+	if err != nil {
+		panic(err)
+	}
+
+	if err := audio.SetBackend(backend); err != nil {
+		panic(err)
+	}
+}